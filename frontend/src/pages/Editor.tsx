@@ -7,7 +7,8 @@ import { FileTree } from '@/components/FileTree';
 import { MonacoEditor } from '@/components/MonacoEditor';
 import { PDFViewer } from '@/components/PDFViewer';
 import { CompilationPanel } from '@/components/CompilationPanel';
-import type { Project, FileItem } from '@/types';
+import axios from 'axios';
+import type { Project, FileItem, FileConflict } from '@/types';
 
 export const Editor: React.FC = () => {
   const { projectId } = useParams<{ projectId: string }>();
@@ -21,6 +22,7 @@ export const Editor: React.FC = () => {
   const [isSaving, setIsSaving] = useState(false);
   const [showSettings, setShowSettings] = useState(false);
   const [fileContent, setFileContent] = useState<Map<string, string>>(new Map());
+  const fileEtagsRef = useRef<Map<string, string>>(new Map());
   const autoSaveTimeoutRef = useRef<NodeJS.Timeout | null>(null);
 
   useEffect(() => {
@@ -96,7 +98,8 @@ export const Editor: React.FC = () => {
     try {
       // Check if content is already loaded
       if (!fileContent.has(file.id)) {
-        const content = await api.getFileContent(projectId, file.id);
+        const { content, etag } = await api.getFileContent(projectId, file.id);
+        fileEtagsRef.current.set(file.id, etag);
         setFileContent((prev) => new Map(prev).set(file.id, content));
       }
 
@@ -109,13 +112,44 @@ export const Editor: React.FC = () => {
   const handleFileSave = async (content: string) => {
     if (!projectId || !currentFile) return;
 
+    const fileId = currentFile.id;
+    const save = async (text: string, etag: string) => {
+      const result = await api.updateFile(projectId, fileId, { content: text }, etag);
+      fileEtagsRef.current.set(fileId, result.etag);
+      setFileContent((prev) => new Map(prev).set(fileId, text));
+      setDirty(false);
+    };
+
     try {
       setIsSaving(true);
-      await api.updateFile(projectId, currentFile.id, { content });
-      setFileContent((prev) => new Map(prev).set(currentFile.id, content));
-      setDirty(false);
+      let etag = fileEtagsRef.current.get(fileId);
+      if (!etag) {
+        // A save must name the version it replaces. Files created here were
+        // never read, so read the saved version first.
+        etag = (await api.getFileContent(projectId, fileId)).etag;
+        if (!etag) {
+          throw new Error('File version is unknown; not saving');
+        }
+        fileEtagsRef.current.set(fileId, etag);
+      }
+      await save(content, etag);
     } catch (err) {
-      console.error('Failed to save file:', err);
+      // Someone else saved the file first. Keep both edits when they merge
+      // cleanly, otherwise leave the file dirty so nothing is lost.
+      if (axios.isAxiosError(err) && err.response?.status === 409) {
+        const conflict = err.response.data as FileConflict;
+        if (conflict.merged_content !== undefined) {
+          try {
+            await save(conflict.merged_content, conflict.etag);
+          } catch (mergeErr) {
+            console.error('Failed to save merged file:', mergeErr);
+          }
+        } else {
+          console.error('File was changed by someone else; changes overlap');
+        }
+      } else {
+        console.error('Failed to save file:', err);
+      }
     } finally {
       setIsSaving(false);
     }
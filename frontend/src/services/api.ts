@@ -10,6 +10,7 @@ import type {
   FileItem,
   CreateFileRequest,
   UpdateFileRequest,
+  FileContent,
   Compilation,
   CompileRequest,
   CompilationStats,
@@ -177,23 +178,33 @@ class APIClient {
     return response.data;
   }
 
-  async updateFile(projectId: string, fileId: string, data: UpdateFileRequest): Promise<FileItem> {
-    const response = await this.client.put<FileItem>(
+  // Saves a file whose content was read with the given ETag. A stale ETag is
+  // rejected with a 409 carrying a FileConflict.
+  async updateFile(
+    projectId: string,
+    fileId: string,
+    data: UpdateFileRequest,
+    etag: string
+  ): Promise<{ file: FileItem; etag: string }> {
+    const response = await this.client.put<FileItem | { file: FileItem }>(
       `/api/v1/projects/${projectId}/files/${fileId}`,
-      data
+      data,
+      { headers: { 'If-Match': etag } }
     );
-    return response.data;
+    // 202: the project tracks changes and the edit was recorded as suggestions
+    const file = response.status === 202 ? (response.data as { file: FileItem }).file : (response.data as FileItem);
+    return { file, etag: response.headers['etag'] };
   }
 
   async deleteFile(projectId: string, fileId: string): Promise<void> {
     await this.client.delete(`/api/v1/projects/${projectId}/files/${fileId}`);
   }
 
-  async getFileContent(projectId: string, fileId: string): Promise<string> {
+  async getFileContent(projectId: string, fileId: string): Promise<FileContent> {
     const response = await this.client.get<{ content: string }>(
       `/api/v1/projects/${projectId}/files/${fileId}/content`
     );
-    return response.data.content;
+    return { content: response.data.content, etag: response.headers['etag'] };
   }
 
   // Compilation API
@@ -90,6 +90,23 @@ export interface UpdateFileRequest {
   content: string;
 }
 
+// File content with the ETag that must be sent back when saving it
+export interface FileContent {
+  content: string;
+  etag: string;
+}
+
+// Returned with a 409 when a save was based on an outdated version
+export interface FileConflict {
+  error: string;
+  file_id: string;
+  current_version: number;
+  etag: string;
+  current_content?: string;
+  base_version?: number;
+  merged_content?: string;
+}
+
 export interface FileNode {
   name: string;
   path: string;
@@ -144,7 +161,12 @@ export type MessageType =
   | 'yjs_update'
   | 'cursor_update'
   | 'selection_update'
-  | 'awareness_update';
+  | 'awareness_update'
+  | 'comment_thread_created'
+  | 'comment_thread_updated'
+  | 'comment_thread_deleted'
+  | 'comment_mention'
+  | 'lint_results';
 
 export interface WebSocketMessage {
   type: MessageType;
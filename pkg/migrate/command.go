@@ -0,0 +1,58 @@
+package migrate
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// RunCommand implements "migrate status", "migrate up [-dry-run]" and
+// "migrate unlock VERSION" for a service binary, writing the outcome to w.
+// args are the arguments after "migrate".
+func (m *Migrator) RunCommand(ctx context.Context, args []string, w io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: migrate status | up [-dry-run] | unlock VERSION")
+	}
+
+	switch args[0] {
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, record := range status {
+			fmt.Fprintf(w, "%4d  %-8s  %s\n", record.Version, record.Status, record.Name)
+		}
+		return nil
+
+	case "up":
+		flags := flag.NewFlagSet("migrate up", flag.ContinueOnError)
+		dryRun := flags.Bool("dry-run", false, "report what the migrations would change without applying them")
+		if err := flags.Parse(args[1:]); err != nil {
+			return err
+		}
+
+		results, err := m.Up(ctx, *dryRun)
+		for _, result := range results {
+			fmt.Fprintf(w, "%4d  %s: %s\n", result.Version, result.Name, result.Summary)
+		}
+		if err == nil && len(results) == 0 {
+			fmt.Fprintln(w, "No pending migrations")
+		}
+		return err
+
+	case "unlock":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: migrate unlock VERSION")
+		}
+		version, err := strconv.Atoi(args[1])
+		if err != nil {
+			return fmt.Errorf("invalid migration version %q", args[1])
+		}
+		return m.Unlock(ctx, version)
+	}
+
+	return fmt.Errorf("unknown migrate command %q", args[0])
+}
@@ -0,0 +1,248 @@
+// Package migrate runs numbered data migrations and records them in the
+// schema_migrations collection, so that each runs once per database. Services
+// sharing a database keep separate version sequences, keyed by service name.
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// Migration states
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusApplied = "applied"
+)
+
+// Migration is one numbered step. Up must be safe to run again after a
+// partial failure, and in a dry run it must not write anything but only
+// describe what it would change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, dryRun bool) (string, error)
+}
+
+// Record is the stored state of a migration
+type Record struct {
+	Service    string     `bson:"service" json:"service"`
+	Version    int        `bson:"version" json:"version"`
+	Name       string     `bson:"name" json:"name"`
+	Status     string     `bson:"status" json:"status"`
+	Summary    string     `bson:"summary,omitempty" json:"summary,omitempty"`
+	StartedAt  time.Time  `bson:"started_at" json:"started_at"`
+	AppliedAt  *time.Time `bson:"applied_at,omitempty" json:"applied_at,omitempty"`
+	DurationMs int64      `bson:"duration_ms,omitempty" json:"duration_ms,omitempty"`
+}
+
+// Result is the outcome of running one migration
+type Result struct {
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	DryRun  bool   `json:"dry_run"`
+	Summary string `json:"summary"`
+}
+
+// Migrator applies the migrations of one service
+type Migrator struct {
+	collection *mongo.Collection
+	service    string
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// New creates a migrator for a service. Migration versions must be unique
+// and positive.
+func New(db *mongo.Database, service string, migrations []Migration, logger *zap.Logger) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	for i, m := range sorted {
+		if m.Version < 1 {
+			return nil, fmt.Errorf("migration %q has an invalid version", m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migration version %d is used twice", m.Version)
+		}
+	}
+
+	return &Migrator{
+		collection: db.Collection("schema_migrations"),
+		service:    service,
+		migrations: sorted,
+		logger:     logger,
+	}, nil
+}
+
+// CreateIndexes creates necessary indexes. The unique index also stops two
+// instances from running the same migration at once.
+func (m *Migrator) CreateIndexes(ctx context.Context) error {
+	_, err := m.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "service", Value: 1}, {Key: "version", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Status lists every known migration with its state. Migrations that have
+// not run are pending.
+func (m *Migrator) Status(ctx context.Context) ([]Record, error) {
+	applied, err := m.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]Record, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		if record, ok := applied[migration.Version]; ok {
+			status = append(status, record)
+			continue
+		}
+		status = append(status, Record{
+			Service: m.service,
+			Version: migration.Version,
+			Name:    migration.Name,
+			Status:  StatusPending,
+		})
+	}
+
+	return status, nil
+}
+
+// Up runs the pending migrations in order and stops at the first failure.
+// A dry run records nothing.
+func (m *Migrator) Up(ctx context.Context, dryRun bool) ([]Result, error) {
+	applied, err := m.records(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []Result{}
+	for _, migration := range m.migrations {
+		if record, ok := applied[migration.Version]; ok {
+			if record.Status == StatusRunning {
+				return results, fmt.Errorf("migration %d (%s) is already running or was interrupted", migration.Version, migration.Name)
+			}
+			continue
+		}
+
+		result, err := m.run(ctx, migration, dryRun)
+		if err != nil {
+			return results, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		results = append(results, *result)
+	}
+
+	return results, nil
+}
+
+// Unlock clears the claim of a migration left running by an instance that
+// stopped mid-way, so that it runs again
+func (m *Migrator) Unlock(ctx context.Context, version int) error {
+	filter := m.filter(version)
+	filter["status"] = StatusRunning
+
+	result, err := m.collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("migration %d is not running", version)
+	}
+
+	return nil
+}
+
+func (m *Migrator) run(ctx context.Context, migration Migration, dryRun bool) (*Result, error) {
+	m.logger.Info("Running migration",
+		zap.String("service", m.service),
+		zap.Int("version", migration.Version),
+		zap.String("name", migration.Name),
+		zap.Bool("dry_run", dryRun),
+	)
+
+	if dryRun {
+		summary, err := migration.Up(ctx, true)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{Version: migration.Version, Name: migration.Name, DryRun: true, Summary: summary}, nil
+	}
+
+	// Claim the migration before running it
+	started := time.Now()
+	record := Record{
+		Service:   m.service,
+		Version:   migration.Version,
+		Name:      migration.Name,
+		Status:    StatusRunning,
+		StartedAt: started,
+	}
+	if _, err := m.collection.InsertOne(ctx, record); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("already running in another instance")
+		}
+		return nil, err
+	}
+
+	summary, err := migration.Up(ctx, false)
+	if err != nil {
+		// Release the claim so that the migration is retried
+		if _, delErr := m.collection.DeleteOne(ctx, m.filter(migration.Version)); delErr != nil {
+			m.logger.Error("Failed to release migration", zap.Int("version", migration.Version), zap.Error(delErr))
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	_, err = m.collection.UpdateOne(ctx, m.filter(migration.Version), bson.M{"$set": bson.M{
+		"status":      StatusApplied,
+		"summary":     summary,
+		"applied_at":  now,
+		"duration_ms": now.Sub(started).Milliseconds(),
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Migration applied",
+		zap.String("service", m.service),
+		zap.Int("version", migration.Version),
+		zap.String("summary", summary),
+	)
+
+	return &Result{Version: migration.Version, Name: migration.Name, Summary: summary}, nil
+}
+
+// records returns the stored migrations of the service by version
+func (m *Migrator) records(ctx context.Context) (map[int]Record, error) {
+	cursor, err := m.collection.Find(ctx, bson.M{"service": m.service})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]Record, len(records))
+	for _, record := range records {
+		byVersion[record.Version] = record
+	}
+
+	return byVersion, nil
+}
+
+func (m *Migrator) filter(version int) bson.M {
+	return bson.M{"service": m.service, "version": version}
+}
@@ -0,0 +1,230 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// The runner tests use a MongoDB instance and are skipped unless
+// TEST_MONGO_URI is set. Each test uses a fresh database.
+
+func testDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+
+	uri := os.Getenv("TEST_MONGO_URI")
+	if uri == "" {
+		t.Skip("TEST_MONGO_URI is required for migration runner tests")
+	}
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	db := client.Database("texflow_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		client.Disconnect(context.Background())
+	})
+
+	return db
+}
+
+// recorder builds migrations that log their runs
+type recorder struct {
+	runs []string
+}
+
+func (r *recorder) migration(version int, name string, err error) Migration {
+	return Migration{
+		Version: version,
+		Name:    name,
+		Up: func(ctx context.Context, dryRun bool) (string, error) {
+			run := name
+			if dryRun {
+				run += " (dry run)"
+			}
+			r.runs = append(r.runs, run)
+			return name + " done", err
+		},
+	}
+}
+
+func newTestMigrator(t *testing.T, db *mongo.Database, migrations ...Migration) *Migrator {
+	t.Helper()
+
+	m, err := New(db, "test", migrations, zap.NewNop())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if err := m.CreateIndexes(context.Background()); err != nil {
+		t.Fatalf("CreateIndexes() error = %v", err)
+	}
+	return m
+}
+
+// statuses returns the state of each migration as version:status
+func statuses(t *testing.T, m *Migrator) map[int]string {
+	t.Helper()
+
+	records, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	got := make(map[int]string, len(records))
+	for _, record := range records {
+		got[record.Version] = record.Status
+	}
+	return got
+}
+
+func TestNew(t *testing.T) {
+	noop := func(ctx context.Context, dryRun bool) (string, error) { return "", nil }
+
+	tests := []struct {
+		name       string
+		migrations []Migration
+		err        string
+	}{
+		{"zero version", []Migration{{Version: 0, Name: "a", Up: noop}}, `migration "a" has an invalid version`},
+		{"negative version", []Migration{{Version: -1, Name: "a", Up: noop}}, `migration "a" has an invalid version`},
+		{"duplicate version", []Migration{{Version: 2, Name: "a", Up: noop}, {Version: 1, Name: "b", Up: noop}, {Version: 2, Name: "c", Up: noop}}, "migration version 2 is used twice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(nil, "test", tt.migrations, zap.NewNop()); err == nil || err.Error() != tt.err {
+				t.Errorf("New() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestUpRunsInVersionOrderAndRecords(t *testing.T) {
+	db := testDatabase(t)
+	r := &recorder{}
+	m := newTestMigrator(t, db, r.migration(3, "third", nil), r.migration(1, "first", nil), r.migration(2, "second", nil))
+
+	results, err := m.Up(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(r.runs, want) {
+		t.Errorf("runs = %v, want %v", r.runs, want)
+	}
+	want := []Result{
+		{Version: 1, Name: "first", Summary: "first done"},
+		{Version: 2, Name: "second", Summary: "second done"},
+		{Version: 3, Name: "third", Summary: "third done"},
+	}
+	if !reflect.DeepEqual(results, want) {
+		t.Errorf("results = %+v, want %+v", results, want)
+	}
+
+	records, err := m.Status(context.Background())
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for _, record := range records {
+		if record.Service != "test" || record.Status != StatusApplied || record.AppliedAt == nil || record.Summary != record.Name+" done" {
+			t.Errorf("unexpected record %+v", record)
+		}
+	}
+
+	count, err := db.Collection("schema_migrations").CountDocuments(context.Background(), bson.M{"service": "test"})
+	if err != nil {
+		t.Fatalf("CountDocuments() error = %v", err)
+	}
+	if count != 3 {
+		t.Errorf("schema_migrations has %d records, want 3", count)
+	}
+}
+
+func TestUpSkipsAppliedMigrations(t *testing.T) {
+	db := testDatabase(t)
+	r := &recorder{}
+	first := r.migration(1, "first", nil)
+
+	if _, err := newTestMigrator(t, db, first).Up(context.Background(), false); err != nil {
+		t.Fatalf("first Up() error = %v", err)
+	}
+
+	// A later release adds a migration; only the new one runs
+	m := newTestMigrator(t, db, first, r.migration(2, "second", nil))
+	results, err := m.Up(context.Background(), false)
+	if err != nil {
+		t.Fatalf("second Up() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Version != 2 {
+		t.Errorf("results = %+v, want only version 2", results)
+	}
+
+	results, err = m.Up(context.Background(), false)
+	if err != nil {
+		t.Fatalf("third Up() error = %v", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("results = %+v, want none", results)
+	}
+
+	if want := []string{"first", "second"}; !reflect.DeepEqual(r.runs, want) {
+		t.Errorf("runs = %v, want %v", r.runs, want)
+	}
+}
+
+func TestUpDryRunRecordsNothing(t *testing.T) {
+	db := testDatabase(t)
+	r := &recorder{}
+	m := newTestMigrator(t, db, r.migration(1, "first", nil), r.migration(2, "second", nil))
+
+	results, err := m.Up(context.Background(), true)
+	if err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	for _, result := range results {
+		if !result.DryRun {
+			t.Errorf("result %+v is not marked as a dry run", result)
+		}
+	}
+	if want := []string{"first (dry run)", "second (dry run)"}; !reflect.DeepEqual(r.runs, want) {
+		t.Errorf("runs = %v, want %v", r.runs, want)
+	}
+	if want := map[int]string{1: StatusPending, 2: StatusPending}; !reflect.DeepEqual(statuses(t, m), want) {
+		t.Errorf("statuses = %v, want %v", statuses(t, m), want)
+	}
+}
+
+func TestUpFailureIsNotRecorded(t *testing.T) {
+	db := testDatabase(t)
+	r := &recorder{}
+	m := newTestMigrator(t, db,
+		r.migration(1, "first", nil),
+		r.migration(2, "broken", errors.New("boom")),
+		r.migration(3, "third", nil),
+	)
+
+	results, err := m.Up(context.Background(), false)
+	if err == nil || err.Error() != "migration 2 (broken) failed: boom" {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Version != 1 {
+		t.Errorf("results = %+v, want only version 1", results)
+	}
+
+	// Migrations after the failure do not run, and the failed one stays pending
+	if want := []string{"first", "broken"}; !reflect.DeepEqual(r.runs, want) {
+		t.Errorf("runs = %v, want %v", r.runs, want)
+	}
+	if want := map[int]string{1: StatusApplied, 2: StatusPending, 3: StatusPending}; !reflect.DeepEqual(statuses(t, m), want) {
+		t.Errorf("statuses = %v, want %v", statuses(t, m), want)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
+	"github.com/texflow/pkg/migrate"
 	"auth/internal/config"
 	"auth/internal/handlers"
 	"auth/internal/middleware"
@@ -82,23 +83,52 @@ func main() {
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
+	tokenRepo := repository.NewTokenRepository(db)
 
 	// Create indexes
 	if err := userRepo.CreateIndexes(context.Background()); err != nil {
 		log.Error("Failed to create indexes", zap.Error(err))
 	}
+	if err := tokenRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create token indexes", zap.Error(err))
+	}
+
+	migrator, err := migrate.New(db, "auth", service.Migrations(), log)
+	if err != nil {
+		log.Fatal("Failed to initialize migrations", zap.Error(err))
+	}
+	if err := migrator.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create migration indexes", zap.Error(err))
+	}
+
+	// "main migrate ..." runs the migration command instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrator.RunCommand(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			log.Error("Migration command failed", zap.Error(err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.RunMigrations {
+		if _, err := migrator.Up(context.Background(), false); err != nil {
+			log.Error("Failed to run migrations", zap.Error(err))
+		}
+	}
 
 	// Initialize services
 	authService := service.NewAuthService(userRepo, jwtManager, redisClient, log, cfg.BCryptCost)
+	tokenService := service.NewTokenService(tokenRepo, log)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService, log)
+	tokenHandler := handlers.NewTokenHandler(tokenService, log)
 
 	// Initialize metrics
 	metricsInst := metrics.NewMetrics("auth_service")
 
 	// Setup HTTP server
-	router := setupRouter(authHandler, jwtManager, metricsInst, log, cfg.Environment)
+	router := setupRouter(authHandler, tokenHandler, jwtManager, metricsInst, log, cfg.Environment)
 
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -161,6 +191,7 @@ func connectMongoDB(uri string, log *zap.Logger) (*mongo.Client, error) {
 
 func setupRouter(
 	authHandler *handlers.AuthHandler,
+	tokenHandler *handlers.TokenHandler,
 	jwtManager *auth.JWTManager,
 	metricsInst *metrics.Metrics,
 	log *zap.Logger,
@@ -201,6 +232,11 @@ func setupRouter(
 			{
 				protected.POST("/logout", authHandler.Logout)
 				protected.GET("/me", authHandler.Me)
+
+				// Personal access tokens for Git and other non-browser clients
+				protected.POST("/tokens", tokenHandler.CreateToken)
+				protected.GET("/tokens", tokenHandler.ListTokens)
+				protected.DELETE("/tokens/:id", tokenHandler.RevokeToken)
 			}
 		}
 	}
@@ -32,6 +32,9 @@ type Config struct {
 	// Security
 	BCryptCost int
 
+	// Migrations
+	RunMigrations bool
+
 	// Logging
 	LogLevel string
 }
@@ -65,6 +68,7 @@ func Load() (*Config, error) {
 		JWTAccessTokenExpiry:   accessTokenExpiry,
 		JWTRefreshTokenExpiry:  refreshTokenExpiry,
 		BCryptCost:             12,
+		RunMigrations:          getEnv("RUN_MIGRATIONS", "true") == "true",
 		LogLevel:               getEnv("LOG_LEVEL", "info"),
 	}
 
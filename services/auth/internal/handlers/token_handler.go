@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"auth/internal/models"
+	"auth/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// TokenHandler handles personal access token HTTP requests
+type TokenHandler struct {
+	tokenService *service.TokenService
+	logger       *zap.Logger
+}
+
+// NewTokenHandler creates a new token handler
+func NewTokenHandler(tokenService *service.TokenService, logger *zap.Logger) *TokenHandler {
+	return &TokenHandler{
+		tokenService: tokenService,
+		logger:       logger,
+	}
+}
+
+// CreateToken creates a personal access token
+// @Summary Create a personal access token
+// @Tags tokens
+// @Security BearerAuth
+// @Accept json
+// @Produce json
+// @Param request body models.CreateTokenRequest true "Token request"
+// @Success 201 {object} models.CreateTokenResponse
+// @Failure 400 {object} map[string]string
+// @Router /auth/tokens [post]
+func (h *TokenHandler) CreateToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.tokenService.CreateToken(c.Request.Context(), userID, &req)
+	if err != nil {
+		if err.Error() == "token limit reached" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to create token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// ListTokens lists the current user's personal access tokens
+// @Summary List personal access tokens
+// @Tags tokens
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {array} models.PersonalAccessToken
+// @Router /auth/tokens [get]
+func (h *TokenHandler) ListTokens(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	tokens, err := h.tokenService.ListTokens(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to list tokens", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list tokens"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// RevokeToken revokes a personal access token
+// @Summary Revoke a personal access token
+// @Tags tokens
+// @Security BearerAuth
+// @Produce json
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Router /auth/tokens/{id} [delete]
+func (h *TokenHandler) RevokeToken(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return
+	}
+
+	tokenID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid token ID"})
+		return
+	}
+
+	if err := h.tokenService.RevokeToken(c.Request.Context(), userID, tokenID); err != nil {
+		if err.Error() == "token not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+			return
+		}
+		h.logger.Error("Failed to revoke token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Token revoked"})
+}
+
+// currentUserID reads the user set by the auth middleware, writing the error
+// response when it is missing or invalid
+func currentUserID(c *gin.Context) (primitive.ObjectID, bool) {
+	userIDStr, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return primitive.NilObjectID, false
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return primitive.NilObjectID, false
+	}
+
+	return userID, true
+}
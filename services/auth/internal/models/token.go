@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PersonalAccessToken is a long-lived credential for non-browser clients such
+// as Git. Only the SHA-256 hash of the token is stored.
+type PersonalAccessToken struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name       string             `bson:"name" json:"name"`
+	TokenHash  string             `bson:"token_hash" json:"-"`
+	Prefix     string             `bson:"prefix" json:"prefix"` // First characters, to recognise a token in listings
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CreateTokenRequest represents a request to create a personal access token
+type CreateTokenRequest struct {
+	Name          string `json:"name" binding:"required,min=1,max=100"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // Zero means no expiry
+}
+
+// CreateTokenResponse carries the plain token, which is only shown once
+type CreateTokenResponse struct {
+	*PersonalAccessToken
+	Token string `json:"token"`
+}
@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"auth/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TokenRepository handles personal access token persistence
+type TokenRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewTokenRepository creates a new token repository
+func NewTokenRepository(db *mongo.Database) *TokenRepository {
+	return &TokenRepository{
+		db:         db,
+		collection: db.Collection("personal_access_tokens"),
+	}
+}
+
+// Create creates a new personal access token
+func (r *TokenRepository) Create(ctx context.Context, token *models.PersonalAccessToken) error {
+	token.ID = primitive.NewObjectID()
+	token.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, token)
+	return err
+}
+
+// FindByUserID finds all tokens of a user, newest first
+func (r *TokenRepository) FindByUserID(ctx context.Context, userID primitive.ObjectID) ([]*models.PersonalAccessToken, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var tokens []*models.PersonalAccessToken
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// CountByUserID counts the tokens of a user
+func (r *TokenRepository) CountByUserID(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID})
+}
+
+// Delete deletes a token owned by the given user
+func (r *TokenRepository) Delete(ctx context.Context, userID, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id, "user_id": userID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("token not found")
+	}
+
+	return nil
+}
+
+// CreateIndexes creates necessary indexes for the tokens collection
+func (r *TokenRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
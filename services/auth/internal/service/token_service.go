@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"auth/internal/models"
+	"auth/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Personal access tokens look like "tfp_<40 hex chars>"
+const (
+	TokenPrefix       = "tfp_"
+	maxTokensPerUser  = 50
+	tokenDisplayChars = 8
+)
+
+// TokenService manages personal access tokens
+type TokenService struct {
+	tokenRepo *repository.TokenRepository
+	logger    *zap.Logger
+}
+
+// NewTokenService creates a new token service
+func NewTokenService(tokenRepo *repository.TokenRepository, logger *zap.Logger) *TokenService {
+	return &TokenService{
+		tokenRepo: tokenRepo,
+		logger:    logger,
+	}
+}
+
+// CreateToken creates a personal access token and returns it in plain text
+// together with its stored metadata
+func (s *TokenService) CreateToken(ctx context.Context, userID primitive.ObjectID, req *models.CreateTokenRequest) (*models.CreateTokenResponse, error) {
+	count, err := s.tokenRepo.CountByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if count >= maxTokensPerUser {
+		return nil, fmt.Errorf("token limit reached")
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	plain := TokenPrefix + hex.EncodeToString(raw)
+
+	token := &models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      req.Name,
+		TokenHash: HashToken(plain),
+		Prefix:    plain[:len(TokenPrefix)+tokenDisplayChars],
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.tokenRepo.Create(ctx, token); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Personal access token created",
+		zap.String("user_id", userID.Hex()),
+		zap.String("token_id", token.ID.Hex()),
+	)
+
+	return &models.CreateTokenResponse{PersonalAccessToken: token, Token: plain}, nil
+}
+
+// ListTokens lists the personal access tokens of a user
+func (s *TokenService) ListTokens(ctx context.Context, userID primitive.ObjectID) ([]*models.PersonalAccessToken, error) {
+	tokens, err := s.tokenRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tokens == nil {
+		tokens = []*models.PersonalAccessToken{}
+	}
+	return tokens, nil
+}
+
+// RevokeToken deletes a personal access token of a user
+func (s *TokenService) RevokeToken(ctx context.Context, userID, tokenID primitive.ObjectID) error {
+	if err := s.tokenRepo.Delete(ctx, userID, tokenID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Personal access token revoked",
+		zap.String("user_id", userID.Hex()),
+		zap.String("token_id", tokenID.Hex()),
+	)
+	return nil
+}
+
+// HashToken returns the hex SHA-256 digest under which a token is stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
+	"github.com/texflow/pkg/migrate"
 	"collaboration/internal/config"
 	"collaboration/internal/handlers"
 	"collaboration/internal/middleware"
@@ -81,6 +82,7 @@ func main() {
 	// Initialize repositories
 	updateRepo := repository.NewUpdateRepository(db)
 	snapshotRepo := repository.NewSnapshotRepository(db)
+	projectRepo := repository.NewProjectRepository(db)
 
 	// Create indexes
 	if err := updateRepo.CreateIndexes(context.Background()); err != nil {
@@ -90,6 +92,29 @@ func main() {
 		log.Error("Failed to create snapshot indexes", zap.Error(err))
 	}
 
+	migrator, err := migrate.New(db, "collaboration", service.Migrations(), log)
+	if err != nil {
+		log.Fatal("Failed to initialize migrations", zap.Error(err))
+	}
+	if err := migrator.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create migration indexes", zap.Error(err))
+	}
+
+	// "main migrate ..." runs the migration command instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrator.RunCommand(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			log.Error("Migration command failed", zap.Error(err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.RunMigrations {
+		if _, err := migrator.Up(context.Background(), false); err != nil {
+			log.Error("Failed to run migrations", zap.Error(err))
+		}
+	}
+
 	// Initialize services
 	collabService := service.NewCollaborationService(
 		updateRepo,
@@ -112,7 +137,7 @@ func main() {
 	}()
 
 	// Initialize handlers
-	collabHandler := handlers.NewCollaborationHandler(collabService, log)
+	collabHandler := handlers.NewCollaborationHandler(collabService, projectRepo, log)
 
 	// Initialize metrics
 	metricsInst := metrics.NewMetrics("collaboration_service")
@@ -33,6 +33,9 @@ type Config struct {
 	UpdateRetentionDays  int   // Days to keep updates before archiving
 	MaxDocumentSizeBytes int64 // Maximum document size
 
+	// Migrations
+	RunMigrations bool
+
 	// Logging
 	LogLevel string
 }
@@ -77,6 +80,7 @@ func Load() (*Config, error) {
 		MaxUpdatesPerFetch:   maxUpdatesPerFetch,
 		UpdateRetentionDays:  updateRetentionDays,
 		MaxDocumentSizeBytes: maxDocumentSize,
+		RunMigrations:        getEnv("RUN_MIGRATIONS", "true") == "true",
 		LogLevel:             getEnv("LOG_LEVEL", "info"),
 	}
 
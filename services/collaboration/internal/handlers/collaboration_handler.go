@@ -7,6 +7,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"collaboration/internal/models"
+	"collaboration/internal/repository"
 	"collaboration/internal/service"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
@@ -15,13 +16,15 @@ import (
 // CollaborationHandler handles collaboration HTTP requests
 type CollaborationHandler struct {
 	collabService *service.CollaborationService
+	projectRepo   *repository.ProjectRepository
 	logger        *zap.Logger
 }
 
 // NewCollaborationHandler creates a new collaboration handler
-func NewCollaborationHandler(collabService *service.CollaborationService, logger *zap.Logger) *CollaborationHandler {
+func NewCollaborationHandler(collabService *service.CollaborationService, projectRepo *repository.ProjectRepository, logger *zap.Logger) *CollaborationHandler {
 	return &CollaborationHandler{
 		collabService: collabService,
+		projectRepo:   projectRepo,
 		logger:        logger,
 	}
 }
@@ -35,6 +38,7 @@ func NewCollaborationHandler(collabService *service.CollaborationService, logger
 // @Param request body models.StoreUpdateRequest true "Update request"
 // @Success 201 {object} models.YjsUpdate
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /collaboration/updates [post]
 func (h *CollaborationHandler) StoreUpdate(c *gin.Context) {
@@ -63,6 +67,10 @@ func (h *CollaborationHandler) StoreUpdate(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeProject(c, projectID, true) {
+		return
+	}
+
 	// Decode base64 update
 	updateData, err := base64.StdEncoding.DecodeString(req.Update)
 	if err != nil {
@@ -103,6 +111,7 @@ func (h *CollaborationHandler) StoreUpdate(c *gin.Context) {
 // @Param since_version query int false "Since version"
 // @Success 200 {object} models.DocumentStateResponse
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /collaboration/state/:project_id/:document_name [get]
 func (h *CollaborationHandler) GetDocumentState(c *gin.Context) {
@@ -115,6 +124,10 @@ func (h *CollaborationHandler) GetDocumentState(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeProject(c, projectID, false) {
+		return
+	}
+
 	// Get optional since_version parameter
 	var sinceVersion int64
 	if sv := c.Query("since_version"); sv != "" {
@@ -148,6 +161,7 @@ func (h *CollaborationHandler) GetDocumentState(c *gin.Context) {
 // @Param limit query int false "Limit"
 // @Success 200 {array} models.YjsUpdate
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /collaboration/updates/:project_id/:document_name [get]
 func (h *CollaborationHandler) GetUpdates(c *gin.Context) {
@@ -160,6 +174,10 @@ func (h *CollaborationHandler) GetUpdates(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeProject(c, projectID, false) {
+		return
+	}
+
 	var sinceVersion int64
 	if sv := c.Query("since_version"); sv != "" {
 		fmt.Sscanf(sv, "%d", &sinceVersion)
@@ -203,6 +221,7 @@ func (h *CollaborationHandler) GetUpdates(c *gin.Context) {
 // @Param document_name path string true "Document name"
 // @Success 200 {object} models.DocumentMetrics
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /collaboration/metrics/:project_id/:document_name [get]
 func (h *CollaborationHandler) GetMetrics(c *gin.Context) {
@@ -215,6 +234,10 @@ func (h *CollaborationHandler) GetMetrics(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeProject(c, projectID, false) {
+		return
+	}
+
 	metrics, err := h.collabService.GetDocumentMetrics(
 		c.Request.Context(),
 		projectID,
@@ -229,6 +252,32 @@ func (h *CollaborationHandler) GetMetrics(c *gin.Context) {
 	c.JSON(http.StatusOK, metrics)
 }
 
+// authorizeProject checks that the user may read the project, or edit it when
+// write is set, writing the error response when they may not
+func (h *CollaborationHandler) authorizeProject(c *gin.Context, projectID primitive.ObjectID, write bool) bool {
+	userIDStr, _ := c.Get("user_id")
+	userIDHex, _ := userIDStr.(string)
+	userID, err := primitive.ObjectIDFromHex(userIDHex)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return false
+	}
+
+	role, err := h.projectRepo.UserRole(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.logger.Error("Failed to check project access", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project access"})
+		return false
+	}
+
+	if role == "" || (write && !models.CanEdit(role)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return false
+	}
+
+	return true
+}
+
 // Health returns the health status of the service
 // @Summary Health check
 // @Tags health
@@ -0,0 +1,9 @@
+package service
+
+import "github.com/texflow/pkg/migrate"
+
+// Migrations lists the data migrations of the collaboration service. Versions are
+// permanent: add new migrations at the end and never renumber them.
+func Migrations() []migrate.Migration {
+	return []migrate.Migration{}
+}
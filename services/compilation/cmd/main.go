@@ -3,18 +3,16 @@ package main
 import (
 	"context"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
-	"github.com/docker/docker/api/types"
-	"github.com/docker/docker/client"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
+	"github.com/texflow/pkg/migrate"
 	"compilation/internal/config"
 	"compilation/internal/handlers"
 	"compilation/internal/middleware"
@@ -32,6 +30,12 @@ import (
 )
 
 func main() {
+	// The process mode can be given as the first argument: api, worker or
+	// all. "migrate" runs the migration command instead.
+	if len(os.Args) > 1 && os.Args[1] != "migrate" {
+		os.Setenv("COMPILATION_MODE", os.Args[1])
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -49,6 +53,7 @@ func main() {
 
 	log.Info("Starting Compilation Service",
 		zap.String("environment", cfg.Environment),
+		zap.String("mode", cfg.Mode),
 		zap.String("port", cfg.Port),
 		zap.Int("max_workers", cfg.MaxWorkers),
 	)
@@ -90,99 +95,76 @@ func main() {
 		log.Fatal("Failed to initialize MinIO client", zap.Error(err))
 	}
 
-	// Initialize Docker client
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
-	if err != nil {
-		log.Fatal("Failed to create Docker client", zap.Error(err))
-	}
-	defer dockerClient.Close()
+	// Initialize repositories
+	compilationRepo := repository.NewCompilationRepository(db)
 
-	log.Info("Connected to Docker daemon successfully")
+	webhookRepo := repository.NewWebhookRepository(db)
 
-	// Pull TeX Live image if not exists
-	if err := pullTexLiveImage(context.Background(), dockerClient, cfg.TexLiveImage, log); err != nil {
-		log.Warn("Failed to pull TeX Live image", zap.Error(err))
+	// Create indexes
+	if err := compilationRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create indexes", zap.Error(err))
+	}
+	if err := webhookRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create webhook indexes", zap.Error(err))
 	}
 
-	// Initialize JWT manager
-	jwtManager, err := auth.NewJWTManager(
-		cfg.JWTPrivateKeyPath,
-		cfg.JWTPublicKeyPath,
-		cfg.JWTSecret,
-		15*time.Minute, // access token duration (unused for validation)
-		7*24*time.Hour, // refresh token duration (unused for validation)
-	)
+	migrator, err := migrate.New(db, "compilation", service.Migrations(), log)
 	if err != nil {
-		log.Fatal("Failed to initialize JWT manager", zap.Error(err))
+		log.Fatal("Failed to initialize migrations", zap.Error(err))
+	}
+	if err := migrator.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create migration indexes", zap.Error(err))
 	}
 
-	// Initialize repositories
-	compilationRepo := repository.NewCompilationRepository(db)
+	// "main migrate ..." runs the migration command instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrator.RunCommand(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			log.Error("Migration command failed", zap.Error(err))
+			os.Exit(1)
+		}
+		return
+	}
 
-	// Create indexes
-	if err := compilationRepo.CreateIndexes(context.Background()); err != nil {
-		log.Error("Failed to create indexes", zap.Error(err))
+	if cfg.RunMigrations {
+		if _, err := migrator.Up(context.Background(), false); err != nil {
+			log.Error("Failed to run migrations", zap.Error(err))
+		}
 	}
 
+	// Webhook deliveries are queued by whichever process sees a compilation finish
+	webhookService := service.NewWebhookService(webhookRepo, log)
+
 	// Initialize Redis queue
 	redisQueue := queue.NewRedisQueue(redisClient, log)
 	if err := redisQueue.Initialize(context.Background()); err != nil {
 		log.Fatal("Failed to initialize Redis queue", zap.Error(err))
 	}
 
-	// Initialize project service
-	projectService := service.NewProjectService(db, minioClient, log)
-
-	// Initialize compilation service
-	compilationService := service.NewCompilationService(
-		compilationRepo,
-		redisQueue,
-		redisClient,
-		minioClient,
-		log,
-		cfg.EnableCache,
-		cfg.CacheTTL,
-		cfg.MaxCompilationsPerUser,
-	)
-
-	// Initialize Docker worker
-	dockerWorker := worker.NewDockerWorker(
-		dockerClient,
-		minioClient,
-		log,
-		cfg.TexLiveImage,
-		cfg.CompilationTimeout,
-		cfg.CompilationMemory,
-		cfg.CompilationCPUs,
-		cfg.CompilationVolume,
-	)
-
-	// Initialize worker manager
-	workerManager := worker.NewManager(
-		redisQueue,
-		compilationRepo,
-		dockerWorker,
-		log,
-		cfg.MaxWorkers,
-	)
-
-	// Start worker manager
-	if err := workerManager.Start(context.Background()); err != nil {
-		log.Fatal("Failed to start worker manager", zap.Error(err))
+	// Start compilation workers
+	var workerManager *worker.Manager
+	var webhookDispatcher *worker.WebhookDispatcher
+	if cfg.RunsWorkers() {
+		workerManager = startWorkers(cfg, redisQueue, compilationRepo, minioClient, log)
+		workerManager.SetNotifier(webhookService)
+
+		webhookDispatcher = worker.NewWebhookDispatcher(
+			webhookRepo,
+			log,
+			cfg.WebhookWorkers,
+			cfg.WebhookMaxAttempts,
+			cfg.WebhookTimeout,
+			cfg.WebhookAllowPrivate,
+		)
+		webhookDispatcher.Start(context.Background())
 	}
 
-	// Initialize handlers
-	compilationHandler := handlers.NewCompilationHandler(
-		compilationService,
-		projectService,
-		log,
-	)
-
-	// Initialize metrics
-	metricsInst := metrics.NewMetrics("compilation_service")
-
 	// Setup HTTP server
-	router := setupRouter(compilationHandler, jwtManager, metricsInst, log, cfg.Environment)
+	var router *gin.Engine
+	if cfg.RunsAPI() {
+		router = setupAPI(cfg, db, redisQueue, redisClient, compilationRepo, webhookService, minioClient, log)
+	} else {
+		router = setupWorkerRouter(workerManager, cfg.Environment)
+	}
 
 	server := &http.Server{
 		Addr:         ":" + cfg.Port,
@@ -207,15 +189,28 @@ func main() {
 
 	log.Info("Shutting down server...")
 
+	// Drain workers first: finish in-flight jobs or requeue them on timeout
+	if workerManager != nil {
+		drainCtx, drainCancel := context.WithTimeout(context.Background(), cfg.WorkerDrainTimeout)
+		if err := workerManager.Shutdown(drainCtx); err != nil {
+			log.Error("Worker manager shutdown error", zap.Error(err))
+		}
+		drainCancel()
+	}
+
+	// Stop webhook delivery; unfinished deliveries are retried after their lease
+	if webhookDispatcher != nil {
+		dispatchCtx, dispatchCancel := context.WithTimeout(context.Background(), cfg.WebhookTimeout+5*time.Second)
+		if err := webhookDispatcher.Shutdown(dispatchCtx); err != nil {
+			log.Error("Webhook dispatcher shutdown error", zap.Error(err))
+		}
+		dispatchCancel()
+	}
+
 	// Graceful shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Shutdown worker manager first
-	if err := workerManager.Shutdown(ctx); err != nil {
-		log.Error("Worker manager shutdown error", zap.Error(err))
-	}
-
 	// Shutdown HTTP server
 	if err := server.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown", zap.Error(err))
@@ -224,6 +219,101 @@ func main() {
 	log.Info("Server exited")
 }
 
+// startWorkers creates and starts the compilation worker pool
+func startWorkers(
+	cfg *config.Config,
+	redisQueue *queue.RedisQueue,
+	compilationRepo *repository.CompilationRepository,
+	minioClient *storage.MinIOClient,
+	log *zap.Logger,
+) *worker.Manager {
+	// Initialize LaTeX worker (compiles with the local TeX installation)
+	dockerWorker := worker.NewDockerWorker(
+		nil,
+		minioClient,
+		log,
+		cfg.TexLiveImage,
+		cfg.CompilationTimeout,
+		cfg.CompilationMemory,
+		cfg.CompilationCPUs,
+		cfg.CompilationVolume,
+	)
+
+	// Initialize worker manager
+	workerManager := worker.NewManager(
+		redisQueue,
+		compilationRepo,
+		dockerWorker,
+		log,
+		cfg.MaxWorkers,
+	)
+
+	// Jobs unacknowledged for longer than a compilation can run were left
+	// behind by a dead worker and are taken over
+	workerManager.SetClaimIdle(cfg.CompilationTimeout + time.Minute)
+
+	// Start worker manager
+	if err := workerManager.Start(context.Background()); err != nil {
+		log.Fatal("Failed to start worker manager", zap.Error(err))
+	}
+
+	return workerManager
+}
+
+// setupAPI wires the services and handlers that make up the HTTP API
+func setupAPI(
+	cfg *config.Config,
+	db *mongo.Database,
+	redisQueue *queue.RedisQueue,
+	redisClient *redis.Client,
+	compilationRepo *repository.CompilationRepository,
+	webhookService *service.WebhookService,
+	minioClient *storage.MinIOClient,
+	log *zap.Logger,
+) *gin.Engine {
+	// Initialize JWT manager
+	jwtManager, err := auth.NewJWTManager(
+		cfg.JWTPrivateKeyPath,
+		cfg.JWTPublicKeyPath,
+		cfg.JWTSecret,
+		15*time.Minute, // access token duration (unused for validation)
+		7*24*time.Hour, // refresh token duration (unused for validation)
+	)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT manager", zap.Error(err))
+	}
+
+	// Initialize project service
+	projectService := service.NewProjectService(db, minioClient, log)
+
+	// Initialize compilation service
+	compilationService := service.NewCompilationService(
+		compilationRepo,
+		redisQueue,
+		redisClient,
+		minioClient,
+		log,
+		cfg.EnableCache,
+		cfg.CacheTTL,
+		cfg.MaxCompilationsPerUser,
+	)
+
+	compilationService.SetNotifier(webhookService)
+
+	// Initialize handlers
+	compilationHandler := handlers.NewCompilationHandler(
+		compilationService,
+		projectService,
+		log,
+	)
+	webhookHandler := handlers.NewWebhookHandler(webhookService, projectService, log)
+
+	// Initialize metrics
+	metricsInst := metrics.NewMetrics("compilation_service")
+
+	return setupRouter(compilationHandler, webhookHandler, jwtManager, metricsInst, log, cfg.Environment)
+}
+
 func connectMongoDB(uri string, log *zap.Logger) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -249,45 +339,9 @@ func connectMongoDB(uri string, log *zap.Logger) (*mongo.Client, error) {
 	return client, nil
 }
 
-func pullTexLiveImage(ctx context.Context, dockerClient *client.Client, image string, log *zap.Logger) error {
-	log.Info("Checking TeX Live image", zap.String("image", image))
-
-	// Check if image exists locally
-	images, err := dockerClient.ImageList(ctx, types.ImageListOptions{})
-	if err != nil {
-		return err
-	}
-
-	for _, img := range images {
-		for _, tag := range img.RepoTags {
-			if tag == image {
-				log.Info("TeX Live image already exists locally")
-				return nil
-			}
-		}
-	}
-
-	log.Info("Pulling TeX Live image (this may take a while)...")
-
-	// Pull the image
-	reader, err := dockerClient.ImagePull(ctx, image, types.ImagePullOptions{})
-	if err != nil {
-		return fmt.Errorf("failed to pull image: %w", err)
-	}
-	defer reader.Close()
-
-	// Read the output to ensure the pull completes
-	_, err = io.Copy(io.Discard, reader)
-	if err != nil {
-		return fmt.Errorf("failed to read pull output: %w", err)
-	}
-
-	log.Info("TeX Live image pulled successfully", zap.String("image", image))
-	return nil
-}
-
 func setupRouter(
 	compilationHandler *handlers.CompilationHandler,
+	webhookHandler *handlers.WebhookHandler,
 	jwtManager *auth.JWTManager,
 	metricsInst *metrics.Metrics,
 	log *zap.Logger,
@@ -323,9 +377,27 @@ func setupRouter(
 			// Get compilation status
 			compilation.GET("/:id", compilationHandler.GetCompilation)
 
+			// Cancel a queued or running compilation
+			compilation.POST("/:id/cancel", compilationHandler.CancelCompilation)
+
 			// List project compilations
 			compilation.GET("/project/:project_id", compilationHandler.ListCompilations)
 
+			// Get project compilation analytics
+			compilation.GET("/project/:project_id/analytics", compilationHandler.GetProjectAnalytics)
+
+			// Project webhooks
+			webhooks := compilation.Group("/project/:project_id/webhooks")
+			{
+				webhooks.POST("", webhookHandler.CreateWebhook)
+				webhooks.GET("", webhookHandler.ListWebhooks)
+				webhooks.PATCH("/:webhook_id", webhookHandler.UpdateWebhook)
+				webhooks.DELETE("/:webhook_id", webhookHandler.DeleteWebhook)
+				webhooks.POST("/:webhook_id/ping", webhookHandler.PingWebhook)
+				webhooks.GET("/:webhook_id/deliveries", webhookHandler.ListDeliveries)
+				webhooks.POST("/:webhook_id/deliveries/:delivery_id/redeliver", webhookHandler.Redeliver)
+			}
+
 			// Get statistics
 			compilation.GET("/stats", compilationHandler.GetStats)
 
@@ -336,3 +408,34 @@ func setupRouter(
 
 	return router
 }
+
+// setupWorkerRouter serves health and metrics endpoints for worker-only processes
+func setupWorkerRouter(workerManager *worker.Manager, environment string) *gin.Engine {
+	if environment == "production" {
+		gin.SetMode(gin.ReleaseMode)
+	}
+
+	router := gin.New()
+	router.Use(gin.Recovery())
+
+	router.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":    "healthy",
+			"service":   "compilation-worker",
+			"in_flight": workerManager.InFlight(),
+		})
+	})
+
+	// Not ready while draining so no new traffic is routed here
+	router.GET("/ready", func(c *gin.Context) {
+		if workerManager.Draining() {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "draining"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	})
+
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	return router
+}
@@ -9,10 +9,18 @@ import (
 	"github.com/joho/godotenv"
 )
 
+// Process modes
+const (
+	ModeAPI    = "api"    // HTTP API only
+	ModeWorker = "worker" // Compilation workers only
+	ModeAll    = "all"    // HTTP API and workers in one process
+)
+
 // Config holds all configuration for the compilation service
 type Config struct {
 	Environment string
 	Port        string
+	Mode        string
 
 	// MongoDB
 	MongoURI      string
@@ -42,15 +50,25 @@ type Config struct {
 	CompilationCPUs      int64 // Nano CPUs (1 CPU = 1e9)
 	MaxWorkers           int
 	WorkerPollInterval   time.Duration
+	WorkerDrainTimeout   time.Duration
 	EnableCache          bool
 	CacheTTL             time.Duration
 	MaxCompilationsPerUser int
 
+	// Webhooks
+	WebhookWorkers      int
+	WebhookMaxAttempts  int
+	WebhookTimeout      time.Duration
+	WebhookAllowPrivate bool // Allow endpoints on loopback and private networks
+
 	// Docker
 	DockerHost        string
 	TexLiveImage      string
 	CompilationVolume string
 
+	// Migrations
+	RunMigrations bool
+
 	// Logging
 	LogLevel string
 }
@@ -70,6 +88,11 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid WORKER_POLL_INTERVAL: %w", err)
 	}
 
+	workerDrainTimeout, err := time.ParseDuration(getEnv("WORKER_DRAIN_TIMEOUT", "60s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WORKER_DRAIN_TIMEOUT: %w", err)
+	}
+
 	cacheTTL, err := time.ParseDuration(getEnv("CACHE_TTL", "1h"))
 	if err != nil {
 		return nil, fmt.Errorf("invalid CACHE_TTL: %w", err)
@@ -95,9 +118,25 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("invalid MAX_COMPILATIONS_PER_USER: %w", err)
 	}
 
+	webhookWorkers, err := strconv.Atoi(getEnv("WEBHOOK_WORKERS", "4"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_WORKERS: %w", err)
+	}
+
+	webhookMaxAttempts, err := strconv.Atoi(getEnv("WEBHOOK_MAX_ATTEMPTS", "8"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS: %w", err)
+	}
+
+	webhookTimeout, err := time.ParseDuration(getEnv("WEBHOOK_TIMEOUT", "10s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid WEBHOOK_TIMEOUT: %w", err)
+	}
+
 	config := &Config{
 		Environment:            getEnv("ENVIRONMENT", "development"),
 		Port:                   getEnv("COMPILATION_SERVICE_PORT", "8084"),
+		Mode:                   getEnv("COMPILATION_MODE", ModeAll),
 		MongoURI:               getEnv("MONGO_URI", "mongodb://localhost:27017"),
 		MongoDatabase:          getEnv("MONGO_DATABASE", "texflow"),
 		RedisAddr:              getEnv("REDIS_ADDR", "localhost:6379"),
@@ -117,12 +156,18 @@ func Load() (*Config, error) {
 		CompilationCPUs:        compilationCPUs * 1e9, // Convert to nano CPUs
 		MaxWorkers:             maxWorkers,
 		WorkerPollInterval:     workerPollInterval,
+		WorkerDrainTimeout:     workerDrainTimeout,
 		EnableCache:            getEnv("ENABLE_COMPILATION_CACHE", "true") == "true",
 		CacheTTL:               cacheTTL,
 		MaxCompilationsPerUser: maxCompilationsPerUser,
+		WebhookWorkers:         webhookWorkers,
+		WebhookMaxAttempts:     webhookMaxAttempts,
+		WebhookTimeout:         webhookTimeout,
+		WebhookAllowPrivate:    getEnv("WEBHOOK_ALLOW_PRIVATE", "false") == "true",
 		DockerHost:             getEnv("DOCKER_HOST", ""),
 		TexLiveImage:           getEnv("TEXLIVE_IMAGE", "texlive/texlive:latest"),
 		CompilationVolume:      getEnv("COMPILATION_VOLUME", "/tmp/compilations"),
+		RunMigrations:          getEnv("RUN_MIGRATIONS", "true") == "true",
 		LogLevel:               getEnv("LOG_LEVEL", "info"),
 	}
 
@@ -147,9 +192,27 @@ func (c *Config) Validate() error {
 	if c.MaxWorkers <= 0 {
 		return fmt.Errorf("MAX_COMPILATION_WORKERS must be positive")
 	}
+	if c.WebhookMaxAttempts <= 0 {
+		return fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be positive")
+	}
+	switch c.Mode {
+	case ModeAPI, ModeWorker, ModeAll:
+	default:
+		return fmt.Errorf("invalid COMPILATION_MODE %q: must be api, worker or all", c.Mode)
+	}
 	return nil
 }
 
+// RunsAPI reports whether the process serves the HTTP API
+func (c *Config) RunsAPI() bool {
+	return c.Mode == ModeAPI || c.Mode == ModeAll
+}
+
+// RunsWorkers reports whether the process runs compilation workers
+func (c *Config) RunsWorkers() bool {
+	return c.Mode == ModeWorker || c.Mode == ModeAll
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
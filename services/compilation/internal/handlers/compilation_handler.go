@@ -41,6 +41,7 @@ func NewCompilationHandler(
 // @Param request body models.CompileRequest true "Compile request"
 // @Success 202 {object} models.Compilation
 // @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
 // @Failure 500 {object} map[string]string
 // @Router /compilation/compile [post]
 func (h *CompilationHandler) Compile(c *gin.Context) {
@@ -69,6 +70,17 @@ func (h *CompilationHandler) Compile(c *gin.Context) {
 		return
 	}
 
+	canEdit, err := h.projectService.UserCanEdit(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.logger.Error("Failed to check project access", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project access"})
+		return
+	}
+	if !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
 	// Get project files
 	files, err := h.projectService.GetProjectFiles(c.Request.Context(), projectID)
 	if err != nil {
@@ -77,6 +89,15 @@ func (h *CompilationHandler) Compile(c *gin.Context) {
 		return
 	}
 
+	// Fall back to the project's post-processing settings
+	postProcess := req.PostProcess
+	if postProcess == nil {
+		postProcess, err = h.projectService.GetPostProcessOptions(c.Request.Context(), projectID)
+		if err != nil {
+			h.logger.Warn("Failed to get project post-processing settings", zap.Error(err))
+		}
+	}
+
 	// Request compilation
 	compilation, err := h.compilationService.RequestCompilation(
 		c.Request.Context(),
@@ -85,6 +106,7 @@ func (h *CompilationHandler) Compile(c *gin.Context) {
 		req.Compiler,
 		req.MainFile,
 		files,
+		postProcess,
 	)
 	if err != nil {
 		h.logger.Error("Failed to request compilation", zap.Error(err))
@@ -102,6 +124,7 @@ func (h *CompilationHandler) Compile(c *gin.Context) {
 // @Security BearerAuth
 // @Param id path string true "Compilation ID"
 // @Success 200 {object} models.Compilation
+// @Failure 403 {object} map[string]string
 // @Failure 404 {object} map[string]string
 // @Router /compilation/{id} [get]
 func (h *CompilationHandler) GetCompilation(c *gin.Context) {
@@ -125,6 +148,76 @@ func (h *CompilationHandler) GetCompilation(c *gin.Context) {
 		return
 	}
 
+	hasAccess, err := h.projectService.UserHasAccess(c.Request.Context(), compilation.ProjectID, userID)
+	if err != nil {
+		h.logger.Error("Failed to check project access", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	c.JSON(http.StatusOK, compilation)
+}
+
+// CancelCompilation cancels a queued or running compilation
+// @Summary Cancel a compilation
+// @Tags compilation
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Compilation ID"
+// @Success 200 {object} models.Compilation
+// @Failure 403 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Router /compilation/{id}/cancel [post]
+func (h *CompilationHandler) CancelCompilation(c *gin.Context) {
+	compilationID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid compilation ID"})
+		return
+	}
+
+	userIDStr, _ := c.Get("user_id")
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	compilation, err := h.compilationService.GetCompilation(c.Request.Context(), compilationID, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	canEdit, err := h.projectService.UserCanEdit(c.Request.Context(), compilation.ProjectID, userID)
+	if err != nil {
+		h.logger.Error("Failed to check project access", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project access"})
+		return
+	}
+	if !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	compilation, err = h.compilationService.CancelCompilation(c.Request.Context(), compilationID)
+	if err != nil {
+		switch err.Error() {
+		case "compilation not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "compilation already finished":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to cancel compilation", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel compilation"})
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, compilation)
 }
 
@@ -136,6 +229,7 @@ func (h *CompilationHandler) GetCompilation(c *gin.Context) {
 // @Param project_id path string true "Project ID"
 // @Param limit query int false "Limit"
 // @Success 200 {array} models.Compilation
+// @Failure 403 {object} map[string]string
 // @Router /compilation/project/{project_id} [get]
 func (h *CompilationHandler) ListCompilations(c *gin.Context) {
 	projectIDStr := c.Param("project_id")
@@ -145,11 +239,29 @@ func (h *CompilationHandler) ListCompilations(c *gin.Context) {
 		return
 	}
 
+	userIDStr, _ := c.Get("user_id")
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
 	limit := 20
 	if l := c.Query("limit"); l != "" {
 		fmt.Sscanf(l, "%d", &limit)
 	}
 
+	hasAccess, err := h.projectService.UserHasAccess(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.logger.Error("Failed to check project access", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
 	compilations, err := h.compilationService.ListProjectCompilations(c.Request.Context(), projectID, limit)
 	if err != nil {
 		h.logger.Error("Failed to list compilations", zap.Error(err))
@@ -160,6 +272,83 @@ func (h *CompilationHandler) ListCompilations(c *gin.Context) {
 	c.JSON(http.StatusOK, compilations)
 }
 
+// GetProjectAnalytics retrieves compilation history analytics for a project
+// @Summary Get project compilation analytics
+// @Tags compilation
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path string true "Project ID"
+// @Param from query string false "Start of range (RFC3339), defaults to 30 days ago"
+// @Param to query string false "End of range (RFC3339), defaults to now"
+// @Param bucket query string false "Bucket size: day, week or month"
+// @Param limit query int false "Number of top errors and warnings"
+// @Success 200 {object} models.ProjectAnalytics
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /compilation/project/{project_id}/analytics [get]
+func (h *CompilationHandler) GetProjectAnalytics(c *gin.Context) {
+	projectID, err := primitive.ObjectIDFromHex(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+
+	userIDStr, _ := c.Get("user_id")
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	to := time.Now().UTC()
+	if t := c.Query("to"); t != "" {
+		if to, err = time.Parse(time.RFC3339, t); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to timestamp"})
+			return
+		}
+	}
+
+	from := to.Add(-30 * 24 * time.Hour)
+	if f := c.Query("from"); f != "" {
+		if from, err = time.Parse(time.RFC3339, f); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from timestamp"})
+			return
+		}
+	}
+
+	bucket := models.AnalyticsBucketUnit(c.DefaultQuery("bucket", string(models.BucketDay)))
+
+	limit := 10
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+
+	hasAccess, err := h.projectService.UserHasAccess(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.logger.Error("Failed to check project access", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project access"})
+		return
+	}
+	if !hasAccess {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return
+	}
+
+	analytics, err := h.compilationService.GetProjectAnalytics(c.Request.Context(), projectID, from, to, bucket, limit)
+	if err != nil {
+		switch err.Error() {
+		case "invalid time range", "time range too large", "invalid bucket":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to get project analytics", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get project analytics"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, analytics)
+}
+
 // GetStats retrieves compilation statistics
 // @Summary Get compilation statistics
 // @Tags compilation
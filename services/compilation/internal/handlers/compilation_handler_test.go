@@ -0,0 +1,292 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"compilation/internal/handlers"
+	"compilation/internal/models"
+	"compilation/internal/queue"
+	"compilation/internal/repository"
+	"compilation/internal/service"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// These tests run the compilation handlers against real MongoDB and Redis
+// instances. They are skipped unless TEST_MONGO_URI and TEST_REDIS_ADDR are
+// set. Object storage is not needed for the records they create.
+
+type handlerEnv struct {
+	db     *mongo.Database
+	redis  *redis.Client
+	repo   *repository.CompilationRepository
+	router *gin.Engine
+}
+
+func newHandlerEnv(t *testing.T) *handlerEnv {
+	t.Helper()
+
+	mongoURI := os.Getenv("TEST_MONGO_URI")
+	redisAddr := os.Getenv("TEST_REDIS_ADDR")
+	if mongoURI == "" || redisAddr == "" {
+		t.Skip("TEST_MONGO_URI and TEST_REDIS_ADDR are required for handler tests")
+	}
+
+	ctx := context.Background()
+	log := zap.NewNop()
+
+	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	db := mongoClient.Database("texflow_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		mongoClient.Disconnect(context.Background())
+	})
+
+	redisDB, err := strconv.Atoi(getEnv("TEST_REDIS_DB", "15"))
+	if err != nil {
+		t.Fatalf("invalid TEST_REDIS_DB: %v", err)
+	}
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     redisAddr,
+		Password: os.Getenv("TEST_REDIS_PASSWORD"),
+		DB:       redisDB,
+	})
+	t.Cleanup(func() { redisClient.Close() })
+
+	repo := repository.NewCompilationRepository(db)
+	compilationService := service.NewCompilationService(
+		repo,
+		queue.NewRedisQueue(redisClient, log),
+		redisClient,
+		nil,
+		log,
+		false,
+		time.Minute,
+		100,
+	)
+	handler := handlers.NewCompilationHandler(compilationService, service.NewProjectService(db, nil, log), log)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", c.GetHeader("X-User-ID"))
+	})
+	router.POST("/compilation/compile", handler.Compile)
+	router.GET("/compilation/:id", handler.GetCompilation)
+	router.POST("/compilation/:id/cancel", handler.CancelCompilation)
+	router.GET("/compilation/project/:project_id", handler.ListCompilations)
+
+	return &handlerEnv{db: db, redis: redisClient, repo: repo, router: router}
+}
+
+// project creates a project owned by owner
+func (e *handlerEnv) project(t *testing.T, owner primitive.ObjectID) primitive.ObjectID {
+	t.Helper()
+
+	id := primitive.NewObjectID()
+	_, err := e.db.Collection("projects").InsertOne(context.Background(), bson.M{
+		"_id":           id,
+		"owner_id":      owner,
+		"collaborators": bson.A{},
+		"deleted_at":    nil,
+		"archived_at":   nil,
+	})
+	if err != nil {
+		t.Fatalf("failed to create project: %v", err)
+	}
+	return id
+}
+
+// grant gives a user a share link grant that expires at expiresAt
+func (e *handlerEnv) grant(t *testing.T, projectID, userID primitive.ObjectID, role string, expiresAt time.Time) {
+	t.Helper()
+
+	_, err := e.db.Collection("project_share_grants").InsertOne(context.Background(), bson.M{
+		"project_id": projectID,
+		"user_id":    userID,
+		"role":       role,
+		"expires_at": expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("failed to create share grant: %v", err)
+	}
+}
+
+// compilation creates a compilation record with the given status
+func (e *handlerEnv) compilation(t *testing.T, projectID, userID primitive.ObjectID, status models.CompilationStatus) primitive.ObjectID {
+	t.Helper()
+
+	ctx := context.Background()
+	compilation := &models.Compilation{ProjectID: projectID, UserID: userID, Compiler: "pdflatex", MainFile: "main.tex"}
+	if err := e.repo.Create(ctx, compilation); err != nil {
+		t.Fatalf("failed to create compilation: %v", err)
+	}
+	if status != models.StatusQueued {
+		if err := e.repo.UpdateStatus(ctx, compilation.ID, status); err != nil {
+			t.Fatalf("failed to set compilation status: %v", err)
+		}
+	}
+	return compilation.ID
+}
+
+// do serves a request with a JSON body as the given user
+func (e *handlerEnv) do(method, path string, userID primitive.ObjectID, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-User-ID", userID.Hex())
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCancelCompilation(t *testing.T) {
+	env := newHandlerEnv(t)
+	owner := primitive.NewObjectID()
+	projectID := env.project(t, owner)
+
+	queued := env.compilation(t, projectID, owner, models.StatusQueued)
+	rec := env.do(http.MethodPost, "/compilation/"+queued.Hex()+"/cancel", owner, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("cancel returned %d: %s", rec.Code, rec.Body.String())
+	}
+	var cancelled models.Compilation
+	if err := json.Unmarshal(rec.Body.Bytes(), &cancelled); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if cancelled.Status != models.StatusCancelled {
+		t.Errorf("expected status %q, got %q", models.StatusCancelled, cancelled.Status)
+	}
+
+	// A worker picking the job up afterwards skips it
+	if err := env.repo.UpdateStatus(context.Background(), queued, models.StatusRunning); err == nil || err.Error() != "compilation cancelled" {
+		t.Errorf("expected cancelled compilation not to start, got %v", err)
+	}
+
+	if rec := env.do(http.MethodPost, "/compilation/"+queued.Hex()+"/cancel", owner, ""); rec.Code != http.StatusConflict {
+		t.Errorf("second cancel returned %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if rec := env.do(http.MethodPost, "/compilation/"+primitive.NewObjectID().Hex()+"/cancel", owner, ""); rec.Code != http.StatusNotFound {
+		t.Errorf("cancel of unknown compilation returned %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestCancelRunningCompilationReachesWorkers(t *testing.T) {
+	env := newHandlerEnv(t)
+	owner := primitive.NewObjectID()
+	projectID := env.project(t, owner)
+	running := env.compilation(t, projectID, owner, models.StatusRunning)
+
+	ctx := context.Background()
+	sub := queue.NewRedisQueue(env.redis, zap.NewNop()).SubscribeCancellations(ctx)
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	if rec := env.do(http.MethodPost, "/compilation/"+running.Hex()+"/cancel", owner, ""); rec.Code != http.StatusOK {
+		t.Fatalf("cancel returned %d: %s", rec.Code, rec.Body.String())
+	}
+
+	select {
+	case msg := <-sub.Channel():
+		if msg.Payload != running.Hex() {
+			t.Errorf("expected cancellation of %s, got %s", running.Hex(), msg.Payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("workers were not told to cancel the compilation")
+	}
+}
+
+func TestCancelCompilationRequiresEditAccess(t *testing.T) {
+	env := newHandlerEnv(t)
+	owner := primitive.NewObjectID()
+	projectID := env.project(t, owner)
+
+	// A former editor whose share link grant has been revoked
+	editor := primitive.NewObjectID()
+	env.grant(t, projectID, editor, "editor", time.Now().Add(-time.Minute))
+	compilation := env.compilation(t, projectID, editor, models.StatusQueued)
+
+	if rec := env.do(http.MethodPost, "/compilation/"+compilation.Hex()+"/cancel", editor, ""); rec.Code != http.StatusForbidden {
+		t.Errorf("cancel returned %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	stored, err := env.repo.FindByID(context.Background(), compilation)
+	if err != nil {
+		t.Fatalf("FindByID failed: %v", err)
+	}
+	if stored.Status != models.StatusQueued {
+		t.Errorf("expected compilation to stay %q, got %q", models.StatusQueued, stored.Status)
+	}
+}
+
+func TestRevokedGrantLosesCompilationAccess(t *testing.T) {
+	env := newHandlerEnv(t)
+	owner := primitive.NewObjectID()
+	projectID := env.project(t, owner)
+
+	// The editor compiled while their grant was valid; it has since been revoked
+	editor := primitive.NewObjectID()
+	env.grant(t, projectID, editor, "editor", time.Now().Add(-time.Minute))
+	compilation := env.compilation(t, projectID, editor, models.StatusQueued)
+
+	// A viewer whose grant is still valid keeps read access
+	viewer := primitive.NewObjectID()
+	env.grant(t, projectID, viewer, "viewer", time.Now().Add(time.Hour))
+
+	compile := `{"project_id": "` + projectID.Hex() + `", "main_file": "main.tex"}`
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		user   primitive.ObjectID
+		body   string
+		want   int
+	}{
+		{"compile", http.MethodPost, "/compilation/compile", editor, compile, http.StatusForbidden},
+		{"get compilation", http.MethodGet, "/compilation/" + compilation.Hex(), editor, "", http.StatusForbidden},
+		{"list compilations", http.MethodGet, "/compilation/project/" + projectID.Hex(), editor, "", http.StatusForbidden},
+		{"viewer cannot compile", http.MethodPost, "/compilation/compile", viewer, compile, http.StatusForbidden},
+		{"viewer lists compilations", http.MethodGet, "/compilation/project/" + projectID.Hex(), viewer, "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := env.do(tt.method, tt.path, tt.user, tt.body); rec.Code != tt.want {
+				t.Errorf("%s %s returned %d, want %d: %s", tt.method, tt.path, rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	count, err := env.db.Collection("compilations").CountDocuments(context.Background(), bson.M{"project_id": projectID})
+	if err != nil {
+		t.Fatalf("CountDocuments failed: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected no compilation to be requested, found %d", count-1)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
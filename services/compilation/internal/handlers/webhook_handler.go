@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"compilation/internal/models"
+	"compilation/internal/service"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// WebhookHandler handles webhook subscription HTTP requests
+type WebhookHandler struct {
+	webhookService *service.WebhookService
+	projectService *service.ProjectService
+	logger         *zap.Logger
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(
+	webhookService *service.WebhookService,
+	projectService *service.ProjectService,
+	logger *zap.Logger,
+) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+		projectService: projectService,
+		logger:         logger,
+	}
+}
+
+// CreateWebhook subscribes a webhook to a project's compilation events
+// @Summary Create a project webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path string true "Project ID"
+// @Param request body models.CreateWebhookRequest true "Webhook"
+// @Success 201 {object} models.Webhook
+// @Failure 400 {object} map[string]string
+// @Failure 403 {object} map[string]string
+// @Router /compilation/project/{project_id}/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	projectID, userID, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := h.webhookService.CreateWebhook(c.Request.Context(), projectID, userID, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook)
+}
+
+// ListWebhooks lists a project's webhooks
+// @Summary List project webhooks
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path string true "Project ID"
+// @Success 200 {array} models.Webhook
+// @Router /compilation/project/{project_id}/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	projectID, _, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	webhooks, err := h.webhookService.ListWebhooks(c.Request.Context(), projectID)
+	if err != nil {
+		h.respondError(c, err, "Failed to list webhooks")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// UpdateWebhook updates a webhook's URL, events or state, or rotates its secret
+// @Summary Update a project webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path string true "Project ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param request body models.UpdateWebhookRequest true "Webhook update"
+// @Success 200 {object} models.Webhook
+// @Router /compilation/project/{project_id}/webhooks/{webhook_id} [patch]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	projectID, _, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := h.webhookService.UpdateWebhook(c.Request.Context(), projectID, webhookID, &req)
+	if err != nil {
+		h.respondError(c, err, "Failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook deletes a webhook
+// @Summary Delete a project webhook
+// @Tags webhooks
+// @Security BearerAuth
+// @Param project_id path string true "Project ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 204
+// @Router /compilation/project/{project_id}/webhooks/{webhook_id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	projectID, _, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	if err := h.webhookService.DeleteWebhook(c.Request.Context(), projectID, webhookID); err != nil {
+		h.respondError(c, err, "Failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// PingWebhook queues a ping delivery
+// @Summary Send a test delivery
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path string true "Project ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Success 202 {object} models.WebhookDelivery
+// @Router /compilation/project/{project_id}/webhooks/{webhook_id}/ping [post]
+func (h *WebhookHandler) PingWebhook(c *gin.Context) {
+	projectID, _, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	delivery, err := h.webhookService.Ping(c.Request.Context(), projectID, webhookID)
+	if err != nil {
+		h.respondError(c, err, "Failed to queue ping")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// ListDeliveries lists a webhook's delivery log
+// @Summary List webhook deliveries
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path string true "Project ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param limit query int false "Limit"
+// @Success 200 {array} models.WebhookDelivery
+// @Router /compilation/project/{project_id}/webhooks/{webhook_id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	projectID, _, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	limit := 20
+	if l := c.Query("limit"); l != "" {
+		fmt.Sscanf(l, "%d", &limit)
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(c.Request.Context(), projectID, webhookID, limit)
+	if err != nil {
+		h.respondError(c, err, "Failed to list deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// Redeliver queues a new delivery of an earlier payload
+// @Summary Redeliver a webhook delivery
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param project_id path string true "Project ID"
+// @Param webhook_id path string true "Webhook ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 202 {object} models.WebhookDelivery
+// @Router /compilation/project/{project_id}/webhooks/{webhook_id}/deliveries/{delivery_id}/redeliver [post]
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	projectID, _, ok := h.authorize(c)
+	if !ok {
+		return
+	}
+
+	webhookID, err := primitive.ObjectIDFromHex(c.Param("webhook_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return
+	}
+
+	deliveryID, err := primitive.ObjectIDFromHex(c.Param("delivery_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid delivery ID"})
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(c.Request.Context(), projectID, webhookID, deliveryID)
+	if err != nil {
+		h.respondError(c, err, "Failed to redeliver")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, delivery)
+}
+
+// authorize parses the project and user IDs and checks that the user may
+// manage the project's webhooks. It writes the error response on failure.
+func (h *WebhookHandler) authorize(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	projectID, err := primitive.ObjectIDFromHex(c.Param("project_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	userIDStr, _ := c.Get("user_id")
+	userID, err := primitive.ObjectIDFromHex(userIDStr.(string))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	canEdit, err := h.projectService.UserCanEdit(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.logger.Error("Failed to check project access", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project access"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+	if !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return projectID, userID, true
+}
+
+// respondError maps webhook service errors to HTTP responses
+func (h *WebhookHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case err.Error() == "webhook not found", err.Error() == "delivery not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err.Error() == "invalid webhook URL",
+		strings.HasPrefix(err.Error(), "invalid event"),
+		strings.HasPrefix(err.Error(), "webhook limit reached"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
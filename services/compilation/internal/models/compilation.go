@@ -34,6 +34,11 @@ type Compilation struct {
 	OutputFileKey string           `bson:"output_file_key,omitempty" json:"output_file_key,omitempty"` // MinIO key
 	LogFileKey    string           `bson:"log_file_key,omitempty" json:"log_file_key,omitempty"`
 	OutputURL     string           `bson:"-" json:"output_url,omitempty"` // Presigned URL
+	LogURL        string           `bson:"-" json:"log_url,omitempty"`    // Presigned URL
+
+	// Post-processing
+	PostProcess   *PostProcessOptions   `bson:"post_process,omitempty" json:"post_process,omitempty"`
+	Artifacts     []CompilationArtifact `bson:"artifacts,omitempty" json:"artifacts,omitempty"`
 
 	// Metrics
 	StartedAt     *time.Time         `bson:"started_at,omitempty" json:"started_at,omitempty"`
@@ -43,6 +48,7 @@ type Compilation struct {
 	// Error information
 	ErrorMessage  string             `bson:"error_message,omitempty" json:"error_message,omitempty"`
 	ExitCode      int                `bson:"exit_code,omitempty" json:"exit_code,omitempty"`
+	Diagnostics   *LogDiagnostics    `bson:"diagnostics,omitempty" json:"diagnostics,omitempty"`
 
 	// Cache information
 	CachedResult  bool               `bson:"cached_result" json:"cached_result"`
@@ -62,6 +68,7 @@ type CompilationJob struct {
 	InputHash     string                 `json:"input_hash"`
 	Files         map[string]string      `json:"files"` // filename -> content
 	Priority      int                    `json:"priority"`
+	PostProcess   *PostProcessOptions    `json:"post_process,omitempty"`
 }
 
 // CompileRequest represents a compilation request from a client
@@ -69,6 +76,9 @@ type CompileRequest struct {
 	ProjectID string `json:"project_id" binding:"required"`
 	Compiler  string `json:"compiler" binding:"omitempty,oneof=pdflatex xelatex lualatex"`
 	MainFile  string `json:"main_file" binding:"required"`
+
+	// Overrides the project's post-processing settings when set
+	PostProcess *PostProcessOptions `json:"post_process"`
 }
 
 // CompilationResult represents the result of a compilation
@@ -80,6 +90,66 @@ type CompilationResult struct {
 	ErrorMessage  string            `json:"error_message,omitempty"`
 	DurationMs    int64             `json:"duration_ms,omitempty"`
 	CachedResult  bool              `json:"cached_result"`
+	Artifacts     []CompilationArtifact `json:"artifacts,omitempty"`
+	Diagnostics   *LogDiagnostics   `json:"diagnostics,omitempty"`
+}
+
+// LogDiagnostics summarizes the normalized errors and warnings in a compilation log
+type LogDiagnostics struct {
+	Errors   []DiagnosticCount `bson:"errors,omitempty" json:"errors,omitempty"`
+	Warnings []DiagnosticCount `bson:"warnings,omitempty" json:"warnings,omitempty"`
+}
+
+// DiagnosticCount represents how often a diagnostic type occurred
+type DiagnosticCount struct {
+	Type   string `bson:"type" json:"type"`
+	Count  int64  `bson:"count" json:"count"`
+	Builds int64  `bson:"builds,omitempty" json:"builds,omitempty"` // Builds affected (analytics only)
+}
+
+// ArtifactVariant identifies a post-processed variant of the output PDF
+type ArtifactVariant string
+
+const (
+	VariantPDFA       ArtifactVariant = "pdfa"       // PDF/A-2b archival copy
+	VariantLinearized ArtifactVariant = "linearized" // Fast web view
+	VariantOptimized  ArtifactVariant = "optimized"  // Downsampled images
+)
+
+// ValidationStatus represents the validation outcome of an artifact
+type ValidationStatus string
+
+const (
+	ValidationPassed     ValidationStatus = "passed"
+	ValidationFailed     ValidationStatus = "failed"
+	ValidationUnverified ValidationStatus = "unverified" // Validator not available
+	ValidationError      ValidationStatus = "error"      // Variant could not be produced
+)
+
+// DefaultImageDPI is the target resolution used when downsampling images
+const DefaultImageDPI = 150
+
+// PostProcessOptions selects the optional steps run after a successful build
+type PostProcessOptions struct {
+	PDFA      bool `bson:"pdfa" json:"pdfa"`
+	Linearize bool `bson:"linearize" json:"linearize"`
+	Optimize  bool `bson:"optimize" json:"optimize"`
+	ImageDPI  int  `bson:"image_dpi,omitempty" json:"image_dpi,omitempty" binding:"omitempty,min=36,max=1200"`
+}
+
+// Enabled reports whether any post-processing step is requested
+func (o *PostProcessOptions) Enabled() bool {
+	return o != nil && (o.PDFA || o.Linearize || o.Optimize)
+}
+
+// CompilationArtifact represents a post-processed variant of the output PDF
+type CompilationArtifact struct {
+	Variant           ArtifactVariant  `bson:"variant" json:"variant"`
+	FileKey           string           `bson:"file_key,omitempty" json:"file_key,omitempty"` // MinIO key
+	URL               string           `bson:"-" json:"url,omitempty"`                        // Presigned URL
+	SizeBytes         int64            `bson:"size_bytes,omitempty" json:"size_bytes,omitempty"`
+	ValidationStatus  ValidationStatus `bson:"validation_status" json:"validation_status"`
+	ValidationMessage string           `bson:"validation_message,omitempty" json:"validation_message,omitempty"`
 }
 
 // CompilationStats represents compilation statistics
@@ -113,3 +183,63 @@ type QueueStats struct {
 	TotalWorkers   int            `json:"total_workers"`
 	Workers        []WorkerStatus `json:"workers"`
 }
+
+// AnalyticsBucketUnit is the date bucket size for project analytics
+type AnalyticsBucketUnit string
+
+const (
+	BucketDay   AnalyticsBucketUnit = "day"
+	BucketWeek  AnalyticsBucketUnit = "week"
+	BucketMonth AnalyticsBucketUnit = "month"
+)
+
+// MaxAnalyticsRange is the longest period a single analytics request may cover
+const MaxAnalyticsRange = 366 * 24 * time.Hour
+
+// DurationPercentiles summarizes compilation durations in milliseconds.
+// Cached results are excluded since they do not run a build.
+type DurationPercentiles struct {
+	Avg float64 `bson:"avg" json:"avg"`
+	P50 float64 `bson:"p50" json:"p50"`
+	P90 float64 `bson:"p90" json:"p90"`
+	P95 float64 `bson:"p95" json:"p95"`
+	P99 float64 `bson:"p99" json:"p99"`
+}
+
+// AnalyticsSummary holds build outcome totals for a period
+type AnalyticsSummary struct {
+	Total       int64               `json:"total"`
+	Succeeded   int64               `json:"succeeded"`
+	Failed      int64               `json:"failed"` // failed or timed out
+	Cached      int64               `json:"cached"`
+	SuccessRate float64             `json:"success_rate"` // percentage of finished builds
+	Duration    DurationPercentiles `json:"duration_ms"`
+}
+
+// AnalyticsBucket holds build outcome totals for one date bucket
+type AnalyticsBucket struct {
+	Start time.Time `json:"start"`
+	AnalyticsSummary
+}
+
+// UserBuildStats represents the builds triggered by one user
+type UserBuildStats struct {
+	UserID      primitive.ObjectID `json:"user_id"`
+	Total       int64              `json:"total"`
+	Succeeded   int64              `json:"succeeded"`
+	Failed      int64              `json:"failed"`
+	LastBuildAt time.Time          `json:"last_build_at"`
+}
+
+// ProjectAnalytics represents compilation history analytics for a project
+type ProjectAnalytics struct {
+	ProjectID   primitive.ObjectID  `json:"project_id"`
+	From        time.Time           `json:"from"`
+	To          time.Time           `json:"to"`
+	BucketUnit  AnalyticsBucketUnit `json:"bucket_unit"`
+	Summary     AnalyticsSummary    `json:"summary"`
+	Buckets     []AnalyticsBucket   `json:"buckets"`
+	TopErrors   []DiagnosticCount   `json:"top_errors"`
+	TopWarnings []DiagnosticCount   `json:"top_warnings"`
+	Users       []UserBuildStats    `json:"users"`
+}
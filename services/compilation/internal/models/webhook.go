@@ -0,0 +1,122 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookEvent represents a compilation event a webhook can subscribe to
+type WebhookEvent string
+
+const (
+	EventCompilationCompleted WebhookEvent = "compilation.completed"
+	EventCompilationFailed    WebhookEvent = "compilation.failed" // failed or timed out
+	EventCompilationCancelled WebhookEvent = "compilation.cancelled"
+	EventPing                 WebhookEvent = "ping"
+)
+
+// WebhookEvents lists the events a webhook can subscribe to
+var WebhookEvents = []WebhookEvent{
+	EventCompilationCompleted,
+	EventCompilationFailed,
+	EventCompilationCancelled,
+}
+
+// EventForStatus returns the webhook event for a finished compilation status
+func EventForStatus(status CompilationStatus) (WebhookEvent, bool) {
+	switch status {
+	case StatusCompleted:
+		return EventCompilationCompleted, true
+	case StatusFailed, StatusTimeout:
+		return EventCompilationFailed, true
+	case StatusCancelled:
+		return EventCompilationCancelled, true
+	default:
+		return "", false
+	}
+}
+
+// Webhook represents a per-project webhook subscription
+type Webhook struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID primitive.ObjectID `bson:"project_id" json:"project_id"`
+	URL       string             `bson:"url" json:"url"`
+	Secret    string             `bson:"secret" json:"secret,omitempty"` // Only returned on creation
+	Events    []WebhookEvent     `bson:"events" json:"events"`
+	Active    bool               `bson:"active" json:"active"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// DeliveryStatus represents the state of a webhook delivery
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed" // Retries exhausted
+)
+
+// WebhookDelivery represents a queued webhook delivery and its attempt log
+type WebhookDelivery struct {
+	ID            primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	WebhookID     primitive.ObjectID  `bson:"webhook_id" json:"webhook_id"`
+	ProjectID     primitive.ObjectID  `bson:"project_id" json:"project_id"`
+	Event         WebhookEvent        `bson:"event" json:"event"`
+	Payload       string              `bson:"payload" json:"payload"`
+	Status        DeliveryStatus      `bson:"status" json:"status"`
+	Attempts      []DeliveryAttempt   `bson:"attempts" json:"attempts"`
+	NextAttemptAt *time.Time          `bson:"next_attempt_at,omitempty" json:"next_attempt_at,omitempty"`
+	LockedUntil   *time.Time          `bson:"locked_until,omitempty" json:"-"`
+	RedeliveryOf  *primitive.ObjectID `bson:"redelivery_of,omitempty" json:"redelivery_of,omitempty"`
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// DeliveryAttempt records a single HTTP attempt of a delivery
+type DeliveryAttempt struct {
+	At           time.Time `bson:"at" json:"at"`
+	ResponseCode int       `bson:"response_code,omitempty" json:"response_code,omitempty"`
+	ResponseBody string    `bson:"response_body,omitempty" json:"response_body,omitempty"` // Truncated
+	Error        string    `bson:"error,omitempty" json:"error,omitempty"`
+	DurationMs   int64     `bson:"duration_ms" json:"duration_ms"`
+}
+
+// WebhookPayload is the JSON body sent to webhook endpoints
+type WebhookPayload struct {
+	ID          string              `json:"id"` // Delivery ID
+	Event       WebhookEvent        `json:"event"`
+	Timestamp   time.Time           `json:"timestamp"`
+	ProjectID   string              `json:"project_id"`
+	Compilation *WebhookCompilation `json:"compilation,omitempty"`
+}
+
+// WebhookCompilation is the compilation summary included in webhook payloads
+type WebhookCompilation struct {
+	ID            string            `json:"id"`
+	UserID        string            `json:"user_id"`
+	Status        CompilationStatus `json:"status"`
+	Compiler      string            `json:"compiler"`
+	MainFile      string            `json:"main_file"`
+	DurationMs    int64             `json:"duration_ms,omitempty"`
+	CachedResult  bool              `json:"cached_result"`
+	ErrorMessage  string            `json:"error_message,omitempty"`
+	OutputFileKey string            `json:"output_file_key,omitempty"`
+	CompletedAt   *time.Time        `json:"completed_at,omitempty"`
+}
+
+// CreateWebhookRequest represents a request to subscribe a webhook
+type CreateWebhookRequest struct {
+	URL    string         `json:"url" binding:"required,url"`
+	Events []WebhookEvent `json:"events"` // Defaults to all compilation events
+}
+
+// UpdateWebhookRequest represents a request to update a webhook
+type UpdateWebhookRequest struct {
+	URL          *string        `json:"url,omitempty" binding:"omitempty,url"`
+	Events       []WebhookEvent `json:"events,omitempty"`
+	Active       *bool          `json:"active,omitempty"`
+	RotateSecret bool           `json:"rotate_secret,omitempty"`
+}
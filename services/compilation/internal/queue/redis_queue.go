@@ -14,6 +14,7 @@ import (
 const (
 	compilationStream = "compilation_queue"
 	consumerGroup     = "compilation_workers"
+	cancelChannel     = "compilation_cancel"
 )
 
 // RedisQueue manages the compilation job queue using Redis Streams
@@ -95,17 +96,66 @@ func (q *RedisQueue) Dequeue(ctx context.Context, workerID string, timeout time.
 	}
 
 	message := streams[0].Messages[0]
+	job, err := decodeJob(message)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return job, message.ID, nil
+}
+
+// Claim takes over the oldest job that has been pending on another consumer
+// for at least minIdle, such as one left behind by a worker that died
+// mid-job. It returns a nil job if there is none.
+func (q *RedisQueue) Claim(ctx context.Context, workerID string, minIdle time.Duration) (*models.CompilationJob, string, error) {
+	messages, _, err := q.redisClient.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   compilationStream,
+		Group:    consumerGroup,
+		Consumer: workerID,
+		MinIdle:  minIdle,
+		Start:    "0-0",
+		Count:    1,
+	}).Result()
+	if err != nil {
+		if err == redis.Nil {
+			return nil, "", nil
+		}
+		return nil, "", fmt.Errorf("failed to claim stale job: %w", err)
+	}
+
+	if len(messages) == 0 {
+		return nil, "", nil
+	}
+
+	message := messages[0]
+	job, err := decodeJob(message)
+	if err != nil {
+		// Acknowledge unreadable messages so they are not claimed again
+		q.Acknowledge(ctx, message.ID)
+		return nil, "", err
+	}
+
+	q.logger.Info("Claimed stale job",
+		zap.String("compilation_id", job.CompilationID),
+		zap.String("worker_id", workerID),
+	)
+
+	return job, message.ID, nil
+}
+
+// decodeJob decodes the job carried by a stream message
+func decodeJob(message redis.XMessage) (*models.CompilationJob, error) {
 	jobDataStr, ok := message.Values["job"].(string)
 	if !ok {
-		return nil, "", fmt.Errorf("invalid job data format")
+		return nil, fmt.Errorf("invalid job data format")
 	}
 
 	var job models.CompilationJob
 	if err := json.Unmarshal([]byte(jobDataStr), &job); err != nil {
-		return nil, "", fmt.Errorf("failed to unmarshal job: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal job: %w", err)
 	}
 
-	return &job, message.ID, nil
+	return &job, nil
 }
 
 // Acknowledge acknowledges that a job has been processed
@@ -113,6 +163,35 @@ func (q *RedisQueue) Acknowledge(ctx context.Context, messageID string) error {
 	return q.redisClient.XAck(ctx, compilationStream, consumerGroup, messageID).Err()
 }
 
+// Requeue puts a job back on the queue and acknowledges its original message.
+// The job is enqueued first so that it is never lost between the two steps.
+func (q *RedisQueue) Requeue(ctx context.Context, job *models.CompilationJob, messageID string) error {
+	if err := q.Enqueue(ctx, job); err != nil {
+		return err
+	}
+
+	if err := q.Acknowledge(ctx, messageID); err != nil {
+		return fmt.Errorf("failed to acknowledge requeued job: %w", err)
+	}
+
+	return nil
+}
+
+// PublishCancellation asks every worker process to stop the compilation if it
+// is running it
+func (q *RedisQueue) PublishCancellation(ctx context.Context, compilationID string) error {
+	if err := q.redisClient.Publish(ctx, cancelChannel, compilationID).Err(); err != nil {
+		return fmt.Errorf("failed to publish cancellation: %w", err)
+	}
+	return nil
+}
+
+// SubscribeCancellations subscribes to cancellation requests. Each message
+// payload is a compilation ID.
+func (q *RedisQueue) SubscribeCancellations(ctx context.Context) *redis.PubSub {
+	return q.redisClient.Subscribe(ctx, cancelChannel)
+}
+
 // GetQueueLength returns the current queue length
 func (q *RedisQueue) GetQueueLength(ctx context.Context) (int64, error) {
 	info, err := q.redisClient.XInfoStream(ctx, compilationStream).Result()
@@ -92,9 +92,9 @@ func (r *CompilationRepository) FindByInputHash(ctx context.Context, inputHash s
 	return &compilation, nil
 }
 
-// UpdateStatus updates the status of a compilation
+// UpdateStatus updates the status of a compilation that has not been cancelled
 func (r *CompilationRepository) UpdateStatus(ctx context.Context, id primitive.ObjectID, status models.CompilationStatus) error {
-	filter := bson.M{"_id": id}
+	filter := bson.M{"_id": id, "status": bson.M{"$ne": models.StatusCancelled}}
 	update := bson.M{
 		"$set": bson.M{
 			"status":     status,
@@ -119,16 +119,21 @@ func (r *CompilationRepository) UpdateStatus(ctx context.Context, id primitive.O
 	}
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("compilation not found")
+		return r.notUpdated(ctx, id)
 	}
 
 	return nil
 }
 
-// UpdateResult updates the result of a compilation
+// UpdateResult updates the result of a compilation. A cancelled compilation
+// only takes a cancelled result, so a job finishing as it is cancelled does
+// not overwrite the cancellation.
 func (r *CompilationRepository) UpdateResult(ctx context.Context, id primitive.ObjectID, result *models.CompilationResult) error {
 	now := time.Now()
 	filter := bson.M{"_id": id}
+	if result.Status != models.StatusCancelled {
+		filter["status"] = bson.M{"$ne": models.StatusCancelled}
+	}
 	update := bson.M{
 		"$set": bson.M{
 			"status":          result.Status,
@@ -137,6 +142,8 @@ func (r *CompilationRepository) UpdateResult(ctx context.Context, id primitive.O
 			"error_message":   result.ErrorMessage,
 			"duration_ms":     result.DurationMs,
 			"cached_result":   result.CachedResult,
+			"artifacts":       result.Artifacts,
+			"diagnostics":     result.Diagnostics,
 			"completed_at":    now,
 			"updated_at":      now,
 		},
@@ -148,12 +155,58 @@ func (r *CompilationRepository) UpdateResult(ctx context.Context, id primitive.O
 	}
 
 	if updateResult.MatchedCount == 0 {
-		return fmt.Errorf("compilation not found")
+		return r.notUpdated(ctx, id)
 	}
 
 	return nil
 }
 
+// Cancel marks a queued or running compilation as cancelled and returns the
+// compilation as it was before
+func (r *CompilationRepository) Cancel(ctx context.Context, id primitive.ObjectID) (*models.Compilation, error) {
+	now := time.Now()
+	filter := bson.M{
+		"_id": id,
+		"status": bson.M{
+			"$in": []models.CompilationStatus{models.StatusQueued, models.StatusRunning},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"status":        models.StatusCancelled,
+			"error_message": "Compilation cancelled",
+			"completed_at":  now,
+			"updated_at":    now,
+		},
+	}
+
+	var previous models.Compilation
+	err := r.collection.FindOneAndUpdate(ctx, filter, update).Decode(&previous)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			if _, err := r.FindByID(ctx, id); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("compilation already finished")
+		}
+		return nil, err
+	}
+
+	return &previous, nil
+}
+
+// notUpdated explains why a compilation matched no update
+func (r *CompilationRepository) notUpdated(ctx context.Context, id primitive.ObjectID) error {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("compilation not found")
+	}
+	return fmt.Errorf("compilation cancelled")
+}
+
 // CountActiveByUser counts active compilations for a user
 func (r *CompilationRepository) CountActiveByUser(ctx context.Context, userID primitive.ObjectID) (int64, error) {
 	filter := bson.M{
@@ -236,6 +289,241 @@ func (r *CompilationRepository) GetStats(ctx context.Context, since time.Time) (
 	}, nil
 }
 
+// GetProjectAnalytics aggregates a project's compilation history between from
+// and to, bucketed by date, in a single $facet aggregation
+func (r *CompilationRepository) GetProjectAnalytics(
+	ctx context.Context,
+	projectID primitive.ObjectID,
+	from, to time.Time,
+	unit models.AnalyticsBucketUnit,
+	topN int,
+) (*models.ProjectAnalytics, error) {
+	bucketStart := bson.M{
+		"$dateTrunc": bson.M{
+			"date":        "$created_at",
+			"unit":        string(unit),
+			"startOfWeek": "monday",
+		},
+	}
+
+	pipeline := []bson.M{
+		{
+			"$match": bson.M{
+				"project_id": projectID,
+				"created_at": bson.M{"$gte": from, "$lt": to},
+			},
+		},
+		{
+			"$facet": bson.M{
+				"summary": []bson.M{
+					{"$group": outcomeGroup(nil)},
+				},
+				"buckets": []bson.M{
+					{"$group": outcomeGroup(bucketStart)},
+					{"$sort": bson.M{"_id": 1}},
+				},
+				"errors":   diagnosticFacet("$diagnostics.errors", topN),
+				"warnings": diagnosticFacet("$diagnostics.warnings", topN),
+				"users": []bson.M{
+					{
+						"$group": bson.M{
+							"_id":           "$user_id",
+							"total":         bson.M{"$sum": 1},
+							"succeeded":     succeededCount(),
+							"failed":        failedCount(),
+							"last_build_at": bson.M{"$max": "$created_at"},
+						},
+					},
+					{"$sort": bson.D{{Key: "total", Value: -1}, {Key: "last_build_at", Value: -1}}},
+				},
+			},
+		},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Summary  []outcomeDoc    `bson:"summary"`
+		Buckets  []outcomeDoc    `bson:"buckets"`
+		Errors   []diagnosticDoc `bson:"errors"`
+		Warnings []diagnosticDoc `bson:"warnings"`
+		Users    []struct {
+			UserID      primitive.ObjectID `bson:"_id"`
+			Total       int64              `bson:"total"`
+			Succeeded   int64              `bson:"succeeded"`
+			Failed      int64              `bson:"failed"`
+			LastBuildAt time.Time          `bson:"last_build_at"`
+		} `bson:"users"`
+	}
+
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	analytics := &models.ProjectAnalytics{
+		ProjectID:   projectID,
+		From:        from,
+		To:          to,
+		BucketUnit:  unit,
+		Buckets:     []models.AnalyticsBucket{},
+		TopErrors:   []models.DiagnosticCount{},
+		TopWarnings: []models.DiagnosticCount{},
+		Users:       []models.UserBuildStats{},
+	}
+
+	if len(results) == 0 {
+		return analytics, nil
+	}
+	result := results[0]
+
+	if len(result.Summary) > 0 {
+		analytics.Summary = result.Summary[0].toSummary()
+	}
+
+	for _, bucket := range result.Buckets {
+		start, ok := bucket.ID.(primitive.DateTime)
+		if !ok {
+			continue
+		}
+		analytics.Buckets = append(analytics.Buckets, models.AnalyticsBucket{
+			Start:            start.Time().UTC(),
+			AnalyticsSummary: bucket.toSummary(),
+		})
+	}
+
+	for _, diag := range result.Errors {
+		analytics.TopErrors = append(analytics.TopErrors, diag.toCount())
+	}
+	for _, diag := range result.Warnings {
+		analytics.TopWarnings = append(analytics.TopWarnings, diag.toCount())
+	}
+
+	for _, user := range result.Users {
+		analytics.Users = append(analytics.Users, models.UserBuildStats{
+			UserID:      user.UserID,
+			Total:       user.Total,
+			Succeeded:   user.Succeeded,
+			Failed:      user.Failed,
+			LastBuildAt: user.LastBuildAt,
+		})
+	}
+
+	return analytics, nil
+}
+
+// outcomeDoc is the result of an outcomeGroup stage
+type outcomeDoc struct {
+	ID          interface{} `bson:"_id"`
+	Total       int64       `bson:"total"`
+	Succeeded   int64       `bson:"succeeded"`
+	Failed      int64       `bson:"failed"`
+	Cached      int64       `bson:"cached"`
+	AvgDuration *float64    `bson:"avg_duration"`
+	Percentiles []*float64  `bson:"percentiles"`
+}
+
+func (d outcomeDoc) toSummary() models.AnalyticsSummary {
+	summary := models.AnalyticsSummary{
+		Total:     d.Total,
+		Succeeded: d.Succeeded,
+		Failed:    d.Failed,
+		Cached:    d.Cached,
+	}
+
+	if finished := d.Succeeded + d.Failed; finished > 0 {
+		summary.SuccessRate = float64(d.Succeeded) / float64(finished) * 100
+	}
+
+	if d.AvgDuration != nil {
+		summary.Duration.Avg = *d.AvgDuration
+	}
+	targets := []*float64{&summary.Duration.P50, &summary.Duration.P90, &summary.Duration.P95, &summary.Duration.P99}
+	for i, value := range d.Percentiles {
+		if i < len(targets) && value != nil {
+			*targets[i] = *value
+		}
+	}
+
+	return summary
+}
+
+// diagnosticDoc is the result of a diagnosticFacet pipeline
+type diagnosticDoc struct {
+	Type   string `bson:"_id"`
+	Count  int64  `bson:"count"`
+	Builds int64  `bson:"builds"`
+}
+
+func (d diagnosticDoc) toCount() models.DiagnosticCount {
+	return models.DiagnosticCount{Type: d.Type, Count: d.Count, Builds: d.Builds}
+}
+
+// outcomeGroup builds a $group stage counting build outcomes and duration
+// percentiles. Cached results are excluded from durations.
+func outcomeGroup(id interface{}) bson.M {
+	builtDuration := bson.M{
+		"$cond": []interface{}{
+			bson.M{"$and": []interface{}{
+				bson.M{"$not": []interface{}{"$cached_result"}},
+				bson.M{"$gt": []interface{}{"$duration_ms", 0}},
+			}},
+			"$duration_ms",
+			nil,
+		},
+	}
+
+	return bson.M{
+		"_id":          id,
+		"total":        bson.M{"$sum": 1},
+		"succeeded":    succeededCount(),
+		"failed":       failedCount(),
+		"cached":       bson.M{"$sum": bson.M{"$cond": []interface{}{"$cached_result", 1, 0}}},
+		"avg_duration": bson.M{"$avg": builtDuration},
+		"percentiles": bson.M{
+			"$percentile": bson.M{
+				"input":  builtDuration,
+				"p":      []float64{0.5, 0.9, 0.95, 0.99},
+				"method": "approximate",
+			},
+		},
+	}
+}
+
+// diagnosticFacet builds a pipeline ranking the diagnostics stored at field.
+// Cached results carry the diagnostics of the build they reuse, so they are
+// skipped to count each build once.
+func diagnosticFacet(field string, topN int) []bson.M {
+	return []bson.M{
+		{"$match": bson.M{"cached_result": bson.M{"$ne": true}}},
+		{"$unwind": field},
+		{
+			"$group": bson.M{
+				"_id":    field + ".type",
+				"count":  bson.M{"$sum": field + ".count"},
+				"builds": bson.M{"$sum": 1},
+			},
+		},
+		{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		{"$limit": topN},
+	}
+}
+
+func succeededCount() bson.M {
+	return bson.M{"$sum": bson.M{"$cond": []interface{}{
+		bson.M{"$eq": []interface{}{"$status", models.StatusCompleted}}, 1, 0,
+	}}}
+}
+
+func failedCount() bson.M {
+	return bson.M{"$sum": bson.M{"$cond": []interface{}{
+		bson.M{"$in": []interface{}{"$status", []models.CompilationStatus{models.StatusFailed, models.StatusTimeout}}}, 1, 0,
+	}}}
+}
+
 // CreateIndexes creates necessary indexes
 func (r *CompilationRepository) CreateIndexes(ctx context.Context) error {
 	indexes := []mongo.IndexModel{
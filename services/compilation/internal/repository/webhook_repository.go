@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"compilation/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookRepository handles webhook subscriptions and the persistent delivery queue
+type WebhookRepository struct {
+	db         *mongo.Database
+	webhooks   *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+// NewWebhookRepository creates a new webhook repository
+func NewWebhookRepository(db *mongo.Database) *WebhookRepository {
+	return &WebhookRepository{
+		db:         db,
+		webhooks:   db.Collection("webhooks"),
+		deliveries: db.Collection("webhook_deliveries"),
+	}
+}
+
+// Create creates a new webhook subscription
+func (r *WebhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	webhook.ID = primitive.NewObjectID()
+	webhook.CreatedAt = time.Now()
+	webhook.UpdatedAt = time.Now()
+
+	_, err := r.webhooks.InsertOne(ctx, webhook)
+	return err
+}
+
+// FindByID finds a webhook by ID within a project
+func (r *WebhookRepository) FindByID(ctx context.Context, projectID, id primitive.ObjectID) (*models.Webhook, error) {
+	var webhook models.Webhook
+	err := r.webhooks.FindOne(ctx, bson.M{"_id": id, "project_id": projectID}).Decode(&webhook)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("webhook not found")
+		}
+		return nil, err
+	}
+
+	return &webhook, nil
+}
+
+// FindByProjectID finds all webhooks of a project
+func (r *WebhookRepository) FindByProjectID(ctx context.Context, projectID primitive.ObjectID) ([]*models.Webhook, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+
+	cursor, err := r.webhooks.Find(ctx, bson.M{"project_id": projectID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	webhooks := []*models.Webhook{}
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// FindSubscribers finds the active webhooks of a project subscribed to an event
+func (r *WebhookRepository) FindSubscribers(ctx context.Context, projectID primitive.ObjectID, event models.WebhookEvent) ([]*models.Webhook, error) {
+	cursor, err := r.webhooks.Find(ctx, bson.M{
+		"project_id": projectID,
+		"active":     true,
+		"events":     event,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var webhooks []*models.Webhook
+	if err := cursor.All(ctx, &webhooks); err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// Update updates a webhook
+func (r *WebhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	webhook.UpdatedAt = time.Now()
+
+	result, err := r.webhooks.UpdateOne(
+		ctx,
+		bson.M{"_id": webhook.ID, "project_id": webhook.ProjectID},
+		bson.M{"$set": bson.M{
+			"url":        webhook.URL,
+			"secret":     webhook.Secret,
+			"events":     webhook.Events,
+			"active":     webhook.Active,
+			"updated_at": webhook.UpdatedAt,
+		}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+
+	return nil
+}
+
+// Delete deletes a webhook and its delivery log
+func (r *WebhookRepository) Delete(ctx context.Context, projectID, id primitive.ObjectID) error {
+	result, err := r.webhooks.DeleteOne(ctx, bson.M{"_id": id, "project_id": projectID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("webhook not found")
+	}
+
+	_, err = r.deliveries.DeleteMany(ctx, bson.M{"webhook_id": id})
+	return err
+}
+
+// CreateDelivery queues a delivery for immediate dispatch
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	now := time.Now()
+	if delivery.ID.IsZero() {
+		delivery.ID = primitive.NewObjectID()
+	}
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = []models.DeliveryAttempt{}
+	delivery.NextAttemptAt = &now
+	delivery.CreatedAt = now
+	delivery.UpdatedAt = now
+
+	_, err := r.deliveries.InsertOne(ctx, delivery)
+	return err
+}
+
+// ClaimDueDelivery atomically leases the oldest pending delivery that is due.
+// It returns nil when no delivery is due. A lease that expires, e.g. because
+// the dispatcher crashed, makes the delivery claimable again.
+func (r *WebhookRepository) ClaimDueDelivery(ctx context.Context, lease time.Duration) (*models.WebhookDelivery, error) {
+	now := time.Now()
+	lockedUntil := now.Add(lease)
+
+	filter := bson.M{
+		"status":          models.DeliveryPending,
+		"next_attempt_at": bson.M{"$lte": now},
+		"$or": []bson.M{
+			{"locked_until": bson.M{"$exists": false}},
+			{"locked_until": nil},
+			{"locked_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"locked_until": lockedUntil, "updated_at": now}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "next_attempt_at", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var delivery models.WebhookDelivery
+	err := r.deliveries.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// RecordAttempt appends an attempt to a delivery and releases its lease.
+// A nil nextAttemptAt means the delivery is finished with the given status.
+func (r *WebhookRepository) RecordAttempt(
+	ctx context.Context,
+	id primitive.ObjectID,
+	attempt models.DeliveryAttempt,
+	status models.DeliveryStatus,
+	nextAttemptAt *time.Time,
+) error {
+	set := bson.M{
+		"status":     status,
+		"updated_at": time.Now(),
+	}
+	unset := bson.M{"locked_until": ""}
+	if nextAttemptAt != nil {
+		set["next_attempt_at"] = nextAttemptAt
+	} else {
+		unset["next_attempt_at"] = ""
+	}
+
+	_, err := r.deliveries.UpdateOne(ctx, bson.M{"_id": id}, bson.M{
+		"$push":  bson.M{"attempts": attempt},
+		"$set":   set,
+		"$unset": unset,
+	})
+	return err
+}
+
+// FindDeliveryByID finds a delivery by ID within a webhook
+func (r *WebhookRepository) FindDeliveryByID(ctx context.Context, webhookID, id primitive.ObjectID) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.deliveries.FindOne(ctx, bson.M{"_id": id, "webhook_id": webhookID}).Decode(&delivery)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("delivery not found")
+		}
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// FindDeliveries lists the most recent deliveries of a webhook
+func (r *WebhookRepository) FindDeliveries(ctx context.Context, webhookID primitive.ObjectID, limit int) ([]*models.WebhookDelivery, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "created_at", Value: -1}}).
+		SetLimit(int64(limit))
+
+	cursor, err := r.deliveries.Find(ctx, bson.M{"webhook_id": webhookID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []*models.WebhookDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// CreateIndexes creates necessary indexes
+func (r *WebhookRepository) CreateIndexes(ctx context.Context) error {
+	_, err := r.webhooks.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "project_id", Value: 1},
+				{Key: "active", Value: 1},
+				{Key: "events", Value: 1},
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = r.deliveries.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys: bson.D{
+				{Key: "status", Value: 1},
+				{Key: "next_attempt_at", Value: 1},
+			},
+		},
+		{
+			Keys: bson.D{
+				{Key: "webhook_id", Value: 1},
+				{Key: "created_at", Value: -1},
+			},
+		},
+		{
+			// Keep the delivery log for 30 days
+			Keys:    bson.D{{Key: "created_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(30 * 24 * 60 * 60),
+		},
+	})
+	return err
+}
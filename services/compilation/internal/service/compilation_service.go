@@ -25,6 +25,7 @@ type CompilationService struct {
 	enableCache     bool
 	cacheTTL        time.Duration
 	maxPerUser      int
+	notifier        worker.CompletionNotifier
 }
 
 // NewCompilationService creates a new compilation service
@@ -50,12 +51,18 @@ func NewCompilationService(
 	}
 }
 
+// SetNotifier sets the notifier called for compilations served from cache
+func (s *CompilationService) SetNotifier(notifier worker.CompletionNotifier) {
+	s.notifier = notifier
+}
+
 // RequestCompilation requests a new compilation
 func (s *CompilationService) RequestCompilation(
 	ctx context.Context,
 	projectID, userID primitive.ObjectID,
 	compiler, mainFile string,
 	files map[string]string,
+	postProcess *models.PostProcessOptions,
 ) (*models.Compilation, error) {
 	// Validate compiler
 	if compiler == "" {
@@ -74,7 +81,10 @@ func (s *CompilationService) RequestCompilation(
 	}
 
 	// Calculate input hash for caching
-	inputHash := worker.CalculateInputHash(files, compiler, mainFile)
+	if !postProcess.Enabled() {
+		postProcess = nil
+	}
+	inputHash := worker.CalculateInputHash(files, compiler, mainFile, postProcess)
 
 	// Check cache if enabled
 	if s.enableCache {
@@ -94,6 +104,9 @@ func (s *CompilationService) RequestCompilation(
 				InputHash:     inputHash,
 				OutputFileKey: cached.OutputFileKey,
 				LogFileKey:    cached.LogFileKey,
+				PostProcess:   postProcess,
+				Artifacts:     cached.Artifacts,
+				Diagnostics:   cached.Diagnostics,
 				CachedResult:  true,
 				DurationMs:    0, // Instant from cache
 			}
@@ -102,11 +115,16 @@ func (s *CompilationService) RequestCompilation(
 				return nil, err
 			}
 
+			if s.notifier != nil {
+				s.notifier.CompilationFinished(ctx, compilation)
+			}
+
 			// Generate presigned URLs
 			if compilation.OutputFileKey != "" {
 				url, _ := s.minioClient.GeneratePresignedURL(ctx, compilation.OutputFileKey, 1*time.Hour)
 				compilation.OutputURL = url
 			}
+			s.presignArtifacts(ctx, compilation)
 
 			return compilation, nil
 		}
@@ -114,12 +132,13 @@ func (s *CompilationService) RequestCompilation(
 
 	// Create compilation record
 	compilation := &models.Compilation{
-		ProjectID: projectID,
-		UserID:    userID,
-		Status:    models.StatusQueued,
-		Compiler:  compiler,
-		MainFile:  mainFile,
-		InputHash: inputHash,
+		ProjectID:   projectID,
+		UserID:      userID,
+		Status:      models.StatusQueued,
+		Compiler:    compiler,
+		MainFile:    mainFile,
+		InputHash:   inputHash,
+		PostProcess: postProcess,
 	}
 
 	if err := s.compilationRepo.Create(ctx, compilation); err != nil {
@@ -136,6 +155,7 @@ func (s *CompilationService) RequestCompilation(
 		InputHash:     inputHash,
 		Files:         files,
 		Priority:      0,
+		PostProcess:   postProcess,
 	}
 
 	if err := s.queue.Enqueue(ctx, job); err != nil {
@@ -174,13 +194,43 @@ func (s *CompilationService) GetCompilation(ctx context.Context, compilationID p
 	if compilation.LogFileKey != "" {
 		logURL, err := s.minioClient.GeneratePresignedURL(ctx, compilation.LogFileKey, 1*time.Hour)
 		if err == nil {
-			// Store in a temporary field for response
-			compilation.OutputURL = compilation.OutputURL // Keep PDF URL
-			// Log URL would be returned separately
-			_ = logURL
+			compilation.LogURL = logURL
+		}
+	}
+
+	s.presignArtifacts(ctx, compilation)
+
+	return compilation, nil
+}
+
+// CancelCompilation cancels a queued or running compilation. Queued jobs are
+// skipped when a worker picks them up; the worker running a job is told to
+// stop it.
+func (s *CompilationService) CancelCompilation(ctx context.Context, compilationID primitive.ObjectID) (*models.Compilation, error) {
+	previous, err := s.compilationRepo.Cancel(ctx, compilationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if previous.Status == models.StatusRunning {
+		if err := s.queue.PublishCancellation(ctx, compilationID.Hex()); err != nil {
+			s.logger.Error("Failed to publish cancellation",
+				zap.String("compilation_id", compilationID.Hex()),
+				zap.Error(err),
+			)
 		}
 	}
 
+	compilation, err := s.compilationRepo.FindByID(ctx, compilationID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Running jobs are reported by their worker once they stop
+	if previous.Status == models.StatusQueued && s.notifier != nil {
+		s.notifier.CompilationFinished(ctx, compilation)
+	}
+
 	return compilation, nil
 }
 
@@ -202,6 +252,7 @@ func (s *CompilationService) ListProjectCompilations(ctx context.Context, projec
 			if err == nil {
 				compilation.OutputURL = url
 			}
+			s.presignArtifacts(ctx, compilation)
 		}
 	}
 
@@ -214,6 +265,34 @@ func (s *CompilationService) GetStats(ctx context.Context, since time.Duration)
 	return s.compilationRepo.GetStats(ctx, sinceTime)
 }
 
+// GetProjectAnalytics retrieves compilation history analytics for a project
+func (s *CompilationService) GetProjectAnalytics(
+	ctx context.Context,
+	projectID primitive.ObjectID,
+	from, to time.Time,
+	unit models.AnalyticsBucketUnit,
+	topN int,
+) (*models.ProjectAnalytics, error) {
+	if !to.After(from) {
+		return nil, fmt.Errorf("invalid time range")
+	}
+	if to.Sub(from) > models.MaxAnalyticsRange {
+		return nil, fmt.Errorf("time range too large")
+	}
+
+	switch unit {
+	case models.BucketDay, models.BucketWeek, models.BucketMonth:
+	default:
+		return nil, fmt.Errorf("invalid bucket")
+	}
+
+	if topN <= 0 || topN > 50 {
+		topN = 10
+	}
+
+	return s.compilationRepo.GetProjectAnalytics(ctx, projectID, from, to, unit, topN)
+}
+
 // GetQueueStats retrieves queue statistics
 func (s *CompilationService) GetQueueStats(ctx context.Context) (*models.QueueStats, error) {
 	queueLength, err := s.queue.GetQueueLength(ctx)
@@ -258,6 +337,20 @@ func (s *CompilationService) checkCache(ctx context.Context, inputHash string) (
 	return compilation, nil
 }
 
+// presignArtifacts generates presigned URLs for post-processed variants
+func (s *CompilationService) presignArtifacts(ctx context.Context, compilation *models.Compilation) {
+	for i := range compilation.Artifacts {
+		artifact := &compilation.Artifacts[i]
+		if artifact.FileKey == "" {
+			continue
+		}
+		url, err := s.minioClient.GeneratePresignedURL(ctx, artifact.FileKey, 1*time.Hour)
+		if err == nil {
+			artifact.URL = url
+		}
+	}
+}
+
 func isValidCompiler(compiler string) bool {
 	validCompilers := map[string]bool{
 		"pdflatex":  true,
@@ -4,11 +4,14 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
+	"compilation/internal/models"
 	"compilation/internal/storage"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
@@ -88,3 +91,90 @@ func (s *ProjectService) GetProjectFiles(ctx context.Context, projectID primitiv
 
 	return files, nil
 }
+
+// GetPostProcessOptions retrieves the project's default post-processing settings
+func (s *ProjectService) GetPostProcessOptions(ctx context.Context, projectID primitive.ObjectID) (*models.PostProcessOptions, error) {
+	var projectDoc struct {
+		Settings struct {
+			PostProcess *models.PostProcessOptions `bson:"post_process"`
+		} `bson:"settings"`
+	}
+
+	err := s.db.Collection("projects").FindOne(
+		ctx,
+		bson.M{"_id": projectID},
+		options.FindOne().SetProjection(bson.M{"settings.post_process": 1}),
+	).Decode(&projectDoc)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("project not found")
+		}
+		return nil, err
+	}
+
+	return projectDoc.Settings.PostProcess, nil
+}
+
+// UserHasAccess checks whether a user owns, collaborates on, holds a share
+// link grant for or can view a public project that is not in the trash
+func (s *ProjectService) UserHasAccess(ctx context.Context, projectID, userID primitive.ObjectID) (bool, error) {
+	count, err := s.db.Collection("projects").CountDocuments(ctx, bson.M{
+		"_id":        projectID,
+		"deleted_at": nil,
+		"$or": []bson.M{
+			{"owner_id": userID},
+			{"collaborators.user_id": userID},
+			{"is_public": true},
+		},
+	})
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	return s.hasShareGrant(ctx, projectID, userID, bson.M{"deleted_at": nil}, "editor", "viewer")
+}
+
+// UserCanEdit checks whether a user owns, is an editor of or holds an edit
+// share link grant for a project that is neither archived nor in the trash
+func (s *ProjectService) UserCanEdit(ctx context.Context, projectID, userID primitive.ObjectID) (bool, error) {
+	count, err := s.db.Collection("projects").CountDocuments(ctx, bson.M{
+		"_id":         projectID,
+		"deleted_at":  nil,
+		"archived_at": nil,
+		"$or": []bson.M{
+			{"owner_id": userID},
+			{"collaborators": bson.M{"$elemMatch": bson.M{
+				"user_id": userID,
+				"role":    bson.M{"$in": []string{"owner", "editor"}},
+			}}},
+		},
+	})
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+
+	return s.hasShareGrant(ctx, projectID, userID, bson.M{"deleted_at": nil, "archived_at": nil}, "editor")
+}
+
+// hasShareGrant checks whether a user opened a share link with one of the
+// given roles that has not expired yet, and that the project still matches
+// the filter
+func (s *ProjectService) hasShareGrant(ctx context.Context, projectID, userID primitive.ObjectID, projectFilter bson.M, roles ...string) (bool, error) {
+	count, err := s.db.Collection("project_share_grants").CountDocuments(ctx, bson.M{
+		"project_id": projectID,
+		"user_id":    userID,
+		"role":       bson.M{"$in": roles},
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	if err != nil || count == 0 {
+		return false, err
+	}
+
+	projectFilter["_id"] = projectID
+	count, err = s.db.Collection("projects").CountDocuments(ctx, projectFilter)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
@@ -0,0 +1,321 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"compilation/internal/models"
+	"compilation/internal/repository"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// maxWebhooksPerProject bounds the number of subscriptions of a project
+const maxWebhooksPerProject = 20
+
+// WebhookService manages webhook subscriptions and queues deliveries
+type WebhookService struct {
+	repo   *repository.WebhookRepository
+	logger *zap.Logger
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(repo *repository.WebhookRepository, logger *zap.Logger) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateWebhook subscribes a URL to a project's compilation events.
+// The returned webhook carries its signing secret, which is not shown again.
+func (s *WebhookService) CreateWebhook(
+	ctx context.Context,
+	projectID, userID primitive.ObjectID,
+	req *models.CreateWebhookRequest,
+) (*models.Webhook, error) {
+	if err := validateWebhookURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	events, err := normalizeEvents(req.Events)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxWebhooksPerProject {
+		return nil, fmt.Errorf("webhook limit reached (%d)", maxWebhooksPerProject)
+	}
+
+	secret, err := generateWebhookSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &models.Webhook{
+		ProjectID: projectID,
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    events,
+		Active:    true,
+		CreatedBy: userID,
+	}
+
+	if err := s.repo.Create(ctx, webhook); err != nil {
+		return nil, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	s.logger.Info("Webhook created",
+		zap.String("webhook_id", webhook.ID.Hex()),
+		zap.String("project_id", projectID.Hex()),
+	)
+
+	return webhook, nil
+}
+
+// ListWebhooks lists a project's webhooks without their secrets
+func (s *WebhookService) ListWebhooks(ctx context.Context, projectID primitive.ObjectID) ([]*models.Webhook, error) {
+	webhooks, err := s.repo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, webhook := range webhooks {
+		webhook.Secret = ""
+	}
+
+	return webhooks, nil
+}
+
+// UpdateWebhook updates a webhook. The secret is only returned when rotated.
+func (s *WebhookService) UpdateWebhook(
+	ctx context.Context,
+	projectID, webhookID primitive.ObjectID,
+	req *models.UpdateWebhookRequest,
+) (*models.Webhook, error) {
+	webhook, err := s.repo.FindByID(ctx, projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *req.URL
+	}
+
+	if req.Events != nil {
+		events, err := normalizeEvents(req.Events)
+		if err != nil {
+			return nil, err
+		}
+		webhook.Events = events
+	}
+
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	if req.RotateSecret {
+		if webhook.Secret, err = generateWebhookSecret(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.Update(ctx, webhook); err != nil {
+		return nil, err
+	}
+
+	if !req.RotateSecret {
+		webhook.Secret = ""
+	}
+
+	return webhook, nil
+}
+
+// DeleteWebhook deletes a webhook and its delivery log
+func (s *WebhookService) DeleteWebhook(ctx context.Context, projectID, webhookID primitive.ObjectID) error {
+	return s.repo.Delete(ctx, projectID, webhookID)
+}
+
+// ListDeliveries lists the recent deliveries of a webhook
+func (s *WebhookService) ListDeliveries(ctx context.Context, projectID, webhookID primitive.ObjectID, limit int) ([]*models.WebhookDelivery, error) {
+	if _, err := s.repo.FindByID(ctx, projectID, webhookID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	return s.repo.FindDeliveries(ctx, webhookID, limit)
+}
+
+// Redeliver queues a new delivery with the payload of an earlier one
+func (s *WebhookService) Redeliver(ctx context.Context, projectID, webhookID, deliveryID primitive.ObjectID) (*models.WebhookDelivery, error) {
+	if _, err := s.repo.FindByID(ctx, projectID, webhookID); err != nil {
+		return nil, err
+	}
+
+	original, err := s.repo.FindDeliveryByID(ctx, webhookID, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &models.WebhookDelivery{
+		WebhookID:    webhookID,
+		ProjectID:    projectID,
+		Event:        original.Event,
+		Payload:      original.Payload,
+		RedeliveryOf: &original.ID,
+	}
+
+	if err := s.repo.CreateDelivery(ctx, delivery); err != nil {
+		return nil, fmt.Errorf("failed to queue delivery: %w", err)
+	}
+
+	return delivery, nil
+}
+
+// Ping queues a ping delivery so that an endpoint can be tested
+func (s *WebhookService) Ping(ctx context.Context, projectID, webhookID primitive.ObjectID) (*models.WebhookDelivery, error) {
+	webhook, err := s.repo.FindByID(ctx, projectID, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.queueDelivery(ctx, webhook, models.EventPing, nil)
+}
+
+// CompilationFinished queues deliveries for the webhooks subscribed to a
+// finished compilation's event. Errors are logged, never returned, so that
+// webhooks cannot fail a compilation.
+func (s *WebhookService) CompilationFinished(ctx context.Context, compilation *models.Compilation) {
+	event, ok := models.EventForStatus(compilation.Status)
+	if !ok {
+		return
+	}
+
+	webhooks, err := s.repo.FindSubscribers(ctx, compilation.ProjectID, event)
+	if err != nil {
+		s.logger.Error("Failed to find webhook subscribers",
+			zap.String("compilation_id", compilation.ID.Hex()),
+			zap.Error(err),
+		)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if _, err := s.queueDelivery(ctx, webhook, event, compilation); err != nil {
+			s.logger.Error("Failed to queue webhook delivery",
+				zap.String("webhook_id", webhook.ID.Hex()),
+				zap.String("compilation_id", compilation.ID.Hex()),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// queueDelivery renders the payload for an event and queues its delivery
+func (s *WebhookService) queueDelivery(
+	ctx context.Context,
+	webhook *models.Webhook,
+	event models.WebhookEvent,
+	compilation *models.Compilation,
+) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{
+		ID:        primitive.NewObjectID(),
+		WebhookID: webhook.ID,
+		ProjectID: webhook.ProjectID,
+		Event:     event,
+	}
+
+	payload := models.WebhookPayload{
+		ID:        delivery.ID.Hex(),
+		Event:     event,
+		Timestamp: time.Now().UTC(),
+		ProjectID: webhook.ProjectID.Hex(),
+	}
+	if compilation != nil {
+		payload.Compilation = &models.WebhookCompilation{
+			ID:            compilation.ID.Hex(),
+			UserID:        compilation.UserID.Hex(),
+			Status:        compilation.Status,
+			Compiler:      compilation.Compiler,
+			MainFile:      compilation.MainFile,
+			DurationMs:    compilation.DurationMs,
+			CachedResult:  compilation.CachedResult,
+			ErrorMessage:  compilation.ErrorMessage,
+			OutputFileKey: compilation.OutputFileKey,
+			CompletedAt:   compilation.CompletedAt,
+		}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %w", err)
+	}
+	delivery.Payload = string(body)
+
+	if err := s.repo.CreateDelivery(ctx, delivery); err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// validateWebhookURL checks that a webhook URL is an absolute http(s) URL
+func validateWebhookURL(rawURL string) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return fmt.Errorf("invalid webhook URL")
+	}
+	return nil
+}
+
+// normalizeEvents validates subscribed events, defaulting to all of them
+func normalizeEvents(events []models.WebhookEvent) ([]models.WebhookEvent, error) {
+	if len(events) == 0 {
+		return append([]models.WebhookEvent{}, models.WebhookEvents...), nil
+	}
+
+	seen := make(map[models.WebhookEvent]bool)
+	var result []models.WebhookEvent
+	for _, event := range events {
+		valid := false
+		for _, known := range models.WebhookEvents {
+			if event == known {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid event: %s", event)
+		}
+		if !seen[event] {
+			seen[event] = true
+			result = append(result, event)
+		}
+	}
+
+	return result, nil
+}
+
+// generateWebhookSecret creates a random signing secret
+func generateWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
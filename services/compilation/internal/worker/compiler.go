@@ -0,0 +1,19 @@
+package worker
+
+import (
+	"context"
+
+	"compilation/internal/models"
+)
+
+// Compiler turns a queued compilation job into a result.
+// DockerWorker is the production implementation; FakeCompiler is a
+// deterministic in-process implementation for tests.
+type Compiler interface {
+	Compile(ctx context.Context, job *models.CompilationJob) (*models.CompilationResult, error)
+}
+
+var (
+	_ Compiler = (*DockerWorker)(nil)
+	_ Compiler = (*FakeCompiler)(nil)
+)
@@ -0,0 +1,84 @@
+package worker
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"compilation/internal/models"
+)
+
+var (
+	// `label' and 'label' style quoted arguments
+	quotedArgPattern = regexp.MustCompile("[`'\"][^`'\"]*['\"]")
+	// Location suffixes such as "on page 3" or "on input line 42"
+	locationPattern = regexp.MustCompile(`\s*(on page \d+|on input line \d+|at lines? \d+(--\d+)?|in paragraph at lines \d+--\d+)`)
+	// Measurements in box warnings such as "(12.3pt too wide)" or "(badness 10000)"
+	measurementPattern = regexp.MustCompile(`\s*\((badness \d+|[\d.]+pt too (wide|high))\)`)
+	warningPattern     = regexp.MustCompile(`^((?:Package|Class) \S+|LaTeX|pdfTeX) [Ww]arning: (.*)$`)
+	boxPattern         = regexp.MustCompile(`^(Overfull|Underfull) \\([hv]box)`)
+	whitespacePattern  = regexp.MustCompile(`\s+`)
+)
+
+// ParseLogDiagnostics extracts normalized error and warning types from a TeX log.
+// Arguments, page numbers and line numbers are stripped so that occurrences of
+// the same problem are counted together.
+func ParseLogDiagnostics(logContent string) *models.LogDiagnostics {
+	errorCounts := make(map[string]int)
+	warningCounts := make(map[string]int)
+
+	for _, line := range strings.Split(logContent, "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(line, "! "):
+			errorCounts[normalizeDiagnostic(strings.TrimPrefix(line, "! "))]++
+		case boxPattern.MatchString(line):
+			match := boxPattern.FindStringSubmatch(line)
+			warningCounts[match[1]+` \`+match[2]]++
+		case warningPattern.MatchString(line):
+			match := warningPattern.FindStringSubmatch(line)
+			warningCounts[match[1]+" Warning: "+normalizeDiagnostic(match[2])]++
+		}
+	}
+
+	if len(errorCounts) == 0 && len(warningCounts) == 0 {
+		return nil
+	}
+
+	return &models.LogDiagnostics{
+		Errors:   sortedCounts(errorCounts),
+		Warnings: sortedCounts(warningCounts),
+	}
+}
+
+// normalizeDiagnostic strips variable parts from a diagnostic message
+func normalizeDiagnostic(message string) string {
+	message = quotedArgPattern.ReplaceAllString(message, "")
+	message = locationPattern.ReplaceAllString(message, "")
+	message = measurementPattern.ReplaceAllString(message, "")
+	message = whitespacePattern.ReplaceAllString(message, " ")
+	message = strings.TrimSpace(message)
+	return strings.TrimRight(message, ".")
+}
+
+// sortedCounts converts a count map to a slice ordered by count, then type
+func sortedCounts(counts map[string]int) []models.DiagnosticCount {
+	if len(counts) == 0 {
+		return nil
+	}
+
+	result := make([]models.DiagnosticCount, 0, len(counts))
+	for diagType, count := range counts {
+		result = append(result, models.DiagnosticCount{Type: diagType, Count: int64(count)})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Type < result[j].Type
+	})
+
+	return result
+}
@@ -18,10 +18,11 @@ import (
 
 // DockerWorker handles LaTeX compilation (now using direct exec instead of Docker)
 type DockerWorker struct {
-	minioClient *storage.MinIOClient
-	logger      *zap.Logger
-	timeout     time.Duration
-	workDir     string
+	minioClient   *storage.MinIOClient
+	postProcessor *PostProcessor
+	logger        *zap.Logger
+	timeout       time.Duration
+	workDir       string
 }
 
 // NewDockerWorker creates a new worker
@@ -36,10 +37,11 @@ func NewDockerWorker(
 	workDir string,
 ) *DockerWorker {
 	return &DockerWorker{
-		minioClient: minioClient,
-		logger:      logger,
-		timeout:     timeout,
-		workDir:     workDir,
+		minioClient:   minioClient,
+		postProcessor: NewPostProcessor(logger, timeout),
+		logger:        logger,
+		timeout:       timeout,
+		workDir:       workDir,
 	}
 }
 
@@ -95,6 +97,11 @@ func (w *DockerWorker) Compile(ctx context.Context, job *models.CompilationJob)
 	result.CompilationID = job.CompilationID
 	result.DurationMs = time.Since(startTime).Milliseconds()
 
+	// Summarize errors and warnings for analytics
+	if logContent, err := os.ReadFile(logPath); err == nil {
+		result.Diagnostics = ParseLogDiagnostics(string(logContent))
+	}
+
 	// Check if compilation was successful
 	if exitCode == 0 && fileExists(outputPath) {
 		// Upload PDF to MinIO
@@ -105,6 +112,11 @@ func (w *DockerWorker) Compile(ctx context.Context, job *models.CompilationJob)
 			result.OutputURL = pdfKey
 		}
 
+		// Run optional post-processing steps on the PDF
+		if job.PostProcess.Enabled() {
+			result.Artifacts = w.postProcess(ctx, job, outputPath)
+		}
+
 		// Upload log file
 		if fileExists(logPath) {
 			logKey := fmt.Sprintf("compilations/%s/%s", job.CompilationID, filepath.Base(logPath))
@@ -152,6 +164,32 @@ func (w *DockerWorker) Compile(ctx context.Context, job *models.CompilationJob)
 	return &result, nil
 }
 
+// postProcess produces the requested PDF variants and uploads them to MinIO
+func (w *DockerWorker) postProcess(ctx context.Context, job *models.CompilationJob, pdfPath string) []models.CompilationArtifact {
+	variants := w.postProcessor.Process(ctx, pdfPath, job.PostProcess)
+
+	artifacts := make([]models.CompilationArtifact, 0, len(variants))
+	for _, variant := range variants {
+		artifact := variant.artifact
+		if artifact.ValidationStatus != models.ValidationError {
+			key := fmt.Sprintf("compilations/%s/%s", job.CompilationID, filepath.Base(variant.path))
+			if err := w.uploadFile(ctx, key, variant.path); err != nil {
+				w.logger.Error("Failed to upload post-processed PDF",
+					zap.String("variant", string(artifact.Variant)),
+					zap.Error(err),
+				)
+				artifact.ValidationStatus = models.ValidationError
+				artifact.ValidationMessage = "failed to upload artifact"
+			} else {
+				artifact.FileKey = key
+			}
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	return artifacts
+}
+
 // runCompilation executes the LaTeX compiler directly
 func (w *DockerWorker) runCompilation(ctx context.Context, projectDir, compiler, mainFile string) (int, error) {
 	// Determine compiler command
@@ -263,7 +301,7 @@ func (w *DockerWorker) uploadFile(ctx context.Context, key, filePath string) err
 }
 
 // CalculateInputHash calculates SHA256 hash of input files
-func CalculateInputHash(files map[string]string, compiler, mainFile string) string {
+func CalculateInputHash(files map[string]string, compiler, mainFile string, postProcess *models.PostProcessOptions) string {
 	h := sha256.New()
 
 	// Sort filenames for consistent hashing
@@ -277,6 +315,12 @@ func CalculateInputHash(files map[string]string, compiler, mainFile string) stri
 	h.Write([]byte(compiler))
 	h.Write([]byte(mainFile))
 
+	// Hash post-processing options so cached results carry the same artifacts
+	if postProcess.Enabled() {
+		fmt.Fprintf(h, "pdfa=%t;linearize=%t;optimize=%t;dpi=%d",
+			postProcess.PDFA, postProcess.Linearize, postProcess.Optimize, postProcess.ImageDPI)
+	}
+
 	// Hash all files
 	for _, filename := range filenames {
 		h.Write([]byte(filename))
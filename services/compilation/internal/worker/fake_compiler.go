@@ -0,0 +1,162 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"compilation/internal/models"
+)
+
+// FakeOutcome describes the canned result a FakeCompiler produces for a job
+type FakeOutcome struct {
+	Status       models.CompilationStatus // Defaults to completed
+	PDF          []byte                   // Defaults to a minimal PDF
+	Log          string                   // Defaults to a short fake log
+	ErrorMessage string
+	Delay        time.Duration // Simulated compilation time
+	Err          error         // Returned instead of a result
+}
+
+// FakeCompiler is a deterministic in-process Compiler that does not need a
+// TeX installation. Outputs are kept in memory under the same keys the
+// DockerWorker would upload to MinIO.
+type FakeCompiler struct {
+	mu       sync.Mutex
+	timeout  time.Duration
+	outcomes map[string]FakeOutcome // main file -> outcome
+	fallback FakeOutcome
+	outputs  map[string][]byte // storage key -> content
+	jobs     []string          // compilation IDs in the order they were compiled
+}
+
+// NewFakeCompiler creates a fake compiler. A zero timeout disables the
+// simulated compilation timeout.
+func NewFakeCompiler(timeout time.Duration) *FakeCompiler {
+	return &FakeCompiler{
+		timeout:  timeout,
+		outcomes: make(map[string]FakeOutcome),
+		outputs:  make(map[string][]byte),
+	}
+}
+
+// SetOutcome sets the outcome for jobs with the given main file
+func (f *FakeCompiler) SetOutcome(mainFile string, outcome FakeOutcome) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.outcomes[mainFile] = outcome
+}
+
+// SetDefault sets the outcome for jobs without a specific outcome
+func (f *FakeCompiler) SetDefault(outcome FakeOutcome) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fallback = outcome
+}
+
+// Compile produces the canned outcome for a job
+func (f *FakeCompiler) Compile(ctx context.Context, job *models.CompilationJob) (*models.CompilationResult, error) {
+	startTime := time.Now()
+
+	f.mu.Lock()
+	outcome, ok := f.outcomes[job.MainFile]
+	if !ok {
+		outcome = f.fallback
+	}
+	f.jobs = append(f.jobs, job.CompilationID)
+	f.mu.Unlock()
+
+	if outcome.Delay > 0 {
+		delay := time.NewTimer(outcome.Delay)
+		defer delay.Stop()
+
+		var deadline <-chan time.Time
+		if f.timeout > 0 {
+			timer := time.NewTimer(f.timeout)
+			defer timer.Stop()
+			deadline = timer.C
+		}
+
+		select {
+		case <-delay.C:
+		case <-deadline:
+			return &models.CompilationResult{
+				CompilationID: job.CompilationID,
+				Status:        models.StatusTimeout,
+				ErrorMessage:  "Compilation timeout exceeded",
+				DurationMs:    time.Since(startTime).Milliseconds(),
+			}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if outcome.Err != nil {
+		return nil, outcome.Err
+	}
+
+	status := outcome.Status
+	if status == "" {
+		status = models.StatusCompleted
+	}
+
+	result := &models.CompilationResult{
+		CompilationID: job.CompilationID,
+		Status:        status,
+		ErrorMessage:  outcome.ErrorMessage,
+		DurationMs:    time.Since(startTime).Milliseconds(),
+	}
+
+	base := strings.TrimSuffix(job.MainFile, filepath.Ext(job.MainFile))
+	prefix := fmt.Sprintf("compilations/%s/", job.CompilationID)
+
+	logContent := outcome.Log
+	if logContent == "" {
+		logContent = fmt.Sprintf("This is FakeTeX, compiling %s with %s\n", job.MainFile, job.Compiler)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result.LogURL = prefix + base + ".log"
+	f.outputs[result.LogURL] = []byte(logContent)
+	result.Diagnostics = ParseLogDiagnostics(logContent)
+
+	if status == models.StatusCompleted {
+		pdf := outcome.PDF
+		if pdf == nil {
+			pdf = fakePDF(job.InputHash)
+		}
+		result.OutputURL = prefix + base + ".pdf"
+		f.outputs[result.OutputURL] = pdf
+	} else if result.ErrorMessage == "" {
+		result.ErrorMessage = "Compilation failed"
+	}
+
+	return result, nil
+}
+
+// Output returns the content stored under a storage key
+func (f *FakeCompiler) Output(key string) ([]byte, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	content, ok := f.outputs[key]
+	return content, ok
+}
+
+// Jobs returns the IDs of compiled jobs in the order they were received
+func (f *FakeCompiler) Jobs() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.jobs...)
+}
+
+// fakePDF returns a minimal PDF document tagged with the input hash
+func fakePDF(inputHash string) []byte {
+	return []byte(fmt.Sprintf("%%PDF-1.4\n%% %s\n1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj\n"+
+		"2 0 obj << /Type /Pages /Kids [] /Count 0 >> endobj\n"+
+		"trailer << /Root 1 0 R >>\n%%%%EOF\n", inputHash))
+}
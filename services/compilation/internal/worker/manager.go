@@ -2,10 +2,13 @@ package worker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/redis/go-redis/v9"
 	"compilation/internal/models"
 	"compilation/internal/queue"
 	"compilation/internal/repository"
@@ -13,46 +16,123 @@ import (
 	"go.uber.org/zap"
 )
 
+// errRequeue is the cancellation cause for jobs interrupted by a shutdown
+var errRequeue = errors.New("worker shutting down, job requeued")
+
+// requeueGracePeriod bounds how long Shutdown waits for interrupted jobs to be requeued
+const requeueGracePeriod = 10 * time.Second
+
+// defaultClaimIdle is how long a job may stay unacknowledged before another
+// worker assumes its worker died and takes it over
+const defaultClaimIdle = 5 * time.Minute
+
 // Manager coordinates compilation workers
 type Manager struct {
 	queue              *queue.RedisQueue
 	repo               *repository.CompilationRepository
-	dockerWorker       *DockerWorker
+	compiler           Compiler
 	logger             *zap.Logger
 	numWorkers         int
+	instanceID         string
 	shutdownChan       chan struct{}
 	wg                 sync.WaitGroup
 	dequeueTimeout     time.Duration
+	claimIdle          time.Duration
+	notifier           CompletionNotifier
+	cancellations      *redis.PubSub
+
+	// Cancel functions for in-flight jobs, keyed by compilation ID
+	inFlight   map[string]context.CancelCauseFunc
+	inFlightMu sync.Mutex
+	draining   bool
 }
 
 // NewManager creates a new worker manager
 func NewManager(
 	queue *queue.RedisQueue,
 	repo *repository.CompilationRepository,
-	dockerWorker *DockerWorker,
+	compiler Compiler,
 	logger *zap.Logger,
 	numWorkers int,
 ) *Manager {
+	// Consumer names must be unique across worker processes sharing the queue
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "compilation"
+	}
+
 	return &Manager{
 		queue:          queue,
 		repo:           repo,
-		dockerWorker:   dockerWorker,
+		compiler:       compiler,
 		logger:         logger,
 		numWorkers:     numWorkers,
+		instanceID:     fmt.Sprintf("%s-%d", hostname, os.Getpid()),
 		shutdownChan:   make(chan struct{}),
 		dequeueTimeout: 5 * time.Second,
+		claimIdle:      defaultClaimIdle,
+		inFlight:       make(map[string]context.CancelCauseFunc),
 	}
 }
 
+// SetDequeueTimeout sets how long a worker blocks waiting for a job
+func (m *Manager) SetDequeueTimeout(timeout time.Duration) {
+	m.dequeueTimeout = timeout
+}
+
+// SetClaimIdle sets how long a job may stay unacknowledged before another
+// worker takes it over. It must exceed the longest time a job can run.
+func (m *Manager) SetClaimIdle(idle time.Duration) {
+	m.claimIdle = idle
+}
+
+// SetNotifier sets the notifier called when a compilation reaches a final status
+func (m *Manager) SetNotifier(notifier CompletionNotifier) {
+	m.notifier = notifier
+}
+
+// Cancel cancels an in-flight compilation. It returns false if the
+// compilation is not currently running on this manager.
+func (m *Manager) Cancel(compilationID string) bool {
+	m.inFlightMu.Lock()
+	defer m.inFlightMu.Unlock()
+
+	cancel, ok := m.inFlight[compilationID]
+	if ok {
+		cancel(context.Canceled)
+	}
+	return ok
+}
+
+// Draining reports whether the manager has stopped accepting new jobs
+func (m *Manager) Draining() bool {
+	m.inFlightMu.Lock()
+	defer m.inFlightMu.Unlock()
+	return m.draining
+}
+
+// InFlight returns the number of jobs currently being compiled
+func (m *Manager) InFlight() int {
+	m.inFlightMu.Lock()
+	defer m.inFlightMu.Unlock()
+	return len(m.inFlight)
+}
+
 // Start starts the worker pool
 func (m *Manager) Start(ctx context.Context) error {
 	m.logger.Info("Starting worker manager",
 		zap.Int("num_workers", m.numWorkers),
+		zap.String("instance_id", m.instanceID),
 	)
 
+	// Cancellations are broadcast to every worker process; the one running
+	// the compilation stops it
+	m.cancellations = m.queue.SubscribeCancellations(ctx)
+	go m.listenForCancellations()
+
 	// Start worker goroutines
 	for i := 0; i < m.numWorkers; i++ {
-		workerID := fmt.Sprintf("worker-%d", i)
+		workerID := fmt.Sprintf("%s-worker-%d", m.instanceID, i)
 		m.wg.Add(1)
 		go m.runWorker(ctx, workerID)
 	}
@@ -61,14 +141,22 @@ func (m *Manager) Start(ctx context.Context) error {
 	return nil
 }
 
-// Shutdown gracefully shuts down all workers
+// Shutdown stops accepting new jobs and drains in-flight ones. Jobs still
+// running when ctx expires are cancelled and put back on the queue so that
+// another worker picks them up.
 func (m *Manager) Shutdown(ctx context.Context) error {
-	m.logger.Info("Shutting down worker manager")
+	m.logger.Info("Shutting down worker manager",
+		zap.Int("in_flight", m.InFlight()),
+	)
+	defer m.cancellations.Close()
 
-	// Signal all workers to stop
+	// Signal all workers to stop dequeueing
+	m.inFlightMu.Lock()
+	m.draining = true
+	m.inFlightMu.Unlock()
 	close(m.shutdownChan)
 
-	// Wait for all workers to finish with timeout
+	// Wait for all workers to finish their current job
 	done := make(chan struct{})
 	go func() {
 		m.wg.Wait()
@@ -80,17 +168,58 @@ func (m *Manager) Shutdown(ctx context.Context) error {
 		m.logger.Info("All workers shut down gracefully")
 		return nil
 	case <-ctx.Done():
-		m.logger.Warn("Worker shutdown timeout exceeded")
+	}
+
+	// Drain timeout exceeded: interrupt remaining jobs and requeue them
+	m.inFlightMu.Lock()
+	m.logger.Warn("Worker drain timeout exceeded, requeueing in-flight jobs",
+		zap.Int("in_flight", len(m.inFlight)),
+	)
+	for _, cancel := range m.inFlight {
+		cancel(errRequeue)
+	}
+	m.inFlightMu.Unlock()
+
+	select {
+	case <-done:
+		m.logger.Info("In-flight jobs requeued")
+		return nil
+	case <-time.After(requeueGracePeriod):
+		m.logger.Error("Timed out waiting for in-flight jobs to be requeued")
 		return ctx.Err()
 	}
 }
 
+// listenForCancellations cancels jobs running on this manager when their
+// cancellation is requested
+func (m *Manager) listenForCancellations() {
+	for msg := range m.cancellations.Channel() {
+		if m.Cancel(msg.Payload) {
+			m.logger.Info("Cancelling compilation",
+				zap.String("compilation_id", msg.Payload),
+			)
+		}
+	}
+}
+
 // runWorker runs a single worker that processes jobs from the queue
 func (m *Manager) runWorker(ctx context.Context, workerID string) {
 	defer m.wg.Done()
 
 	m.logger.Info("Worker started", zap.String("worker_id", workerID))
 
+	// Shutdown cuts blocking reads short so no job is taken once draining
+	// starts. Jobs already running keep ctx and are drained.
+	dequeueCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-m.shutdownChan:
+			cancel()
+		case <-dequeueCtx.Done():
+		}
+	}()
+
 	for {
 		select {
 		case <-m.shutdownChan:
@@ -101,8 +230,11 @@ func (m *Manager) runWorker(ctx context.Context, workerID string) {
 			return
 		default:
 			// Try to dequeue a job
-			job, messageID, err := m.queue.Dequeue(ctx, workerID, m.dequeueTimeout)
+			job, messageID, err := m.nextJob(dequeueCtx, workerID)
 			if err != nil {
+				if dequeueCtx.Err() != nil {
+					continue
+				}
 				m.logger.Error("Failed to dequeue job",
 					zap.String("worker_id", workerID),
 					zap.Error(err),
@@ -116,12 +248,34 @@ func (m *Manager) runWorker(ctx context.Context, workerID string) {
 				continue
 			}
 
+			// A job read just as draining started goes back for another worker
+			if m.Draining() {
+				if err := m.queue.Requeue(ctx, job, messageID); err != nil {
+					m.logger.Error("Failed to return job dequeued during shutdown",
+						zap.String("compilation_id", job.CompilationID),
+						zap.Error(err),
+					)
+				}
+				continue
+			}
+
 			// Process the job
 			m.processJob(ctx, workerID, job, messageID)
 		}
 	}
 }
 
+// nextJob returns a job abandoned by a dead worker if there is one, and
+// otherwise waits for a new job
+func (m *Manager) nextJob(ctx context.Context, workerID string) (*models.CompilationJob, string, error) {
+	job, messageID, err := m.queue.Claim(ctx, workerID, m.claimIdle)
+	if err != nil || job != nil {
+		return job, messageID, err
+	}
+
+	return m.queue.Dequeue(ctx, workerID, m.dequeueTimeout)
+}
+
 // processJob processes a single compilation job
 func (m *Manager) processJob(ctx context.Context, workerID string, job *models.CompilationJob, messageID string) {
 	m.logger.Info("Processing compilation job",
@@ -140,8 +294,27 @@ func (m *Manager) processJob(ctx context.Context, workerID string, job *models.C
 		return
 	}
 
+	// Track the job so it can be cancelled or requeued while running
+	jobCtx, cancel := context.WithCancelCause(ctx)
+	m.inFlightMu.Lock()
+	m.inFlight[job.CompilationID] = cancel
+	m.inFlightMu.Unlock()
+	defer func() {
+		m.inFlightMu.Lock()
+		delete(m.inFlight, job.CompilationID)
+		m.inFlightMu.Unlock()
+		cancel(nil)
+	}()
+
 	// Update status to running
 	if err := m.repo.UpdateStatus(ctx, compilationID, models.StatusRunning); err != nil {
+		if err.Error() == "compilation cancelled" {
+			m.logger.Info("Skipping cancelled compilation",
+				zap.String("compilation_id", job.CompilationID),
+			)
+			m.queue.Acknowledge(ctx, messageID)
+			return
+		}
 		m.logger.Error("Failed to update compilation status to running",
 			zap.String("compilation_id", job.CompilationID),
 			zap.Error(err),
@@ -151,9 +324,29 @@ func (m *Manager) processJob(ctx context.Context, workerID string, job *models.C
 	}
 
 	// Run compilation
-	result, err := m.dockerWorker.Compile(ctx, job)
+	result, err := m.compiler.Compile(jobCtx, job)
+
+	// Jobs interrupted by a shutdown go back on the queue
+	if errors.Is(context.Cause(jobCtx), errRequeue) {
+		m.requeueJob(ctx, compilationID, job, messageID)
+		return
+	}
+
+	// A cancelled job context without a cancelled parent means Cancel was called
+	if err != nil && errors.Is(err, context.Canceled) && ctx.Err() == nil {
+		m.logger.Info("Compilation cancelled",
+			zap.String("compilation_id", job.CompilationID),
+		)
+		result = &models.CompilationResult{
+			CompilationID: job.CompilationID,
+			Status:        models.StatusCancelled,
+			ErrorMessage:  "Compilation cancelled",
+		}
+		err = nil
+	}
 
 	// Update compilation record based on result
+	var updateErr error
 	if err != nil {
 		m.logger.Error("Compilation failed",
 			zap.String("compilation_id", job.CompilationID),
@@ -165,7 +358,7 @@ func (m *Manager) processJob(ctx context.Context, workerID string, job *models.C
 			ErrorMessage: err.Error(),
 		}
 
-		updateErr := m.repo.UpdateResult(ctx, compilationID, failResult)
+		updateErr = m.repo.UpdateResult(ctx, compilationID, failResult)
 		if updateErr != nil {
 			m.logger.Error("Failed to update compilation status to failed",
 				zap.String("compilation_id", job.CompilationID),
@@ -173,19 +366,20 @@ func (m *Manager) processJob(ctx context.Context, workerID string, job *models.C
 			)
 		}
 	} else {
-		m.logger.Info("Compilation completed successfully",
+		m.logger.Info("Compilation finished",
 			zap.String("compilation_id", job.CompilationID),
+			zap.String("status", string(result.Status)),
 			zap.String("output_url", result.OutputURL),
 			zap.Int64("duration_ms", result.DurationMs),
 		)
 
-		updateErr := m.repo.UpdateResult(
+		updateErr = m.repo.UpdateResult(
 			ctx,
 			compilationID,
 			result,
 		)
 		if updateErr != nil {
-			m.logger.Error("Failed to update compilation status to completed",
+			m.logger.Error("Failed to update compilation result",
 				zap.String("compilation_id", job.CompilationID),
 				zap.Error(updateErr),
 			)
@@ -199,4 +393,51 @@ func (m *Manager) processJob(ctx context.Context, workerID string, job *models.C
 			zap.Error(err),
 		)
 	}
+
+	if updateErr == nil {
+		m.notify(ctx, compilationID)
+	}
+}
+
+// notify passes the final compilation record to the notifier, if any
+func (m *Manager) notify(ctx context.Context, compilationID primitive.ObjectID) {
+	if m.notifier == nil {
+		return
+	}
+
+	compilation, err := m.repo.FindByID(ctx, compilationID)
+	if err != nil {
+		m.logger.Error("Failed to load compilation for notification",
+			zap.String("compilation_id", compilationID.Hex()),
+			zap.Error(err),
+		)
+		return
+	}
+
+	m.notifier.CompilationFinished(ctx, compilation)
+}
+
+// requeueJob puts an interrupted job back on the queue. The status is reset
+// first so that a worker picking the job up again is not overwritten.
+func (m *Manager) requeueJob(ctx context.Context, compilationID primitive.ObjectID, job *models.CompilationJob, messageID string) {
+	if err := m.repo.UpdateStatus(ctx, compilationID, models.StatusQueued); err != nil {
+		m.logger.Error("Failed to reset compilation status to queued",
+			zap.String("compilation_id", job.CompilationID),
+			zap.Error(err),
+		)
+	}
+
+	if err := m.queue.Requeue(ctx, job, messageID); err != nil {
+		// The message stays pending and is claimed by another worker
+		// once it has been idle for the claim timeout
+		m.logger.Error("Failed to requeue compilation job",
+			zap.String("compilation_id", job.CompilationID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	m.logger.Info("Compilation job requeued",
+		zap.String("compilation_id", job.CompilationID),
+	)
 }
@@ -0,0 +1,330 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"compilation/internal/models"
+	"compilation/internal/queue"
+	"compilation/internal/repository"
+	"compilation/internal/service"
+	"compilation/internal/storage"
+	"compilation/internal/worker"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.uber.org/zap"
+)
+
+// These tests exercise the queue, worker manager and repository against real
+// Redis, MongoDB and MinIO instances using the FakeCompiler. They are skipped
+// unless TEST_REDIS_ADDR, TEST_MONGO_URI and TEST_MINIO_ENDPOINT are set, e.g.
+// against the services from deployments/docker/docker-compose.yml.
+//
+// The Redis database selected by TEST_REDIS_DB (default 15) is flushed.
+
+const statusWaitTimeout = 10 * time.Second
+
+type testEnv struct {
+	queue    *queue.RedisQueue
+	repo     *repository.CompilationRepository
+	service  *service.CompilationService
+	manager  *worker.Manager
+	compiler *worker.FakeCompiler
+}
+
+func newTestEnv(t *testing.T, compilerTimeout time.Duration) *testEnv {
+	t.Helper()
+
+	env := newTestBackends(t)
+	env.compiler = worker.NewFakeCompiler(compilerTimeout)
+	env.manager = env.startManager(t, context.Background(), env.compiler, time.Minute)
+	return env
+}
+
+// newTestBackends connects to the test services without starting a manager
+func newTestBackends(t *testing.T) *testEnv {
+	t.Helper()
+
+	redisAddr := os.Getenv("TEST_REDIS_ADDR")
+	mongoURI := os.Getenv("TEST_MONGO_URI")
+	minioEndpoint := os.Getenv("TEST_MINIO_ENDPOINT")
+	if redisAddr == "" || mongoURI == "" || minioEndpoint == "" {
+		t.Skip("TEST_REDIS_ADDR, TEST_MONGO_URI and TEST_MINIO_ENDPOINT are required for integration tests")
+	}
+
+	ctx := context.Background()
+	log := zap.NewNop()
+
+	redisDB, err := strconv.Atoi(getEnv("TEST_REDIS_DB", "15"))
+	if err != nil {
+		t.Fatalf("invalid TEST_REDIS_DB: %v", err)
+	}
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     redisAddr,
+		Password: os.Getenv("TEST_REDIS_PASSWORD"),
+		DB:       redisDB,
+	})
+	t.Cleanup(func() { redisClient.Close() })
+	if err := redisClient.FlushDB(ctx).Err(); err != nil {
+		t.Fatalf("failed to flush Redis: %v", err)
+	}
+
+	mongoClient, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		t.Fatalf("failed to connect to MongoDB: %v", err)
+	}
+	db := mongoClient.Database("texflow_test_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() {
+		db.Drop(context.Background())
+		mongoClient.Disconnect(context.Background())
+	})
+
+	minioClient, err := storage.NewMinIOClient(
+		minioEndpoint,
+		minioEndpoint,
+		getEnv("TEST_MINIO_ACCESS_KEY", "minioadmin"),
+		getEnv("TEST_MINIO_SECRET_KEY", "minioadmin"),
+		getEnv("TEST_MINIO_BUCKET", "texflow-test"),
+		false,
+		log,
+	)
+	if err != nil {
+		t.Fatalf("failed to create MinIO client: %v", err)
+	}
+
+	repo := repository.NewCompilationRepository(db)
+	redisQueue := queue.NewRedisQueue(redisClient, log)
+	if err := redisQueue.Initialize(ctx); err != nil {
+		t.Fatalf("failed to initialize queue: %v", err)
+	}
+
+	compilationService := service.NewCompilationService(
+		repo,
+		redisQueue,
+		redisClient,
+		minioClient,
+		log,
+		true,
+		time.Minute,
+		100,
+	)
+
+	return &testEnv{
+		queue:   redisQueue,
+		repo:    repo,
+		service: compilationService,
+	}
+}
+
+// startManager starts a worker manager that is shut down when the test ends.
+// Jobs unacknowledged for claimIdle are taken over by its workers.
+func (e *testEnv) startManager(t *testing.T, ctx context.Context, compiler worker.Compiler, claimIdle time.Duration) *worker.Manager {
+	t.Helper()
+
+	manager := worker.NewManager(e.queue, e.repo, compiler, zap.NewNop(), 2)
+	manager.SetDequeueTimeout(200 * time.Millisecond)
+	manager.SetClaimIdle(claimIdle)
+	if err := manager.Start(ctx); err != nil {
+		t.Fatalf("failed to start manager: %v", err)
+	}
+	t.Cleanup(func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		manager.Shutdown(shutdownCtx)
+	})
+
+	return manager
+}
+
+// request enqueues a compilation of a single-file project
+func (e *testEnv) request(t *testing.T, mainFile, content string) *models.Compilation {
+	t.Helper()
+
+	compilation, err := e.service.RequestCompilation(
+		context.Background(),
+		primitive.NewObjectID(),
+		primitive.NewObjectID(),
+		"pdflatex",
+		mainFile,
+		map[string]string{mainFile: content},
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("RequestCompilation failed: %v", err)
+	}
+	return compilation
+}
+
+// waitForStatus polls the repository until the compilation reaches the status
+func (e *testEnv) waitForStatus(t *testing.T, id primitive.ObjectID, status models.CompilationStatus) *models.Compilation {
+	t.Helper()
+
+	deadline := time.Now().Add(statusWaitTimeout)
+	var last *models.Compilation
+	for time.Now().Before(deadline) {
+		compilation, err := e.repo.FindByID(context.Background(), id)
+		if err != nil {
+			t.Fatalf("FindByID failed: %v", err)
+		}
+		if compilation.Status == status {
+			return compilation
+		}
+		last = compilation
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	t.Fatalf("compilation %s did not reach status %q, last status %q", id.Hex(), status, last.Status)
+	return nil
+}
+
+func TestManagerCompletesQueuedJob(t *testing.T) {
+	env := newTestEnv(t, 0)
+
+	compilation := env.request(t, "main.tex", `\documentclass{article}\begin{document}Hi\end{document}`)
+	if compilation.Status != models.StatusQueued {
+		t.Fatalf("expected status %q, got %q", models.StatusQueued, compilation.Status)
+	}
+
+	completed := env.waitForStatus(t, compilation.ID, models.StatusCompleted)
+	if completed.StartedAt == nil || completed.CompletedAt == nil {
+		t.Error("expected started_at and completed_at to be set")
+	}
+	if completed.OutputFileKey == "" || completed.LogFileKey == "" {
+		t.Fatalf("expected output and log keys, got %q and %q", completed.OutputFileKey, completed.LogFileKey)
+	}
+
+	pdf, ok := env.compiler.Output(completed.OutputFileKey)
+	if !ok || len(pdf) == 0 {
+		t.Fatalf("expected PDF output under %q", completed.OutputFileKey)
+	}
+	if jobs := env.compiler.Jobs(); len(jobs) != 1 || jobs[0] != compilation.ID.Hex() {
+		t.Errorf("expected exactly one compiled job %s, got %v", compilation.ID.Hex(), jobs)
+	}
+}
+
+func TestManagerRecordsFailedCompilation(t *testing.T) {
+	env := newTestEnv(t, 0)
+	env.compiler.SetOutcome("broken.tex", worker.FakeOutcome{
+		Status:       models.StatusFailed,
+		Log:          "! Undefined control sequence.\n",
+		ErrorMessage: "! Undefined control sequence.",
+	})
+
+	compilation := env.request(t, "broken.tex", `\documentclass{article}\begin{document}\foo\end{document}`)
+
+	failed := env.waitForStatus(t, compilation.ID, models.StatusFailed)
+	if failed.ErrorMessage != "! Undefined control sequence." {
+		t.Errorf("unexpected error message %q", failed.ErrorMessage)
+	}
+	if failed.OutputFileKey != "" {
+		t.Errorf("expected no output for failed compilation, got %q", failed.OutputFileKey)
+	}
+	if logContent, ok := env.compiler.Output(failed.LogFileKey); !ok || len(logContent) == 0 {
+		t.Errorf("expected log output under %q", failed.LogFileKey)
+	}
+}
+
+func TestManagerRecordsCompilerError(t *testing.T) {
+	env := newTestEnv(t, 0)
+	env.compiler.SetDefault(worker.FakeOutcome{Err: errors.New("failed to write project files")})
+
+	compilation := env.request(t, "main.tex", "content")
+
+	failed := env.waitForStatus(t, compilation.ID, models.StatusFailed)
+	if failed.ErrorMessage != "failed to write project files" {
+		t.Errorf("unexpected error message %q", failed.ErrorMessage)
+	}
+}
+
+func TestCacheHitSkipsCompiler(t *testing.T) {
+	env := newTestEnv(t, 0)
+	content := `\documentclass{article}\begin{document}Cached\end{document}`
+
+	first := env.request(t, "main.tex", content)
+	completed := env.waitForStatus(t, first.ID, models.StatusCompleted)
+
+	second := env.request(t, "main.tex", content)
+	if !second.CachedResult {
+		t.Fatal("expected second compilation to be served from cache")
+	}
+	if second.Status != models.StatusCompleted {
+		t.Errorf("expected cached compilation to be %q, got %q", models.StatusCompleted, second.Status)
+	}
+	if second.OutputFileKey != completed.OutputFileKey {
+		t.Errorf("expected cached output key %q, got %q", completed.OutputFileKey, second.OutputFileKey)
+	}
+	if jobs := env.compiler.Jobs(); len(jobs) != 1 {
+		t.Errorf("expected compiler to run once, ran %d times", len(jobs))
+	}
+
+	third := env.request(t, "main.tex", content+"%changed")
+	if third.CachedResult {
+		t.Error("expected changed input to miss the cache")
+	}
+	env.waitForStatus(t, third.ID, models.StatusCompleted)
+}
+
+func TestManagerRecordsTimeout(t *testing.T) {
+	env := newTestEnv(t, 100*time.Millisecond)
+	env.compiler.SetDefault(worker.FakeOutcome{Delay: 5 * time.Second})
+
+	compilation := env.request(t, "main.tex", "slow")
+
+	timedOut := env.waitForStatus(t, compilation.ID, models.StatusTimeout)
+	if timedOut.ErrorMessage == "" {
+		t.Error("expected a timeout error message")
+	}
+}
+
+func TestManagerCancelsRunningJob(t *testing.T) {
+	env := newTestEnv(t, 0)
+	env.compiler.SetDefault(worker.FakeOutcome{Delay: 30 * time.Second})
+
+	compilation := env.request(t, "main.tex", "long")
+	env.waitForStatus(t, compilation.ID, models.StatusRunning)
+
+	if !env.manager.Cancel(compilation.ID.Hex()) {
+		t.Fatal("expected running compilation to be cancellable")
+	}
+
+	cancelled := env.waitForStatus(t, compilation.ID, models.StatusCancelled)
+	if cancelled.OutputFileKey != "" {
+		t.Errorf("expected no output for cancelled compilation, got %q", cancelled.OutputFileKey)
+	}
+}
+
+func TestManagerReclaimsJobOfDeadWorker(t *testing.T) {
+	env := newTestBackends(t)
+
+	// The first worker dies mid-job: its context goes away before it can
+	// record a result or acknowledge the message
+	stuck := worker.NewFakeCompiler(0)
+	stuck.SetDefault(worker.FakeOutcome{Delay: 30 * time.Second})
+	deadCtx, kill := context.WithCancel(context.Background())
+	env.startManager(t, deadCtx, stuck, time.Minute)
+
+	compilation := env.request(t, "main.tex", "abandoned")
+	env.waitForStatus(t, compilation.ID, models.StatusRunning)
+	kill()
+
+	survivor := worker.NewFakeCompiler(0)
+	env.startManager(t, context.Background(), survivor, 500*time.Millisecond)
+
+	env.waitForStatus(t, compilation.ID, models.StatusCompleted)
+	if jobs := survivor.Jobs(); len(jobs) != 1 || jobs[0] != compilation.ID.Hex() {
+		t.Errorf("expected the surviving worker to compile %s, got %v", compilation.ID.Hex(), jobs)
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
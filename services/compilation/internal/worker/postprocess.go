@@ -0,0 +1,211 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"compilation/internal/models"
+	"go.uber.org/zap"
+)
+
+// PostProcessor runs optional post-processing steps on a compiled PDF.
+// Ghostscript handles PDF/A conversion and image downsampling, qpdf handles
+// linearization and structural validation.
+type PostProcessor struct {
+	logger  *zap.Logger
+	timeout time.Duration
+}
+
+// processedVariant is a post-processed PDF written to the working directory
+type processedVariant struct {
+	artifact models.CompilationArtifact
+	path     string
+}
+
+// NewPostProcessor creates a new post-processor
+func NewPostProcessor(logger *zap.Logger, timeout time.Duration) *PostProcessor {
+	return &PostProcessor{
+		logger:  logger,
+		timeout: timeout,
+	}
+}
+
+// Process produces every variant requested in opts from the PDF at pdfPath.
+// A failing step is reported on its artifact and does not affect the others.
+func (p *PostProcessor) Process(ctx context.Context, pdfPath string, opts *models.PostProcessOptions) []processedVariant {
+	if !opts.Enabled() {
+		return nil
+	}
+
+	base := strings.TrimSuffix(pdfPath, filepath.Ext(pdfPath))
+	var variants []processedVariant
+
+	if opts.PDFA {
+		variants = append(variants, p.runStep(ctx, models.VariantPDFA, base+".pdfa.pdf", func(ctx context.Context, out string) error {
+			return p.convertToPDFA(ctx, pdfPath, out)
+		}))
+	}
+
+	if opts.Linearize {
+		variants = append(variants, p.runStep(ctx, models.VariantLinearized, base+".linearized.pdf", func(ctx context.Context, out string) error {
+			return p.runTool(ctx, "qpdf", "--linearize", pdfPath, out)
+		}))
+	}
+
+	if opts.Optimize {
+		dpi := opts.ImageDPI
+		if dpi <= 0 {
+			dpi = models.DefaultImageDPI
+		}
+		variants = append(variants, p.runStep(ctx, models.VariantOptimized, base+".optimized.pdf", func(ctx context.Context, out string) error {
+			return p.downsampleImages(ctx, pdfPath, out, dpi)
+		}))
+	}
+
+	return variants
+}
+
+// runStep produces a single variant and validates the result
+func (p *PostProcessor) runStep(
+	ctx context.Context,
+	variant models.ArtifactVariant,
+	outputPath string,
+	produce func(ctx context.Context, out string) error,
+) processedVariant {
+	result := processedVariant{
+		artifact: models.CompilationArtifact{Variant: variant},
+		path:     outputPath,
+	}
+
+	stepCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	if err := produce(stepCtx, outputPath); err != nil || !fileExists(outputPath) {
+		if err == nil {
+			err = fmt.Errorf("no output produced")
+		}
+		p.logger.Warn("Post-processing step failed",
+			zap.String("variant", string(variant)),
+			zap.Error(err),
+		)
+		result.artifact.ValidationStatus = models.ValidationError
+		result.artifact.ValidationMessage = err.Error()
+		return result
+	}
+
+	if stat, err := os.Stat(outputPath); err == nil {
+		result.artifact.SizeBytes = stat.Size()
+	}
+
+	status, message := p.validate(stepCtx, variant, outputPath)
+	result.artifact.ValidationStatus = status
+	result.artifact.ValidationMessage = message
+
+	return result
+}
+
+// convertToPDFA converts a PDF to PDF/A-2b using Ghostscript
+func (p *PostProcessor) convertToPDFA(ctx context.Context, input, output string) error {
+	return p.runTool(ctx, "gs",
+		"-dPDFA=2",
+		"-dBATCH",
+		"-dNOPAUSE",
+		"-dNOOUTERSAVE",
+		"-dQUIET",
+		"-dPDFACompatibilityPolicy=1",
+		"-sColorConversionStrategy=RGB",
+		"-sDEVICE=pdfwrite",
+		"-sOutputFile="+output,
+		input,
+	)
+}
+
+// downsampleImages rewrites a PDF with images downsampled to the given resolution
+func (p *PostProcessor) downsampleImages(ctx context.Context, input, output string, dpi int) error {
+	resolution := fmt.Sprintf("%d", dpi)
+	return p.runTool(ctx, "gs",
+		"-dBATCH",
+		"-dNOPAUSE",
+		"-dQUIET",
+		"-sDEVICE=pdfwrite",
+		"-dCompatibilityLevel=1.5",
+		"-dDetectDuplicateImages=true",
+		"-dCompressFonts=true",
+		"-dDownsampleColorImages=true",
+		"-dDownsampleGrayImages=true",
+		"-dDownsampleMonoImages=true",
+		"-dColorImageResolution="+resolution,
+		"-dGrayImageResolution="+resolution,
+		"-dMonoImageResolution="+resolution,
+		"-sOutputFile="+output,
+		input,
+	)
+}
+
+// validate checks a produced variant. Structural checks use qpdf; PDF/A
+// output is additionally checked for its identification metadata.
+func (p *PostProcessor) validate(ctx context.Context, variant models.ArtifactVariant, path string) (models.ValidationStatus, string) {
+	args := []string{"--check", path}
+	if variant == models.VariantLinearized {
+		args = []string{"--check-linearization", path}
+	}
+
+	if err := p.runTool(ctx, "qpdf", args...); err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return models.ValidationUnverified, "qpdf is not installed"
+		}
+		return models.ValidationFailed, err.Error()
+	}
+
+	if variant == models.VariantPDFA {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return models.ValidationFailed, err.Error()
+		}
+		if !hasPDFAIdentification(content, "2", "B") {
+			return models.ValidationFailed, "missing PDF/A-2b identification metadata"
+		}
+	}
+
+	return models.ValidationPassed, ""
+}
+
+// runTool executes an external tool and returns its output on failure
+func (p *PostProcessor) runTool(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s timed out", name)
+		}
+		if len(output) > 0 {
+			return fmt.Errorf("%s: %w: %s", name, err, strings.TrimSpace(lastLine(output)))
+		}
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// hasPDFAIdentification checks the XMP metadata for the PDF/A part and conformance
+func hasPDFAIdentification(content []byte, part, conformance string) bool {
+	partAttr := fmt.Sprintf(`pdfaid:part="%s"`, part)
+	partElem := fmt.Sprintf("<pdfaid:part>%s</pdfaid:part>", part)
+	confAttr := fmt.Sprintf(`pdfaid:conformance="%s"`, conformance)
+	confElem := fmt.Sprintf("<pdfaid:conformance>%s</pdfaid:conformance>", conformance)
+
+	hasPart := bytes.Contains(content, []byte(partAttr)) || bytes.Contains(content, []byte(partElem))
+	hasConf := bytes.Contains(content, []byte(confAttr)) || bytes.Contains(content, []byte(confElem))
+	return hasPart && hasConf
+}
+
+func lastLine(output []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return lines[len(lines)-1]
+}
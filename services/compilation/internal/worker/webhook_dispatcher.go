@@ -0,0 +1,285 @@
+package worker
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"compilation/internal/models"
+	"compilation/internal/repository"
+	"go.uber.org/zap"
+)
+
+// Webhook delivery headers
+const (
+	SignatureHeader = "X-TexFlow-Signature-256"
+	EventHeader     = "X-TexFlow-Event"
+	DeliveryHeader  = "X-TexFlow-Delivery"
+)
+
+const (
+	// maxResponseBody bounds how much of an endpoint's response is logged
+	maxResponseBody = 1024
+	// retryBaseDelay is the delay before the first retry, doubled on each attempt
+	retryBaseDelay = 30 * time.Second
+	// retryMaxDelay caps the delay between two attempts
+	retryMaxDelay = time.Hour
+)
+
+// CompletionNotifier is notified when a compilation reaches a final status
+type CompletionNotifier interface {
+	CompilationFinished(ctx context.Context, compilation *models.Compilation)
+}
+
+// WebhookDispatcher delivers queued webhooks, retrying failed deliveries
+// with exponential backoff. Deliveries are leased from MongoDB so that any
+// number of dispatchers can share the queue.
+type WebhookDispatcher struct {
+	repo         *repository.WebhookRepository
+	client       *http.Client
+	logger       *zap.Logger
+	numWorkers   int
+	maxAttempts  int
+	pollInterval time.Duration
+	timeout      time.Duration
+	shutdownChan chan struct{}
+	wg           sync.WaitGroup
+}
+
+// NewWebhookDispatcher creates a new webhook dispatcher. Unless allowPrivate is
+// set, endpoints resolving to loopback, private or link-local addresses are refused.
+func NewWebhookDispatcher(
+	repo *repository.WebhookRepository,
+	logger *zap.Logger,
+	numWorkers int,
+	maxAttempts int,
+	timeout time.Duration,
+	allowPrivate bool,
+) *WebhookDispatcher {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivate {
+		dialer.Control = refusePrivateAddresses
+	}
+
+	return &WebhookDispatcher{
+		repo: repo,
+		client: &http.Client{
+			Timeout: timeout,
+			// No proxy: the dialer must see the endpoint's own address for
+			// the private network check to hold
+			Transport: &http.Transport{
+				Proxy:               nil,
+				DialContext:         dialer.DialContext,
+				TLSHandshakeTimeout: timeout,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+			},
+			// Redirects are not followed; the response is logged instead
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		logger:       logger,
+		numWorkers:   numWorkers,
+		maxAttempts:  maxAttempts,
+		pollInterval: time.Second,
+		timeout:      timeout,
+		shutdownChan: make(chan struct{}),
+	}
+}
+
+// Start starts the delivery workers
+func (d *WebhookDispatcher) Start(ctx context.Context) {
+	d.logger.Info("Starting webhook dispatcher", zap.Int("num_workers", d.numWorkers))
+
+	for i := 0; i < d.numWorkers; i++ {
+		d.wg.Add(1)
+		go d.run(ctx)
+	}
+}
+
+// Shutdown stops the delivery workers after their current delivery
+func (d *WebhookDispatcher) Shutdown(ctx context.Context) error {
+	close(d.shutdownChan)
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		// Unfinished deliveries are retried once their lease expires
+		return ctx.Err()
+	}
+}
+
+// run claims and delivers due deliveries until shutdown
+func (d *WebhookDispatcher) run(ctx context.Context) {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-d.shutdownChan:
+			return
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		// The lease outlives the HTTP timeout so a delivery is never sent twice concurrently
+		delivery, err := d.repo.ClaimDueDelivery(ctx, 2*d.timeout+10*time.Second)
+		if err != nil {
+			d.logger.Error("Failed to claim webhook delivery", zap.Error(err))
+		}
+
+		if delivery == nil {
+			select {
+			case <-d.shutdownChan:
+				return
+			case <-ctx.Done():
+				return
+			case <-time.After(d.pollInterval):
+			}
+			continue
+		}
+
+		d.deliver(ctx, delivery)
+	}
+}
+
+// deliver sends a delivery and records the attempt
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) {
+	webhook, err := d.repo.FindByID(ctx, delivery.ProjectID, delivery.WebhookID)
+	if err != nil || !webhook.Active {
+		reason := "webhook is inactive"
+		if err != nil {
+			reason = err.Error()
+		}
+		d.record(ctx, delivery, models.DeliveryAttempt{At: time.Now(), Error: reason}, false)
+		return
+	}
+
+	attempt := d.send(ctx, webhook, delivery)
+	success := attempt.Error == "" && attempt.ResponseCode >= 200 && attempt.ResponseCode < 300
+	d.record(ctx, delivery, attempt, !success)
+}
+
+// send performs a single signed HTTP POST of the delivery payload
+func (d *WebhookDispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) models.DeliveryAttempt {
+	attempt := models.DeliveryAttempt{At: time.Now()}
+	body := []byte(delivery.Payload)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "TexFlow-Webhooks/1.0")
+	req.Header.Set(EventHeader, string(delivery.Event))
+	req.Header.Set(DeliveryHeader, delivery.ID.Hex())
+	req.Header.Set(SignatureHeader, SignPayload(webhook.Secret, body))
+
+	resp, err := d.client.Do(req)
+	attempt.DurationMs = time.Since(attempt.At).Milliseconds()
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+
+	attempt.ResponseCode = resp.StatusCode
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	attempt.ResponseBody = string(respBody)
+
+	return attempt
+}
+
+// record stores an attempt and schedules a retry when one is due
+func (d *WebhookDispatcher) record(ctx context.Context, delivery *models.WebhookDelivery, attempt models.DeliveryAttempt, retry bool) {
+	status := models.DeliverySucceeded
+	var nextAttemptAt *time.Time
+
+	attempts := len(delivery.Attempts) + 1
+	if retry {
+		status = models.DeliveryFailed
+		if attempts < d.maxAttempts {
+			next := time.Now().Add(RetryDelay(attempts))
+			nextAttemptAt = &next
+			status = models.DeliveryPending
+		}
+	} else if attempt.ResponseCode == 0 {
+		// Not sent at all, e.g. the webhook was deleted
+		status = models.DeliveryFailed
+	}
+
+	if err := d.repo.RecordAttempt(ctx, delivery.ID, attempt, status, nextAttemptAt); err != nil {
+		d.logger.Error("Failed to record webhook delivery attempt",
+			zap.String("delivery_id", delivery.ID.Hex()),
+			zap.Error(err),
+		)
+		return
+	}
+
+	d.logger.Info("Webhook delivery attempted",
+		zap.String("delivery_id", delivery.ID.Hex()),
+		zap.String("webhook_id", delivery.WebhookID.Hex()),
+		zap.String("event", string(delivery.Event)),
+		zap.Int("attempt", attempts),
+		zap.Int("response_code", attempt.ResponseCode),
+		zap.String("status", string(status)),
+	)
+}
+
+// RetryDelay returns the backoff before retrying after the given number of attempts
+func RetryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts && delay < retryMaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	return delay
+}
+
+// SignPayload returns the signature header value for a payload: the hex
+// encoded HMAC-SHA256 of the raw body keyed with the webhook secret.
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// refusePrivateAddresses is a dialer control that blocks internal networks
+func refusePrivateAddresses(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid address %q", host)
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("webhook address %s is not allowed", ip)
+	}
+
+	return nil
+}
@@ -11,11 +11,16 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"github.com/texflow/pkg/migrate"
 	"github.com/texflow/services/project/internal/config"
+	"github.com/texflow/services/project/internal/events"
 	"github.com/texflow/services/project/internal/handlers"
+	"github.com/texflow/services/project/internal/models"
 	"github.com/texflow/services/project/internal/repository"
 	"github.com/texflow/services/project/internal/service"
 	"github.com/texflow/services/project/internal/storage"
+	"github.com/texflow/services/project/pkg/auth"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
@@ -68,12 +73,179 @@ func main() {
 	// Initialize repositories
 	projectRepo := repository.NewProjectRepository(db)
 	fileRepo := repository.NewFileRepository(db)
+	folderRepo := repository.NewFolderRepository(db)
+	gitRepo := repository.NewGitRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	versionRepo := repository.NewVersionRepository(db)
+	templateRepo := repository.NewTemplateRepository(db)
+	invitationRepo := repository.NewInvitationRepository(db)
+	shareLinkRepo := repository.NewShareLinkRepository(db)
+	searchRepo := repository.NewSearchRepository(db)
+	outlineRepo := repository.NewOutlineRepository(db)
+	uploadRepo := repository.NewUploadRepository(db)
+	quotaRepo := repository.NewQuotaRepository(db)
+	fsckRepo := repository.NewFsckRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	notificationRepo := repository.NewNotificationRepository(db)
+	suggestionRepo := repository.NewSuggestionRepository(db)
+	lintRepo := repository.NewLintRepository(db)
+
+	// Create indexes
+	if err := projectRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create project indexes", zap.Error(err))
+	}
+	if err := fileRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create file indexes", zap.Error(err))
+	}
+	if err := folderRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create folder indexes", zap.Error(err))
+	}
+	if err := gitRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create git indexes", zap.Error(err))
+	}
+	if err := versionRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create version indexes", zap.Error(err))
+	}
+	if err := templateRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create template indexes", zap.Error(err))
+	}
+	if err := invitationRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create invitation indexes", zap.Error(err))
+	}
+	if err := shareLinkRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create share link indexes", zap.Error(err))
+	}
+	if err := searchRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create search indexes", zap.Error(err))
+	}
+	if err := outlineRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create outline indexes", zap.Error(err))
+	}
+	if err := uploadRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create upload indexes", zap.Error(err))
+	}
+	if err := quotaRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create quota indexes", zap.Error(err))
+	}
+	if err := fsckRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create storage check indexes", zap.Error(err))
+	}
+	if err := commentRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create comment indexes", zap.Error(err))
+	}
+	if err := notificationRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create notification indexes", zap.Error(err))
+	}
+	if err := suggestionRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create suggestion indexes", zap.Error(err))
+	}
+	if err := lintRepo.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create lint indexes", zap.Error(err))
+	}
+
+	// Redis carries events to the websocket service. Without it the service
+	// works, but clients only see changes when they reload.
+	redisClient := redis.NewClient(&redis.Options{
+		Addr:     cfg.RedisAddr,
+		Password: cfg.RedisPassword,
+	})
+	defer redisClient.Close()
+	if err := redisClient.Ping(context.Background()).Err(); err != nil {
+		log.Error("Failed to connect to Redis", zap.Error(err))
+	}
+	eventPublisher := events.NewPublisher(redisClient, log)
+
+	// Git clients authenticate with the same keys as the auth service
+	jwtManager, err := auth.NewJWTManager(
+		cfg.JWTPrivateKeyPath,
+		cfg.JWTPublicKeyPath,
+		cfg.JWTSecret,
+		15*time.Minute,
+		7*24*time.Hour,
+	)
+	if err != nil {
+		log.Fatal("Failed to initialize JWT manager", zap.Error(err))
+	}
 
 	// Initialize services
-	projectService := service.NewProjectService(projectRepo, fileRepo, minioClient, log)
+	retention := service.VersionRetention{
+		KeepLatest:  cfg.VersionKeepLatest,
+		MaxVersions: cfg.VersionMaxCount,
+		MaxAge:      time.Duration(cfg.VersionMaxAgeDays) * 24 * time.Hour,
+	}
+	quotaDefaults := service.QuotaDefaults{
+		User: models.QuotaLimits{
+			MaxStorageBytes:  int64(cfg.UserQuotaStorageMB) << 20,
+			MaxFiles:         int64(cfg.UserQuotaMaxFiles),
+			MaxFileSizeBytes: int64(cfg.UserQuotaMaxFileSizeMB) << 20,
+		},
+		Organization: models.QuotaLimits{
+			MaxStorageBytes:  int64(cfg.OrgQuotaStorageMB) << 20,
+			MaxFiles:         int64(cfg.OrgQuotaMaxFiles),
+			MaxFileSizeBytes: int64(cfg.OrgQuotaMaxFileSizeMB) << 20,
+		},
+	}
+	projectService := service.NewProjectService(
+		projectRepo,
+		fileRepo,
+		folderRepo,
+		gitRepo,
+		userRepo,
+		versionRepo,
+		templateRepo,
+		invitationRepo,
+		shareLinkRepo,
+		searchRepo,
+		outlineRepo,
+		uploadRepo,
+		quotaRepo,
+		fsckRepo,
+		commentRepo,
+		notificationRepo,
+		suggestionRepo,
+		lintRepo,
+		minioClient,
+		eventPublisher,
+		retention,
+		time.Duration(cfg.TrashRetentionDays)*24*time.Hour,
+		time.Duration(cfg.ShareGrantTTLHours)*time.Hour,
+		int64(cfg.MaxUploadSizeMB)<<20,
+		quotaDefaults,
+		log,
+	)
+	gitAuthenticator := service.NewGitAuthenticator(jwtManager, userRepo, log)
+
+	migrator, err := migrate.New(db, "project", projectService.Migrations(), log)
+	if err != nil {
+		log.Fatal("Failed to initialize migrations", zap.Error(err))
+	}
+	if err := migrator.CreateIndexes(context.Background()); err != nil {
+		log.Error("Failed to create migration indexes", zap.Error(err))
+	}
+
+	// "main migrate ..." runs the migration command instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrator.RunCommand(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			log.Error("Migration command failed", zap.Error(err))
+			os.Exit(1)
+		}
+		return
+	}
+
+	if cfg.RunMigrations {
+		if _, err := migrator.Up(context.Background(), false); err != nil {
+			log.Error("Failed to run migrations", zap.Error(err))
+		}
+	}
+
+	if err := projectService.SeedBuiltinTemplates(context.Background()); err != nil {
+		log.Error("Failed to seed built-in templates", zap.Error(err))
+	}
 
 	// Initialize handlers
 	projectHandler := handlers.NewProjectHandler(projectService, log)
+	gitHandler := handlers.NewGitHandler(projectService, gitAuthenticator, log)
+	migrationHandler := handlers.NewMigrationHandler(migrator, log)
 
 	// Setup Router
 	if cfg.Environment == "production" {
@@ -94,19 +266,119 @@ func main() {
 		{
 			projects.POST("", projectHandler.CreateProject)
 			projects.GET("", projectHandler.GetProjects)
+			projects.POST("/import", projectHandler.ImportProject)
+			projects.GET("/shared", projectHandler.GetSharedProjects)
+			projects.GET("/search", projectHandler.SearchAllProjects)
+			projects.GET("/usage", projectHandler.GetStorageUsage)
+			projects.GET("/notifications", projectHandler.ListNotifications)
+			projects.POST("/notifications/read", projectHandler.MarkNotificationsRead)
+			projects.GET("/invitations", projectHandler.ListMyInvitations)
+			projects.POST("/invitations/:invitationId/accept", projectHandler.AcceptInvitation)
+			projects.POST("/invitations/:invitationId/decline", projectHandler.DeclineInvitation)
+			projects.POST("/links/resolve", projectHandler.ResolveShareLink)
 			projects.GET("/:id", projectHandler.GetProject)
 			projects.PUT("/:id", projectHandler.UpdateProject)
 			projects.DELETE("/:id", projectHandler.DeleteProject)
 			projects.POST("/:id/share", projectHandler.ShareProject)
+			projects.GET("/:id/collaborators", projectHandler.ListCollaborators)
+			projects.PUT("/:id/collaborators/:userId", projectHandler.UpdateCollaborator)
+			projects.DELETE("/:id/collaborators/:userId", projectHandler.RemoveCollaborator)
+			projects.POST("/:id/leave", projectHandler.LeaveProject)
+			projects.POST("/:id/invitations", projectHandler.InviteCollaborator)
+			projects.GET("/:id/invitations", projectHandler.ListProjectInvitations)
+			projects.DELETE("/:id/invitations/:invitationId", projectHandler.RevokeInvitation)
+			projects.POST("/:id/links", projectHandler.CreateShareLink)
+			projects.GET("/:id/links", projectHandler.ListShareLinks)
+			projects.DELETE("/:id/links/:linkId", projectHandler.RevokeShareLink)
+			projects.POST("/:id/archive", projectHandler.ArchiveProject)
+			projects.POST("/:id/unarchive", projectHandler.UnarchiveProject)
+			projects.POST("/:id/restore", projectHandler.RestoreProject)
+			projects.DELETE("/:id/permanent", projectHandler.PurgeProject)
+			projects.POST("/:id/duplicate", projectHandler.DuplicateProject)
+			projects.GET("/:id/export", projectHandler.ExportProject)
+			projects.GET("/:id/search", projectHandler.SearchProject)
+			projects.POST("/:id/search/reindex", projectHandler.ReindexProject)
+			projects.GET("/:id/index", projectHandler.GetProjectIndex)
+			projects.GET("/:id/dependencies", projectHandler.GetDependencyGraph)
+			projects.POST("/:id/dependencies/move-unused", projectHandler.MoveUnusedFiles)
+			projects.GET("/:id/bibliography", projectHandler.ListBibliography)
+			projects.GET("/:id/bibliography/issues", projectHandler.CheckBibliography)
+			projects.POST("/:id/bibliography/import", projectHandler.ImportBibliography)
+			projects.POST("/:id/bibliography/:fileId/normalize", projectHandler.NormalizeBibliography)
+			projects.GET("/:id/lint", projectHandler.ListProjectLint)
+			projects.GET("/:id/lint/rules", projectHandler.ListLintRules)
+			projects.POST("/:id/template", projectHandler.PublishTemplate)
+			projects.GET("/:id/comments", projectHandler.ListCommentThreads)
+			projects.POST("/:id/comments", projectHandler.CreateCommentThread)
+			projects.GET("/:id/comments/:threadId", projectHandler.GetCommentThread)
+			projects.DELETE("/:id/comments/:threadId", projectHandler.DeleteCommentThread)
+			projects.POST("/:id/comments/:threadId/replies", projectHandler.ReplyToCommentThread)
+			projects.PUT("/:id/comments/:threadId/replies/:commentId", projectHandler.EditComment)
+			projects.DELETE("/:id/comments/:threadId/replies/:commentId", projectHandler.DeleteComment)
+			projects.POST("/:id/comments/:threadId/resolve", projectHandler.ResolveCommentThread)
+			projects.POST("/:id/comments/:threadId/reopen", projectHandler.ReopenCommentThread)
+			projects.GET("/:id/suggestions", projectHandler.ListSuggestions)
+			projects.POST("/:id/suggestions/accept", projectHandler.AcceptSuggestions)
+			projects.POST("/:id/suggestions/reject", projectHandler.RejectSuggestions)
+			projects.POST("/:id/suggestions/:suggestionId/accept", projectHandler.AcceptSuggestion)
+			projects.POST("/:id/suggestions/:suggestionId/reject", projectHandler.RejectSuggestion)
 			projects.POST("/:id/files", projectHandler.CreateFile)
+			projects.POST("/:id/files/upload", projectHandler.UploadFile)
 			projects.GET("/:id/files", projectHandler.ListFiles)
 			projects.GET("/:id/files/:fileId", projectHandler.GetFileMetadata)
 			projects.GET("/:id/files/:fileId/content", projectHandler.GetFileContent)
+			projects.GET("/:id/files/:fileId/download", projectHandler.DownloadFile)
 			projects.PUT("/:id/files/:fileId", projectHandler.UpdateFile)
+			projects.DELETE("/:id/files/:fileId", projectHandler.DeleteFile)
+			projects.POST("/:id/files/:fileId/rename", projectHandler.RenameFile)
+			projects.POST("/:id/files/:fileId/move", projectHandler.MoveFile)
+			projects.GET("/:id/files/:fileId/lint", projectHandler.GetFileLint)
+			projects.GET("/:id/files/:fileId/versions", projectHandler.ListFileVersions)
+			projects.GET("/:id/files/:fileId/versions/:version/content", projectHandler.GetFileVersionContent)
+			projects.POST("/:id/files/:fileId/versions/:version/restore", projectHandler.RestoreFileVersion)
+			projects.GET("/:id/files/:fileId/diff", projectHandler.DiffFileVersions)
+			projects.POST("/:id/folders", projectHandler.CreateFolder)
+			projects.GET("/:id/folders", projectHandler.ListFolders)
+			projects.DELETE("/:id/folders/:folderId", projectHandler.DeleteFolder)
+			projects.POST("/:id/folders/:folderId/rename", projectHandler.RenameFolder)
+			projects.POST("/:id/folders/:folderId/move", projectHandler.MoveFolder)
+
+			// Resumable uploads
+			projects.POST("/:id/uploads", projectHandler.CreateUpload)
+			projects.GET("/:id/uploads/:uploadId", projectHandler.GetUpload)
+			projects.PUT("/:id/uploads/:uploadId/chunks/:index", projectHandler.UploadChunk)
+			projects.POST("/:id/uploads/:uploadId/complete", projectHandler.CompleteUpload)
+			projects.DELETE("/:id/uploads/:uploadId", projectHandler.AbortUpload)
+
+			// Git smart HTTP, authenticated by the handler itself
+			projects.GET("/:id/git/info/refs", gitHandler.InfoRefs)
+			projects.POST("/:id/git/git-upload-pack", gitHandler.UploadPack)
+			projects.POST("/:id/git/git-receive-pack", gitHandler.ReceivePack)
+		}
+
+		templates := api.Group("/templates")
+		{
+			templates.GET("", projectHandler.ListTemplates)
+			templates.GET("/:id", projectHandler.GetTemplate)
+			templates.PUT("/:id", projectHandler.UpdateTemplate)
+			templates.DELETE("/:id", projectHandler.DeleteTemplate)
+			templates.GET("/:id/thumbnail", projectHandler.GetTemplateThumbnail)
+			templates.PUT("/:id/thumbnail", projectHandler.UploadTemplateThumbnail)
 		}
 
 		// Admin/migration endpoints
-		api.POST("/admin/migrate-latex", projectHandler.MigrateLatexFiles)
+		admin := api.Group("/admin", handlers.RequireAdmin(cfg.AdminUserIDs))
+		{
+			admin.GET("/migrations", migrationHandler.ListMigrations)
+			admin.POST("/migrations", migrationHandler.RunMigrations)
+			admin.GET("/quotas", projectHandler.ListQuotaOverrides)
+			admin.GET("/quotas/:subjectType/:subjectId", projectHandler.GetQuota)
+			admin.PUT("/quotas/:subjectType/:subjectId", projectHandler.SetQuotaOverride)
+			admin.DELETE("/quotas/:subjectType/:subjectId", projectHandler.DeleteQuotaOverride)
+			admin.POST("/fsck", projectHandler.StartStorageCheck)
+			admin.GET("/fsck", projectHandler.ListStorageChecks)
+			admin.GET("/fsck/:checkId", projectHandler.GetStorageCheck)
+		}
 	}
 
 	// Start server
@@ -115,6 +387,14 @@ func main() {
 		Handler: router,
 	}
 
+	// Purge projects whose time in the trash is up
+	purgeCtx, stopPurge := context.WithCancel(context.Background())
+	defer stopPurge()
+	go runTrashPurger(purgeCtx, projectService, log)
+	if cfg.FsckIntervalHours > 0 {
+		go runStorageChecker(purgeCtx, projectService, time.Duration(cfg.FsckIntervalHours)*time.Hour, cfg.FsckRepair, log)
+	}
+
 	go func() {
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			log.Fatal("listen: ", zap.Error(err))
@@ -135,6 +415,54 @@ func main() {
 	log.Info("Server exiting")
 }
 
+// runTrashPurger periodically deletes expired projects from the trash and
+// abandoned uploads
+func runTrashPurger(ctx context.Context, projectService *service.ProjectService, log *zap.Logger) {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		purged, err := projectService.PurgeExpiredProjects(ctx)
+		if err != nil {
+			log.Error("Failed to purge trashed projects", zap.Error(err))
+		} else if purged > 0 {
+			log.Info("Purged trashed projects", zap.Int("count", purged))
+		}
+
+		uploads, err := projectService.PurgeExpiredUploads(ctx)
+		if err != nil {
+			log.Error("Failed to purge expired uploads", zap.Error(err))
+		} else if uploads > 0 {
+			log.Info("Purged expired uploads", zap.Int("count", uploads))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runStorageChecker compares object storage with the database at every
+// interval, only reporting what it finds unless repair is set
+func runStorageChecker(ctx context.Context, projectService *service.ProjectService, interval time.Duration, repair bool, log *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if _, err := projectService.RunStorageCheck(ctx, repair); err != nil {
+			log.Error("Failed to check storage", zap.Error(err))
+		}
+	}
+}
+
 func connectMongoDB(uri string, log *zap.Logger) (*mongo.Client, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -0,0 +1,168 @@
+package bibtex
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Issue kinds
+const (
+	IssueSyntax         = "syntax_error"
+	IssueDuplicateKey   = "duplicate_key"
+	IssueDuplicateField = "duplicate_field"
+	IssueMissingField   = "missing_field"
+	IssueEmptyField     = "empty_field"
+	IssueUnknownType    = "unknown_type"
+)
+
+// Issue severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Issue is a problem found in a database
+type Issue struct {
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line"`
+	Kind     string `json:"kind"`
+	Severity string `json:"severity"`
+	Key      string `json:"key,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Required fields of the BibTeX and BibLaTeX entry types. Each requirement
+// lists alternatives, any one of which satisfies it, so that both the
+// BibTeX and BibLaTeX field names are accepted.
+var requiredFields = map[string][][]string{
+	"article":       {{"author"}, {"title"}, {"journal", "journaltitle"}, {"year", "date"}},
+	"book":          {{"author", "editor"}, {"title"}, {"publisher"}, {"year", "date"}},
+	"booklet":       {{"title"}},
+	"inbook":        {{"author", "editor"}, {"title"}, {"chapter", "pages"}, {"publisher"}, {"year", "date"}},
+	"incollection":  {{"author"}, {"title"}, {"booktitle"}, {"publisher"}, {"year", "date"}},
+	"inproceedings": {{"author"}, {"title"}, {"booktitle"}, {"year", "date"}},
+	"conference":    {{"author"}, {"title"}, {"booktitle"}, {"year", "date"}},
+	"manual":        {{"title"}},
+	"mastersthesis": {{"author"}, {"title"}, {"school", "institution"}, {"year", "date"}},
+	"phdthesis":     {{"author"}, {"title"}, {"school", "institution"}, {"year", "date"}},
+	"misc":          {},
+	"proceedings":   {{"title"}, {"year", "date"}},
+	"techreport":    {{"author"}, {"title"}, {"institution"}, {"year", "date"}},
+	"unpublished":   {{"author"}, {"title"}, {"note"}},
+
+	// BibLaTeX
+	"online":         {{"author", "editor"}, {"title"}, {"url", "doi", "eprint"}, {"year", "date"}},
+	"electronic":     {{"title"}, {"url", "doi", "eprint"}},
+	"www":            {{"title"}, {"url", "doi", "eprint"}},
+	"report":         {{"author"}, {"title"}, {"type"}, {"institution"}, {"year", "date"}},
+	"thesis":         {{"author"}, {"title"}, {"type"}, {"institution", "school"}, {"year", "date"}},
+	"collection":     {{"editor"}, {"title"}, {"year", "date"}},
+	"mvbook":         {{"author"}, {"title"}, {"year", "date"}},
+	"mvcollection":   {{"editor"}, {"title"}, {"year", "date"}},
+	"mvproceedings":  {{"title"}, {"year", "date"}},
+	"bookinbook":     {{"author"}, {"title"}, {"year", "date"}},
+	"suppbook":       {{"author"}, {"title"}, {"booktitle"}, {"year", "date"}},
+	"suppcollection": {{"editor"}, {"title"}, {"booktitle"}, {"year", "date"}},
+	"suppperiodical": {{"author"}, {"title"}, {"journal", "journaltitle"}, {"year", "date"}},
+	"periodical":     {{"editor"}, {"title"}, {"year", "date"}},
+	"patent":         {{"author"}, {"title"}, {"number"}, {"year", "date"}},
+	"dataset":        {{"author", "editor"}, {"title"}, {"year", "date"}},
+	"software":       {{"author", "editor"}, {"title"}, {"year", "date"}},
+	"reference":      {{"editor"}, {"title"}, {"year", "date"}},
+	"inreference":    {{"author"}, {"title"}, {"booktitle"}, {"year", "date"}},
+	"artwork":        {{"author"}, {"title"}, {"year", "date"}},
+	"audio":          {{"title"}},
+	"video":          {{"title"}},
+	"image":          {{"title"}},
+	"movie":          {{"title"}},
+	"music":          {{"title"}},
+	"letter":         {{"author"}, {"title"}, {"year", "date"}},
+	"set":            {},
+	"xdata":          {},
+}
+
+// Check finds syntax errors, duplicate keys and fields, and missing or
+// empty required fields in the databases of a project, keyed by path.
+// BibTeX compares keys ignoring case, so keys that differ only in case are
+// duplicates too, also across files.
+func Check(databases map[string]*Database) []Issue {
+	paths := make([]string, 0, len(databases))
+	for p := range databases {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	issues := []Issue{}
+	type location struct {
+		file string
+		line int
+	}
+	seen := map[string]location{}
+
+	for _, p := range paths {
+		db := databases[p]
+		for _, err := range db.Errors {
+			issues = append(issues, Issue{
+				File: p, Line: err.Line, Kind: IssueSyntax, Severity: SeverityError, Message: err.Message,
+			})
+		}
+
+		for _, entry := range db.Entries {
+			issue := func(kind, severity, field, message string) {
+				issues = append(issues, Issue{
+					File: p, Line: entry.Line, Kind: kind, Severity: severity,
+					Key: entry.Key, Field: field, Message: message,
+				})
+			}
+
+			folded := strings.ToLower(entry.Key)
+			if first, ok := seen[folded]; ok {
+				issue(IssueDuplicateKey, SeverityError, "",
+					fmt.Sprintf("key %s is already used in %s at line %d", entry.Key, first.file, first.line))
+			} else {
+				seen[folded] = location{p, entry.Line}
+			}
+
+			required, known := requiredFields[entry.Type]
+			if !known {
+				issue(IssueUnknownType, SeverityWarning, "", fmt.Sprintf("unknown entry type @%s", entry.Type))
+			}
+
+			fields := map[string]bool{}
+			for _, f := range entry.Fields {
+				if fields[f.Name] {
+					issue(IssueDuplicateField, SeverityWarning, f.Name,
+						fmt.Sprintf("field %s is set more than once; only the first is used", f.Name))
+					continue
+				}
+				fields[f.Name] = true
+			}
+
+			// Fields may be inherited from the parent entry
+			if fields["crossref"] || fields["xdata"] {
+				required = nil
+			}
+			for _, alternatives := range required {
+				present, empty := "", false
+				for _, name := range alternatives {
+					if fields[name] {
+						present = name
+						empty = db.Text(entry, name) == ""
+						break
+					}
+				}
+				switch {
+				case present == "":
+					issue(IssueMissingField, SeverityWarning, alternatives[0],
+						fmt.Sprintf("@%s requires %s", entry.Type, strings.Join(alternatives, " or ")))
+				case empty:
+					issue(IssueEmptyField, SeverityWarning, present, fmt.Sprintf("required field %s is empty", present))
+				}
+			}
+		}
+	}
+
+	return issues
+}
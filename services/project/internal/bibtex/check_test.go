@@ -0,0 +1,93 @@
+package bibtex
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		databases map[string]string
+		want      []string
+	}{
+		{
+			name: "complete entries",
+			databases: map[string]string{
+				"refs.bib": "@article{a, author = {A}, title = {T}, journaltitle = {J}, date = {2020}}\n@misc{b,}",
+			},
+		},
+		{
+			name: "syntax error",
+			databases: map[string]string{
+				"refs.bib": "@misc{a, title = {open",
+			},
+			want: []string{"refs.bib:1 syntax_error "},
+		},
+		{
+			name: "duplicate keys ignore case and span files",
+			databases: map[string]string{
+				"a.bib": "@misc{Knuth,}",
+				"b.bib": "@misc{knuth,}\n@misc{KNUTH,}",
+			},
+			want: []string{"b.bib:1 duplicate_key knuth", "b.bib:2 duplicate_key KNUTH"},
+		},
+		{
+			name: "duplicate field",
+			databases: map[string]string{
+				"refs.bib": "@misc{a, note = {x}, Note = {y}}",
+			},
+			want: []string{"refs.bib:1 duplicate_field a.note"},
+		},
+		{
+			name: "missing and empty required fields",
+			databases: map[string]string{
+				"refs.bib": "@book{a, editor = {E}, title = { {} }, year = 2020}",
+			},
+			want: []string{"refs.bib:1 empty_field a.title", "refs.bib:1 missing_field a.publisher"},
+		},
+		{
+			name: "empty macro value",
+			databases: map[string]string{
+				"refs.bib": "@string{none = {}}\n@manual{a, title = none}",
+			},
+			want: []string{"refs.bib:2 empty_field a.title"},
+		},
+		{
+			name: "crossref inherits required fields",
+			databases: map[string]string{
+				"refs.bib": "@inproceedings{a, crossref = {conf}}",
+			},
+		},
+		{
+			name: "unknown type",
+			databases: map[string]string{
+				"refs.bib": "@webpage{a,}",
+			},
+			want: []string{"refs.bib:1 unknown_type a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			databases := make(map[string]*Database, len(tt.databases))
+			for p, content := range tt.databases {
+				databases[p] = Parse(content)
+			}
+
+			var got []string
+			for _, issue := range Check(databases) {
+				subject := issue.Key
+				if issue.Field != "" {
+					subject += "." + issue.Field
+				}
+				got = append(got, fmt.Sprintf("%s:%d %s %s", issue.File, issue.Line, issue.Kind, subject))
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,268 @@
+package bibtex
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Key styles for generated keys
+const (
+	KeyStyleKeep            = "keep"
+	KeyStyleAuthorYear      = "author_year"       // knuth1984
+	KeyStyleAuthorYearTitle = "author_year_title" // knuth1984literate
+)
+
+// Order of the common fields in a formatted entry. Other fields follow in
+// the order they were written.
+var fieldOrder = []string{
+	"author", "editor", "title", "subtitle", "booktitle", "journal", "journaltitle",
+	"series", "edition", "volume", "number", "chapter", "pages", "publisher",
+	"school", "institution", "organization", "address", "location", "type",
+	"month", "year", "date", "isbn", "issn", "doi", "eprint", "eprinttype",
+	"url", "urldate", "keywords", "abstract", "note", "crossref",
+}
+
+var spacePattern = regexp.MustCompile(`\s+`)
+
+// Words skipped when a title word is used in a key
+var titleStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "on": true, "of": true, "in": true,
+	"for": true, "to": true, "and": true, "with": true, "from": true, "by": true,
+	"at": true, "towards": true, "toward": true, "is": true, "are": true,
+}
+
+// ValidKeyStyle reports whether a key style is known
+func ValidKeyStyle(style string) bool {
+	switch style {
+	case KeyStyleKeep, KeyStyleAuthorYear, KeyStyleAuthorYearTitle:
+		return true
+	}
+	return false
+}
+
+// FormatOptions controls how a database is normalized
+type FormatOptions struct {
+	// KeyStyle regenerates the entry keys unless empty or KeyStyleKeep
+	KeyStyle string
+
+	// Sort orders the entries by key, after everything else
+	Sort bool
+
+	// Reserved holds the lowercase keys used elsewhere in the project,
+	// which generated keys avoid
+	Reserved map[string]bool
+}
+
+// Format writes a database in a normalized form: lowercase types and field
+// names, one field per line in a consistent order, values in braces and
+// whitespace collapsed. Text that is not an entry is kept as written. It
+// returns the new content along with the keys that were changed, old to
+// new.
+func Format(db *Database, opts FormatOptions) (string, map[string]string) {
+	renamed := map[string]string{}
+	if opts.KeyStyle != "" && opts.KeyStyle != KeyStyleKeep {
+		renamed = db.rekey(opts.KeyStyle, opts.Reserved)
+	}
+
+	blocks := db.Blocks
+	if opts.Sort {
+		blocks = make([]Block, 0, len(db.Blocks))
+		var entries []Block
+		for _, block := range db.Blocks {
+			if block.Entry != nil {
+				entries = append(entries, block)
+			} else {
+				blocks = append(blocks, block)
+			}
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return strings.ToLower(entries[i].Entry.Key) < strings.ToLower(entries[j].Entry.Key)
+		})
+		blocks = append(blocks, entries...)
+	}
+
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if block.Entry != nil {
+			parts = append(parts, FormatEntry(block.Entry))
+		} else {
+			parts = append(parts, block.Raw)
+		}
+	}
+	if len(parts) == 0 {
+		return "", renamed
+	}
+
+	return strings.Join(parts, "\n\n") + "\n", renamed
+}
+
+// FormatEntry writes one entry in the normalized form, without a trailing
+// newline
+func FormatEntry(entry *Entry) string {
+	fields := make([]Field, len(entry.Fields))
+	copy(fields, entry.Fields)
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fieldRank(fields[i].Name) < fieldRank(fields[j].Name)
+	})
+
+	var b strings.Builder
+	b.WriteString("@" + strings.ToLower(entry.Type) + "{" + entry.Key + ",\n")
+	for _, f := range fields {
+		b.WriteString("  " + f.Name + " = " + normalizeValue(f.Value) + ",\n")
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func fieldRank(name string) int {
+	for i, n := range fieldOrder {
+		if n == name {
+			return i
+		}
+	}
+	return len(fieldOrder)
+}
+
+// normalizeValue braces quoted strings and collapses whitespace, leaving
+// numbers and macros as they are. The parts of a concatenation keep a
+// space at either end, which separates them from the next.
+func normalizeValue(value string) string {
+	parts := splitConcat(value)
+	for i, part := range parts {
+		if part == "" || (part[0] != '{' && part[0] != '"') {
+			continue
+		}
+		text := part[1 : len(part)-1]
+		if len(parts) == 1 {
+			text = collapseSpace(text)
+		} else {
+			text = spacePattern.ReplaceAllString(text, " ")
+		}
+		parts[i] = "{" + text + "}"
+	}
+	return strings.Join(parts, " # ")
+}
+
+// rekey gives the entries keys in the given style. Entries whose key
+// already follows it are left alone.
+func (db *Database) rekey(style string, reserved map[string]bool) map[string]string {
+	taken := map[string]bool{}
+	for key := range reserved {
+		taken[key] = true
+	}
+
+	bases := make([]string, len(db.Entries))
+	pending := make([]bool, len(db.Entries))
+	for i, entry := range db.Entries {
+		bases[i] = db.baseKey(entry, style)
+		folded := strings.ToLower(entry.Key)
+		if bases[i] == "" || (hasBase(folded, bases[i]) && !taken[folded]) {
+			taken[folded] = true
+			continue
+		}
+		pending[i] = true
+	}
+
+	renamed := map[string]string{}
+	for i, entry := range db.Entries {
+		if !pending[i] {
+			continue
+		}
+		key := uniqueKey(bases[i], taken)
+		taken[key] = true
+		renamed[entry.Key] = key
+		entry.Key = key
+	}
+
+	return renamed
+}
+
+// GenerateKey returns a key in the given style for an entry that is not in
+// a database, such as an imported one, avoiding the lowercase keys taken.
+// An entry with neither names nor a title gets a key based on "ref".
+func GenerateKey(entry *Entry, style string, taken map[string]bool) string {
+	base := (&Database{}).baseKey(entry, style)
+	if base == "" {
+		base = "ref"
+	}
+	return uniqueKey(base, taken)
+}
+
+// baseKey builds a key from the family name of the first author or editor,
+// the year and, in the longer style, the first significant title word
+func (db *Database) baseKey(entry *Entry, style string) string {
+	name := ""
+	for _, field := range []string{"author", "editor"} {
+		value, ok := entry.Get(field)
+		if !ok {
+			continue
+		}
+		if names := splitNames(expandValue(value, db.Strings, 0)); len(names) > 0 {
+			name = strings.ReplaceAll(Fold(lastName(names[0])), " ", "")
+			break
+		}
+	}
+
+	titleWord := ""
+	for _, word := range strings.Fields(Fold(db.Text(entry, "title"))) {
+		if !titleStopWords[word] {
+			titleWord = word
+			break
+		}
+	}
+	if name == "" {
+		name, titleWord = titleWord, ""
+	}
+	if name == "" {
+		return ""
+	}
+
+	key := name + entryYear(db, entry)
+	if style == KeyStyleAuthorYearTitle {
+		key += titleWord
+	}
+	return key
+}
+
+// entryYear returns the year of an entry, from the year field or the start
+// of a BibLaTeX date
+func entryYear(db *Database, entry *Entry) string {
+	for _, field := range []string{"year", "date"} {
+		text := db.Text(entry, field)
+		for i := 0; i+4 <= len(text); i++ {
+			if _, err := strconv.Atoi(text[i : i+4]); err == nil && !strings.ContainsAny(text[i:i+4], "+-") {
+				return text[i : i+4]
+			}
+		}
+	}
+	return ""
+}
+
+// hasBase reports whether a key is the base key, possibly with a letter
+// added to tell it from other entries
+func hasBase(key, base string) bool {
+	if key == base {
+		return true
+	}
+	return len(key) == len(base)+1 && strings.HasPrefix(key, base) && key[len(base)] >= 'a' && key[len(base)] <= 'z'
+}
+
+// uniqueKey adds a letter to a base key until it is not taken, then falls
+// back to numbers
+func uniqueKey(base string, taken map[string]bool) string {
+	if !taken[base] {
+		return base
+	}
+	for c := 'a'; c <= 'z'; c++ {
+		if key := base + string(c); !taken[key] {
+			return key
+		}
+	}
+	for n := 2; ; n++ {
+		if key := base + "-" + strconv.Itoa(n); !taken[key] {
+			return key
+		}
+	}
+}
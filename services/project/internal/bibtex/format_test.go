@@ -0,0 +1,103 @@
+package bibtex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		opts    FormatOptions
+		want    string
+		renamed map[string]string
+	}{
+		{
+			name:    "fields are ordered, braced and collapsed",
+			content: "@ARTICLE{k,\n  Year = 1984, note = \"A  note\",\n  Title = {Literate\n    Programming},\n  author={Knuth, D.},\n  month = jan # \"~1\"}",
+			want:    "@article{k,\n  author = {Knuth, D.},\n  title = {Literate Programming},\n  month = jan # {~1},\n  year = 1984,\n  note = {A note},\n}\n",
+		},
+		{
+			name:    "comments and strings are kept",
+			content: "% header\n@string{acm = {ACM}}\n@comment{ {keep} }\n@misc{k,}",
+			want:    "% header\n\n@string{acm = {ACM}}\n\n@comment{ {keep} }\n\n@misc{k,\n}\n",
+		},
+		{
+			name:    "unparsed entries are kept as written",
+			content: "@misc{bad, title = {open\n@misc{good,}",
+			want:    "@misc{bad, title = {open\n\n@misc{good,\n}\n",
+		},
+		{
+			name:    "sorted after other blocks",
+			content: "@misc{b,}\n@string{x = {y}}\n@misc{A,}",
+			opts:    FormatOptions{Sort: true},
+			want:    "@string{x = {y}}\n\n@misc{A,\n}\n\n@misc{b,\n}\n",
+		},
+		{
+			name:    "author and year keys",
+			content: "@misc{old, author = {Donald E. Knuth}, year = 1984}\n@misc{knuth1984, author = {Knuth, Donald}, date = {1984-05}}\n@misc{x, title = {The Art}}",
+			opts:    FormatOptions{KeyStyle: KeyStyleAuthorYear},
+			want:    "@misc{knuth1984a,\n  author = {Donald E. Knuth},\n  year = 1984,\n}\n\n@misc{knuth1984,\n  author = {Knuth, Donald},\n  date = {1984-05},\n}\n\n@misc{art,\n  title = {The Art},\n}\n",
+			renamed: map[string]string{"old": "knuth1984a", "x": "art"},
+		},
+		{
+			name:    "generated keys avoid reserved keys",
+			content: "@misc{a, author = {van Dyke, Ann}, title = {On Graphs}, year = {2001}}",
+			opts:    FormatOptions{KeyStyle: KeyStyleAuthorYearTitle, Reserved: map[string]bool{"vandyke2001graphs": true}},
+			want:    "@misc{vandyke2001graphsa,\n  author = {van Dyke, Ann},\n  title = {On Graphs},\n  year = {2001},\n}\n",
+			renamed: map[string]string{"a": "vandyke2001graphsa"},
+		},
+		{
+			name:    "empty database",
+			content: "  \n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, renamed := Format(Parse(tt.content), tt.opts)
+			wantRenamed := tt.renamed
+			if wantRenamed == nil {
+				wantRenamed = map[string]string{}
+			}
+
+			if got != tt.want {
+				t.Errorf("Format() =\n%s\nwant\n%s", got, tt.want)
+			}
+			if !reflect.DeepEqual(renamed, wantRenamed) {
+				t.Errorf("renamed = %v, want %v", renamed, wantRenamed)
+			}
+		})
+	}
+}
+
+func TestGenerateKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[string]string
+		style  string
+		taken  map[string]bool
+		want   string
+	}{
+		{"author and year", map[string]string{"author": "Lamport, Leslie", "year": "1994"}, KeyStyleAuthorYear, nil, "lamport1994"},
+		{"accents are folded", map[string]string{"author": "Erdős, Paul", "year": "1947"}, KeyStyleAuthorYear, nil, "erdos1947"},
+		{"editor without author", map[string]string{"editor": "Ann Smith"}, KeyStyleAuthorYear, nil, "smith"},
+		{"title word", map[string]string{"author": "Doe, J.", "title": "A Theory of Everything", "year": "2020"}, KeyStyleAuthorYearTitle, nil, "doe2020theory"},
+		{"title without author", map[string]string{"title": "The Manual"}, KeyStyleAuthorYear, nil, "manual"},
+		{"nothing to go on", map[string]string{}, KeyStyleAuthorYear, nil, "ref"},
+		{"taken keys get letters", map[string]string{"author": "Roe, R."}, KeyStyleAuthorYear, map[string]bool{"roe": true, "roea": true}, "roeb"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry := &Entry{Type: "misc", Key: "x"}
+			for name, text := range tt.fields {
+				entry.Set(name, text)
+			}
+			if got := GenerateKey(entry, tt.style, tt.taken); got != tt.want {
+				t.Errorf("GenerateKey() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package bibtex
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Import formats
+const (
+	FormatBibTeX  = "bibtex"
+	FormatRIS     = "ris"
+	FormatCSLJSON = "csl-json"
+)
+
+var risTagPattern = regexp.MustCompile(`^([A-Z][A-Z0-9])  -( (.*))?$`)
+
+// Entry types of RIS reference types
+var risTypes = map[string]string{
+	"JOUR": "article", "JFULL": "article", "MGZN": "article", "NEWS": "article",
+	"EJOUR": "article", "BOOK": "book", "EBOOK": "book", "EDBOOK": "book",
+	"CHAP": "incollection", "ECHAP": "incollection", "CONF": "inproceedings",
+	"CPAPER": "inproceedings", "THES": "phdthesis", "RPRT": "techreport",
+	"UNPB": "unpublished", "MANSCPT": "unpublished",
+}
+
+// Entry types of CSL types
+var cslTypes = map[string]string{
+	"article-journal": "article", "article-magazine": "article",
+	"article-newspaper": "article", "article": "article", "book": "book",
+	"chapter": "incollection", "paper-conference": "inproceedings",
+	"thesis": "phdthesis", "report": "techreport", "manuscript": "unpublished",
+}
+
+// DetectFormat guesses the format of pasted references
+func DetectFormat(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{") {
+		if json.Valid([]byte(trimmed)) {
+			return FormatCSLJSON
+		}
+	}
+	for _, line := range strings.SplitN(trimmed, "\n", 20) {
+		if strings.HasPrefix(strings.TrimSpace(line), "TY  -") {
+			return FormatRIS
+		}
+	}
+	return FormatBibTeX
+}
+
+// Import converts references in one of the import formats, or in the
+// detected format if it is empty, to BibTeX entries. Keys are kept when the
+// source has them and are otherwise left empty.
+func Import(content, format string) ([]*Entry, error) {
+	if format == "" {
+		format = DetectFormat(content)
+	}
+
+	var entries []*Entry
+	var err error
+	switch format {
+	case FormatBibTeX:
+		db := Parse(content)
+		if len(db.Errors) > 0 {
+			return nil, fmt.Errorf("invalid BibTeX: %s", db.Errors[0].Error())
+		}
+		entries = db.Entries
+		// Macros defined with the entries are expanded, since the
+		// database they are added to may not define them
+		for _, entry := range entries {
+			for i, f := range entry.Fields {
+				if !isPlainValue(f.Value) {
+					entry.Fields[i].Value = "{" + balanceBraces(expandValue(f.Value, db.Strings, 0)) + "}"
+				}
+			}
+		}
+	case FormatRIS:
+		entries, err = ParseRIS(content)
+	case FormatCSLJSON:
+		entries, err = ParseCSLJSON([]byte(content))
+	default:
+		return nil, fmt.Errorf("unknown import format")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no entries to import")
+	}
+
+	return entries, nil
+}
+
+// isPlainValue reports whether a value needs no macros: a single braced or
+// quoted string, or a number
+func isPlainValue(value string) bool {
+	parts := splitConcat(value)
+	if len(parts) != 1 || parts[0] == "" {
+		return false
+	}
+	if c := parts[0][0]; c == '{' || c == '"' {
+		return true
+	}
+	_, err := strconv.Atoi(parts[0])
+	return err == nil
+}
+
+// ParseRIS converts RIS records to entries
+func ParseRIS(content string) ([]*Entry, error) {
+	var entries []*Entry
+	var entry *Entry
+	var authors, editors, keywords []string
+	var startPage, endPage string
+
+	finish := func() {
+		if entry == nil {
+			return
+		}
+		if len(authors) > 0 {
+			entry.Set("author", strings.Join(authors, " and "))
+		}
+		if len(editors) > 0 {
+			entry.Set("editor", strings.Join(editors, " and "))
+		}
+		if len(keywords) > 0 {
+			entry.Set("keywords", strings.Join(keywords, ", "))
+		}
+		if startPage != "" && endPage != "" && endPage != startPage {
+			entry.Set("pages", startPage+"--"+endPage)
+		} else if startPage != "" {
+			entry.Set("pages", startPage)
+		}
+		entries = append(entries, entry)
+		entry, authors, editors, keywords, startPage, endPage = nil, nil, nil, nil, "", ""
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(strings.TrimPrefix(scanner.Text(), "\ufeff"), " \r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		m := risTagPattern.FindStringSubmatch(text)
+		if m == nil {
+			return nil, fmt.Errorf("invalid RIS at line %d", line)
+		}
+		tag, value := m[1], strings.TrimSpace(m[3])
+
+		if tag == "TY" {
+			finish()
+			entryType, ok := risTypes[value]
+			if !ok {
+				entryType = "misc"
+			}
+			entry = &Entry{Type: entryType, Fields: []Field{}}
+			continue
+		}
+		if entry == nil {
+			return nil, fmt.Errorf("invalid RIS at line %d: expected TY", line)
+		}
+		if value == "" && tag != "ER" {
+			continue
+		}
+
+		switch tag {
+		case "ER":
+			finish()
+		case "ID":
+			entry.Key = strings.Join(strings.Fields(value), "")
+		case "AU", "A1":
+			authors = append(authors, value)
+		case "A2", "ED":
+			if entry.Type == "article" {
+				continue
+			}
+			editors = append(editors, value)
+		case "TI", "T1":
+			entry.Set("title", value)
+		case "T2", "JO", "JF", "JA", "BT":
+			switch entry.Type {
+			case "incollection", "inproceedings":
+				entry.Set("booktitle", value)
+			case "article":
+				if _, ok := entry.Get("journal"); !ok || tag == "JF" || tag == "T2" {
+					entry.Set("journal", value)
+				}
+			case "book":
+				if tag == "T2" {
+					entry.Set("series", value)
+				}
+			}
+		case "T3":
+			entry.Set("series", value)
+		case "PY", "Y1", "DA":
+			if year := firstYear(value); year != "" {
+				if _, ok := entry.Get("year"); !ok || tag == "PY" {
+					entry.Set("year", year)
+				}
+			}
+		case "VL":
+			entry.Set("volume", value)
+		case "IS":
+			entry.Set("number", value)
+		case "SP":
+			startPage = value
+		case "EP":
+			endPage = value
+		case "PB":
+			if entry.Type == "phdthesis" {
+				entry.Set("school", value)
+			} else if entry.Type == "techreport" {
+				entry.Set("institution", value)
+			} else {
+				entry.Set("publisher", value)
+			}
+		case "CY":
+			entry.Set("address", value)
+		case "SN":
+			if entry.Type == "book" || entry.Type == "incollection" {
+				entry.Set("isbn", value)
+			} else {
+				entry.Set("issn", value)
+			}
+		case "DO":
+			entry.Set("doi", strings.TrimPrefix(value, "https://doi.org/"))
+		case "UR":
+			if _, ok := entry.Get("url"); !ok {
+				entry.Set("url", value)
+			}
+		case "AB", "N2":
+			entry.Set("abstract", value)
+		case "KW":
+			keywords = append(keywords, value)
+		case "N1":
+			entry.Set("note", value)
+		case "LA":
+			entry.Set("language", value)
+		case "ET":
+			entry.Set("edition", value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	finish()
+
+	return entries, nil
+}
+
+// cslItem holds the CSL-JSON variables that are converted. Numbers may be
+// written as strings or numbers.
+type cslItem struct {
+	ID              json.RawMessage `json:"id"`
+	Type            string          `json:"type"`
+	Title           string          `json:"title"`
+	ContainerTitle  string          `json:"container-title"`
+	CollectionTitle string          `json:"collection-title"`
+	Author          []cslName       `json:"author"`
+	Editor          []cslName       `json:"editor"`
+	Issued          *cslDate        `json:"issued"`
+	Volume          json.RawMessage `json:"volume"`
+	Issue           json.RawMessage `json:"issue"`
+	Page            json.RawMessage `json:"page"`
+	Edition         json.RawMessage `json:"edition"`
+	Publisher       string          `json:"publisher"`
+	PublisherPlace  string          `json:"publisher-place"`
+	DOI             string          `json:"DOI"`
+	URL             string          `json:"URL"`
+	ISBN            string          `json:"ISBN"`
+	ISSN            string          `json:"ISSN"`
+	Abstract        string          `json:"abstract"`
+	Note            string          `json:"note"`
+	Language        string          `json:"language"`
+}
+
+type cslName struct {
+	Family              string `json:"family"`
+	Given               string `json:"given"`
+	NonDroppingParticle string `json:"non-dropping-particle"`
+	Literal             string `json:"literal"`
+}
+
+type cslDate struct {
+	DateParts [][]json.RawMessage `json:"date-parts"`
+	Raw       string              `json:"raw"`
+	Literal   string              `json:"literal"`
+}
+
+// ParseCSLJSON converts CSL-JSON items, either an array or a single item,
+// to entries
+func ParseCSLJSON(data []byte) ([]*Entry, error) {
+	var items []cslItem
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "{") {
+		var item cslItem
+		if err := json.Unmarshal(data, &item); err != nil {
+			return nil, fmt.Errorf("invalid CSL-JSON: %w", err)
+		}
+		items = []cslItem{item}
+	} else if err := json.Unmarshal(data, &items); err != nil {
+		return nil, fmt.Errorf("invalid CSL-JSON: %w", err)
+	}
+
+	entries := make([]*Entry, 0, len(items))
+	for _, item := range items {
+		entryType, ok := cslTypes[item.Type]
+		if !ok {
+			entryType = "misc"
+		}
+		entry := &Entry{Type: entryType, Key: strings.Join(strings.Fields(jsonText(item.ID)), ""), Fields: []Field{}}
+
+		if names := cslNames(item.Author); names != "" {
+			entry.Set("author", names)
+		}
+		if names := cslNames(item.Editor); names != "" {
+			entry.Set("editor", names)
+		}
+		setText := func(name, text string) {
+			if text = strings.TrimSpace(text); text != "" {
+				entry.Set(name, text)
+			}
+		}
+		setText("title", item.Title)
+		switch entryType {
+		case "article":
+			setText("journal", item.ContainerTitle)
+		case "incollection", "inproceedings":
+			setText("booktitle", item.ContainerTitle)
+		}
+		setText("series", item.CollectionTitle)
+		if item.Issued != nil {
+			setText("year", item.Issued.year())
+		}
+		setText("volume", jsonText(item.Volume))
+		setText("number", jsonText(item.Issue))
+		setText("pages", strings.Replace(jsonText(item.Page), "-", "--", 1))
+		setText("edition", jsonText(item.Edition))
+		switch entryType {
+		case "phdthesis":
+			setText("school", item.Publisher)
+		case "techreport":
+			setText("institution", item.Publisher)
+		default:
+			setText("publisher", item.Publisher)
+		}
+		setText("address", item.PublisherPlace)
+		setText("doi", item.DOI)
+		setText("url", item.URL)
+		setText("isbn", item.ISBN)
+		setText("issn", item.ISSN)
+		setText("abstract", item.Abstract)
+		setText("note", item.Note)
+		setText("language", item.Language)
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// cslNames writes CSL names as a BibTeX name list. Literal names, such as
+// organizations, are braced so BibTeX does not split them.
+func cslNames(names []cslName) string {
+	list := make([]string, 0, len(names))
+	for _, n := range names {
+		switch {
+		case n.Family != "":
+			family := n.Family
+			if n.NonDroppingParticle != "" {
+				family = n.NonDroppingParticle + " " + family
+			}
+			if n.Given != "" {
+				list = append(list, family+", "+n.Given)
+			} else {
+				list = append(list, family)
+			}
+		case n.Literal != "":
+			list = append(list, "{"+n.Literal+"}")
+		}
+	}
+	return strings.Join(list, " and ")
+}
+
+func (d *cslDate) year() string {
+	if len(d.DateParts) > 0 && len(d.DateParts[0]) > 0 {
+		return jsonText(d.DateParts[0][0])
+	}
+	if d.Raw != "" {
+		return firstYear(d.Raw)
+	}
+	return firstYear(d.Literal)
+}
+
+// jsonText returns a JSON string or number as text
+func jsonText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String()
+	}
+	return ""
+}
+
+var yearPattern = regexp.MustCompile(`\b\d{4}\b`)
+
+func firstYear(text string) string {
+	return yearPattern.FindString(text)
+}
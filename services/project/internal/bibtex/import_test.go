@@ -0,0 +1,116 @@
+package bibtex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		content string
+		want    string
+	}{
+		{"@misc{a,}", FormatBibTeX},
+		{"\n  TY  - JOUR\nER  - ", FormatRIS},
+		{`[{"type": "book"}]`, FormatCSLJSON},
+		{`{"type": "book"}`, FormatCSLJSON},
+		{"{not json", FormatBibTeX},
+	}
+
+	for _, tt := range tests {
+		if got := DetectFormat(tt.content); got != tt.want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		format  string
+		want    []string
+		err     string
+	}{
+		{
+			name:    "bibtex macros are expanded",
+			content: "@string{acm = {ACM}}\n@book{k, publisher = acm # { Press}, year = 2001, month = jan}",
+			want:    []string{"@book{k,\n  publisher = {ACM Press},\n  month = {January},\n  year = 2001,\n}"},
+		},
+		{
+			name: "ris",
+			content: "TY  - JOUR\nID  - smith 2020\nAU  - Smith, Ann\nAU  - Roe, R.\nTI  - Results\n" +
+				"JO  - J. Res.\nJF  - Journal of Results\nPY  - 2020/05/01\nSP  - 10\nEP  - 20\n" +
+				"DO  - https://doi.org/10.1/x\nKW  - one\nKW  - two\nER  - \n" +
+				"TY  - THES\nAU  - Doe, J.\nPB  - MIT\nER  - ",
+			want: []string{
+				"@article{smith2020,\n  author = {Smith, Ann and Roe, R.},\n  title = {Results},\n  journal = {Journal of Results},\n  pages = {10--20},\n  year = {2020},\n  doi = {10.1/x},\n  keywords = {one, two},\n}",
+				"@phdthesis{,\n  author = {Doe, J.},\n  school = {MIT},\n}",
+			},
+		},
+		{
+			name: "csl-json",
+			content: `[{"id": 7, "type": "paper-conference", "title": "50% off $5 & more", "container-title": "Proc.",
+				"author": [{"family": "Beethoven", "non-dropping-particle": "van", "given": "L."}, {"literal": "ACME Inc."}],
+				"issued": {"date-parts": [[2019, 3]]}, "page": "1-5", "volume": 3}]`,
+			want: []string{"@inproceedings{7,\n  author = {van Beethoven, L. and {ACME Inc.}},\n  title = {50\\% off \\$5 \\& more},\n  booktitle = {Proc.},\n  volume = {3},\n  pages = {1--5},\n  year = {2019},\n}"},
+		},
+		{
+			name:    "invalid bibtex",
+			content: "@misc{a, title = {open",
+			format:  FormatBibTeX,
+			err:     "invalid BibTeX: line 1: unbalanced braces in field title of entry a",
+		},
+		{
+			name:    "invalid ris line",
+			content: "TY  - BOOK\nnot a tag\nER  - ",
+			err:     "invalid RIS at line 2",
+		},
+		{
+			name:    "ris without type",
+			content: "AU  - Smith\nTY  - BOOK",
+			format:  FormatRIS,
+			err:     "invalid RIS at line 1: expected TY",
+		},
+		{
+			name:    "invalid csl-json",
+			content: `[{"title": 3}]`,
+			format:  FormatCSLJSON,
+			err:     "invalid CSL-JSON",
+		},
+		{
+			name:    "nothing to import",
+			content: "% just a comment",
+			err:     "no entries to import",
+		},
+		{
+			name:    "unknown format",
+			content: "@misc{a,}",
+			format:  "endnote",
+			err:     "unknown import format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Import(tt.content, tt.format)
+			if tt.err != "" {
+				if err == nil || !strings.HasPrefix(err.Error(), tt.err) {
+					t.Fatalf("Import() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Import() error = %v", err)
+			}
+
+			var got []string
+			for _, entry := range entries {
+				got = append(got, FormatEntry(entry))
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("Import() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
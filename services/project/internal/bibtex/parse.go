@@ -0,0 +1,368 @@
+// Package bibtex reads, checks and writes BibTeX and BibLaTeX databases.
+// Parsing keeps everything that is not an entry, such as @string and
+// @comment blocks or free text between entries, so that a database can be
+// written back in a normalized form without losing content. Entries in the
+// RIS and CSL-JSON formats can be converted for import.
+package bibtex
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Field is a field of an entry. Value is the value as written, with its
+// delimiters, such as {Title}, "Title", 2020 or jan # "~1".
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Entry is a bibliography entry. Type and field names are lowercase.
+type Entry struct {
+	File   string  `json:"file,omitempty"`
+	Type   string  `json:"type"`
+	Key    string  `json:"key"`
+	Fields []Field `json:"fields"`
+	Line   int     `json:"line"`
+}
+
+// Block is an entry or a stretch of text kept as written, in the order they
+// appear in a database
+type Block struct {
+	Entry *Entry
+	Raw   string
+}
+
+// ParseError is a syntax error in a database. The text of an entry that
+// could not be parsed is kept as a raw block.
+type ParseError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Database is a parsed .bib file
+type Database struct {
+	Entries []*Entry
+	Blocks  []Block
+	Errors  []*ParseError
+
+	// @string macros keyed by lowercase name, with values as written
+	Strings map[string]string
+}
+
+// Get returns the value of the first field with the given name, as written
+func (e *Entry) Get(name string) (string, bool) {
+	name = strings.ToLower(name)
+	for _, f := range e.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set replaces the value of a field, or adds the field, with plain text
+// that is braced and escaped as needed
+func (e *Entry) Set(name, text string) {
+	name = strings.ToLower(name)
+	value := "{" + escapeValue(name, text) + "}"
+	for i, f := range e.Fields {
+		if f.Name == name {
+			e.Fields[i].Value = value
+			return
+		}
+	}
+	e.Fields = append(e.Fields, Field{Name: name, Value: value})
+}
+
+// Text returns the value of a field as plain text: delimiters are removed,
+// macros expanded and whitespace collapsed. LaTeX commands are kept.
+func (db *Database) Text(e *Entry, name string) string {
+	value, ok := e.Get(name)
+	if !ok {
+		return ""
+	}
+	return valueText(value, db.Strings)
+}
+
+// Parse parses a BibTeX or BibLaTeX database. Syntax errors do not stop
+// parsing: the entry is kept as raw text and parsing resumes at the next
+// line starting with @.
+func Parse(content string) *Database {
+	p := &parser{src: content}
+	db := &Database{Strings: map[string]string{}}
+
+	last := 0
+	for p.pos < len(p.src) {
+		at := strings.IndexByte(p.src[p.pos:], '@')
+		if at < 0 {
+			break
+		}
+		start := p.pos + at
+		p.pos = start + 1
+
+		entryType := strings.ToLower(p.ident())
+		p.skipSpace()
+		if entryType == "" || p.pos >= len(p.src) || (p.src[p.pos] != '{' && p.src[p.pos] != '(') {
+			// An @ in free text, such as an email address
+			continue
+		}
+
+		db.addRaw(p.src[last:start])
+
+		entry, err := p.block(entryType, db)
+		if err != nil {
+			db.Errors = append(db.Errors, &ParseError{Line: p.line(start), Message: err.Error()})
+			p.pos = p.nextEntry(start)
+			db.addRaw(p.src[start:p.pos])
+		} else if entry != nil {
+			entry.Line = p.line(start)
+			db.Entries = append(db.Entries, entry)
+			db.Blocks = append(db.Blocks, Block{Entry: entry})
+		} else {
+			db.addRaw(p.src[start:p.pos])
+		}
+		last = p.pos
+	}
+	db.addRaw(p.src[last:])
+
+	return db
+}
+
+func (db *Database) addRaw(text string) {
+	if text = strings.TrimSpace(text); text != "" {
+		db.Blocks = append(db.Blocks, Block{Raw: text})
+	}
+}
+
+type parser struct {
+	src string
+	pos int
+
+	// Line counting resumes from the last offset asked for
+	lineOffset, lineNumber int
+}
+
+// block parses the body of an @ block, from its opening delimiter. It
+// returns nil for @comment, @preamble and @string, which are kept as raw
+// text.
+func (p *parser) block(entryType string, db *Database) (*Entry, error) {
+	closer := byte('}')
+	if p.src[p.pos] == '(' {
+		closer = ')'
+	}
+	p.pos++
+
+	switch entryType {
+	case "comment":
+		// Skips to the matching delimiter, whatever is inside
+		return nil, p.skipBalanced(closer)
+	case "preamble":
+		p.skipSpace()
+		if _, err := p.value(); err != nil {
+			return nil, err
+		}
+		return nil, p.close(closer)
+	case "string":
+		p.skipSpace()
+		name := p.ident()
+		if name == "" {
+			return nil, fmt.Errorf("missing @string name")
+		}
+		p.skipSpace()
+		if !p.consume('=') {
+			return nil, fmt.Errorf("expected = after @string name")
+		}
+		p.skipSpace()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.close(closer); err != nil {
+			return nil, err
+		}
+		db.Strings[strings.ToLower(name)] = value
+		return nil, nil
+	}
+
+	p.skipSpace()
+	keyStart := p.pos
+	for p.pos < len(p.src) && !strings.ContainsRune(", \t\r\n{}", rune(p.src[p.pos])) && p.src[p.pos] != closer {
+		p.pos++
+	}
+	entry := &Entry{Type: entryType, Key: p.src[keyStart:p.pos], Fields: []Field{}}
+	if entry.Key == "" {
+		return nil, fmt.Errorf("missing entry key")
+	}
+
+	for {
+		p.skipSpace()
+		if p.consume(closer) {
+			return entry, nil
+		}
+		if !p.consume(',') {
+			return nil, fmt.Errorf("expected , or %c in entry %s", closer, entry.Key)
+		}
+		p.skipSpace()
+		if p.consume(closer) {
+			return entry, nil
+		}
+
+		name := strings.ToLower(p.ident())
+		if name == "" {
+			return nil, fmt.Errorf("expected a field name in entry %s", entry.Key)
+		}
+		p.skipSpace()
+		if !p.consume('=') {
+			return nil, fmt.Errorf("expected = after field %s in entry %s", name, entry.Key)
+		}
+		p.skipSpace()
+		value, err := p.value()
+		if err != nil {
+			return nil, fmt.Errorf("%s in field %s of entry %s", err, name, entry.Key)
+		}
+		entry.Fields = append(entry.Fields, Field{Name: name, Value: value})
+	}
+}
+
+// value parses a field value: braced or quoted strings, numbers and macro
+// names joined with #. It returns the value as written.
+func (p *parser) value() (string, error) {
+	start := p.pos
+	for {
+		if p.pos >= len(p.src) {
+			return "", fmt.Errorf("unexpected end of file")
+		}
+		switch c := p.src[p.pos]; {
+		case c == '{':
+			p.pos++
+			if err := p.skipBalanced('}'); err != nil {
+				return "", err
+			}
+		case c == '"':
+			p.pos++
+			if err := p.skipQuoted(); err != nil {
+				return "", err
+			}
+		case isIdentByte(c):
+			p.ident()
+		default:
+			return "", fmt.Errorf("expected a value")
+		}
+
+		end := p.pos
+		p.skipSpace()
+		if !p.consume('#') {
+			p.pos = end
+			return strings.TrimSpace(p.src[start:end]), nil
+		}
+		p.skipSpace()
+	}
+}
+
+// skipBalanced skips to just after the closing delimiter matching an
+// opening one already consumed, honouring nested braces. Like BibTeX, it
+// counts escaped braces too.
+func (p *parser) skipBalanced(closer byte) error {
+	depth := 0
+	for ; p.pos < len(p.src); p.pos++ {
+		switch c := p.src[p.pos]; {
+		case c == '{':
+			depth++
+		case c == closer && depth == 0:
+			p.pos++
+			return nil
+		case c == '}':
+			depth--
+		}
+	}
+	return fmt.Errorf("unbalanced braces")
+}
+
+// skipQuoted skips to just after the closing quote of a quoted string. A
+// quote inside braces does not end it.
+func (p *parser) skipQuoted() error {
+	depth := 0
+	for ; p.pos < len(p.src); p.pos++ {
+		switch p.src[p.pos] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '"':
+			if depth == 0 {
+				p.pos++
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("unterminated quoted string")
+}
+
+func (p *parser) close(closer byte) error {
+	p.skipSpace()
+	if !p.consume(closer) {
+		return fmt.Errorf("expected %c", closer)
+	}
+	return nil
+}
+
+func (p *parser) consume(c byte) bool {
+	if p.pos < len(p.src) && p.src[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) && isIdentByte(p.src[p.pos]) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.src) && strings.IndexByte(" \t\r\n", p.src[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// nextEntry finds the next @ at the start of a line after an entry that
+// could not be parsed, where parsing resumes
+func (p *parser) nextEntry(from int) int {
+	for i := from; i < len(p.src); i++ {
+		if p.src[i] != '\n' {
+			continue
+		}
+		j := i + 1
+		for j < len(p.src) && (p.src[j] == ' ' || p.src[j] == '\t') {
+			j++
+		}
+		if j < len(p.src) && p.src[j] == '@' {
+			return i + 1
+		}
+	}
+	return len(p.src)
+}
+
+func (p *parser) line(offset int) int {
+	if offset < p.lineOffset {
+		p.lineOffset, p.lineNumber = 0, 0
+	}
+	p.lineNumber += strings.Count(p.src[p.lineOffset:offset], "\n")
+	p.lineOffset = offset
+	return p.lineNumber + 1
+}
+
+// isIdentByte matches the characters of entry types, field names and macro
+// names. BibTeX allows more, but these cover real databases.
+func isIdentByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == ':' || c == '.' || c == '+' || c == '/' || c >= 0x80
+}
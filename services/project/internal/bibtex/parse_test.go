@@ -0,0 +1,156 @@
+package bibtex
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		keys    []string
+		raw     []string
+		errors  []string
+		strings map[string]string
+	}{
+		{
+			name:    "entries and free text",
+			content: "Intro text\n@Article{knuth84,\n  Title = {Literate {P}rogramming},\n  year = 1984,\n}\n@book(lamport94, title = \"LaTeX\")",
+			keys:    []string{"knuth84", "lamport94"},
+			raw:     []string{"Intro text"},
+		},
+		{
+			name:    "string macros",
+			content: "@String{ACM = \"ACM\"}\n@string(pub = acm # { Press})\n@misc{key, publisher = pub}",
+			keys:    []string{"key"},
+			raw:     []string{"@String{ACM = \"ACM\"}", "@string(pub = acm # { Press})"},
+			strings: map[string]string{"acm": `"ACM"`, "pub": "acm # { Press}"},
+		},
+		{
+			name:    "nested braces in a comment",
+			content: "@comment{a {nested @misc{hidden,}} block}\n@misc{shown,}",
+			keys:    []string{"shown"},
+			raw:     []string{"@comment{a {nested @misc{hidden,}} block}"},
+		},
+		{
+			name:    "string inside a comment is not defined",
+			content: "@comment{@string{x = {y}}}",
+			raw:     []string{"@comment{@string{x = {y}}}"},
+		},
+		{
+			name:    "email address",
+			content: "Contact me@example.org\n@misc{key,}",
+			keys:    []string{"key"},
+			raw:     []string{"Contact me@example.org"},
+		},
+		{
+			name:    "unterminated braces recover at the next entry",
+			content: "@article{broken,\n  title = {Open\n}\n@misc{after,}",
+			keys:    []string{"after"},
+			raw:     []string{"@article{broken,\n  title = {Open\n}"},
+			errors:  []string{"line 1: expected , or } in entry broken"},
+		},
+		{
+			name:    "unterminated value at end of file",
+			content: "@misc{key, title = {Open",
+			raw:     []string{"@misc{key, title = {Open"},
+			errors:  []string{"line 1: unbalanced braces in field title of entry key"},
+		},
+		{
+			name:    "unterminated quoted string",
+			content: "@misc{key, title = \"Open}",
+			raw:     []string{"@misc{key, title = \"Open}"},
+			errors:  []string{"line 1: unterminated quoted string in field title of entry key"},
+		},
+		{
+			name:    "unterminated comment recovers at the next entry",
+			content: "@comment{open\n@misc{after,}",
+			keys:    []string{"after"},
+			raw:     []string{"@comment{open"},
+			errors:  []string{"line 1: unbalanced braces"},
+		},
+		{
+			name:    "malformed string",
+			content: "@string{= {x}}\n@string{name {x}}\n@misc{key,}",
+			keys:    []string{"key"},
+			raw:     []string{"@string{= {x}}", "@string{name {x}}"},
+			errors:  []string{"line 1: missing @string name", "line 2: expected = after @string name"},
+		},
+		{
+			name:    "missing key",
+			content: "@misc{,\n title = {x}}",
+			raw:     []string{"@misc{,\n title = {x}}"},
+			errors:  []string{"line 1: missing entry key"},
+		},
+		{
+			name:    "missing field value",
+			content: "@misc{key, title = }",
+			raw:     []string{"@misc{key, title = }"},
+			errors:  []string{"line 1: expected a value in field title of entry key"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := Parse(tt.content)
+
+			var keys, raw, errors []string
+			for _, entry := range db.Entries {
+				keys = append(keys, entry.Key)
+			}
+			for _, block := range db.Blocks {
+				if block.Entry == nil {
+					raw = append(raw, block.Raw)
+				}
+			}
+			for _, err := range db.Errors {
+				errors = append(errors, err.Error())
+			}
+			wantStrings := tt.strings
+			if wantStrings == nil {
+				wantStrings = map[string]string{}
+			}
+
+			if !reflect.DeepEqual(keys, tt.keys) {
+				t.Errorf("keys = %q, want %q", keys, tt.keys)
+			}
+			if !reflect.DeepEqual(raw, tt.raw) {
+				t.Errorf("raw blocks = %q, want %q", raw, tt.raw)
+			}
+			if !reflect.DeepEqual(errors, tt.errors) {
+				t.Errorf("errors = %q, want %q", errors, tt.errors)
+			}
+			if !reflect.DeepEqual(db.Strings, wantStrings) {
+				t.Errorf("strings = %q, want %q", db.Strings, wantStrings)
+			}
+		})
+	}
+}
+
+func TestParseEntry(t *testing.T) {
+	db := Parse("\n\n@InProceedings{ key:2020,\n  Author = {Doe, Jane} # \" and \" # {Roe, R.},\n  MONTH = jan,\n  pages = \"1--2\",\n}")
+	if len(db.Entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(db.Entries))
+	}
+
+	want := &Entry{
+		Type: "inproceedings",
+		Key:  "key:2020",
+		Fields: []Field{
+			{Name: "author", Value: `{Doe, Jane} # " and " # {Roe, R.}`},
+			{Name: "month", Value: "jan"},
+			{Name: "pages", Value: `"1--2"`},
+		},
+		Line: 3,
+	}
+	if !reflect.DeepEqual(db.Entries[0], want) {
+		t.Errorf("entry = %+v, want %+v", db.Entries[0], want)
+	}
+	if got := db.Text(db.Entries[0], "author"); got != "Doe, Jane and Roe, R." {
+		t.Errorf("author text = %q", got)
+	}
+	if got := db.Text(db.Entries[0], "month"); got != "January" {
+		t.Errorf("month text = %q", got)
+	}
+}
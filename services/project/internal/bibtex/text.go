@@ -0,0 +1,236 @@
+package bibtex
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Month macros predefined by the standard styles
+var monthNames = map[string]string{
+	"jan": "January", "feb": "February", "mar": "March", "apr": "April",
+	"may": "May", "jun": "June", "jul": "July", "aug": "August",
+	"sep": "September", "oct": "October", "nov": "November", "dec": "December",
+}
+
+// Fields whose values are not LaTeX and must not be escaped
+var verbatimFields = map[string]bool{
+	"url": true, "doi": true, "eprint": true, "file": true, "pdf": true,
+	"urldate": true, "verba": true, "verbb": true, "verbc": true,
+}
+
+// valueText converts a value as written to plain text
+func valueText(value string, macros map[string]string) string {
+	return collapseSpace(stripBraces(expandValue(value, macros, 0)))
+}
+
+// expandValue joins the parts of a value, removing their delimiters and
+// expanding macros but keeping inner braces. Depth guards against macros
+// that refer to themselves.
+func expandValue(value string, macros map[string]string, depth int) string {
+	var b strings.Builder
+	for _, part := range splitConcat(value) {
+		switch {
+		case part == "":
+		case part[0] == '{' || part[0] == '"':
+			b.WriteString(part[1 : len(part)-1])
+		default:
+			name := strings.ToLower(part)
+			if def, ok := macros[name]; ok && depth < 8 {
+				b.WriteString(expandValue(def, macros, depth+1))
+			} else if month, ok := monthNames[name]; ok {
+				b.WriteString(month)
+			} else {
+				b.WriteString(part)
+			}
+		}
+	}
+	return b.String()
+}
+
+// splitConcat splits a value at the # outside braces and quotes
+func splitConcat(value string) []string {
+	var parts []string
+	depth, quoted, start := 0, false, 0
+	for i := 0; i < len(value); i++ {
+		switch value[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case '"':
+			if depth == 0 {
+				quoted = !quoted
+			}
+		case '#':
+			if depth == 0 && !quoted {
+				parts = append(parts, strings.TrimSpace(value[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, strings.TrimSpace(value[start:]))
+}
+
+// stripBraces removes the braces that protect text from case changes
+func stripBraces(text string) string {
+	return strings.NewReplacer("{", "", "}", "").Replace(text)
+}
+
+func collapseSpace(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// escapeValue prepares plain text for a braced value. LaTeX special
+// characters are escaped except in verbatim fields, and unbalanced braces
+// are dropped since BibTeX cannot read them.
+func escapeValue(name, text string) string {
+	text = collapseSpace(text)
+	if !verbatimFields[name] {
+		var b strings.Builder
+		var prev rune
+		for _, r := range text {
+			if strings.ContainsRune("&%$#_", r) && prev != '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+			prev = r
+		}
+		text = b.String()
+	}
+	return balanceBraces(text)
+}
+
+func balanceBraces(text string) string {
+	depth := 0
+	var b strings.Builder
+	for _, r := range text {
+		switch r {
+		case '{':
+			depth++
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+		}
+		b.WriteRune(r)
+	}
+	out := b.String()
+	// Drops the opening braces that were never closed, last first
+	for ; depth > 0; depth-- {
+		i := strings.LastIndexByte(out, '{')
+		out = out[:i] + out[i+1:]
+	}
+	return out
+}
+
+// Fold reduces text to lowercase ASCII letters, digits and spaces for
+// matching and generating keys: accents, LaTeX accent commands and
+// punctuation are dropped.
+func Fold(text string) string {
+	var b strings.Builder
+	space := false
+	for i := 0; i < len(text); i++ {
+		// Drops the backslash and symbol of accent commands like \" and \'
+		if text[i] == '\\' && i+1 < len(text) && !isLetter(text[i+1]) {
+			i++
+			continue
+		}
+		if text[i] == '\\' {
+			continue
+		}
+		b.WriteByte(text[i])
+	}
+
+	var out strings.Builder
+	for _, r := range norm.NFD.String(b.String()) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if space && out.Len() > 0 {
+				out.WriteByte(' ')
+			}
+			space = false
+			out.WriteRune(unicode.ToLower(r))
+		case r == 'ß':
+			out.WriteString("ss")
+		case unicode.IsSpace(r) || r == '-' || r == '~':
+			space = true
+		}
+	}
+	return out.String()
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// splitNames splits a name list at the "and" outside braces
+func splitNames(names string) []string {
+	var list []string
+	depth, start := 0, 0
+	for i := 0; i < len(names); i++ {
+		switch names[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case ' ', '\t', '\n':
+			if depth == 0 && i+5 <= len(names) && strings.EqualFold(names[i:i+5], " and ") {
+				list = append(list, strings.TrimSpace(names[start:i]))
+				start = i + 5
+				i += 3
+			}
+		}
+	}
+	if name := strings.TrimSpace(names[start:]); name != "" {
+		list = append(list, name)
+	}
+	return list
+}
+
+// lastName returns the family name of a name written "Last, First" or
+// "First Last", keeping a braced corporate name whole
+func lastName(name string) string {
+	if comma := topLevelIndex(name, ','); comma >= 0 {
+		return strings.TrimSpace(name[:comma])
+	}
+	if strings.HasPrefix(name, "{") && strings.HasSuffix(name, "}") {
+		return name
+	}
+
+	words := strings.Fields(name)
+	if len(words) == 0 {
+		return ""
+	}
+	// Lowercase particles such as "van der" belong to the family name
+	i := len(words) - 1
+	for i > 0 && isParticle(words[i-1]) {
+		i--
+	}
+	return strings.Join(words[i:], " ")
+}
+
+func isParticle(word string) bool {
+	return word != "" && unicode.IsLower(rune(word[0]))
+}
+
+// topLevelIndex finds a byte outside braces
+func topLevelIndex(text string, c byte) int {
+	depth := 0
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		case c:
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
@@ -3,39 +3,99 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
-	Environment      string
-	Port             string
-	MongoURI         string
-	MongoDatabase    string
-	RedisAddr        string
-	RedisPassword    string
-	MinIOEndpoint    string
-	MinIOAccessKey   string
-	MinIOSecretKey   string
-	MinIOBucket      string
-	MinIOUseSSL      bool
-	JWTSecret        string
-	LogLevel         string
+	Environment       string
+	Port              string
+	MongoURI          string
+	MongoDatabase     string
+	RedisAddr         string
+	RedisPassword     string
+	MinIOEndpoint     string
+	MinIOAccessKey    string
+	MinIOSecretKey    string
+	MinIOBucket       string
+	MinIOUseSSL       bool
+	JWTSecret         string
+	JWTPrivateKeyPath string
+	JWTPublicKeyPath  string
+	LogLevel          string
+
+	// File version retention
+	VersionKeepLatest int
+	VersionMaxCount   int
+	VersionMaxAgeDays int
+
+	// Days a deleted project stays in the trash before it is purged
+	TrashRetentionDays int
+
+	// Hours a share link grant lasts before the link has to be opened again
+	ShareGrantTTLHours int
+
+	// Largest file accepted by uploads
+	MaxUploadSizeMB int
+
+	// Default storage quotas; 0 means unlimited
+	UserQuotaStorageMB     int
+	UserQuotaMaxFiles      int
+	UserQuotaMaxFileSizeMB int
+	OrgQuotaStorageMB      int
+	OrgQuotaMaxFiles       int
+	OrgQuotaMaxFileSizeMB  int
+
+	// Users allowed to use the admin API
+	AdminUserIDs []string
+
+	// Hours between scheduled storage checks (0 disables them) and whether
+	// they repair what they find rather than only report it
+	FsckIntervalHours int
+	FsckRepair        bool
+
+	// Whether pending data migrations run when the service starts
+	RunMigrations bool
 }
 
 func Load() (*Config, error) {
 	return &Config{
-		Environment:      getEnv("ENVIRONMENT", "development"),
-		Port:             getEnv("PROJECT_SERVICE_PORT", "8081"),
-		MongoURI:         getEnv("MONGO_URI", "mongodb://localhost:27017"),
-		MongoDatabase:    getEnv("MONGO_DATABASE", "texflow"),
-		RedisAddr:        getEnv("REDIS_ADDR", "localhost:6379"),
-		RedisPassword:    getEnv("REDIS_PASSWORD", ""),
-		MinIOEndpoint:    getEnv("MINIO_ENDPOINT", "localhost:9000"),
-		MinIOAccessKey:   getEnv("MINIO_ACCESS_KEY", "minioadmin"),
-		MinIOSecretKey:   getEnv("MINIO_SECRET_KEY", "minioadmin"),
-		MinIOBucket:      getEnv("MINIO_BUCKET", "texflow"),
-		MinIOUseSSL:      getEnvAsBool("MINIO_USE_SSL", false),
-		JWTSecret:        getEnv("JWT_SECRET", "secret"),
-		LogLevel:         getEnv("LOG_LEVEL", "info"),
+		Environment:       getEnv("ENVIRONMENT", "development"),
+		Port:              getEnv("PROJECT_SERVICE_PORT", "8081"),
+		MongoURI:          getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		MongoDatabase:     getEnv("MONGO_DATABASE", "texflow"),
+		RedisAddr:         getEnv("REDIS_ADDR", "localhost:6379"),
+		RedisPassword:     getEnv("REDIS_PASSWORD", ""),
+		MinIOEndpoint:     getEnv("MINIO_ENDPOINT", "localhost:9000"),
+		MinIOAccessKey:    getEnv("MINIO_ACCESS_KEY", "minioadmin"),
+		MinIOSecretKey:    getEnv("MINIO_SECRET_KEY", "minioadmin"),
+		MinIOBucket:       getEnv("MINIO_BUCKET", "texflow"),
+		MinIOUseSSL:       getEnvAsBool("MINIO_USE_SSL", false),
+		JWTSecret:         getEnv("JWT_SECRET", "secret"),
+		JWTPrivateKeyPath: getEnv("JWT_PRIVATE_KEY_PATH", "./keys/jwt-private.pem"),
+		JWTPublicKeyPath:  getEnv("JWT_PUBLIC_KEY_PATH", "./keys/jwt-public.pem"),
+		LogLevel:          getEnv("LOG_LEVEL", "info"),
+
+		VersionKeepLatest: getEnvAsInt("FILE_VERSION_KEEP_LATEST", 20),
+		VersionMaxCount:   getEnvAsInt("FILE_VERSION_MAX_COUNT", 200),
+		VersionMaxAgeDays: getEnvAsInt("FILE_VERSION_MAX_AGE_DAYS", 90),
+
+		TrashRetentionDays: getEnvAsInt("PROJECT_TRASH_RETENTION_DAYS", 30),
+		ShareGrantTTLHours: getEnvAsInt("SHARE_GRANT_TTL_HOURS", 168),
+		MaxUploadSizeMB:    getEnvAsInt("MAX_UPLOAD_SIZE_MB", 100),
+
+		UserQuotaStorageMB:     getEnvAsInt("USER_QUOTA_STORAGE_MB", 2048),
+		UserQuotaMaxFiles:      getEnvAsInt("USER_QUOTA_MAX_FILES", 20000),
+		UserQuotaMaxFileSizeMB: getEnvAsInt("USER_QUOTA_MAX_FILE_SIZE_MB", 100),
+		OrgQuotaStorageMB:      getEnvAsInt("ORG_QUOTA_STORAGE_MB", 0),
+		OrgQuotaMaxFiles:       getEnvAsInt("ORG_QUOTA_MAX_FILES", 0),
+		OrgQuotaMaxFileSizeMB:  getEnvAsInt("ORG_QUOTA_MAX_FILE_SIZE_MB", 0),
+
+		AdminUserIDs: getEnvAsList("ADMIN_USER_IDS"),
+
+		FsckIntervalHours: getEnvAsInt("FSCK_INTERVAL_HOURS", 24),
+		FsckRepair:        getEnvAsBool("FSCK_REPAIR", false),
+
+		RunMigrations: getEnvAsBool("RUN_MIGRATIONS", true),
 	}, nil
 }
 
@@ -57,3 +117,25 @@ func getEnvAsBool(key string, fallback bool) bool {
 	}
 	return val
 }
+
+func getEnvAsInt(key string, fallback int) int {
+	valStr := getEnv(key, "")
+	if valStr == "" {
+		return fallback
+	}
+	val, err := strconv.Atoi(valStr)
+	if err != nil {
+		return fallback
+	}
+	return val
+}
+
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
@@ -0,0 +1,81 @@
+// Package events publishes project events to the websocket service, which
+// relays the messages of the Redis channel "room:<project ID>" to every client
+// connected to that project.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// Message types, matching those of the websocket service
+const (
+	CommentThreadCreated = "comment_thread_created"
+	CommentThreadUpdated = "comment_thread_updated"
+	CommentThreadDeleted = "comment_thread_deleted"
+	CommentMention       = "comment_mention"
+	LintResults          = "lint_results"
+)
+
+// message is the envelope the websocket service sends to its clients
+type message struct {
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+	Timestamp time.Time       `json:"timestamp"`
+	UserID    string          `json:"user_id,omitempty"`
+	Username  string          `json:"username,omitempty"`
+}
+
+// Publisher sends events to the clients connected to a project. Events are
+// best effort: a failure is logged and never fails the request that caused
+// it.
+type Publisher struct {
+	redisClient *redis.Client
+	logger      *zap.Logger
+}
+
+// NewPublisher creates a publisher. A nil client disables publishing.
+func NewPublisher(redisClient *redis.Client, logger *zap.Logger) *Publisher {
+	return &Publisher{
+		redisClient: redisClient,
+		logger:      logger,
+	}
+}
+
+// Publish sends an event to the room of a project on behalf of a user
+func (p *Publisher) Publish(ctx context.Context, projectID, msgType string, payload interface{}, userID, username string) {
+	if p == nil || p.redisClient == nil {
+		return
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		p.logger.Error("Failed to marshal event payload", zap.String("type", msgType), zap.Error(err))
+		return
+	}
+	data, err := json.Marshal(message{
+		Type:      msgType,
+		Payload:   payloadBytes,
+		Timestamp: time.Now(),
+		UserID:    userID,
+		Username:  username,
+	})
+	if err != nil {
+		p.logger.Error("Failed to marshal event", zap.String("type", msgType), zap.Error(err))
+		return
+	}
+
+	channel := fmt.Sprintf("room:%s", projectID)
+	if err := p.redisClient.Publish(ctx, channel, data).Err(); err != nil {
+		p.logger.Error("Failed to publish event",
+			zap.String("channel", channel),
+			zap.String("type", msgType),
+			zap.Error(err),
+		)
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// ImportProject creates a project from an uploaded zip archive (form field "file")
+func (h *ProjectHandler) ImportProject(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxImportArchiveSize+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A zip archive is required in the \"file\" field"})
+		return
+	}
+	if header.Size > service.MaxImportArchiveSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Archive too large"})
+		return
+	}
+
+	name := strings.TrimSpace(c.PostForm("name"))
+	if name == "" {
+		name = strings.TrimSuffix(filepath.Base(header.Filename), filepath.Ext(header.Filename))
+	}
+	if name == "" || len(name) > 100 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project name"})
+		return
+	}
+
+	archive, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read archive"})
+		return
+	}
+	defer archive.Close()
+
+	project, err := h.projectService.ImportProject(c.Request.Context(), userID, name, archive, header.Size)
+	if err != nil {
+		if respondQuotaError(c, err) {
+			return
+		}
+		msg := err.Error()
+		switch {
+		case msg == "invalid zip archive",
+			msg == "archive contains no LaTeX files",
+			msg == "archive too large when extracted",
+			strings.HasPrefix(msg, "archive has too many entries"),
+			strings.HasPrefix(msg, "invalid file path in archive"),
+			strings.HasPrefix(msg, "file too large in archive"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		default:
+			h.logger.Error("Failed to import project", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import project"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// ExportProject streams the project tree as a zip archive
+func (h *ProjectHandler) ExportProject(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	project, files, folders, err := h.projectService.GetExportTree(c.Request.Context(), projectID, userID)
+	if err != nil {
+		if err.Error() == "project not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		if err.Error() == "access denied" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+			return
+		}
+		h.logger.Error("Failed to export project", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export project"})
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.zip"`, exportFileName(project.Name, projectID)))
+	c.Status(http.StatusOK)
+
+	// Headers are already sent, so a failure can only abort the stream
+	if err := h.projectService.WriteProjectZip(c.Request.Context(), c.Writer, files, folders); err != nil {
+		h.logger.Error("Failed to stream project export",
+			zap.String("project_id", projectID.Hex()),
+			zap.Error(err),
+		)
+		c.Abort()
+	}
+}
+
+// exportFileName derives a safe archive name from the project name
+func exportFileName(name string, projectID primitive.ObjectID) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		case r == ' ':
+			return '_'
+		default:
+			return -1
+		}
+	}, name)
+	if safe == "" {
+		return projectID.Hex()
+	}
+	return safe
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// ListBibliography lists the entries of a project's .bib files. "q"
+// searches their keys and fields.
+func (h *ProjectHandler) ListBibliography(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	entries, err := h.projectService.ListBibliography(c.Request.Context(), projectID, userID, c.Query("q"))
+	if err != nil {
+		h.respondBibliographyError(c, err, "Failed to list bibliography")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": entries})
+}
+
+// CheckBibliography reports syntax errors, duplicate keys and missing
+// required fields in a project's .bib files
+func (h *ProjectHandler) CheckBibliography(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	issues, err := h.projectService.CheckBibliography(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.respondBibliographyError(c, err, "Failed to check bibliography")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": issues})
+}
+
+// NormalizeBibliography rewrites a .bib file in a consistent format
+func (h *ProjectHandler) NormalizeBibliography(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	fileID, err := primitive.ObjectIDFromHex(c.Param("fileId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	var req models.NormalizeBibliographyRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	response, err := h.projectService.NormalizeBibliography(c.Request.Context(), projectID, fileID, userID, &req)
+	if err != nil {
+		h.respondBibliographyError(c, err, "Failed to normalize bibliography")
+		return
+	}
+
+	c.Header("ETag", response.File.ETag())
+	if response.Suggestions != nil {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// ImportBibliography adds pasted BibTeX, RIS or CSL-JSON references to a
+// .bib file of the project
+func (h *ProjectHandler) ImportBibliography(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ImportBibliographyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response, err := h.projectService.ImportBibliography(c.Request.Context(), projectID, userID, &req)
+	if err != nil {
+		h.respondBibliographyError(c, err, "Failed to import references")
+		return
+	}
+
+	if response.Suggestions != nil {
+		c.JSON(http.StatusAccepted, response)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// respondBibliographyError maps bibliography errors to HTTP responses
+func (h *ProjectHandler) respondBibliographyError(c *gin.Context, err error, message string) {
+	if respondQuotaError(c, err) {
+		return
+	}
+	if strings.HasPrefix(err.Error(), "invalid references: ") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	switch err.Error() {
+	case "project not found", "file not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "access denied", "permission denied":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "project is archived", "project is in trash", "file was modified during the update",
+		"file already exists at this path", "folder already exists at this path":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "invalid file ID", "not a bibliography file", "file_id is required when the project has several bibliography files":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// GetSharedProjects lists the projects the user has accepted invitations to
+func (h *ProjectHandler) GetSharedProjects(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 10
+	}
+
+	status := c.DefaultQuery("status", models.ProjectStatusActive)
+	if status != models.ProjectStatusActive && status != models.ProjectStatusArchived {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active or archived"})
+		return
+	}
+
+	projects, total, err := h.projectService.ListSharedProjects(c.Request.Context(), userID, status, page, limit)
+	if err != nil {
+		h.logger.Error("Failed to list shared projects", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list shared projects"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":   projects,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+		"status": status,
+	})
+}
+
+func (h *ProjectHandler) InviteCollaborator(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var req models.InviteCollaboratorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitation, err := h.projectService.InviteCollaborator(c.Request.Context(), projectID, userID, req.Email, req.Role)
+	if err != nil {
+		h.respondCollaboratorError(c, err, "Failed to invite collaborator")
+		return
+	}
+
+	c.JSON(http.StatusCreated, invitation)
+}
+
+// ListProjectInvitations lists a project's invitations, optionally filtered
+// by the "status" query parameter
+func (h *ProjectHandler) ListProjectInvitations(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.InvitationStatusPending, models.InvitationStatusAccepted, models.InvitationStatusDeclined:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be pending, accepted or declined"})
+		return
+	}
+
+	invitations, err := h.projectService.ListProjectInvitations(c.Request.Context(), projectID, userID, status)
+	if err != nil {
+		h.respondCollaboratorError(c, err, "Failed to list invitations")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": invitations})
+}
+
+func (h *ProjectHandler) RevokeInvitation(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	invitationID, err := primitive.ObjectIDFromHex(c.Param("invitationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	if err := h.projectService.RevokeInvitation(c.Request.Context(), projectID, invitationID, userID); err != nil {
+		h.respondCollaboratorError(c, err, "Failed to revoke invitation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked"})
+}
+
+// ListMyInvitations lists the pending invitations addressed to the user
+func (h *ProjectHandler) ListMyInvitations(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	invitations, err := h.projectService.ListUserInvitations(c.Request.Context(), userID)
+	if err != nil {
+		h.respondCollaboratorError(c, err, "Failed to list invitations")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": invitations})
+}
+
+func (h *ProjectHandler) AcceptInvitation(c *gin.Context) {
+	userID, invitationID, ok := getUserAndInvitationID(c)
+	if !ok {
+		return
+	}
+
+	project, err := h.projectService.AcceptInvitation(c.Request.Context(), invitationID, userID)
+	if err != nil {
+		h.respondCollaboratorError(c, err, "Failed to accept invitation")
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+func (h *ProjectHandler) DeclineInvitation(c *gin.Context) {
+	userID, invitationID, ok := getUserAndInvitationID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.projectService.DeclineInvitation(c.Request.Context(), invitationID, userID); err != nil {
+		h.respondCollaboratorError(c, err, "Failed to decline invitation")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation declined"})
+}
+
+func (h *ProjectHandler) ListCollaborators(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	collaborators, err := h.projectService.ListCollaborators(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.respondCollaboratorError(c, err, "Failed to list collaborators")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": collaborators})
+}
+
+func (h *ProjectHandler) UpdateCollaborator(c *gin.Context) {
+	userID, projectID, collaboratorID, ok := getUserProjectAndCollaboratorID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateCollaboratorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.projectService.UpdateCollaboratorRole(c.Request.Context(), projectID, userID, collaboratorID, req.Role); err != nil {
+		h.respondCollaboratorError(c, err, "Failed to update collaborator")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collaborator updated"})
+}
+
+func (h *ProjectHandler) RemoveCollaborator(c *gin.Context) {
+	userID, projectID, collaboratorID, ok := getUserProjectAndCollaboratorID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.projectService.RemoveCollaborator(c.Request.Context(), projectID, userID, collaboratorID); err != nil {
+		h.respondCollaboratorError(c, err, "Failed to remove collaborator")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Collaborator removed"})
+}
+
+func (h *ProjectHandler) LeaveProject(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.projectService.LeaveProject(c.Request.Context(), projectID, userID); err != nil {
+		h.respondCollaboratorError(c, err, "Failed to leave project")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left project"})
+}
+
+// respondCollaboratorError maps sharing and invitation errors to HTTP
+// responses
+func (h *ProjectHandler) respondCollaboratorError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "project not found", "user not found", "invitation not found", "collaborator not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "access denied", "only project owner can share project",
+		"only project owner can manage collaborators":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "cannot invite the project owner", "project owner cannot leave project":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "project already shared with this user", "invitation already pending for this email",
+		"invitation is no longer pending", "project is in trash":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// getUserAndInvitationID extracts the user and invitation IDs, writing the
+// error response when either is missing or invalid
+func getUserAndInvitationID(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	invitationID, err := primitive.ObjectIDFromHex(c.Param("invitationId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return userID, invitationID, true
+}
+
+func getUserProjectAndCollaboratorID(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return primitive.NilObjectID, primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	collaboratorID, err := primitive.ObjectIDFromHex(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return primitive.NilObjectID, primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return userID, projectID, collaboratorID, true
+}
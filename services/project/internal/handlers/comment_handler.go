@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// ListCommentThreads lists the comment threads of a project. "file_id"
+// limits them to one file and "status" to open or resolved threads.
+func (h *ProjectHandler) ListCommentThreads(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var fileID *primitive.ObjectID
+	if hex := c.Query("file_id"); hex != "" {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+			return
+		}
+		fileID = &id
+	}
+
+	threads, err := h.projectService.ListCommentThreads(c.Request.Context(), projectID, userID, fileID, c.Query("status"))
+	if err != nil {
+		h.respondCommentError(c, err, "Failed to list comments")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": threads})
+}
+
+func (h *ProjectHandler) CreateCommentThread(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateCommentThreadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	thread, err := h.projectService.CreateCommentThread(c.Request.Context(), projectID, userID, &req)
+	if err != nil {
+		h.respondCommentError(c, err, "Failed to create comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, thread)
+}
+
+func (h *ProjectHandler) GetCommentThread(c *gin.Context) {
+	userID, projectID, threadID, ok := getCommentThreadID(c)
+	if !ok {
+		return
+	}
+
+	thread, err := h.projectService.GetCommentThread(c.Request.Context(), projectID, threadID, userID)
+	if err != nil {
+		h.respondCommentError(c, err, "Failed to get comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+func (h *ProjectHandler) DeleteCommentThread(c *gin.Context) {
+	userID, projectID, threadID, ok := getCommentThreadID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.projectService.DeleteCommentThread(c.Request.Context(), projectID, threadID, userID); err != nil {
+		h.respondCommentError(c, err, "Failed to delete comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Comment thread deleted"})
+}
+
+func (h *ProjectHandler) ReplyToCommentThread(c *gin.Context) {
+	userID, projectID, threadID, ok := getCommentThreadID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	thread, err := h.projectService.ReplyToCommentThread(c.Request.Context(), projectID, threadID, userID, &req)
+	if err != nil {
+		h.respondCommentError(c, err, "Failed to reply to comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, thread)
+}
+
+func (h *ProjectHandler) EditComment(c *gin.Context) {
+	userID, projectID, threadID, ok := getCommentThreadID(c)
+	if !ok {
+		return
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(c.Param("commentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	var req models.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	thread, err := h.projectService.EditComment(c.Request.Context(), projectID, threadID, commentID, userID, &req)
+	if err != nil {
+		h.respondCommentError(c, err, "Failed to edit comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+func (h *ProjectHandler) DeleteComment(c *gin.Context) {
+	userID, projectID, threadID, ok := getCommentThreadID(c)
+	if !ok {
+		return
+	}
+
+	commentID, err := primitive.ObjectIDFromHex(c.Param("commentId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment ID"})
+		return
+	}
+
+	thread, err := h.projectService.DeleteComment(c.Request.Context(), projectID, threadID, commentID, userID)
+	if err != nil {
+		h.respondCommentError(c, err, "Failed to delete comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+func (h *ProjectHandler) ResolveCommentThread(c *gin.Context) {
+	userID, projectID, threadID, ok := getCommentThreadID(c)
+	if !ok {
+		return
+	}
+
+	thread, err := h.projectService.ResolveCommentThread(c.Request.Context(), projectID, threadID, userID)
+	if err != nil {
+		h.respondCommentError(c, err, "Failed to resolve comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+func (h *ProjectHandler) ReopenCommentThread(c *gin.Context) {
+	userID, projectID, threadID, ok := getCommentThreadID(c)
+	if !ok {
+		return
+	}
+
+	thread, err := h.projectService.ReopenCommentThread(c.Request.Context(), projectID, threadID, userID)
+	if err != nil {
+		h.respondCommentError(c, err, "Failed to reopen comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, thread)
+}
+
+// ListNotifications lists the latest notifications of the user, or with
+// "unread=true" only the unread ones
+func (h *ProjectHandler) ListNotifications(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	unreadOnly, _ := strconv.ParseBool(c.Query("unread"))
+
+	notifications, unread, err := h.projectService.ListNotifications(c.Request.Context(), userID, unreadOnly)
+	if err != nil {
+		h.logger.Error("Failed to list notifications", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": notifications, "unread": unread})
+}
+
+// MarkNotificationsRead marks the given notifications of the user as read,
+// or all of them when the body lists none
+func (h *ProjectHandler) MarkNotificationsRead(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.MarkNotificationsReadRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	marked, err := h.projectService.MarkNotificationsRead(c.Request.Context(), userID, &req)
+	if err != nil {
+		if err.Error() == "invalid notification ID" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to mark notifications as read", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark notifications as read"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"marked": marked})
+}
+
+func getCommentThreadID(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return primitive.NilObjectID, primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	threadID, err := primitive.ObjectIDFromHex(c.Param("threadId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid comment thread ID"})
+		return primitive.NilObjectID, primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return userID, projectID, threadID, true
+}
+
+// respondCommentError maps comment errors to HTTP responses
+func (h *ProjectHandler) respondCommentError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "project not found", "file not found", "comment thread not found", "comment not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "access denied", "permission denied":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "comment thread is resolved", "comment thread is not resolved", "project is archived", "project is in trash":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "invalid file ID", "invalid comment anchor", "invalid comment status",
+		"cannot comment on a binary file", "cannot delete the first comment of a thread":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+)
+
+// GetDependencyGraph returns the file dependency graph of a project as JSON,
+// or in Graphviz format with "format=dot"
+func (h *ProjectHandler) GetDependencyGraph(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	graph, err := h.projectService.GetDependencyGraph(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to build dependency graph")
+		return
+	}
+
+	switch c.DefaultQuery("format", "json") {
+	case "json":
+		c.JSON(http.StatusOK, graph)
+	case "dot":
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(graph.DOT()))
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or dot"})
+	}
+}
+
+// MoveUnusedFiles moves the files the main file never reaches into the
+// unused folder
+func (h *ProjectHandler) MoveUnusedFiles(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var req models.MoveUnusedFilesRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	response, err := h.projectService.MoveUnusedFiles(c.Request.Context(), projectID, userID, &req)
+	if err != nil {
+		if err.Error() == "main file not found" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		h.respondFileTreeError(c, err, "Failed to move unused files")
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+func (h *ProjectHandler) DeleteFile(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	fileID, err := primitive.ObjectIDFromHex(c.Param("fileId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	if err := h.projectService.DeleteFile(c.Request.Context(), projectID, fileID, userID); err != nil {
+		h.respondFileTreeError(c, err, "Failed to delete file")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "File deleted"})
+}
+
+func (h *ProjectHandler) RenameFile(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	fileID, err := primitive.ObjectIDFromHex(c.Param("fileId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	var req models.RenameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := h.projectService.RenameFile(c.Request.Context(), projectID, fileID, userID, req.Name)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to rename file")
+		return
+	}
+
+	c.JSON(http.StatusOK, file)
+}
+
+func (h *ProjectHandler) MoveFile(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	fileID, err := primitive.ObjectIDFromHex(c.Param("fileId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	var req models.MoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	file, err := h.projectService.MoveFile(c.Request.Context(), projectID, fileID, userID, req.Folder)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to move file")
+		return
+	}
+
+	c.JSON(http.StatusOK, file)
+}
+
+func (h *ProjectHandler) CreateFolder(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	folder, err := h.projectService.CreateFolder(c.Request.Context(), projectID, userID, &req)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to create folder")
+		return
+	}
+
+	c.JSON(http.StatusCreated, folder)
+}
+
+func (h *ProjectHandler) ListFolders(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	folders, err := h.projectService.ListFolders(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to list folders")
+		return
+	}
+
+	c.JSON(http.StatusOK, folders)
+}
+
+func (h *ProjectHandler) DeleteFolder(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	folderID, err := primitive.ObjectIDFromHex(c.Param("folderId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	if err := h.projectService.DeleteFolder(c.Request.Context(), projectID, folderID, userID); err != nil {
+		h.respondFileTreeError(c, err, "Failed to delete folder")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Folder deleted"})
+}
+
+func (h *ProjectHandler) RenameFolder(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	folderID, err := primitive.ObjectIDFromHex(c.Param("folderId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	var req models.RenameRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	folder, err := h.projectService.RenameFolder(c.Request.Context(), projectID, folderID, userID, req.Name)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to rename folder")
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+func (h *ProjectHandler) MoveFolder(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	folderID, err := primitive.ObjectIDFromHex(c.Param("folderId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid folder ID"})
+		return
+	}
+
+	var req models.MoveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	folder, err := h.projectService.MoveFolder(c.Request.Context(), projectID, folderID, userID, req.Folder)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to move folder")
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+// respondFileTreeError maps file and folder operation errors to HTTP responses
+func (h *ProjectHandler) respondFileTreeError(c *gin.Context, err error, message string) {
+	if respondQuotaError(c, err) {
+		return
+	}
+
+	switch err.Error() {
+	case "project not found", "file not found", "folder not found", "version not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "permission denied", "access denied", "changes are tracked; only the project owner can make this change":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "file already exists at this path", "folder already exists at this path",
+		"project is archived", "project is in trash", "file has been modified":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "invalid name", "invalid file path", "invalid folder path",
+		"cannot delete the main file", "cannot move a folder into itself",
+		"cannot diff binary files":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// getUserAndProjectID extracts the user and project IDs, writing the error
+// response when either is missing or invalid
+func getUserAndProjectID(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	projectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return userID, projectID, true
+}
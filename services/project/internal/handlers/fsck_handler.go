@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// StartStorageCheck starts comparing object storage with the file records.
+// The check is a dry run unless the body asks for repairs; its report is
+// polled with GetStorageCheck.
+func (h *ProjectHandler) StartStorageCheck(c *gin.Context) {
+	adminID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.RunFsckRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	report, err := h.projectService.StartStorageCheck(c.Request.Context(), adminID, &req)
+	if err != nil {
+		switch err.Error() {
+		case "invalid project ID":
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case "project not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "storage check already running":
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to start storage check", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start storage check"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, report)
+}
+
+func (h *ProjectHandler) ListStorageChecks(c *gin.Context) {
+	reports, err := h.projectService.ListStorageChecks(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list storage checks", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list storage checks"})
+		return
+	}
+
+	if reports == nil {
+		reports = []*models.FsckReport{}
+	}
+	c.JSON(http.StatusOK, reports)
+}
+
+func (h *ProjectHandler) GetStorageCheck(c *gin.Context) {
+	checkID, err := primitive.ObjectIDFromHex(c.Param("checkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid storage check ID"})
+		return
+	}
+
+	report, err := h.projectService.GetStorageCheck(c.Request.Context(), checkID)
+	if err != nil {
+		if err.Error() == "storage check not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to get storage check", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get storage check"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
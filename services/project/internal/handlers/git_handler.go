@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// maxGitFetchRequestSize limits the want/have negotiation of a fetch
+const maxGitFetchRequestSize = 10 << 20
+
+// GitHandler serves projects over the Git smart HTTP protocol. Git clients
+// authenticate themselves instead of going through the gateway login.
+type GitHandler struct {
+	projectService *service.ProjectService
+	authenticator  *service.GitAuthenticator
+	logger         *zap.Logger
+}
+
+func NewGitHandler(projectService *service.ProjectService, authenticator *service.GitAuthenticator, logger *zap.Logger) *GitHandler {
+	return &GitHandler{
+		projectService: projectService,
+		authenticator:  authenticator,
+		logger:         logger,
+	}
+}
+
+func (h *GitHandler) InfoRefs(c *gin.Context) {
+	svc := c.Query("service")
+	if svc != service.GitUploadPack && svc != service.GitReceivePack {
+		c.String(http.StatusForbidden, "Only smart HTTP is supported")
+		return
+	}
+
+	userID, projectID, ok := h.authenticate(c)
+	if !ok {
+		return
+	}
+
+	refs, err := h.projectService.AdvertiseGitRefs(c.Request.Context(), projectID, userID, svc)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+
+	c.Header("Content-Type", fmt.Sprintf("application/x-%s-advertisement", svc))
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+	if err := service.WriteGitAdvertisement(c.Writer, svc, refs); err != nil {
+		h.logger.Error("Failed to write git advertisement", zap.Error(err))
+	}
+}
+
+func (h *GitHandler) UploadPack(c *gin.Context) {
+	_, projectID, body, ok := h.openGitRequest(c, service.GitUploadPack, maxGitFetchRequestSize)
+	if !ok {
+		return
+	}
+	defer body.Close()
+
+	c.Header("Content-Type", "application/x-git-upload-pack-result")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+
+	if err := h.projectService.UploadPack(c.Request.Context(), projectID, body, c.Writer); err != nil {
+		h.logger.Error("Failed to serve git fetch",
+			zap.String("project_id", projectID.Hex()),
+			zap.Error(err),
+		)
+	}
+}
+
+func (h *GitHandler) ReceivePack(c *gin.Context) {
+	userID, projectID, body, ok := h.openGitRequest(c, service.GitReceivePack, service.MaxGitPushSize)
+	if !ok {
+		return
+	}
+	defer body.Close()
+
+	c.Header("Content-Type", "application/x-git-receive-pack-result")
+	c.Header("Cache-Control", "no-cache")
+	c.Status(http.StatusOK)
+
+	if err := h.projectService.ReceivePack(c.Request.Context(), projectID, userID, body, c.Writer); err != nil {
+		h.logger.Error("Failed to apply git push",
+			zap.String("project_id", projectID.Hex()),
+			zap.String("user_id", userID.Hex()),
+			zap.Error(err),
+		)
+	}
+}
+
+// openGitRequest authenticates and authorizes a service request and returns
+// its body, inflating it when the client compressed it
+func (h *GitHandler) openGitRequest(c *gin.Context, svc string, limit int64) (primitive.ObjectID, primitive.ObjectID, io.ReadCloser, bool) {
+	userID, projectID, ok := h.authenticate(c)
+	if !ok {
+		return primitive.NilObjectID, primitive.NilObjectID, nil, false
+	}
+
+	if err := h.projectService.AuthorizeGit(c.Request.Context(), projectID, userID, svc); err != nil {
+		h.handleError(c, err)
+		return primitive.NilObjectID, primitive.NilObjectID, nil, false
+	}
+
+	body, err := requestBody(c.Writer, c.Request, limit)
+	if err != nil {
+		c.String(http.StatusBadRequest, "Invalid gzip body")
+		return primitive.NilObjectID, primitive.NilObjectID, nil, false
+	}
+
+	return userID, projectID, body, true
+}
+
+// requestBody returns the request body, inflated when gzip encoded. The limit
+// applies to the inflated stream as well, so that a small compressed body
+// cannot expand without bound.
+func requestBody(w http.ResponseWriter, r *http.Request, limit int64) (io.ReadCloser, error) {
+	body := http.MaxBytesReader(w, r.Body, limit)
+	if r.Header.Get("Content-Encoding") != "gzip" {
+		return body, nil
+	}
+
+	gz, err := gzip.NewReader(body)
+	if err != nil {
+		return nil, err
+	}
+
+	return http.MaxBytesReader(w, gz, limit), nil
+}
+
+// authenticate resolves the user from the password of HTTP basic auth or a
+// bearer token, asking the client for credentials when there are none
+func (h *GitHandler) authenticate(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	projectID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.String(http.StatusNotFound, "Repository not found")
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	_, secret, ok := c.Request.BasicAuth()
+	if !ok {
+		if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+			secret = strings.TrimPrefix(header, "Bearer ")
+		}
+	}
+	if secret == "" {
+		h.requestCredentials(c)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	userID, err := h.authenticator.Authenticate(c.Request.Context(), secret)
+	if err != nil {
+		h.requestCredentials(c)
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return userID, projectID, true
+}
+
+func (h *GitHandler) requestCredentials(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="TexFlow Git"`)
+	c.String(http.StatusUnauthorized, "Authentication required")
+}
+
+func (h *GitHandler) handleError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "project not found":
+		c.String(http.StatusNotFound, "Repository not found")
+	case "access denied", "permission denied":
+		c.String(http.StatusForbidden, "Access denied")
+	case "repository is busy":
+		c.String(http.StatusServiceUnavailable, "Repository is busy, try again")
+	default:
+		h.logger.Error("Git request failed", zap.Error(err))
+		c.String(http.StatusInternalServerError, "Internal error")
+	}
+}
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		t.Fatalf("gzip write failed: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip close failed: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRequestBody(t *testing.T) {
+	const limit = 4 << 10
+
+	// Highly compressible: well under the limit on the wire, far over it inflated
+	bomb := gzipped(t, make([]byte, 1<<20))
+	if len(bomb) >= limit {
+		t.Fatalf("compressed body is %d bytes, want it under the %d byte limit", len(bomb), limit)
+	}
+
+	tests := []struct {
+		name     string
+		body     []byte
+		encoding string
+		want     int
+		tooLarge bool
+	}{
+		{"plain", []byte("0000"), "", 4, false},
+		{"plain over the limit", make([]byte, limit+1), "", limit, true},
+		{"gzip", gzipped(t, []byte("0032want")), "gzip", 8, false},
+		{"gzip inflating over the limit", bomb, "gzip", limit, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/git-receive-pack", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				req.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			body, err := requestBody(httptest.NewRecorder(), req, limit)
+			if err != nil {
+				t.Fatalf("requestBody() error = %v", err)
+			}
+			defer body.Close()
+
+			data, err := io.ReadAll(body)
+			var maxBytesErr *http.MaxBytesError
+			if tooLarge := errors.As(err, &maxBytesErr); tooLarge != tt.tooLarge {
+				t.Errorf("ReadAll() error = %v, want limit exceeded %v", err, tt.tooLarge)
+			}
+			if len(data) != tt.want {
+				t.Errorf("read %d bytes, want %d", len(data), tt.want)
+			}
+		})
+	}
+}
+
+func TestRequestBodyInvalidGzip(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/git-receive-pack", bytes.NewReader([]byte("not gzip")))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	if _, err := requestBody(httptest.NewRecorder(), req, 1<<10); err == nil {
+		t.Error("requestBody() accepted a body that is not gzip")
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"go.uber.org/zap"
+)
+
+func (h *ProjectHandler) ArchiveProject(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	project, err := h.projectService.ArchiveProject(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.respondLifecycleError(c, err, "Failed to archive project")
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+func (h *ProjectHandler) UnarchiveProject(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	project, err := h.projectService.UnarchiveProject(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.respondLifecycleError(c, err, "Failed to unarchive project")
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+func (h *ProjectHandler) RestoreProject(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	project, err := h.projectService.RestoreProject(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.respondLifecycleError(c, err, "Failed to restore project")
+		return
+	}
+
+	c.JSON(http.StatusOK, project)
+}
+
+// PurgeProject permanently deletes a project that is already in the trash
+func (h *ProjectHandler) PurgeProject(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.projectService.PurgeProject(c.Request.Context(), projectID, userID); err != nil {
+		h.respondLifecycleError(c, err, "Failed to delete project")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Project permanently deleted"})
+}
+
+func (h *ProjectHandler) DuplicateProject(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var req models.DuplicateProjectRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	project, err := h.projectService.DuplicateProject(c.Request.Context(), projectID, userID, &req)
+	if err != nil {
+		h.respondLifecycleError(c, err, "Failed to duplicate project")
+		return
+	}
+
+	c.JSON(http.StatusCreated, project)
+}
+
+// respondLifecycleError maps archive, trash and duplication errors to HTTP
+// responses
+func (h *ProjectHandler) respondLifecycleError(c *gin.Context, err error, message string) {
+	if respondQuotaError(c, err) {
+		return
+	}
+
+	switch err.Error() {
+	case "project not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "access denied",
+		"only project owner can archive project",
+		"only project owner can restore project",
+		"only project owner can delete project",
+		"only project owner can copy collaborators":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "project is in trash", "project is not in trash":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// ListProjectLint returns the lint warnings of every .tex file of a project
+func (h *ProjectHandler) ListProjectLint(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	results, err := h.projectService.ListProjectLint(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.respondLintError(c, err, "Failed to lint project")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": results})
+}
+
+// ListLintRules lists the lint rules and the severity the project gives them
+func (h *ProjectHandler) ListLintRules(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	rules, err := h.projectService.ListLintRules(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.respondLintError(c, err, "Failed to list lint rules")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": rules})
+}
+
+// GetFileLint returns the lint warnings of a .tex file
+func (h *ProjectHandler) GetFileLint(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	fileID, err := primitive.ObjectIDFromHex(c.Param("fileId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	result, err := h.projectService.GetFileLint(c.Request.Context(), projectID, fileID, userID)
+	if err != nil {
+		h.respondLintError(c, err, "Failed to lint file")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// respondLintError maps lint errors to HTTP responses
+func (h *ProjectHandler) respondLintError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "project not found", "file not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "access denied":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "only .tex files are linted":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
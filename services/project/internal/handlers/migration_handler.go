@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/pkg/migrate"
+	"go.uber.org/zap"
+)
+
+// MigrationHandler exposes the data migrations to administrators
+type MigrationHandler struct {
+	migrator *migrate.Migrator
+	logger   *zap.Logger
+}
+
+func NewMigrationHandler(migrator *migrate.Migrator, logger *zap.Logger) *MigrationHandler {
+	return &MigrationHandler{
+		migrator: migrator,
+		logger:   logger,
+	}
+}
+
+// ListMigrations lists the migrations with their state
+func (h *MigrationHandler) ListMigrations(c *gin.Context) {
+	status, err := h.migrator.Status(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list migrations", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list migrations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// RunMigrations runs the pending migrations, or with "dry_run=true" reports
+// what they would change
+func (h *MigrationHandler) RunMigrations(c *gin.Context) {
+	dryRun, _ := strconv.ParseBool(c.Query("dry_run"))
+
+	results, err := h.migrator.Up(c.Request.Context(), dryRun)
+	if err != nil {
+		h.logger.Error("Migration failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Migration failed",
+			"details": err.Error(),
+			"applied": results,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"dry_run": dryRun, "applied": results})
+}
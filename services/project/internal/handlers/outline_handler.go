@@ -0,0 +1,32 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// GetProjectIndex returns the outline, label and citation index of a project
+func (h *ProjectHandler) GetProjectIndex(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	index, err := h.projectService.GetProjectIndex(c.Request.Context(), projectID, userID)
+	if err != nil {
+		switch err.Error() {
+		case "project not found":
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case "access denied":
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to build project index", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build project index"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, index)
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -46,6 +47,14 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 
 	project, err := h.projectService.CreateProject(c.Request.Context(), userID, &req)
 	if err != nil {
+		if err.Error() == "invalid template ID" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+			return
+		}
+		if err.Error() == "template not found" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+			return
+		}
 		h.logger.Error("Failed to create project", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
 		return
@@ -64,7 +73,15 @@ func (h *ProjectHandler) GetProjects(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
 
-	projects, total, err := h.projectService.ListUserProjects(c.Request.Context(), userID, page, limit)
+	status := c.DefaultQuery("status", models.ProjectStatusActive)
+	switch status {
+	case models.ProjectStatusActive, models.ProjectStatusArchived, models.ProjectStatusTrashed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "status must be active, archived or trashed"})
+		return
+	}
+
+	projects, total, err := h.projectService.ListUserProjects(c.Request.Context(), userID, status, page, limit)
 	if err != nil {
 		h.logger.Error("Failed to list projects", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list projects"})
@@ -72,10 +89,11 @@ func (h *ProjectHandler) GetProjects(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data":  projects,
-		"total": total,
-		"page":  page,
-		"limit": limit,
+		"data":   projects,
+		"total":  total,
+		"page":   page,
+		"limit":  limit,
+		"status": status,
 	})
 }
 
@@ -131,6 +149,14 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
+		if err.Error() == "project is archived" || err.Error() == "project is in trash" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if err.Error() == "unknown lint rule" || err.Error() == "invalid lint severity" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to update project", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
 		return
@@ -157,12 +183,16 @@ func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
+		if err.Error() == "project is in trash" {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to delete project", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Project deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "Project moved to trash"})
 }
 
 func (h *ProjectHandler) ShareProject(c *gin.Context) {
@@ -190,21 +220,13 @@ func (h *ProjectHandler) ShareProject(c *gin.Context) {
 		return
 	}
 
-	if err := h.projectService.ShareProject(c.Request.Context(), projectID, userID, collaboratorID, req.Role); err != nil {
-		if err.Error() == "only project owner can share project" {
-			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
-			return
-		}
-		if err.Error() == "project already shared with this user" {
-			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
-			return
-		}
-		h.logger.Error("Failed to share project", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to share project"})
+	invitation, err := h.projectService.ShareProject(c.Request.Context(), projectID, userID, collaboratorID, req.Role)
+	if err != nil {
+		h.respondCollaboratorError(c, err, "Failed to share project")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Project shared"})
+	c.JSON(http.StatusCreated, invitation)
 }
 
 func (h *ProjectHandler) CreateFile(c *gin.Context) {
@@ -232,6 +254,13 @@ func (h *ProjectHandler) CreateFile(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 			return
 		}
+		if err.Error() == "file exceeds the maximum upload size" {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		if respondQuotaError(c, err) {
+			return
+		}
 		h.logger.Error("Failed to create file", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create file"})
 		return
@@ -322,7 +351,7 @@ func (h *ProjectHandler) GetFileContent(c *gin.Context) {
 		return
 	}
 
-	_, content, err := h.projectService.GetFile(c.Request.Context(), fileID, userID)
+	file, content, err := h.projectService.GetFile(c.Request.Context(), fileID, userID)
 	if err != nil {
 		if err.Error() == "file not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
@@ -341,7 +370,14 @@ func (h *ProjectHandler) GetFileContent(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"content": string(content)})
+	// Clients send the ETag back in If-Match when saving the file
+	c.Header("ETag", file.ETag())
+	if c.GetHeader("If-None-Match") == file.ETag() {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"content": string(content), "version": file.Version})
 }
 
 func (h *ProjectHandler) UpdateFile(c *gin.Context) {
@@ -363,8 +399,23 @@ func (h *ProjectHandler) UpdateFile(c *gin.Context) {
 		return
 	}
 
-	file, err := h.projectService.UpdateFile(c.Request.Context(), fileID, userID, &req)
+	ifMatch := c.GetHeader("If-Match")
+	if ifMatch == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": "If-Match header is required"})
+		return
+	}
+
+	file, suggestions, err := h.projectService.UpdateFile(c.Request.Context(), fileID, userID, &req, ifMatch)
 	if err != nil {
+		var conflict *service.FileConflictError
+		if errors.As(err, &conflict) {
+			c.Header("ETag", conflict.Conflict.ETag)
+			c.JSON(http.StatusConflict, conflict.Conflict)
+			return
+		}
+		if respondQuotaError(c, err) {
+			return
+		}
 		if err.Error() == "file not found" {
 			c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 			return
@@ -377,45 +428,22 @@ func (h *ProjectHandler) UpdateFile(c *gin.Context) {
 			c.JSON(http.StatusForbidden, gin.H{"error": "Permission denied"})
 			return
 		}
+		if err.Error() == "changes are tracked; only the project owner can make this change" {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to update file", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update file"})
 		return
 	}
 
-	c.JSON(http.StatusOK, file)
-}
-
-// MigrateLatexFiles migrates existing main.tex files to escape LaTeX special characters
-// This is a one-time migration endpoint to fix files created before the escaping fix
-func (h *ProjectHandler) MigrateLatexFiles(c *gin.Context) {
-	h.logger.Info("Starting LaTeX file migration")
-
-	fixed, errors, err := h.projectService.MigrateLatexFiles(c.Request.Context())
-	if err != nil {
-		h.logger.Error("Migration failed", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "Migration failed",
-			"details": err.Error(),
-		})
+	c.Header("ETag", file.ETag())
+	if suggestions != nil {
+		// Tracked changes wait for the owner's review
+		c.JSON(http.StatusAccepted, models.UpdateFileResponse{File: file, Suggestions: suggestions})
 		return
 	}
-
-	response := gin.H{
-		"message": "Migration completed",
-		"files_fixed": fixed,
-		"errors_count": len(errors),
-	}
-
-	if len(errors) > 0 {
-		response["errors"] = errors
-	}
-
-	h.logger.Info("LaTeX migration completed",
-		zap.Int("fixed", fixed),
-		zap.Int("errors", len(errors)),
-	)
-
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusOK, file)
 }
 
 // Helper to extract user ID from context (set by auth middleware)
@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Quota errors carry a stable code so that clients can tell the limits apart
+var quotaErrors = map[string]struct {
+	status int
+	code   string
+}{
+	"file exceeds the maximum file size": {http.StatusRequestEntityTooLarge, "file_too_large"},
+	"storage quota exceeded":             {http.StatusInsufficientStorage, "storage_quota_exceeded"},
+	"file quota exceeded":                {http.StatusInsufficientStorage, "file_quota_exceeded"},
+}
+
+// RequireAdmin rejects requests from users that are not administrators
+func RequireAdmin(adminIDs []string) gin.HandlerFunc {
+	admins := make(map[string]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+
+	return func(c *gin.Context) {
+		userID, err := getUserID(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+		if !admins[userID.Hex()] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetStorageUsage reports the storage used by the user and their
+// organization against their quotas
+func (h *ProjectHandler) GetStorageUsage(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	usage, err := h.projectService.GetStorageUsage(c.Request.Context(), userID)
+	if err != nil {
+		h.logger.Error("Failed to get storage usage", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get storage usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, usage)
+}
+
+func (h *ProjectHandler) ListQuotaOverrides(c *gin.Context) {
+	overrides, err := h.projectService.ListQuotaOverrides(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list quota overrides", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list quota overrides"})
+		return
+	}
+
+	if overrides == nil {
+		overrides = []*models.QuotaOverride{}
+	}
+	c.JSON(http.StatusOK, overrides)
+}
+
+func (h *ProjectHandler) GetQuota(c *gin.Context) {
+	subjectID, ok := getQuotaSubjectID(c)
+	if !ok {
+		return
+	}
+
+	status, err := h.projectService.GetQuota(c.Request.Context(), c.Param("subjectType"), subjectID)
+	if err != nil {
+		h.respondQuotaAdminError(c, err, "Failed to get quota")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *ProjectHandler) SetQuotaOverride(c *gin.Context) {
+	adminID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	subjectID, ok := getQuotaSubjectID(c)
+	if !ok {
+		return
+	}
+
+	var req models.SetQuotaOverrideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	status, err := h.projectService.SetQuotaOverride(c.Request.Context(), c.Param("subjectType"), subjectID, adminID, &req)
+	if err != nil {
+		h.respondQuotaAdminError(c, err, "Failed to set quota override")
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *ProjectHandler) DeleteQuotaOverride(c *gin.Context) {
+	subjectID, ok := getQuotaSubjectID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.projectService.DeleteQuotaOverride(c.Request.Context(), c.Param("subjectType"), subjectID); err != nil {
+		h.respondQuotaAdminError(c, err, "Failed to delete quota override")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondQuotaError writes the response for a quota error and reports
+// whether err was one
+func respondQuotaError(c *gin.Context, err error) bool {
+	quotaErr, ok := quotaErrors[err.Error()]
+	if !ok {
+		return false
+	}
+
+	c.JSON(quotaErr.status, gin.H{"error": err.Error(), "code": quotaErr.code})
+	return true
+}
+
+// respondQuotaAdminError maps quota administration errors to HTTP responses
+func (h *ProjectHandler) respondQuotaAdminError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "invalid quota subject":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "quota override not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// getQuotaSubjectID extracts the ID of the user or organization whose quota
+// is administered
+func getQuotaSubjectID(c *gin.Context) (primitive.ObjectID, bool) {
+	subjectID, err := primitive.ObjectIDFromHex(c.Param("subjectId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid subject ID"})
+		return primitive.NilObjectID, false
+	}
+
+	return subjectID, true
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SearchProject searches the files of one project for the "q" query
+// parameter
+func (h *ProjectHandler) SearchProject(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	q, caseSensitive, limit := searchParams(c)
+	response, err := h.projectService.SearchProject(c.Request.Context(), projectID, userID, q, caseSensitive, limit)
+	if err != nil {
+		h.respondSearchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SearchAllProjects searches every project the user can access for the "q"
+// query parameter
+func (h *ProjectHandler) SearchAllProjects(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	q, caseSensitive, limit := searchParams(c)
+	response, err := h.projectService.SearchAllProjects(c.Request.Context(), userID, q, caseSensitive, limit)
+	if err != nil {
+		h.respondSearchError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *ProjectHandler) ReindexProject(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	indexed, err := h.projectService.ReindexProject(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to rebuild search index")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"indexed_files": indexed})
+}
+
+// respondSearchError maps search errors to HTTP responses
+func (h *ProjectHandler) respondSearchError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "search query is empty", "search query has too many terms":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case "project not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "access denied":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Failed to search", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search"})
+	}
+}
+
+// searchParams reads the query, case sensitivity and result limit of a
+// search request
+func searchParams(c *gin.Context) (string, bool, int) {
+	caseSensitive, _ := strconv.ParseBool(c.Query("case_sensitive"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if limit < 1 || limit > 500 {
+		limit = 50
+	}
+	return c.Query("q"), caseSensitive, limit
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+func (h *ProjectHandler) CreateShareLink(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	link, err := h.projectService.CreateShareLink(c.Request.Context(), projectID, userID, &req)
+	if err != nil {
+		h.respondShareLinkError(c, err, "Failed to create share link")
+		return
+	}
+
+	c.JSON(http.StatusCreated, link)
+}
+
+func (h *ProjectHandler) ListShareLinks(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	links, err := h.projectService.ListShareLinks(c.Request.Context(), projectID, userID)
+	if err != nil {
+		h.respondShareLinkError(c, err, "Failed to list share links")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": links})
+}
+
+func (h *ProjectHandler) RevokeShareLink(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	linkID, err := primitive.ObjectIDFromHex(c.Param("linkId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share link ID"})
+		return
+	}
+
+	if err := h.projectService.RevokeShareLink(c.Request.Context(), projectID, linkID, userID); err != nil {
+		h.respondShareLinkError(c, err, "Failed to revoke share link")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share link revoked"})
+}
+
+// ResolveShareLink opens a share link for the user, returning the project and
+// the temporary role it grants
+func (h *ProjectHandler) ResolveShareLink(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var req models.ResolveShareLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	grant, err := h.projectService.ResolveShareLink(c.Request.Context(), userID, &req)
+	if err != nil {
+		h.respondShareLinkError(c, err, "Failed to resolve share link")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"project_id": grant.ProjectID,
+		"role":       grant.Role,
+		"expires_at": grant.ExpiresAt,
+	})
+}
+
+// respondShareLinkError maps share link errors to HTTP responses
+func (h *ProjectHandler) respondShareLinkError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "project not found", "share link not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "only project owner can share project":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "password required", "invalid password":
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case "share link has been revoked", "share link has expired":
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case "share link already revoked", "share link limit reached", "project is in trash":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
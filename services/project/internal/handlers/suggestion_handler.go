@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// ListSuggestions lists the tracked changes of a project. "file_id" limits
+// them to one file and "status" to pending, accepted or rejected ones.
+func (h *ProjectHandler) ListSuggestions(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var fileID *primitive.ObjectID
+	if hex := c.Query("file_id"); hex != "" {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+			return
+		}
+		fileID = &id
+	}
+
+	suggestions, err := h.projectService.ListSuggestions(c.Request.Context(), projectID, userID, fileID, c.Query("status"))
+	if err != nil {
+		h.respondSuggestionError(c, err, "Failed to list suggestions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": suggestions})
+}
+
+// AcceptSuggestions accepts the pending suggestions selected by the body,
+// or all of them when it selects none
+func (h *ProjectHandler) AcceptSuggestions(c *gin.Context) {
+	h.reviewSuggestions(c, true, "")
+}
+
+// RejectSuggestions rejects the pending suggestions selected by the body,
+// or all of them when it selects none
+func (h *ProjectHandler) RejectSuggestions(c *gin.Context) {
+	h.reviewSuggestions(c, false, "")
+}
+
+func (h *ProjectHandler) AcceptSuggestion(c *gin.Context) {
+	h.reviewSuggestions(c, true, c.Param("suggestionId"))
+}
+
+func (h *ProjectHandler) RejectSuggestion(c *gin.Context) {
+	h.reviewSuggestions(c, false, c.Param("suggestionId"))
+}
+
+func (h *ProjectHandler) reviewSuggestions(c *gin.Context, accept bool, suggestionID string) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ReviewSuggestionsRequest
+	if suggestionID != "" {
+		req.IDs = []string{suggestionID}
+	} else if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	var response *models.ReviewSuggestionsResponse
+	var err error
+	if accept {
+		response, err = h.projectService.AcceptSuggestions(c.Request.Context(), projectID, userID, &req)
+	} else {
+		response, err = h.projectService.RejectSuggestions(c.Request.Context(), projectID, userID, &req)
+	}
+	if err != nil {
+		h.respondSuggestionError(c, err, "Failed to review suggestions")
+		return
+	}
+
+	// A single suggestion that could not be applied is a conflict
+	if suggestionID != "" && len(response.Reviewed) == 0 && len(response.Skipped) > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": response.Skipped[0].Reason})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// respondSuggestionError maps suggestion errors to HTTP responses
+func (h *ProjectHandler) respondSuggestionError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "project not found", "suggestion not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case "access denied", "permission denied", "only project owner can accept suggestions":
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case "project is archived", "project is in trash", "file has been modified":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case "invalid suggestion ID", "invalid file ID", "invalid suggestion status":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
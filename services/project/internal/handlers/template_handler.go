@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"github.com/texflow/services/project/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// ListTemplates searches the template gallery by the "q" and "category"
+// query parameters
+func (h *ProjectHandler) ListTemplates(c *gin.Context) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	templates, total, err := h.projectService.ListTemplates(c.Request.Context(), models.TemplateFilter{
+		Query:    strings.TrimSpace(c.Query("q")),
+		Category: c.Query("category"),
+		ViewerID: userID,
+		Page:     page,
+		Limit:    limit,
+	})
+	if err != nil {
+		h.logger.Error("Failed to list templates", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list templates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":  templates,
+		"total": total,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+func (h *ProjectHandler) GetTemplate(c *gin.Context) {
+	userID, templateID, ok := getUserAndTemplateID(c)
+	if !ok {
+		return
+	}
+
+	template, err := h.projectService.GetTemplate(c.Request.Context(), templateID, userID)
+	if err != nil {
+		h.respondTemplateError(c, err, "Failed to get template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *ProjectHandler) PublishTemplate(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var req models.PublishTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.projectService.PublishTemplate(c.Request.Context(), projectID, userID, &req)
+	if err != nil {
+		h.respondTemplateError(c, err, "Failed to publish template")
+		return
+	}
+
+	c.JSON(http.StatusCreated, template)
+}
+
+func (h *ProjectHandler) UpdateTemplate(c *gin.Context) {
+	userID, templateID, ok := getUserAndTemplateID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	template, err := h.projectService.UpdateTemplate(c.Request.Context(), templateID, userID, &req)
+	if err != nil {
+		h.respondTemplateError(c, err, "Failed to update template")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *ProjectHandler) DeleteTemplate(c *gin.Context) {
+	userID, templateID, ok := getUserAndTemplateID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.projectService.DeleteTemplate(c.Request.Context(), templateID, userID); err != nil {
+		h.respondTemplateError(c, err, "Failed to delete template")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted"})
+}
+
+// UploadTemplateThumbnail sets the preview image of a template from the
+// "file" form field
+func (h *ProjectHandler) UploadTemplateThumbnail(c *gin.Context) {
+	userID, templateID, ok := getUserAndTemplateID(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxTemplateThumbnailSize+1<<20)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An image is required in the \"file\" field"})
+		return
+	}
+	if header.Size > service.MaxTemplateThumbnailSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Thumbnail too large"})
+		return
+	}
+
+	f, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read thumbnail"})
+		return
+	}
+	defer f.Close()
+
+	image, err := io.ReadAll(f)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read thumbnail"})
+		return
+	}
+
+	template, err := h.projectService.SetTemplateThumbnail(c.Request.Context(), templateID, userID, image)
+	if err != nil {
+		h.respondTemplateError(c, err, "Failed to upload thumbnail")
+		return
+	}
+
+	c.JSON(http.StatusOK, template)
+}
+
+func (h *ProjectHandler) GetTemplateThumbnail(c *gin.Context) {
+	userID, templateID, ok := getUserAndTemplateID(c)
+	if !ok {
+		return
+	}
+
+	image, err := h.projectService.GetTemplateThumbnail(c.Request.Context(), templateID, userID)
+	if err != nil {
+		h.respondTemplateError(c, err, "Failed to get thumbnail")
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, http.DetectContentType(image), image)
+}
+
+// respondTemplateError maps template errors to HTTP responses
+func (h *ProjectHandler) respondTemplateError(c *gin.Context, err error, message string) {
+	msg := err.Error()
+	switch {
+	case msg == "project not found", msg == "template not found", msg == "thumbnail not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+	case msg == "permission denied", msg == "only project owner can publish a template",
+		msg == "built-in templates cannot be modified":
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	case strings.HasPrefix(msg, "thumbnail must be"):
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// getUserAndTemplateID extracts the user and template IDs, writing the error
+// response when either is missing or invalid
+func getUserAndTemplateID(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, err := getUserID(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	templateID, err := primitive.ObjectIDFromHex(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid template ID"})
+		return primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return userID, templateID, true
+}
@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"github.com/texflow/services/project/internal/service"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// multipartOverhead allows for the form boundaries and headers around the
+// file part of an upload
+const multipartOverhead = 1 << 20
+
+// UploadFile creates a file from a multipart/form-data body. The file is
+// streamed to storage; its path comes from the "path" query parameter or
+// form field, which must precede the "file" part, or else the file name.
+func (h *ProjectHandler) UploadFile(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.projectService.MaxUploadSize()+multipartOverhead)
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Expected a multipart/form-data body"})
+		return
+	}
+
+	filePath := c.Query("path")
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Missing file part"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart body"})
+			return
+		}
+
+		switch part.FormName() {
+		case "path":
+			value, err := io.ReadAll(io.LimitReader(part, 4096))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart body"})
+				return
+			}
+			filePath = string(value)
+		case "file":
+			if filePath == "" {
+				filePath = part.FileName()
+			}
+
+			file, err := h.projectService.UploadFile(c.Request.Context(), projectID, userID, filePath, part)
+			if err != nil {
+				h.respondUploadError(c, err, "Failed to upload file")
+				return
+			}
+
+			c.JSON(http.StatusCreated, file)
+			return
+		}
+	}
+}
+
+// DownloadFile streams the content of a file, honouring Range requests
+func (h *ProjectHandler) DownloadFile(c *gin.Context) {
+	userID, projectID, fileID, ok := getUserProjectAndFileID(c)
+	if !ok {
+		return
+	}
+
+	file, content, err := h.projectService.OpenFile(c.Request.Context(), projectID, fileID, userID)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to download file")
+		return
+	}
+	defer content.Close()
+
+	c.Header("ETag", file.ETag())
+	c.Header("Content-Type", file.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	http.ServeContent(c.Writer, c.Request, file.Name, file.UpdatedAt, content)
+}
+
+func (h *ProjectHandler) CreateUpload(c *gin.Context) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return
+	}
+
+	var req models.CreateUploadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	upload, err := h.projectService.CreateUpload(c.Request.Context(), projectID, userID, &req)
+	if err != nil {
+		h.respondUploadError(c, err, "Failed to create upload")
+		return
+	}
+
+	c.JSON(http.StatusCreated, upload)
+}
+
+func (h *ProjectHandler) GetUpload(c *gin.Context) {
+	userID, projectID, uploadID, ok := getUserProjectAndUploadID(c)
+	if !ok {
+		return
+	}
+
+	upload, err := h.projectService.GetUpload(c.Request.Context(), projectID, uploadID, userID)
+	if err != nil {
+		h.respondUploadError(c, err, "Failed to get upload")
+		return
+	}
+
+	c.JSON(http.StatusOK, upload)
+}
+
+// UploadChunk stores the request body as the chunk given by the "index"
+// path parameter. The body must be exactly the chunk size.
+func (h *ProjectHandler) UploadChunk(c *gin.Context) {
+	userID, projectID, uploadID, ok := getUserProjectAndUploadID(c)
+	if !ok {
+		return
+	}
+
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chunk index"})
+		return
+	}
+	if c.Request.ContentLength < 0 {
+		c.JSON(http.StatusLengthRequired, gin.H{"error": "Content-Length is required"})
+		return
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, service.UploadChunkSize)
+	upload, err := h.projectService.UploadChunk(c.Request.Context(), projectID, uploadID, userID, index, body, c.Request.ContentLength)
+	if err != nil {
+		h.respondUploadError(c, err, "Failed to upload chunk")
+		return
+	}
+
+	c.JSON(http.StatusOK, upload)
+}
+
+func (h *ProjectHandler) CompleteUpload(c *gin.Context) {
+	userID, projectID, uploadID, ok := getUserProjectAndUploadID(c)
+	if !ok {
+		return
+	}
+
+	file, err := h.projectService.CompleteUpload(c.Request.Context(), projectID, uploadID, userID)
+	if err != nil {
+		h.respondUploadError(c, err, "Failed to complete upload")
+		return
+	}
+
+	c.JSON(http.StatusCreated, file)
+}
+
+func (h *ProjectHandler) AbortUpload(c *gin.Context) {
+	userID, projectID, uploadID, ok := getUserProjectAndUploadID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.projectService.AbortUpload(c.Request.Context(), projectID, uploadID, userID); err != nil {
+		h.respondUploadError(c, err, "Failed to abort upload")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondUploadError maps upload errors to HTTP responses, falling back to
+// the file tree errors
+func (h *ProjectHandler) respondUploadError(c *gin.Context, err error, message string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge), err.Error() == "file exceeds the maximum upload size":
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "file exceeds the maximum upload size"})
+	case err.Error() == "upload not found":
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case err.Error() == "upload is incomplete":
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err.Error() == "invalid chunk index", err.Error() == "chunk has the wrong size":
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.respondFileTreeError(c, err, message)
+	}
+}
+
+// getUserProjectAndUploadID extracts the user, project and upload IDs,
+// writing the error response when any is missing or invalid
+func getUserProjectAndUploadID(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return primitive.NilObjectID, primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	uploadID, err := primitive.ObjectIDFromHex(c.Param("uploadId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload ID"})
+		return primitive.NilObjectID, primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return userID, projectID, uploadID, true
+}
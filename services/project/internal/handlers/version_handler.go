@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (h *ProjectHandler) ListFileVersions(c *gin.Context) {
+	userID, projectID, fileID, ok := getUserProjectAndFileID(c)
+	if !ok {
+		return
+	}
+
+	versions, err := h.projectService.ListFileVersions(c.Request.Context(), projectID, fileID, userID)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to list file versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+func (h *ProjectHandler) GetFileVersionContent(c *gin.Context) {
+	userID, projectID, fileID, ok := getUserProjectAndFileID(c)
+	if !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	v, content, err := h.projectService.GetFileVersion(c.Request.Context(), projectID, fileID, userID, version)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to get file version")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"version": v, "content": string(content)})
+}
+
+// DiffFileVersions diffs the versions given by the "from" and "to" query
+// parameters; either defaults to the current version
+func (h *ProjectHandler) DiffFileVersions(c *gin.Context) {
+	userID, projectID, fileID, ok := getUserProjectAndFileID(c)
+	if !ok {
+		return
+	}
+
+	from, err := strconv.Atoi(c.DefaultQuery("from", "0"))
+	if err != nil || from < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from version"})
+		return
+	}
+	to, err := strconv.Atoi(c.DefaultQuery("to", "0"))
+	if err != nil || to < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to version"})
+		return
+	}
+
+	diff, err := h.projectService.DiffFileVersions(c.Request.Context(), projectID, fileID, userID, from, to)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to diff file versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+func (h *ProjectHandler) RestoreFileVersion(c *gin.Context) {
+	userID, projectID, fileID, ok := getUserProjectAndFileID(c)
+	if !ok {
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version"})
+		return
+	}
+
+	file, suggestions, err := h.projectService.RestoreFileVersion(c.Request.Context(), projectID, fileID, userID, version)
+	if err != nil {
+		h.respondFileTreeError(c, err, "Failed to restore file version")
+		return
+	}
+
+	if suggestions != nil {
+		// Tracked changes wait for the owner's review
+		c.JSON(http.StatusAccepted, models.UpdateFileResponse{File: file, Suggestions: suggestions})
+		return
+	}
+	c.JSON(http.StatusOK, file)
+}
+
+// getUserProjectAndFileID extends getUserAndProjectID with the file ID
+func getUserProjectAndFileID(c *gin.Context) (primitive.ObjectID, primitive.ObjectID, primitive.ObjectID, bool) {
+	userID, projectID, ok := getUserAndProjectID(c)
+	if !ok {
+		return primitive.NilObjectID, primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	fileID, err := primitive.ObjectIDFromHex(c.Param("fileId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return primitive.NilObjectID, primitive.NilObjectID, primitive.NilObjectID, false
+	}
+
+	return userID, projectID, fileID, true
+}
@@ -0,0 +1,163 @@
+// Package lint checks LaTeX sources for common mistakes before they are
+// compiled, in the manner of chktex: wrong quotes, missing ties before
+// references, unbalanced braces and environments, $$ display math and
+// obsolete packages. Each rule can be turned off or given another severity
+// per project, and single warnings can be silenced with comments in the
+// source:
+//
+//	% lint-disable-line missing-tie
+//	% lint-disable-next-line
+//	% lint-disable wrong-quotes
+//	% lint-enable wrong-quotes
+package lint
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Version changes whenever rules change, so that stored results are redone
+const Version = 1
+
+// MaxWarnings bounds the warnings reported for one file
+const MaxWarnings = 500
+
+// Severities. SeverityOff turns a rule off.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+	SeverityOff     = "off"
+)
+
+// Warning is a problem found in a file. Lines and columns start at 1;
+// columns and lengths count characters.
+type Warning struct {
+	Rule     string `bson:"rule" json:"rule"`
+	Severity string `bson:"severity" json:"severity"`
+	Line     int    `bson:"line" json:"line"`
+	Column   int    `bson:"column" json:"column"`
+	Length   int    `bson:"length" json:"length"`
+	Message  string `bson:"message" json:"message"`
+}
+
+// Rule is a check with its default severity
+type Rule struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Severity    string `json:"severity"`
+
+	check func(d *document) []finding
+}
+
+// Rules returns the available rules
+func Rules() []Rule {
+	list := make([]Rule, len(rules))
+	for i, rule := range rules {
+		list[i] = *rule
+		list[i].check = nil
+	}
+	return list
+}
+
+// IsLintable reports whether a file is a LaTeX document that is linted.
+// Packages and classes are left out, as their internal commands trip the
+// rules.
+func IsLintable(filePath string) bool {
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".tex", ".ltx":
+		return true
+	}
+	return false
+}
+
+// Config selects the severity of rules by ID, or turns them off. Rules
+// not listed keep their default severity.
+type Config struct {
+	Severities map[string]string
+}
+
+// Validate checks that a configuration names known rules and severities
+func (c Config) Validate() error {
+	for id, severity := range c.Severities {
+		if findRule(id) == nil {
+			return fmt.Errorf("unknown lint rule")
+		}
+		switch severity {
+		case SeverityError, SeverityWarning, SeverityInfo, SeverityOff:
+		default:
+			return fmt.Errorf("invalid lint severity")
+		}
+	}
+	return nil
+}
+
+// Fingerprint identifies the rules and severities a configuration runs,
+// so results can be reused while it stays the same
+func (c Config) Fingerprint() string {
+	parts := []string{fmt.Sprintf("v%d", Version)}
+	for _, rule := range rules {
+		parts = append(parts, rule.ID+"="+c.severity(rule))
+	}
+	return strings.Join(parts, ";")
+}
+
+func (c Config) severity(rule *Rule) string {
+	if severity, ok := c.Severities[rule.ID]; ok {
+		return severity
+	}
+	return rule.Severity
+}
+
+func findRule(id string) *Rule {
+	for _, rule := range rules {
+		if rule.ID == id {
+			return rule
+		}
+	}
+	return nil
+}
+
+// Lint checks a LaTeX source with the rules a configuration enables.
+// Warnings are sorted by position; at most MaxWarnings are returned.
+func Lint(content string, cfg Config) []Warning {
+	d := analyze(content)
+
+	warnings := []Warning{}
+	for _, rule := range rules {
+		severity := cfg.severity(rule)
+		if severity == SeverityOff {
+			continue
+		}
+
+		for _, f := range rule.check(d) {
+			line, column := d.position(f.offset)
+			if d.suppressions.suppressed(rule.ID, line) {
+				continue
+			}
+			warnings = append(warnings, Warning{
+				Rule:     rule.ID,
+				Severity: severity,
+				Line:     line,
+				Column:   column,
+				Length:   utf8.RuneCountInString(content[f.offset : f.offset+f.length]),
+				Message:  f.message,
+			})
+		}
+	}
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		if warnings[i].Line != warnings[j].Line {
+			return warnings[i].Line < warnings[j].Line
+		}
+		return warnings[i].Column < warnings[j].Column
+	})
+	if len(warnings) > MaxWarnings {
+		warnings = warnings[:MaxWarnings]
+	}
+
+	return warnings
+}
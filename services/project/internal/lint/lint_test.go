@@ -0,0 +1,193 @@
+package lint
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// warningsOf formats warnings as rule@line:column for comparison
+func warningsOf(warnings []Warning) []string {
+	var got []string
+	for _, w := range warnings {
+		got = append(got, fmt.Sprintf("%s@%d:%d", w.Rule, w.Line, w.Column))
+	}
+	return got
+}
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"clean document", "\\documentclass{article}\n\\begin{document}\nSee ``this'' and Fig.~\\ref{f}\\dots\n\\end{document}", nil},
+
+		{"straight double quotes", `He said "hi" to me.`, []string{"wrong-quotes@1:9", "wrong-quotes@1:12"}},
+		{"opening single quote", "a 'word' here", []string{"wrong-quotes@1:3"}},
+		{"two single quotes opening", "a ''word'' here", []string{"wrong-quotes@1:3"}},
+		{"babel shorthand and escaped quote", `Gro"se \" ok`, nil},
+		{"quotes in math and verbatim", "$f'(x)$ \\verb|\"a\"| \\begin{verbatim}\n\"x\"\n\\end{verbatim}", nil},
+
+		{"space before ref", "Figure \\ref{f} and see \\cite{k}", []string{"missing-tie@1:7", "missing-tie@1:23"}},
+		{"tie or line start before ref", "Figure~\\ref{f}\n\n\\cite{k} (\\ref{g})", nil},
+
+		{"unclosed brace", "\\textbf{bold", []string{"unbalanced-braces@1:8"}},
+		{"unmatched brace", "text}", []string{"unbalanced-braces@1:5"}},
+		{"escaped and commented braces", "\\{ % {\n\\}", nil},
+
+		{"unclosed environment", "\\begin{itemize}\n\\item a", []string{"unbalanced-environments@1:1"}},
+		{"unmatched end", "\\end{itemize}", []string{"unbalanced-environments@1:1"}},
+		{"crossed environments", "\\begin{a}\\begin{b}\\end{a}", []string{"unbalanced-environments@1:10"}},
+
+		{"display dollars", "$$x$$ and $$y$$", []string{"display-math-dollars@1:1", "display-math-dollars@1:11"}},
+		{"inline dollars", "$x$ and \\$\\$", nil},
+
+		{"deprecated packages", "\\usepackage{amsmath, epsfig,times}", []string{"deprecated-package@1:22", "deprecated-package@1:29"}},
+		{"deprecated package with options", "\\usepackage[T1]{t1enc}", []string{"deprecated-package@1:17"}},
+		{"deprecated package in a comment", "% \\usepackage{a4}", nil},
+
+		{"ellipsis", "wait... and ....", []string{"ellipsis@1:5", "ellipsis@1:13"}},
+		{"ellipsis in math", "$1,...,n$", nil},
+
+		{"environment in a definition", "\\newcommand{\\bi}{\\begin{itemize}}\n\\newenvironment{x}{\\begin{center}}{\\end{center}}\n\\begin{document}\\end{document}", nil},
+		{"def with a control sequence name", "\\def\\x{\\begin{center}}\n\\begin{document}\"\\end{document}", []string{"wrong-quotes@2:17"}},
+		{"xparse definition", "\\NewDocumentCommand{\\y}{m}{\\end{center}}\n\\begin{document}\\end{document}", nil},
+		{"unterminated definition", "\\newcommand{\\x}{\\begin{center}\n\\begin{document}", []string{"unbalanced-braces@1:16"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := warningsOf(Lint(tt.content, Config{}))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintSuppressions(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{"disable line", "a \"b\" % lint-disable-line wrong-quotes", nil},
+		{"disable line for another rule", "a \"b\" % lint-disable-line missing-tie", []string{"wrong-quotes@1:3", "wrong-quotes@1:5"}},
+		{"disable next line", "% lint-disable-next-line\na \"b\"\nc \"d\"", []string{"wrong-quotes@3:3", "wrong-quotes@3:5"}},
+		{"disable region", "% lint-disable ellipsis\na...\n% lint-enable ellipsis\nb...", []string{"ellipsis@4:2"}},
+		{"open region", "a...\n% lint-disable\nb... \"c\"", []string{"ellipsis@1:2"}},
+		{"several rules", "% lint-disable ellipsis, wrong-quotes\na... \"b\" see \\ref{x}", []string{"missing-tie@2:13"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := warningsOf(Lint(tt.content, Config{}))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintConfig(t *testing.T) {
+	content := "a... \"b\"\n\\begin{x}"
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want []string
+	}{
+		{"defaults", Config{}, []string{"info ellipsis", "warning wrong-quotes", "warning wrong-quotes", "error unbalanced-environments"}},
+		{"rule off", Config{Severities: map[string]string{"wrong-quotes": SeverityOff}}, []string{"info ellipsis", "error unbalanced-environments"}},
+		{"severity override", Config{Severities: map[string]string{"ellipsis": SeverityError, "unbalanced-environments": SeverityInfo}}, []string{"error ellipsis", "warning wrong-quotes", "warning wrong-quotes", "info unbalanced-environments"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, w := range Lint(content, tt.cfg) {
+				got = append(got, w.Severity+" "+w.Rule)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lint() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintPositions(t *testing.T) {
+	warnings := Lint("é \"x\"\n\tsee \\ref{a}", Config{})
+	want := []Warning{
+		{Rule: "wrong-quotes", Severity: SeverityWarning, Line: 1, Column: 3, Length: 1, Message: "Use `` for an opening double quote instead of \""},
+		{Rule: "wrong-quotes", Severity: SeverityWarning, Line: 1, Column: 5, Length: 1, Message: "Use '' for a closing double quote instead of \""},
+		{Rule: "missing-tie", Severity: SeverityWarning, Line: 2, Column: 5, Length: 1, Message: `Use ~ before \ref so that it is not separated from the preceding word`},
+	}
+	if !reflect.DeepEqual(warnings, want) {
+		t.Errorf("Lint() = %+v, want %+v", warnings, want)
+	}
+}
+
+func TestLintMaxWarnings(t *testing.T) {
+	if got := len(Lint(strings.Repeat("}", MaxWarnings+10), Config{})); got != MaxWarnings {
+		t.Errorf("got %d warnings, want %d", got, MaxWarnings)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	tests := []struct {
+		name       string
+		severities map[string]string
+		err        string
+	}{
+		{"empty", nil, ""},
+		{"known rules", map[string]string{"ellipsis": SeverityOff, "missing-tie": SeverityError}, ""},
+		{"unknown rule", map[string]string{"no-such-rule": SeverityOff}, "unknown lint rule"},
+		{"invalid severity", map[string]string{"ellipsis": "fatal"}, "invalid lint severity"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Config{Severities: tt.severities}.Validate()
+			switch {
+			case tt.err == "" && err != nil:
+				t.Errorf("Validate() error = %v", err)
+			case tt.err != "" && (err == nil || err.Error() != tt.err):
+				t.Errorf("Validate() error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestConfigFingerprint(t *testing.T) {
+	defaults := Config{}.Fingerprint()
+
+	// Naming a rule with its default severity runs the same rules
+	same := Config{Severities: map[string]string{"ellipsis": SeverityInfo}}.Fingerprint()
+	if same != defaults {
+		t.Errorf("fingerprint changed for a default severity: %q != %q", same, defaults)
+	}
+
+	off := Config{Severities: map[string]string{"ellipsis": SeverityOff}}.Fingerprint()
+	raised := Config{Severities: map[string]string{"ellipsis": SeverityError}}.Fingerprint()
+	if off == defaults || raised == defaults || off == raised {
+		t.Errorf("fingerprints do not tell configurations apart: %q, %q, %q", defaults, off, raised)
+	}
+}
+
+func TestRules(t *testing.T) {
+	seen := map[string]bool{}
+	for _, rule := range Rules() {
+		if seen[rule.ID] {
+			t.Errorf("rule %s is listed twice", rule.ID)
+		}
+		seen[rule.ID] = true
+		if rule.check != nil {
+			t.Errorf("rule %s exposes its check", rule.ID)
+		}
+		if err := (Config{Severities: map[string]string{rule.ID: rule.Severity}}).Validate(); err != nil {
+			t.Errorf("rule %s has default severity %q: %v", rule.ID, rule.Severity, err)
+		}
+	}
+}
@@ -0,0 +1,300 @@
+package lint
+
+import (
+	"fmt"
+	"strings"
+)
+
+// finding is a problem reported by a rule, as a byte range of the source
+type finding struct {
+	offset, length int
+	message        string
+}
+
+// Rules, in the order their warnings are reported on the same spot
+var rules = []*Rule{
+	{
+		ID:          "wrong-quotes",
+		Description: `Straight double quotes and ' as an opening quote, instead of ` + "``" + ` and '' or ` + "`",
+		Severity:    SeverityWarning,
+		check:       checkQuotes,
+	},
+	{
+		ID:          "missing-tie",
+		Description: `A space instead of ~ before \ref or \cite, which allows a line break before the number`,
+		Severity:    SeverityWarning,
+		check:       checkTies,
+	},
+	{
+		ID:          "unbalanced-braces",
+		Description: "A { without a matching } or the reverse",
+		Severity:    SeverityError,
+		check:       checkBraces,
+	},
+	{
+		ID:          "unbalanced-environments",
+		Description: `A \begin without a matching \end or the reverse`,
+		Severity:    SeverityError,
+		check:       checkEnvironments,
+	},
+	{
+		ID:          "display-math-dollars",
+		Description: `Display math in $$ ... $$, which breaks vertical spacing, instead of \[ ... \]`,
+		Severity:    SeverityWarning,
+		check:       checkDisplayDollars,
+	},
+	{
+		ID:          "deprecated-package",
+		Description: "A package that is obsolete and has a better replacement",
+		Severity:    SeverityWarning,
+		check:       checkDeprecatedPackages,
+	},
+	{
+		ID:          "ellipsis",
+		Description: `Three periods instead of \dots`,
+		Severity:    SeverityInfo,
+		check:       checkEllipsis,
+	},
+}
+
+// References whose number should not start a line
+var tieCommands = map[string]bool{
+	"ref": true, "eqref": true, "pageref": true, "vref": true, "cite": true,
+}
+
+// Obsolete packages and what replaces them
+var deprecatedPackages = map[string]string{
+	"a4": "geometry", "a4wide": "geometry", "anysize": "geometry",
+	"epsf": "graphicx", "epsfig": "graphicx", "psfig": "graphicx",
+	"times": "newtxtext and newtxmath", "mathptm": "newtxmath",
+	"pslatex": "newtxtext and newtxmath", "palatino": "newpxtext and newpxmath",
+	"mathpple": "newpxmath", "utopia": "fourier", "ae": "lmodern",
+	"aecompl": "lmodern", "zefonts": "lmodern", "isolatin1": "inputenc",
+	"umlaut": "inputenc", "t1enc": "fontenc", "doublespace": "setspace",
+	"fancyheadings": "fancyhdr", "scrpage2": "scrlayer-scrpage",
+	"subfigure": "subcaption", "caption2": "caption", "glossary": "glossaries",
+	"here": "float", "picins": "wrapfig",
+}
+
+func checkQuotes(d *document) []finding {
+	var findings []finding
+	src := d.src
+	for i := 0; i < len(src); i++ {
+		if !d.text(i) || (i > 0 && src[i-1] == '\\') {
+			continue
+		}
+		prev, next := byteAt(src, i-1), byteAt(src, i+1)
+
+		switch src[i] {
+		case '"':
+			// Babel shorthands such as "a sit inside words
+			if isWordByte(prev) && isWordByte(next) {
+				continue
+			}
+			if opensQuote(prev) {
+				findings = append(findings, finding{i, 1, "Use `` for an opening double quote instead of \""})
+			} else {
+				findings = append(findings, finding{i, 1, "Use '' for a closing double quote instead of \""})
+			}
+		case '\'':
+			if !opensQuote(prev) {
+				continue
+			}
+			if next == '\'' {
+				findings = append(findings, finding{i, 2, "Use `` for an opening double quote instead of ''"})
+				i++
+			} else if isWordByte(next) {
+				findings = append(findings, finding{i, 1, "Use ` for an opening single quote instead of '"})
+			}
+		}
+	}
+	return findings
+}
+
+// opensQuote reports whether a quote after a byte starts a quotation
+func opensQuote(prev byte) bool {
+	return prev == 0 || strings.IndexByte(" \t\n([{~", prev) >= 0
+}
+
+func checkTies(d *document) []finding {
+	var findings []finding
+	d.commands(func(name string, start, end int) {
+		if !tieCommands[name] || !d.text(start) {
+			return
+		}
+
+		// The space before the command must follow a word on the same
+		// paragraph
+		k := start - 1
+		newlines := 0
+		for k >= 0 && strings.IndexByte(" \t\r\n", d.src[k]) >= 0 {
+			if d.src[k] == '\n' {
+				newlines++
+			}
+			k--
+		}
+		if k == start-1 || k < 0 || newlines > 1 || !isWordByte(d.src[k]) {
+			return
+		}
+
+		findings = append(findings, finding{
+			k + 1, start - k - 1,
+			fmt.Sprintf(`Use ~ before \%s so that it is not separated from the preceding word`, name),
+		})
+	})
+	return findings
+}
+
+func checkBraces(d *document) []finding {
+	var findings []finding
+	var open []int
+	src := d.src
+	for i := 0; i < len(src); i++ {
+		if d.class[i]&(classComment|classVerbatim) != 0 {
+			continue
+		}
+		switch src[i] {
+		case '\\':
+			i++
+		case '{':
+			open = append(open, i)
+		case '}':
+			if len(open) == 0 {
+				findings = append(findings, finding{i, 1, "Unmatched }"})
+				continue
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	for _, i := range open {
+		findings = append(findings, finding{i, 1, "Unclosed {"})
+	}
+	return findings
+}
+
+func checkEnvironments(d *document) []finding {
+	type environment struct {
+		name       string
+		start, end int
+	}
+	var findings []finding
+	var open []environment
+
+	unclosed := func(env environment) {
+		findings = append(findings, finding{
+			env.start, env.end - env.start,
+			fmt.Sprintf(`\begin{%s} is never closed`, env.name),
+		})
+	}
+
+	d.commands(func(name string, start, end int) {
+		if (name != "begin" && name != "end") || !d.code(start) {
+			return
+		}
+		env, argEnd := groupArgument(d.src, end)
+		if env == "" {
+			return
+		}
+
+		if name == "begin" {
+			open = append(open, environment{env, start, argEnd})
+			return
+		}
+
+		for k := len(open) - 1; k >= 0; k-- {
+			if open[k].name != env {
+				continue
+			}
+			for _, inner := range open[k+1:] {
+				unclosed(inner)
+			}
+			open = open[:k]
+			return
+		}
+		findings = append(findings, finding{
+			start, argEnd - start,
+			fmt.Sprintf(`\end{%s} has no matching \begin{%s}`, env, env),
+		})
+	})
+
+	for _, env := range open {
+		unclosed(env)
+	}
+	return findings
+}
+
+func checkDisplayDollars(d *document) []finding {
+	findings := make([]finding, 0, len(d.displayDollars))
+	for _, i := range d.displayDollars {
+		findings = append(findings, finding{i, 2, `Use \[ ... \] instead of $$ ... $$ for display math`})
+	}
+	return findings
+}
+
+func checkDeprecatedPackages(d *document) []finding {
+	var findings []finding
+	d.commands(func(name string, start, end int) {
+		if (name != "usepackage" && name != "RequirePackage") || !d.code(start) {
+			return
+		}
+
+		src := d.src
+		i := end
+		for i < len(src) && strings.IndexByte(" \t\n", src[i]) >= 0 {
+			i++
+		}
+		if i < len(src) && src[i] == '[' {
+			bracket := strings.IndexByte(src[i:], ']')
+			if bracket < 0 {
+				return
+			}
+			i += bracket + 1
+		}
+		for i < len(src) && strings.IndexByte(" \t\n", src[i]) >= 0 {
+			i++
+		}
+		if i >= len(src) || src[i] != '{' {
+			return
+		}
+		brace := strings.IndexByte(src[i:], '}')
+		if brace < 0 {
+			return
+		}
+
+		// Packages are separated by commas, possibly with spaces
+		offset := i + 1
+		for _, part := range strings.Split(src[i+1:i+brace], ",") {
+			pkg := strings.TrimSpace(part)
+			if replacement, ok := deprecatedPackages[pkg]; ok {
+				findings = append(findings, finding{
+					offset + strings.Index(part, pkg), len(pkg),
+					fmt.Sprintf("Package %s is obsolete; use %s instead", pkg, replacement),
+				})
+			}
+			offset += len(part) + 1
+		}
+	})
+	return findings
+}
+
+func checkEllipsis(d *document) []finding {
+	var findings []finding
+	src := d.src
+	for i := 0; i+2 < len(src); i++ {
+		if src[i] == '.' && src[i+1] == '.' && src[i+2] == '.' && d.text(i) && d.text(i+2) {
+			findings = append(findings, finding{i, 3, `Use \dots instead of ...`})
+			for i+1 < len(src) && src[i+1] == '.' {
+				i++
+			}
+		}
+	}
+	return findings
+}
+
+// byteAt returns the byte at an offset, or 0 outside the source
+func byteAt(src string, i int) byte {
+	if i < 0 || i >= len(src) {
+		return 0
+	}
+	return src[i]
+}
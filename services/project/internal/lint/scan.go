@@ -0,0 +1,472 @@
+package lint
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Classes of the bytes of a document. Text has no class bits set.
+const (
+	classComment uint8 = 1 << iota
+	classVerbatim
+	classMath
+	classDefinition
+)
+
+// Environments whose content is not LaTeX
+var verbatimEnvironments = map[string]bool{
+	"verbatim": true, "verbatim*": true, "Verbatim": true, "lstlisting": true,
+	"minted": true, "comment": true, "filecontents": true, "filecontents*": true,
+}
+
+// Environments typeset in math mode
+var mathEnvironments = map[string]bool{
+	"math": true, "displaymath": true, "equation": true, "equation*": true,
+	"align": true, "align*": true, "alignat": true, "alignat*": true,
+	"flalign": true, "flalign*": true, "gather": true, "gather*": true,
+	"multline": true, "multline*": true, "eqnarray": true, "eqnarray*": true,
+}
+
+// Commands whose arguments are a URL or path rather than LaTeX
+var urlCommands = map[string]bool{
+	"url": true, "path": true, "href": true, "includegraphics": true,
+}
+
+// Commands that define macros or environments, with the number of their
+// mandatory arguments counting the name. The arguments hold fragments such
+// as a lone \begin, which are only checked where used.
+var definitionCommands = map[string]int{
+	"def": 2, "gdef": 2, "edef": 2, "xdef": 2,
+	"newcommand": 2, "renewcommand": 2, "providecommand": 2, "DeclareRobustCommand": 2,
+	"newenvironment": 3, "renewenvironment": 3,
+	"NewDocumentCommand": 3, "RenewDocumentCommand": 3, "ProvideDocumentCommand": 3,
+	"NewDocumentEnvironment": 4, "RenewDocumentEnvironment": 4,
+}
+
+// Math modes
+const (
+	mathNone = iota
+	mathDollar
+	mathDisplayDollar
+	mathParen
+	mathBracket
+	mathEnvironment
+)
+
+// document is a source file with the class of each byte worked out once for
+// all rules
+type document struct {
+	src        string
+	class      []uint8
+	lineStarts []int
+
+	// Offsets of the $$ that open display math
+	displayDollars []int
+
+	suppressions suppressions
+}
+
+// analyze classifies the bytes of a source file: comments, verbatim text,
+// math and macro definitions. Suppression comments are collected on the
+// way.
+func analyze(src string) *document {
+	d := &document{
+		src:        src,
+		class:      make([]uint8, len(src)),
+		lineStarts: []int{0},
+	}
+	for i := 0; i < len(src); i++ {
+		if src[i] == '\n' {
+			d.lineStarts = append(d.lineStarts, i+1)
+		}
+	}
+
+	mode, mathEnv := mathNone, ""
+	mark := func(from, to int, class uint8) {
+		if mode != mathNone {
+			class |= classMath
+		}
+		for k := from; k < to && k < len(src); k++ {
+			d.class[k] = class
+		}
+	}
+
+	n := len(src)
+	for i := 0; i < n; {
+		c := src[i]
+		switch {
+		case c == '%':
+			end := strings.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = n
+			} else {
+				end += i
+			}
+			for k := i; k < end; k++ {
+				d.class[k] = classComment
+			}
+			d.suppressions.parse(src[i+1:end], d.line(i))
+			i = end
+
+		case c == '$':
+			if i+1 < n && src[i+1] == '$' {
+				switch mode {
+				case mathNone:
+					mode = mathDisplayDollar
+					d.displayDollars = append(d.displayDollars, i)
+					mark(i, i+2, 0)
+				case mathDisplayDollar:
+					mark(i, i+2, 0)
+					mode = mathNone
+				default:
+					mark(i, i+2, 0)
+				}
+				i += 2
+				continue
+			}
+			switch mode {
+			case mathNone:
+				mode = mathDollar
+				mark(i, i+1, 0)
+			case mathDollar:
+				mark(i, i+1, 0)
+				mode = mathNone
+			default:
+				mark(i, i+1, 0)
+			}
+			i++
+
+		case c == '\\' && i+1 < n && !isLetter(src[i+1]):
+			// A control symbol such as \%, \{ or a math delimiter
+			switch {
+			case src[i+1] == '(' && mode == mathNone:
+				mode = mathParen
+				mark(i, i+2, 0)
+			case src[i+1] == '[' && mode == mathNone:
+				mode = mathBracket
+				mark(i, i+2, 0)
+			case src[i+1] == ')' && mode == mathParen, src[i+1] == ']' && mode == mathBracket:
+				mark(i, i+2, 0)
+				mode = mathNone
+			default:
+				mark(i, i+2, 0)
+			}
+			i += 2
+
+		case c == '\\' && i+1 < n:
+			j := i + 1
+			for j < n && isLetter(src[j]) {
+				j++
+			}
+			name := src[i+1 : j]
+
+			switch {
+			case name == "verb":
+				k := j
+				if k < n && src[k] == '*' {
+					k++
+				}
+				if k < n && src[k] != '\n' {
+					end := strings.IndexAny(src[k+1:], string(src[k])+"\n")
+					if end >= 0 && src[k+1+end] == src[k] {
+						mark(i, k+1, 0)
+						mark(k+1, k+1+end, classVerbatim)
+						mark(k+1+end, k+2+end, 0)
+						i = k + 2 + end
+						continue
+					}
+				}
+
+			case name == "begin" || name == "end":
+				env, argEnd := groupArgument(src, j)
+				if env == "" {
+					break
+				}
+				switch {
+				case name == "begin" && verbatimEnvironments[env]:
+					mark(i, argEnd, 0)
+					closer := "\\end{" + env + "}"
+					end := strings.Index(src[argEnd:], closer)
+					if end < 0 {
+						end = n
+					} else {
+						end += argEnd
+					}
+					mark(argEnd, end, classVerbatim)
+					i = end
+					continue
+				case name == "begin" && mathEnvironments[env] && mode == mathNone:
+					mark(i, argEnd, 0)
+					mode, mathEnv = mathEnvironment, env
+					i = argEnd
+					continue
+				case name == "end" && mode == mathEnvironment && env == mathEnv:
+					mode, mathEnv = mathNone, ""
+					mark(i, argEnd, 0)
+					i = argEnd
+					continue
+				}
+
+			case urlCommands[name]:
+				k := j
+				if k < n && src[k] == '[' {
+					if end := strings.IndexByte(src[k:], ']'); end >= 0 {
+						k += end + 1
+					}
+				}
+				if k < n && src[k] == '{' {
+					if end := strings.IndexByte(src[k:], '}'); end >= 0 {
+						mark(i, k+1, 0)
+						mark(k+1, k+end, classVerbatim)
+						mark(k+end, k+end+1, 0)
+						i = k + end + 1
+						continue
+					}
+				}
+
+			case definitionCommands[name] > 0:
+				end := definitionEnd(src, j, definitionCommands[name])
+				mark(i, end, classDefinition)
+				i = end
+				continue
+			}
+
+			mark(i, j, 0)
+			i = j
+
+		default:
+			mark(i, i+1, 0)
+			i++
+		}
+	}
+
+	return d
+}
+
+// groupArgument reads a {name} argument starting at offset i, returning the
+// name and the offset after the closing brace
+func groupArgument(src string, i int) (string, int) {
+	for i < len(src) && (src[i] == ' ' || src[i] == '\t') {
+		i++
+	}
+	if i >= len(src) || src[i] != '{' {
+		return "", i
+	}
+	end := strings.IndexAny(src[i+1:], "}\n")
+	if end < 0 || src[i+1+end] != '}' {
+		return "", i
+	}
+	return strings.TrimSpace(src[i+1 : i+1+end]), i + end + 2
+}
+
+// definitionEnd skips the arguments of a definition command: the name of
+// the macro, given as a group or a control sequence, optional arguments,
+// parameter text and the remaining groups
+func definitionEnd(src string, i, args int) int {
+	for done := 0; done < args; {
+		j := i
+		for j < len(src) && strings.IndexByte(" \t\n", src[j]) >= 0 {
+			j++
+		}
+		if j >= len(src) {
+			return j
+		}
+
+		switch c := src[j]; {
+		case c == '{':
+			i = balancedEnd(src, j)
+			done++
+		case c == '[':
+			end := strings.IndexByte(src[j:], ']')
+			if end < 0 {
+				return j
+			}
+			i = j + end + 1
+		case c == '*' || c == '#' || c >= '0' && c <= '9':
+			i = j + 1
+		case c == '\\' && j+1 < len(src) && done == 0:
+			// The name of the macro defined, as in \def\name or
+			// \newcommand\name
+			k := j + 1
+			for k < len(src) && isLetter(src[k]) {
+				k++
+			}
+			if k == j+1 {
+				k++
+			}
+			i = k
+			done++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// balancedEnd returns the offset after the brace closing the one at i, or
+// the end of the source if it is never closed
+func balancedEnd(src string, i int) int {
+	depth := 0
+	for ; i < len(src); i++ {
+		switch src[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(src)
+}
+
+// line returns the 1-based line of an offset
+func (d *document) line(offset int) int {
+	return sort.Search(len(d.lineStarts), func(i int) bool { return d.lineStarts[i] > offset })
+}
+
+// position returns the 1-based line and column, in characters, of an offset
+func (d *document) position(offset int) (int, int) {
+	line := d.line(offset)
+	return line, utf8.RuneCountInString(d.src[d.lineStarts[line-1]:offset]) + 1
+}
+
+// text reports whether a byte is plain text outside math
+func (d *document) text(i int) bool {
+	return d.class[i] == 0
+}
+
+// code reports whether a byte is LaTeX that is typeset or executed, as
+// opposed to comments, verbatim text and macro definitions
+func (d *document) code(i int) bool {
+	return d.class[i]&(classComment|classVerbatim|classDefinition) == 0
+}
+
+// commands calls fn with the name and offsets of each control word outside
+// comments and verbatim text
+func (d *document) commands(fn func(name string, start, end int)) {
+	src := d.src
+	for i := 0; i < len(src); i++ {
+		if src[i] != '\\' || d.class[i]&(classComment|classVerbatim) != 0 {
+			continue
+		}
+		j := i + 1
+		for j < len(src) && isLetter(src[j]) {
+			j++
+		}
+		if j == i+1 {
+			// A control symbol such as \\ or \%
+			i++
+			continue
+		}
+		fn(src[i+1:j], i, j)
+		i = j - 1
+	}
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isWordByte(c byte) bool {
+	return isLetter(c) || c >= '0' && c <= '9' || c >= 0x80
+}
+
+// suppressions records the rules turned off by comments:
+//
+//	% lint-disable-line missing-tie
+//	% lint-disable-next-line wrong-quotes
+//	% lint-disable unbalanced-braces
+//	% lint-enable unbalanced-braces
+//
+// Without rule names they apply to all rules.
+type suppressions struct {
+	lines   map[int]map[string]bool
+	regions []region
+	open    map[string]int
+}
+
+// region is a range of lines, end excluded, where a rule is off. An open
+// region has no end.
+type region struct {
+	rule       string
+	start, end int
+}
+
+const allRules = "*"
+
+func (s *suppressions) parse(comment string, line int) {
+	fields := strings.FieldsFunc(comment, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ',' || r == '\r'
+	})
+	if len(fields) == 0 {
+		return
+	}
+	rules := fields[1:]
+	if len(rules) == 0 {
+		rules = []string{allRules}
+	}
+
+	switch fields[0] {
+	case "lint-disable-line":
+		s.disableLine(line, rules)
+	case "lint-disable-next-line":
+		s.disableLine(line+1, rules)
+	case "lint-disable":
+		if s.open == nil {
+			s.open = map[string]int{}
+		}
+		for _, rule := range rules {
+			if _, ok := s.open[rule]; !ok {
+				s.open[rule] = line
+			}
+		}
+	case "lint-enable":
+		if len(fields) == 1 {
+			rules = rules[:0]
+			for rule := range s.open {
+				rules = append(rules, rule)
+			}
+		}
+		for _, rule := range rules {
+			if start, ok := s.open[rule]; ok {
+				s.regions = append(s.regions, region{rule: rule, start: start, end: line})
+				delete(s.open, rule)
+			}
+		}
+	}
+}
+
+func (s *suppressions) disableLine(line int, rules []string) {
+	if s.lines == nil {
+		s.lines = map[int]map[string]bool{}
+	}
+	if s.lines[line] == nil {
+		s.lines[line] = map[string]bool{}
+	}
+	for _, rule := range rules {
+		s.lines[line][rule] = true
+	}
+}
+
+// suppressed reports whether a rule is off on a line
+func (s *suppressions) suppressed(rule string, line int) bool {
+	if off := s.lines[line]; off[rule] || off[allRules] {
+		return true
+	}
+	for _, r := range s.regions {
+		if (r.rule == rule || r.rule == allRules) && line >= r.start && line < r.end {
+			return true
+		}
+	}
+	for r, start := range s.open {
+		if (r == rule || r == allRules) && line >= start {
+			return true
+		}
+	}
+	return false
+}
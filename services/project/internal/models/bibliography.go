@@ -0,0 +1,55 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BibliographyEntry is an entry of one of a project's .bib files, with its
+// fields as plain text
+type BibliographyEntry struct {
+	FileID primitive.ObjectID `json:"file_id"`
+	File   string             `json:"file"`
+	Type   string             `json:"type"`
+	Key    string             `json:"key"`
+	Line   int                `json:"line"`
+	Fields map[string]string  `json:"fields"`
+}
+
+// NormalizeBibliographyRequest controls how a .bib file is normalized. The
+// key style defaults to the project's.
+type NormalizeBibliographyRequest struct {
+	KeyStyle *string `json:"key_style" binding:"omitempty,oneof=keep author_year author_year_title"`
+	Sort     bool    `json:"sort"`
+}
+
+// NormalizeBibliographyResponse is the normalized file along with the keys
+// that were changed, old to new. Citations of renamed keys are not updated.
+type NormalizeBibliographyResponse struct {
+	File        *File             `json:"file"`
+	Suggestions []*Suggestion     `json:"suggestions,omitempty"`
+	Renamed     map[string]string `json:"renamed"`
+}
+
+// ImportBibliographyRequest adds pasted references to a .bib file: the one
+// given, else the project's only one, else a new references.bib. The format
+// is detected when not given.
+type ImportBibliographyRequest struct {
+	FileID   string  `json:"file_id"`
+	Format   string  `json:"format" binding:"omitempty,oneof=bibtex ris csl-json"`
+	Content  string  `json:"content" binding:"required"`
+	KeyStyle *string `json:"key_style" binding:"omitempty,oneof=keep author_year author_year_title"`
+}
+
+// SkippedBibliographyEntry is an imported entry that was not added
+type SkippedBibliographyEntry struct {
+	Key    string `json:"key"`
+	Reason string `json:"reason"`
+}
+
+// ImportBibliographyResponse lists the keys of the imported entries
+type ImportBibliographyResponse struct {
+	File        *File                      `json:"file"`
+	Suggestions []*Suggestion              `json:"suggestions,omitempty"`
+	Imported    []string                   `json:"imported"`
+	Skipped     []SkippedBibliographyEntry `json:"skipped"`
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Comment thread states
+const (
+	CommentThreadOpen     = "open"
+	CommentThreadResolved = "resolved"
+)
+
+// Comment thread event actions
+const (
+	CommentActionCreated      = "created"
+	CommentActionReplied      = "replied"
+	CommentActionEdited       = "edited"
+	CommentActionReplyDeleted = "reply_deleted"
+	CommentActionResolved     = "resolved"
+	CommentActionReopened     = "reopened"
+	CommentActionDeleted      = "deleted"
+)
+
+// CommentAnchor is the range of text a thread is about. Start and End are
+// character offsets into the file, moved along with every saved edit. When
+// the file is edited through the collaboration service the client also
+// stores Yjs relative positions, which follow concurrent edits exactly and
+// take precedence over the offsets.
+type CommentAnchor struct {
+	Start    int    `bson:"start" json:"start"`
+	End      int    `bson:"end" json:"end"`
+	YjsStart string `bson:"yjs_start,omitempty" json:"yjs_start,omitempty"` // Base64 encoded Y.RelativePosition
+	YjsEnd   string `bson:"yjs_end,omitempty" json:"yjs_end,omitempty"`
+}
+
+// Comment is one message in a thread
+type Comment struct {
+	ID         primitive.ObjectID   `bson:"_id" json:"id"`
+	AuthorID   primitive.ObjectID   `bson:"author_id" json:"author_id"`
+	AuthorName string               `bson:"author_name" json:"author_name"`
+	Body       string               `bson:"body" json:"body"`
+	Mentions   []primitive.ObjectID `bson:"mentions,omitempty" json:"mentions,omitempty"`
+	CreatedAt  time.Time            `bson:"created_at" json:"created_at"`
+	EditedAt   *time.Time           `bson:"edited_at,omitempty" json:"edited_at,omitempty"`
+}
+
+// CommentThread is a discussion anchored to a range of a file. Resolved
+// threads are kept, read-only, as a record of the review.
+type CommentThread struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ProjectID  primitive.ObjectID  `bson:"project_id" json:"project_id"`
+	FileID     primitive.ObjectID  `bson:"file_id" json:"file_id"`
+	Anchor     CommentAnchor       `bson:"anchor" json:"anchor"`
+	Quote      string              `bson:"quote" json:"quote"` // Text of the range when the thread was opened
+	Status     string              `bson:"status" json:"status"`
+	Comments   []Comment           `bson:"comments" json:"comments"`
+	CreatedBy  primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	ResolvedBy *primitive.ObjectID `bson:"resolved_by,omitempty" json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time          `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// CommentAnchorRequest is the range a new thread is anchored to
+type CommentAnchorRequest struct {
+	Start    int    `json:"start" binding:"min=0"`
+	End      int    `json:"end" binding:"min=0"`
+	YjsStart string `json:"yjs_start"`
+	YjsEnd   string `json:"yjs_end"`
+}
+
+// CreateCommentThreadRequest represents a request to open a thread
+type CreateCommentThreadRequest struct {
+	FileID string               `json:"file_id" binding:"required"`
+	Anchor CommentAnchorRequest `json:"anchor"`
+	Body   string               `json:"body" binding:"required,max=10000"`
+}
+
+// CommentRequest represents a request to reply to a thread or edit a comment
+type CommentRequest struct {
+	Body string `json:"body" binding:"required,max=10000"`
+}
+
+// CommentThreadEvent is broadcast to the clients connected to a project when
+// a thread changes. Thread is omitted when it was deleted.
+type CommentThreadEvent struct {
+	Action   string             `json:"action"`
+	ThreadID primitive.ObjectID `json:"thread_id"`
+	FileID   primitive.ObjectID `json:"file_id"`
+	Thread   *CommentThread     `json:"thread,omitempty"`
+}
+
+// CommentMentionEvent is broadcast when a comment mentions project members
+type CommentMentionEvent struct {
+	UserIDs   []primitive.ObjectID `json:"user_ids"`
+	ThreadID  primitive.ObjectID   `json:"thread_id"`
+	CommentID primitive.ObjectID   `json:"comment_id"`
+	FileID    primitive.ObjectID   `json:"file_id"`
+	Excerpt   string               `json:"excerpt"`
+}
@@ -0,0 +1,19 @@
+package models
+
+// MoveUnusedFilesRequest represents a request to move unused files into the
+// unused folder. Without paths every unused file is moved.
+type MoveUnusedFilesRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// SkippedFile is a file that a bulk action left alone
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// MoveUnusedFilesResponse lists the files moved into the unused folder
+type MoveUnusedFilesResponse struct {
+	Moved   []*File       `json:"moved"`
+	Skipped []SkippedFile `json:"skipped"`
+}
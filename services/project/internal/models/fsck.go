@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Storage check issue kinds
+const (
+	// FsckOrphanedObject is an object that no record refers to: a project
+	// file without a file record, or anything left behind by a deleted
+	// project or upload
+	FsckOrphanedObject = "orphaned_object"
+	// FsckMissingObject is a file record whose content is not in storage
+	FsckMissingObject = "missing_object"
+	// FsckHashMismatch is a file record whose size or hash differs from the
+	// stored content
+	FsckHashMismatch = "hash_mismatch"
+)
+
+// Storage check states
+const (
+	FsckStatusRunning   = "running"
+	FsckStatusCompleted = "completed"
+	FsckStatusFailed    = "failed"
+)
+
+// FsckIssue is an inconsistency between object storage and the database
+type FsckIssue struct {
+	Kind        string              `bson:"kind" json:"kind"`
+	ProjectID   *primitive.ObjectID `bson:"project_id,omitempty" json:"project_id,omitempty"`
+	FileID      *primitive.ObjectID `bson:"file_id,omitempty" json:"file_id,omitempty"`
+	Path        string              `bson:"path,omitempty" json:"path,omitempty"`
+	StorageKey  string              `bson:"storage_key" json:"storage_key"`
+	Detail      string              `bson:"detail,omitempty" json:"detail,omitempty"`
+	Repaired    bool                `bson:"repaired" json:"repaired"`
+	RepairError string              `bson:"repair_error,omitempty" json:"repair_error,omitempty"`
+}
+
+// FsckReport is the outcome of one storage check. Only the first issues are
+// kept; Summary counts all of them by kind.
+type FsckReport struct {
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Status          string              `bson:"status" json:"status"`
+	Trigger         string              `bson:"trigger" json:"trigger"`
+	RequestedBy     *primitive.ObjectID `bson:"requested_by,omitempty" json:"requested_by,omitempty"`
+	ProjectID       *primitive.ObjectID `bson:"project_id,omitempty" json:"project_id,omitempty"`
+	DryRun          bool                `bson:"dry_run" json:"dry_run"`
+	VerifyHashes    bool                `bson:"verify_hashes" json:"verify_hashes"`
+	ProjectsChecked int                 `bson:"projects_checked" json:"projects_checked"`
+	FilesChecked    int                 `bson:"files_checked" json:"files_checked"`
+	ObjectsChecked  int                 `bson:"objects_checked" json:"objects_checked"`
+	Summary         map[string]int      `bson:"summary" json:"summary"`
+	Repaired        int                 `bson:"repaired" json:"repaired"`
+	Issues          []FsckIssue         `bson:"issues" json:"issues"`
+	Truncated       bool                `bson:"truncated,omitempty" json:"truncated,omitempty"`
+	Error           string              `bson:"error,omitempty" json:"error,omitempty"`
+	StartedAt       time.Time           `bson:"started_at" json:"started_at"`
+	FinishedAt      *time.Time          `bson:"finished_at,omitempty" json:"finished_at,omitempty"`
+}
+
+// RunFsckRequest represents a request to check storage consistency. The
+// check only reports unless Repair is set.
+type RunFsckRequest struct {
+	ProjectID    string `json:"project_id"`
+	Repair       bool   `json:"repair"`
+	VerifyHashes bool   `json:"verify_hashes"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// GitRepo holds the Git state of a project: the head of its single branch
+// and the index the head was built from
+type GitRepo struct {
+	ProjectID   primitive.ObjectID `bson:"_id" json:"project_id"`
+	Head        string             `bson:"head,omitempty" json:"head,omitempty"`
+	Index       []GitIndexEntry    `bson:"index" json:"-"`
+	LockedUntil *time.Time         `bson:"locked_until,omitempty" json:"-"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// GitIndexEntry maps a file path to the blob committed for it. Hash is the
+// SHA-256 content hash also stored on the file record, so that changed files
+// are found without reading them.
+type GitIndexEntry struct {
+	Path string `bson:"path"`
+	Blob string `bson:"blob"`
+	Hash string `bson:"hash"`
+}
+
+// GitPendingChange records who last changed a path in the web editor since
+// the last commit. A path ending in "/" covers a whole folder.
+type GitPendingChange struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	ProjectID primitive.ObjectID `bson:"project_id"`
+	Path      string             `bson:"path"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	ChangedAt time.Time          `bson:"changed_at"`
+}
+
+// User is the part of an account record used for commit attribution
+type User struct {
+	ID       primitive.ObjectID `bson:"_id"`
+	Email    string             `bson:"email"`
+	Username string             `bson:"username"`
+	FullName string             `bson:"full_name"`
+}
+
+// PersonalAccessToken is a token issued by the auth service, looked up by
+// the SHA-256 hash of its value
+type PersonalAccessToken struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	UserID    primitive.ObjectID `bson:"user_id"`
+	ExpiresAt *time.Time         `bson:"expires_at,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Invitation is a pending, accepted or declined request for a user to
+// collaborate on a project. It is addressed by email so that people without
+// an account can be invited; InviteeID is set once the email is known to
+// belong to a user.
+type Invitation struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ProjectID   primitive.ObjectID  `bson:"project_id" json:"project_id"`
+	ProjectName string              `bson:"project_name" json:"project_name"`
+	InviterID   primitive.ObjectID  `bson:"inviter_id" json:"inviter_id"`
+	Email       string              `bson:"email" json:"email"`
+	InviteeID   *primitive.ObjectID `bson:"invitee_id,omitempty" json:"invitee_id,omitempty"`
+	Role        string              `bson:"role" json:"role"` // editor, viewer
+	Status      string              `bson:"status" json:"status"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	RespondedAt *time.Time          `bson:"responded_at,omitempty" json:"responded_at,omitempty"`
+}
+
+// Invitation statuses
+const (
+	InvitationStatusPending  = "pending"
+	InvitationStatusAccepted = "accepted"
+	InvitationStatusDeclined = "declined"
+)
+
+// CollaboratorInfo is a collaborator together with the user's profile
+type CollaboratorInfo struct {
+	Collaborator
+	Email    string `json:"email,omitempty"`
+	Username string `json:"username,omitempty"`
+	FullName string `json:"full_name,omitempty"`
+}
+
+// InviteCollaboratorRequest represents a request to invite someone by email
+type InviteCollaboratorRequest struct {
+	Email string `json:"email" binding:"required,email"`
+	Role  string `json:"role" binding:"required,oneof=editor viewer"`
+}
+
+// UpdateCollaboratorRequest represents a request to change a collaborator's role
+type UpdateCollaboratorRequest struct {
+	Role string `json:"role" binding:"required,oneof=editor viewer"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/texflow/services/project/internal/lint"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LintResult holds the warnings of the last lint of a file, with the
+// content and rules it was computed for
+type LintResult struct {
+	FileID    primitive.ObjectID `bson:"_id" json:"file_id"`
+	ProjectID primitive.ObjectID `bson:"project_id" json:"project_id"`
+	Path      string             `bson:"-" json:"path"`
+	Hash      string             `bson:"hash" json:"-"`
+	Config    string             `bson:"config" json:"-"`
+	Warnings  []lint.Warning     `bson:"warnings" json:"warnings"`
+	LintedAt  time.Time          `bson:"linted_at" json:"linted_at"`
+}
+
+// LintRule is a lint rule with the severity a project gives it
+type LintRule struct {
+	lint.Rule
+	DefaultSeverity string `json:"default_severity"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Notification types
+const (
+	NotificationCommentMention = "comment_mention"
+)
+
+// Notification tells a user about something that involves them, such as a
+// comment mentioning them
+type Notification struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Type      string             `bson:"type" json:"type"`
+	ProjectID primitive.ObjectID `bson:"project_id" json:"project_id"`
+	FileID    primitive.ObjectID `bson:"file_id" json:"file_id"`
+	ThreadID  primitive.ObjectID `bson:"thread_id" json:"thread_id"`
+	CommentID primitive.ObjectID `bson:"comment_id" json:"comment_id"`
+	ActorID   primitive.ObjectID `bson:"actor_id" json:"actor_id"`
+	ActorName string             `bson:"actor_name" json:"actor_name"`
+	Excerpt   string             `bson:"excerpt" json:"excerpt"`
+	ReadAt    *time.Time         `bson:"read_at,omitempty" json:"read_at,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// MarkNotificationsReadRequest lists the notifications to mark as read.
+// Without IDs every notification of the user is marked.
+type MarkNotificationsReadRequest struct {
+	IDs []string `json:"ids"`
+}
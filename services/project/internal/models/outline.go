@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"github.com/texflow/services/project/internal/outline"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutlineDocument is the parsed structure of a .tex or .bib file
+type OutlineDocument struct {
+	FileID    primitive.ObjectID `bson:"_id"`
+	ProjectID primitive.ObjectID `bson:"project_id"`
+	Path      string             `bson:"path"`
+	Hash      string             `bson:"hash"`
+	Index     *outline.FileIndex `bson:"index"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
@@ -8,22 +9,35 @@ import (
 
 // Project represents a LaTeX project
 type Project struct {
-	ID              primitive.ObjectID `bson:"_id,omitempty" json:"id"`
-	Name            string             `bson:"name" json:"name"`
-	Description     string             `bson:"description,omitempty" json:"description,omitempty"`
-	OwnerID         primitive.ObjectID `bson:"owner_id" json:"owner_id"`
-	Collaborators   []Collaborator     `bson:"collaborators,omitempty" json:"collaborators,omitempty"`
-	Settings        ProjectSettings    `bson:"settings" json:"settings"`
-	CreatedAt       time.Time          `bson:"created_at" json:"created_at"`
-	UpdatedAt       time.Time          `bson:"updated_at" json:"updated_at"`
-	LastCompiledAt  *time.Time         `bson:"last_compiled_at,omitempty" json:"last_compiled_at,omitempty"`
-	TemplateID      *primitive.ObjectID `bson:"template_id,omitempty" json:"template_id,omitempty"`
-	FileCount       int                `bson:"file_count" json:"file_count"`
-	TotalSizeBytes  int64              `bson:"total_size_bytes" json:"total_size_bytes"`
-	IsPublic        bool               `bson:"is_public" json:"is_public"`
-	Tags            []string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	ID               primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name             string              `bson:"name" json:"name"`
+	Description      string              `bson:"description,omitempty" json:"description,omitempty"`
+	OwnerID          primitive.ObjectID  `bson:"owner_id" json:"owner_id"`
+	Collaborators    []Collaborator      `bson:"collaborators,omitempty" json:"collaborators,omitempty"`
+	Settings         ProjectSettings     `bson:"settings" json:"settings"`
+	CreatedAt        time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt        time.Time           `bson:"updated_at" json:"updated_at"`
+	LastCompiledAt   *time.Time          `bson:"last_compiled_at,omitempty" json:"last_compiled_at,omitempty"`
+	ArchivedAt       *time.Time          `bson:"archived_at,omitempty" json:"archived_at,omitempty"`
+	DeletedAt        *time.Time          `bson:"deleted_at,omitempty" json:"deleted_at,omitempty"`
+	PurgeAt          *time.Time          `bson:"purge_at,omitempty" json:"purge_at,omitempty"`
+	SearchIndexedAt  *time.Time          `bson:"search_indexed_at,omitempty" json:"-"`
+	OutlineIndexedAt *time.Time          `bson:"outline_indexed_at,omitempty" json:"-"`
+	TemplateID       *primitive.ObjectID `bson:"template_id,omitempty" json:"template_id,omitempty"`
+	FileCount        int                 `bson:"file_count" json:"file_count"`
+	TotalSizeBytes   int64               `bson:"total_size_bytes" json:"total_size_bytes"`
+	IsPublic         bool                `bson:"is_public" json:"is_public"`
+	Tags             []string            `bson:"tags,omitempty" json:"tags,omitempty"`
 }
 
+// Project list filters. Archived projects are read-only and trashed
+// projects are purged once their purge time has passed.
+const (
+	ProjectStatusActive   = "active"
+	ProjectStatusArchived = "archived"
+	ProjectStatusTrashed  = "trashed"
+)
+
 // Collaborator represents a project collaborator
 type Collaborator struct {
 	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
@@ -38,6 +52,35 @@ type ProjectSettings struct {
 	MainFile    string `bson:"main_file" json:"main_file"`
 	SpellCheck  bool   `bson:"spell_check" json:"spell_check"`
 	AutoCompile bool   `bson:"auto_compile" json:"auto_compile"`
+
+	// Edits by anyone but the owner are recorded as suggestions for the
+	// owner to accept or reject
+	TrackChanges bool `bson:"track_changes" json:"track_changes"`
+
+	// Key style applied when .bib files are normalized or references
+	// imported: keep, author_year or author_year_title
+	BibKeyStyle string `bson:"bib_key_style,omitempty" json:"bib_key_style,omitempty"`
+
+	// Optional steps run by the compilation service after a successful build
+	PostProcess *PostProcessSettings `bson:"post_process,omitempty" json:"post_process,omitempty"`
+
+	// Rules of the linter run when .tex files are saved
+	Lint *LintSettings `bson:"lint,omitempty" json:"lint,omitempty"`
+}
+
+// LintSettings turns the linter off or overrides the severity of its rules:
+// error, warning, info or off
+type LintSettings struct {
+	Disabled bool              `bson:"disabled" json:"disabled"`
+	Rules    map[string]string `bson:"rules,omitempty" json:"rules,omitempty"`
+}
+
+// PostProcessSettings holds the default PDF post-processing steps for a project
+type PostProcessSettings struct {
+	PDFA      bool `bson:"pdfa" json:"pdfa"`           // PDF/A-2b conversion
+	Linearize bool `bson:"linearize" json:"linearize"` // Fast web view
+	Optimize  bool `bson:"optimize" json:"optimize"`   // Image downsampling
+	ImageDPI  int  `bson:"image_dpi,omitempty" json:"image_dpi,omitempty" binding:"omitempty,min=36,max=1200"`
 }
 
 // File represents a file within a project
@@ -57,6 +100,22 @@ type File struct {
 	Hash        string             `bson:"hash" json:"hash"`
 }
 
+// ETag identifies the saved content of a file for conditional requests
+func (f *File) ETag() string {
+	return fmt.Sprintf(`"%d-%s"`, f.Version, f.Hash)
+}
+
+// Folder represents a folder within a project
+type Folder struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID primitive.ObjectID `bson:"project_id" json:"project_id"`
+	Name      string             `bson:"name" json:"name"`
+	Path      string             `bson:"path" json:"path"`
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
 // CreateProjectRequest represents a request to create a project
 type CreateProjectRequest struct {
 	Name        string   `json:"name" binding:"required,min=1,max=100"`
@@ -64,6 +123,7 @@ type CreateProjectRequest struct {
 	Compiler    string   `json:"compiler" binding:"omitempty,oneof=pdflatex xelatex lualatex"`
 	IsPublic    bool     `json:"is_public"`
 	Tags        []string `json:"tags" binding:"max=10"`
+	TemplateID  string   `json:"template_id"`
 }
 
 // UpdateProjectRequest represents a request to update a project
@@ -74,14 +134,26 @@ type UpdateProjectRequest struct {
 	MainFile    *string  `json:"main_file"`
 	IsPublic    *bool    `json:"is_public"`
 	Tags        []string `json:"tags" binding:"omitempty,max=10"`
+
+	TrackChanges *bool                `json:"track_changes"`
+	BibKeyStyle  *string              `json:"bib_key_style" binding:"omitempty,oneof=keep author_year author_year_title"`
+	PostProcess  *PostProcessSettings `json:"post_process"`
+	Lint         *LintSettings        `json:"lint"`
+}
+
+// DuplicateProjectRequest represents a request to duplicate a project
+type DuplicateProjectRequest struct {
+	Name              string `json:"name" binding:"omitempty,min=1,max=100"`
+	CopyCollaborators bool   `json:"copy_collaborators"`
 }
 
-// CreateFileRequest represents a request to create/upload a file
+// CreateFileRequest represents a request to create a small file. Whether it
+// is binary is detected from the content; large files use the multipart or
+// resumable upload endpoints.
 type CreateFileRequest struct {
-	Name     string `json:"name" binding:"required"`
-	Path     string `json:"path" binding:"required"`
-	Content  []byte `json:"content"`
-	IsBinary bool   `json:"is_binary"`
+	Name    string `json:"name" binding:"required"`
+	Path    string `json:"path" binding:"required"`
+	Content []byte `json:"content"`
 }
 
 // UpdateFileRequest represents a request to update a file's content
@@ -89,6 +161,34 @@ type UpdateFileRequest struct {
 	Content string `json:"content" binding:"required"`
 }
 
+// FileConflict describes a file update that was based on an outdated version
+// of the file. MergedContent is set when the update and the changes made
+// since its base version do not overlap, so both can be kept.
+type FileConflict struct {
+	Error          string  `json:"error"`
+	FileID         string  `json:"file_id"`
+	CurrentVersion int     `json:"current_version"`
+	ETag           string  `json:"etag"`
+	CurrentContent string  `json:"current_content,omitempty"`
+	BaseVersion    int     `json:"base_version,omitempty"`
+	MergedContent  *string `json:"merged_content,omitempty"`
+}
+
+// CreateFolderRequest represents a request to create a folder
+type CreateFolderRequest struct {
+	Path string `json:"path" binding:"required"`
+}
+
+// RenameRequest represents a request to rename a file or folder in place
+type RenameRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// MoveRequest represents a request to move a file or folder to another folder
+type MoveRequest struct {
+	Folder string `json:"folder"` // Destination folder path, empty for the project root
+}
+
 // ShareProjectRequest represents a request to share a project
 type ShareProjectRequest struct {
 	UserID string `json:"user_id" binding:"required"`
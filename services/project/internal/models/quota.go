@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Quota subjects. A user's usage covers the projects they own; an
+// organization's covers the projects of all its members.
+const (
+	QuotaSubjectUser         = "user"
+	QuotaSubjectOrganization = "organization"
+)
+
+// QuotaLimits bounds the storage of a quota subject. Zero means unlimited.
+type QuotaLimits struct {
+	MaxStorageBytes  int64 `json:"max_storage_bytes"`
+	MaxFiles         int64 `json:"max_files"`
+	MaxFileSizeBytes int64 `json:"max_file_size_bytes"`
+}
+
+// QuotaOverride replaces some of the default limits of one account. Unset
+// fields keep the default.
+type QuotaOverride struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	SubjectType      string             `bson:"subject_type" json:"subject_type"`
+	SubjectID        primitive.ObjectID `bson:"subject_id" json:"subject_id"`
+	MaxStorageBytes  *int64             `bson:"max_storage_bytes,omitempty" json:"max_storage_bytes,omitempty"`
+	MaxFiles         *int64             `bson:"max_files,omitempty" json:"max_files,omitempty"`
+	MaxFileSizeBytes *int64             `bson:"max_file_size_bytes,omitempty" json:"max_file_size_bytes,omitempty"`
+	Reason           string             `bson:"reason,omitempty" json:"reason,omitempty"`
+	UpdatedBy        primitive.ObjectID `bson:"updated_by" json:"updated_by"`
+	UpdatedAt        time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// StorageUsage is the running total checked against a quota. Writes reserve
+// their size before storing a file, and the total is recomputed from the
+// project stats after every change unless a reservation came in meanwhile.
+type StorageUsage struct {
+	SubjectType  string             `bson:"subject_type" json:"-"`
+	SubjectID    primitive.ObjectID `bson:"subject_id" json:"-"`
+	StorageBytes int64              `bson:"storage_bytes" json:"storage_bytes"`
+	FileCount    int64              `bson:"file_count" json:"file_count"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+
+	// Revision counts the changes to the usage, so that a recomputed total
+	// only replaces the usage it was computed from
+	Revision int64 `bson:"revision" json:"-"`
+}
+
+// QuotaStatus reports the usage and effective limits of a quota subject
+type QuotaStatus struct {
+	SubjectType string             `json:"subject_type"`
+	SubjectID   primitive.ObjectID `json:"subject_id"`
+	Usage       StorageUsage       `json:"usage"`
+	Limits      QuotaLimits        `json:"limits"`
+	Overridden  bool               `json:"overridden"`
+}
+
+// UsageResponse reports the quotas that apply to a user
+type UsageResponse struct {
+	User         *QuotaStatus `json:"user"`
+	Organization *QuotaStatus `json:"organization,omitempty"`
+}
+
+// SetQuotaOverrideRequest represents a request to override the quota of an
+// account
+type SetQuotaOverrideRequest struct {
+	MaxStorageBytes  *int64 `json:"max_storage_bytes" binding:"omitempty,min=0"`
+	MaxFiles         *int64 `json:"max_files" binding:"omitempty,min=0"`
+	MaxFileSizeBytes *int64 `json:"max_file_size_bytes" binding:"omitempty,min=0"`
+	Reason           string `json:"reason" binding:"max=500"`
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"github.com/texflow/services/project/internal/search"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// SearchDocument is the search index entry of a text file
+type SearchDocument struct {
+	FileID    primitive.ObjectID `bson:"_id"`
+	ProjectID primitive.ObjectID `bson:"project_id"`
+	Path      string             `bson:"path"`
+	Hash      string             `bson:"hash"`
+	Terms     []string           `bson:"terms,omitempty"`
+	Content   string             `bson:"content"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+// SearchResult is a line of a file that matched a search
+type SearchResult struct {
+	ProjectID   primitive.ObjectID `json:"project_id"`
+	ProjectName string             `json:"project_name"`
+	FileID      primitive.ObjectID `json:"file_id"`
+	Path        string             `json:"path"`
+	search.Match
+}
+
+// SearchResponse holds the results of a search
+type SearchResponse struct {
+	Query     string          `json:"query"`
+	Results   []*SearchResult `json:"results"`
+	Truncated bool            `json:"truncated"` // More matches exist than were returned
+}
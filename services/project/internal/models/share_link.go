@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ShareLink is a revocable link that gives whoever opens it read-only or
+// edit access to a project. Only the SHA-256 hash of the token is stored.
+type ShareLink struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID    primitive.ObjectID `bson:"project_id" json:"project_id"`
+	CreatedBy    primitive.ObjectID `bson:"created_by" json:"created_by"`
+	Role         string             `bson:"role" json:"role"` // editor, viewer
+	TokenHash    string             `bson:"token_hash" json:"-"`
+	Prefix       string             `bson:"prefix" json:"prefix"`
+	PasswordHash string             `bson:"password_hash,omitempty" json:"-"`
+	HasPassword  bool               `bson:"has_password" json:"has_password"`
+	ExpiresAt    *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt    *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	LastUsedAt   *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// ShareGrant is the temporary role a user holds on a project after opening
+// a share link. Every service checks grants when authorizing project access.
+type ShareGrant struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	LinkID    primitive.ObjectID `bson:"link_id" json:"link_id"`
+	ProjectID primitive.ObjectID `bson:"project_id" json:"project_id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Role      string             `bson:"role" json:"role"`
+	ExpiresAt time.Time          `bson:"expires_at" json:"expires_at"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// CreateShareLinkRequest represents a request to create a share link
+type CreateShareLinkRequest struct {
+	Role          string `json:"role" binding:"required,oneof=editor viewer"`
+	ExpiresInDays int    `json:"expires_in_days" binding:"omitempty,min=1,max=365"` // Zero means no expiry
+	Password      string `json:"password" binding:"omitempty,min=4,max=128"`
+}
+
+// CreateShareLinkResponse carries the plain token, which is only shown once
+type CreateShareLinkResponse struct {
+	*ShareLink
+	Token string `json:"token"`
+}
+
+// ResolveShareLinkRequest represents a request to open a share link
+type ResolveShareLinkRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Suggestion kinds
+const (
+	SuggestionInsertion   = "insertion"
+	SuggestionDeletion    = "deletion"
+	SuggestionReplacement = "replacement"
+)
+
+// Suggestion states
+const (
+	SuggestionPending  = "pending"
+	SuggestionAccepted = "accepted"
+	SuggestionRejected = "rejected"
+)
+
+// Suggestion is a tracked change to a file: Deleted is replaced by Inserted
+// at Offset, a character offset into the stored content. The stored content
+// only changes when the suggestion is accepted.
+type Suggestion struct {
+	ID         primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	ProjectID  primitive.ObjectID  `bson:"project_id" json:"project_id"`
+	FileID     primitive.ObjectID  `bson:"file_id" json:"file_id"`
+	Kind       string              `bson:"kind" json:"kind"`
+	Offset     int                 `bson:"offset" json:"offset"`
+	Deleted    string              `bson:"deleted,omitempty" json:"deleted,omitempty"`
+	Inserted   string              `bson:"inserted,omitempty" json:"inserted,omitempty"`
+	AuthorID   primitive.ObjectID  `bson:"author_id" json:"author_id"`
+	AuthorName string              `bson:"author_name" json:"author_name"`
+	Status     string              `bson:"status" json:"status"`
+	ReviewedBy *primitive.ObjectID `bson:"reviewed_by,omitempty" json:"reviewed_by,omitempty"`
+	ReviewedAt *time.Time          `bson:"reviewed_at,omitempty" json:"reviewed_at,omitempty"`
+	CreatedAt  time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// ReviewSuggestionsRequest selects the pending suggestions to accept or
+// reject: those listed, else those of a file, else all of the project
+type ReviewSuggestionsRequest struct {
+	IDs    []string `json:"ids"`
+	FileID string   `json:"file_id"`
+}
+
+// SkippedSuggestion is a suggestion that a review left pending
+type SkippedSuggestion struct {
+	ID     primitive.ObjectID `json:"id"`
+	Reason string             `json:"reason"`
+}
+
+// ReviewSuggestionsResponse lists the suggestions a review accepted or
+// rejected
+type ReviewSuggestionsResponse struct {
+	Reviewed []*Suggestion       `json:"reviewed"`
+	Skipped  []SkippedSuggestion `json:"skipped"`
+}
+
+// UpdateFileResponse is returned instead of the file when an edit was
+// recorded as suggestions
+type UpdateFileResponse struct {
+	File        *File         `json:"file"`
+	Suggestions []*Suggestion `json:"suggestions"`
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Template is a snapshot of a project's files that new projects can start
+// from. Built-in templates ship with the service and have no owner.
+type Template struct {
+	ID              primitive.ObjectID  `bson:"_id,omitempty" json:"id"`
+	Name            string              `bson:"name" json:"name"`
+	Description     string              `bson:"description,omitempty" json:"description,omitempty"`
+	Category        string              `bson:"category" json:"category"`
+	Compiler        string              `bson:"compiler" json:"compiler"`
+	MainFile        string              `bson:"main_file" json:"main_file"`
+	Tags            []string            `bson:"tags,omitempty" json:"tags,omitempty"`
+	OwnerID         primitive.ObjectID  `bson:"owner_id,omitempty" json:"owner_id,omitempty"`
+	SourceProjectID *primitive.ObjectID `bson:"source_project_id,omitempty" json:"source_project_id,omitempty"`
+	BuiltinKey      string              `bson:"builtin_key,omitempty" json:"builtin_key,omitempty"`
+	IsPublic        bool                `bson:"is_public" json:"is_public"`
+	ThumbnailKey    string              `bson:"thumbnail_key,omitempty" json:"-"`
+	HasThumbnail    bool                `bson:"-" json:"has_thumbnail"`
+	Files           []TemplateFile      `bson:"files" json:"files"`
+	Folders         []string            `bson:"folders,omitempty" json:"folders,omitempty"`
+	UsageCount      int64               `bson:"usage_count" json:"usage_count"`
+	CreatedAt       time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// TemplateFile is a file of a template
+type TemplateFile struct {
+	Path       string `bson:"path" json:"path"`
+	SizeBytes  int64  `bson:"size_bytes" json:"size_bytes"`
+	IsBinary   bool   `bson:"is_binary" json:"is_binary"`
+	Hash       string `bson:"hash" json:"hash"`
+	StorageKey string `bson:"storage_key" json:"-"`
+}
+
+// PublishTemplateRequest represents a request to publish a project as a template
+type PublishTemplateRequest struct {
+	Name        string   `json:"name" binding:"required,min=1,max=100"`
+	Description string   `json:"description" binding:"max=500"`
+	Category    string   `json:"category" binding:"required,min=1,max=50"`
+	Compiler    string   `json:"compiler" binding:"omitempty,oneof=pdflatex xelatex lualatex"`
+	Tags        []string `json:"tags" binding:"max=10"`
+	IsPublic    bool     `json:"is_public"`
+}
+
+// UpdateTemplateRequest represents a request to update template metadata
+type UpdateTemplateRequest struct {
+	Name        *string  `json:"name" binding:"omitempty,min=1,max=100"`
+	Description *string  `json:"description" binding:"omitempty,max=500"`
+	Category    *string  `json:"category" binding:"omitempty,min=1,max=50"`
+	Compiler    *string  `json:"compiler" binding:"omitempty,oneof=pdflatex xelatex lualatex"`
+	Tags        []string `json:"tags" binding:"omitempty,max=10"`
+	IsPublic    *bool    `json:"is_public"`
+}
+
+// TemplateFilter narrows a template search
+type TemplateFilter struct {
+	Query    string
+	Category string
+	ViewerID primitive.ObjectID
+	Page     int
+	Limit    int
+}
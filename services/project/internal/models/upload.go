@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UploadSession is a resumable upload of a large file. The file is sent in
+// fixed-size chunks that may arrive in any order and be retried; once all
+// have arrived the upload is completed into a project file.
+type UploadSession struct {
+	ID             primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	ProjectID      primitive.ObjectID `bson:"project_id" json:"project_id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name           string             `bson:"name" json:"name"`
+	Path           string             `bson:"path" json:"path"`
+	SizeBytes      int64              `bson:"size_bytes" json:"size_bytes"`
+	ChunkSize      int64              `bson:"chunk_size" json:"chunk_size"`
+	TotalChunks    int                `bson:"total_chunks" json:"total_chunks"`
+	ReceivedChunks []int              `bson:"received_chunks" json:"received_chunks"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	ExpiresAt      time.Time          `bson:"expires_at" json:"expires_at"`
+}
+
+// CreateUploadRequest represents a request to start a resumable upload
+type CreateUploadRequest struct {
+	Path      string `json:"path" binding:"required"`
+	SizeBytes int64  `json:"size_bytes" binding:"required,min=1"`
+}
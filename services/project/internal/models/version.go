@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FileVersion is an immutable snapshot of a file's content, taken on every
+// save. Snapshots are stored by content hash, so identical content is kept
+// once per project.
+type FileVersion struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id"`
+	FileID       primitive.ObjectID `bson:"file_id" json:"file_id"`
+	ProjectID    primitive.ObjectID `bson:"project_id" json:"project_id"`
+	Version      int                `bson:"version" json:"version"`
+	Path         string             `bson:"path" json:"path"` // Path at the time of the save
+	SizeBytes    int64              `bson:"size_bytes" json:"size_bytes"`
+	Hash         string             `bson:"hash" json:"hash"`
+	StorageKey   string             `bson:"storage_key" json:"-"`
+	IsBinary     bool               `bson:"is_binary" json:"is_binary"`
+	CreatedBy    primitive.ObjectID `bson:"created_by" json:"created_by"`
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	RestoredFrom int                `bson:"restored_from,omitempty" json:"restored_from,omitempty"`
+}
+
+// FileDiff is a line diff between two versions of a file
+type FileDiff struct {
+	FileID      primitive.ObjectID `json:"file_id"`
+	FromVersion int                `json:"from_version"`
+	ToVersion   int                `json:"to_version"`
+	Additions   int                `json:"additions"`
+	Deletions   int                `json:"deletions"`
+	Hunks       []DiffHunk         `json:"hunks"`
+}
+
+// DiffHunk is a run of changed lines with surrounding context, numbered as
+// in a unified diff
+type DiffHunk struct {
+	OldStart int        `json:"old_start"`
+	OldLines int        `json:"old_lines"`
+	NewStart int        `json:"new_start"`
+	NewLines int        `json:"new_lines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+// DiffLine is a single line of a hunk
+type DiffLine struct {
+	Type    string `json:"type"` // context, add, delete
+	Content string `json:"content"`
+}
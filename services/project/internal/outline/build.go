@@ -0,0 +1,298 @@
+package outline
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Diagnostic severities
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+// Diagnostic is a problem found in the project structure
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	File     string `json:"file,omitempty"`
+	Line     int    `json:"line,omitempty"`
+}
+
+// Index is the structure of a whole project, in document order
+type Index struct {
+	Root        string       `json:"root"`
+	Files       []string     `json:"files"`
+	Outline     []Section    `json:"outline"`
+	Labels      []Label      `json:"labels"`
+	References  []Reference  `json:"references"`
+	Citations   []Reference  `json:"citations"`
+	BibEntries  []BibEntry   `json:"bib_entries"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// builder walks the include tree of a project
+type builder struct {
+	root    string
+	files   map[string]*FileIndex
+	index   *Index
+	visited map[string]bool
+	active  map[string]bool
+	bibs    []string
+}
+
+// Build joins the parsed files of a project, keyed by path, into one index.
+// Project files that are not parsed, such as figures, map to nil. Files are
+// visited from root through their includes. When root does not exist every
+// file with a \documentclass is used as a root instead.
+func Build(root string, files map[string]*FileIndex) *Index {
+	b := &builder{
+		root:  strings.TrimPrefix(root, "/"),
+		files: files,
+		index: &Index{
+			Root:        strings.TrimPrefix(root, "/"),
+			Files:       []string{},
+			Outline:     []Section{},
+			Labels:      []Label{},
+			References:  []Reference{},
+			Citations:   []Reference{},
+			BibEntries:  []BibEntry{},
+			Diagnostics: []Diagnostic{},
+		},
+		visited: make(map[string]bool),
+		active:  make(map[string]bool),
+	}
+
+	roots := []string{b.root}
+	if _, ok := files[b.root]; !ok {
+		b.diagnose(SeverityError, "missing_root", fmt.Sprintf("main file %q not found", b.root), "", 0)
+		roots = documentRoots(files)
+	}
+	for _, r := range roots {
+		if !b.visited[r] {
+			b.visit(r)
+		}
+	}
+
+	b.collectBibliographies()
+	b.checkLabels()
+	b.checkCitations()
+
+	return b.index
+}
+
+// visit adds a file and, at the position of each include, the files it
+// includes
+func (b *builder) visit(filePath string) {
+	file := b.files[filePath]
+	b.visited[filePath] = true
+	b.active[filePath] = true
+	defer delete(b.active, filePath)
+
+	b.index.Files = append(b.index.Files, filePath)
+	if file == nil {
+		return
+	}
+	for _, label := range file.Labels {
+		label.File = filePath
+		b.index.Labels = append(b.index.Labels, label)
+	}
+	for _, ref := range file.References {
+		ref.File = filePath
+		b.index.References = append(b.index.References, ref)
+	}
+	for _, cite := range file.Citations {
+		cite.File = filePath
+		b.index.Citations = append(b.index.Citations, cite)
+	}
+	for _, entry := range file.BibEntries {
+		entry.File = filePath
+		b.index.BibEntries = append(b.index.BibEntries, entry)
+	}
+	for _, bib := range file.Bibliographies {
+		target, ok := b.resolve(filePath, bib.Path, ".bib")
+		if !ok {
+			b.diagnose(SeverityError, "missing_file", fmt.Sprintf("bibliography %q not found", bib.Path), filePath, bib.Line)
+			continue
+		}
+		b.bibs = append(b.bibs, target)
+	}
+
+	// Sections and includes are merged by line so that included files appear
+	// in the outline where they are pulled in
+	s := 0
+	for _, include := range file.Includes {
+		for ; s < len(file.Sections) && file.Sections[s].Line <= include.Line; s++ {
+			b.addSection(filePath, file.Sections[s])
+		}
+
+		target, ok := b.resolve(filePath, include.Path, ".tex")
+		switch {
+		case !ok:
+			b.diagnose(SeverityError, "missing_file", fmt.Sprintf("included file %q not found", include.Path), filePath, include.Line)
+		case b.active[target]:
+			b.diagnose(SeverityError, "include_cycle", fmt.Sprintf("%q includes itself", target), filePath, include.Line)
+		case !b.visited[target]:
+			b.visit(target)
+		}
+	}
+	for ; s < len(file.Sections); s++ {
+		b.addSection(filePath, file.Sections[s])
+	}
+}
+
+func (b *builder) addSection(filePath string, section Section) {
+	section.File = filePath
+	b.index.Outline = append(b.index.Outline, section)
+}
+
+// resolve finds an included file. LaTeX resolves paths against the
+// directory of the main file; \subfile paths are also tried relative to the
+// including file.
+func (b *builder) resolve(from, name, ext string) (string, bool) {
+	return resolvePath(b.files, []string{path.Dir(b.root), path.Dir(from)}, name, ext)
+}
+
+// resolvePath looks for name in each directory in turn, adding each of the
+// extensions LaTeX would try if name does not already have it
+func resolvePath(files map[string]*FileIndex, dirs []string, name string, exts ...string) (string, bool) {
+	name = strings.Trim(strings.TrimSpace(name), `"`)
+	if name == "" {
+		return "", false
+	}
+
+	for _, dir := range dirs {
+		candidate := path.Clean(path.Join(dir, name))
+		if strings.HasPrefix(candidate, "../") || candidate == ".." {
+			continue
+		}
+		for _, ext := range exts {
+			if path.Ext(candidate) != ext {
+				if _, ok := files[candidate+ext]; ok {
+					return candidate + ext, true
+				}
+			}
+		}
+		if _, ok := files[candidate]; ok {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// collectBibliographies adds the entries of the bibliography databases in
+// use: those named by \bibliography or \addbibresource, or every .bib file
+// when none are named
+func (b *builder) collectBibliographies() {
+	bibs := b.bibs
+	if len(bibs) == 0 {
+		for filePath := range b.files {
+			if strings.EqualFold(path.Ext(filePath), ".bib") {
+				bibs = append(bibs, filePath)
+			}
+		}
+		sort.Strings(bibs)
+	}
+
+	for _, bib := range bibs {
+		if b.visited[bib] {
+			continue
+		}
+		b.visited[bib] = true
+		b.index.Files = append(b.index.Files, bib)
+		if b.files[bib] == nil {
+			continue
+		}
+		for _, entry := range b.files[bib].BibEntries {
+			entry.File = bib
+			b.index.BibEntries = append(b.index.BibEntries, entry)
+		}
+	}
+}
+
+// checkLabels reports duplicate labels and references to undefined labels
+func (b *builder) checkLabels() {
+	defined := make(map[string]bool)
+	for _, label := range b.index.Labels {
+		if defined[label.Name] {
+			b.diagnose(SeverityWarning, "duplicate_label", fmt.Sprintf("label %q is defined more than once", label.Name), label.File, label.Line)
+		}
+		defined[label.Name] = true
+	}
+
+	for _, ref := range b.index.References {
+		if !defined[ref.Key] {
+			b.diagnose(SeverityWarning, "undefined_label", fmt.Sprintf("label %q is not defined", ref.Key), ref.File, ref.Line)
+		}
+	}
+}
+
+// checkCitations reports duplicate bibliography entries, undefined citations
+// and entries that are never cited
+func (b *builder) checkCitations() {
+	defined := make(map[string]bool)
+	for _, entry := range b.index.BibEntries {
+		if defined[entry.Key] {
+			b.diagnose(SeverityWarning, "duplicate_bib_entry", fmt.Sprintf("bibliography entry %q is defined more than once", entry.Key), entry.File, entry.Line)
+		}
+		defined[entry.Key] = true
+	}
+
+	cited := make(map[string]bool)
+	citeAll := false
+	for _, cite := range b.index.Citations {
+		if cite.Key == "*" {
+			citeAll = true
+			continue
+		}
+		cited[cite.Key] = true
+		if !defined[cite.Key] {
+			b.diagnose(SeverityWarning, "undefined_citation", fmt.Sprintf("citation %q is not in the bibliography", cite.Key), cite.File, cite.Line)
+		}
+	}
+
+	if citeAll {
+		return
+	}
+	for _, entry := range b.index.BibEntries {
+		if !cited[entry.Key] {
+			b.diagnose(SeverityInfo, "unused_bib_entry", fmt.Sprintf("bibliography entry %q is never cited", entry.Key), entry.File, entry.Line)
+		}
+	}
+}
+
+func (b *builder) diagnose(severity, code, message, filePath string, line int) {
+	b.index.Diagnostics = append(b.index.Diagnostics, Diagnostic{
+		Severity: severity,
+		Code:     code,
+		Message:  message,
+		File:     filePath,
+		Line:     line,
+	})
+}
+
+// documentRoots lists the files that start a document, falling back to
+// every .tex file
+func documentRoots(files map[string]*FileIndex) []string {
+	var roots, sources []string
+	for filePath, file := range files {
+		if !strings.EqualFold(path.Ext(filePath), ".tex") {
+			continue
+		}
+		sources = append(sources, filePath)
+		if file != nil && file.DocumentClass {
+			roots = append(roots, filePath)
+		}
+	}
+
+	if len(roots) == 0 {
+		roots = sources
+	}
+	sort.Strings(roots)
+	return roots
+}
@@ -0,0 +1,107 @@
+package outline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name    string
+		root    string
+		files   map[string]string
+		outline []string
+		visited []string
+		codes   []string
+	}{
+		{
+			name: "includes are placed where they are pulled in",
+			root: "main.tex",
+			files: map[string]string{
+				"main.tex":           "\\documentclass{article}\n\\section{One}\n\\input{chapters/two}\n\\section{Three}",
+				"chapters/two.tex":   "\\section{Two}",
+				"chapters/notes.tex": "\\section{Unused}",
+			},
+			outline: []string{"One", "Two", "Three"},
+			visited: []string{"main.tex", "chapters/two.tex"},
+		},
+		{
+			name: "missing include",
+			root: "main.tex",
+			files: map[string]string{
+				"main.tex": "\\input{missing}\n\\section{After}",
+			},
+			outline: []string{"After"},
+			visited: []string{"main.tex"},
+			codes:   []string{"missing_file"},
+		},
+		{
+			name: "include cycle",
+			root: "main.tex",
+			files: map[string]string{
+				"main.tex": "\\input{a}",
+				"a.tex":    "\\input{main}",
+			},
+			visited: []string{"main.tex", "a.tex"},
+			codes:   []string{"include_cycle"},
+		},
+		{
+			name: "missing root falls back to document classes",
+			root: "gone.tex",
+			files: map[string]string{
+				"paper.tex": "\\documentclass{article}\n\\section{Paper}",
+				"part.tex":  "\\section{Part}",
+			},
+			outline: []string{"Paper"},
+			visited: []string{"paper.tex"},
+			codes:   []string{"missing_root"},
+		},
+		{
+			name: "labels and citations",
+			root: "main.tex",
+			files: map[string]string{
+				"main.tex": "\\label{a}\\label{a}\\ref{b}\\cite{known,unknown}\\bibliography{refs}",
+				"refs.bib": "@misc{known,}\n@misc{idle,}",
+			},
+			visited: []string{"main.tex", "refs.bib"},
+			codes:   []string{"duplicate_label", "undefined_label", "undefined_citation", "unused_bib_entry"},
+		},
+		{
+			name: "missing bibliography",
+			root: "main.tex",
+			files: map[string]string{
+				"main.tex": "\\nocite{*}\\addbibresource{refs.bib}",
+			},
+			visited: []string{"main.tex"},
+			codes:   []string{"missing_file"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := make(map[string]*FileIndex, len(tt.files))
+			for p, content := range tt.files {
+				files[p] = Parse(p, content)
+			}
+			index := Build(tt.root, files)
+
+			var outline, codes []string
+			for _, section := range index.Outline {
+				outline = append(outline, section.Title)
+			}
+			for _, d := range index.Diagnostics {
+				codes = append(codes, d.Code)
+			}
+
+			if !reflect.DeepEqual(outline, tt.outline) {
+				t.Errorf("outline = %v, want %v", outline, tt.outline)
+			}
+			if !reflect.DeepEqual(index.Files, tt.visited) {
+				t.Errorf("files = %v, want %v", index.Files, tt.visited)
+			}
+			if !reflect.DeepEqual(codes, tt.codes) {
+				t.Errorf("diagnostics = %v, want %v", codes, tt.codes)
+			}
+		})
+	}
+}
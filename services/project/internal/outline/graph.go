@@ -0,0 +1,252 @@
+package outline
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Dependency kinds
+const (
+	DependencyInclude      = "include"
+	DependencyBibliography = "bibliography"
+	DependencyGraphic      = "graphic"
+	DependencyPackage      = "package"
+)
+
+// UnusedFolder is where unused files are moved to
+const UnusedFolder = "unused"
+
+// graphicExtensions are tried in order for an \includegraphics without an
+// extension, as graphicx does
+var graphicExtensions = []string{".pdf", ".png", ".jpg", ".jpeg", ".eps", ".mps", ".jbig2", ".jb2"}
+
+// buildFiles are read by build tools rather than referenced from the
+// document, so they are never reported as unused
+var buildFiles = map[string]bool{
+	"latexmkrc": true, ".latexmkrc": true,
+}
+
+// GraphNode is a project file, or a referenced file that does not exist
+type GraphNode struct {
+	Path      string `json:"path"`
+	Root      bool   `json:"root,omitempty"`
+	Reachable bool   `json:"reachable"`
+	Missing   bool   `json:"missing,omitempty"`
+}
+
+// GraphEdge is a reference from one file to another
+type GraphEdge struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Kind    string `json:"kind"`
+	Command string `json:"command"`
+	Line    int    `json:"line"`
+	Missing bool   `json:"missing,omitempty"`
+}
+
+// Graph is the file dependency graph of a project, from its main file
+// outward
+type Graph struct {
+	Root    string      `json:"root"`
+	Nodes   []GraphNode `json:"nodes"`
+	Edges   []GraphEdge `json:"edges"`
+	Unused  []string    `json:"unused"`
+	Missing []GraphEdge `json:"missing"`
+}
+
+// graphBuilder walks the references of a project
+type graphBuilder struct {
+	files     map[string]*FileIndex
+	graph     *Graph
+	rootDir   string
+	imageDirs []string
+	reached   map[string]bool
+	missing   map[string]bool
+}
+
+// BuildGraph follows \input, \include, \subfile, \bibliography,
+// \addbibresource, \includegraphics and local packages and classes from the
+// root file, keyed by path as for Build. Files that are never reached are
+// unused, except those already in the unused folder.
+func BuildGraph(root string, files map[string]*FileIndex) *Graph {
+	root = strings.TrimPrefix(root, "/")
+	g := &graphBuilder{
+		files: files,
+		graph: &Graph{
+			Root:    root,
+			Nodes:   []GraphNode{},
+			Edges:   []GraphEdge{},
+			Unused:  []string{},
+			Missing: []GraphEdge{},
+		},
+		rootDir: path.Dir(root),
+		reached: make(map[string]bool),
+		missing: make(map[string]bool),
+	}
+	g.collectImageDirs()
+
+	roots := []string{root}
+	if _, ok := files[root]; !ok {
+		roots = documentRoots(files)
+	}
+	for _, r := range roots {
+		g.visit(r)
+	}
+
+	g.collectNodes(roots)
+
+	return g.graph
+}
+
+// visit marks a file as reached and follows its references
+func (g *graphBuilder) visit(filePath string) {
+	if g.reached[filePath] {
+		return
+	}
+	g.reached[filePath] = true
+
+	file := g.files[filePath]
+	if file == nil {
+		return
+	}
+
+	dirs := []string{g.rootDir, path.Dir(filePath)}
+	for _, include := range file.Includes {
+		g.follow(filePath, include, DependencyInclude, dirs, ".tex")
+	}
+	for _, bib := range file.Bibliographies {
+		g.follow(filePath, bib, DependencyBibliography, dirs, ".bib")
+	}
+	for _, graphic := range file.Graphics {
+		g.follow(filePath, graphic, DependencyGraphic, append(dirs, g.imageDirs...), graphicExtensions...)
+	}
+	for _, pkg := range file.Packages {
+		// Packages and classes not in the project come from the distribution
+		target, ok := resolvePath(g.files, dirs, pkg.Path, packageCommands[pkg.Command])
+		if ok {
+			g.addEdge(filePath, target, DependencyPackage, pkg, false)
+			g.visit(target)
+		}
+	}
+}
+
+// follow adds the edge for a reference and visits its target, or records it
+// as missing
+func (g *graphBuilder) follow(from string, ref Include, kind string, dirs []string, exts ...string) {
+	target, ok := resolvePath(g.files, dirs, ref.Path, exts...)
+	if !ok {
+		edge := g.addEdge(from, ref.Path, kind, ref, true)
+		g.graph.Missing = append(g.graph.Missing, edge)
+		g.missing[ref.Path] = true
+		return
+	}
+
+	g.addEdge(from, target, kind, ref, false)
+	g.visit(target)
+}
+
+func (g *graphBuilder) addEdge(from, to, kind string, ref Include, missing bool) GraphEdge {
+	edge := GraphEdge{
+		From:    from,
+		To:      to,
+		Kind:    kind,
+		Command: ref.Command,
+		Line:    ref.Line,
+		Missing: missing,
+	}
+	g.graph.Edges = append(g.graph.Edges, edge)
+	return edge
+}
+
+// collectImageDirs gathers the \graphicspath directories, which are
+// relative to the main file
+func (g *graphBuilder) collectImageDirs() {
+	seen := make(map[string]bool)
+	for _, file := range g.files {
+		if file == nil {
+			continue
+		}
+		for _, dir := range file.GraphicsPaths {
+			dir = path.Join(g.rootDir, dir)
+			if !seen[dir] {
+				seen[dir] = true
+				g.imageDirs = append(g.imageDirs, dir)
+			}
+		}
+	}
+	sort.Strings(g.imageDirs)
+}
+
+// collectNodes lists every project file and missing reference, and the
+// files that were never reached
+func (g *graphBuilder) collectNodes(roots []string) {
+	isRoot := make(map[string]bool, len(roots))
+	for _, r := range roots {
+		isRoot[r] = true
+	}
+
+	for filePath := range g.files {
+		g.graph.Nodes = append(g.graph.Nodes, GraphNode{
+			Path:      filePath,
+			Root:      isRoot[filePath],
+			Reachable: g.reached[filePath],
+		})
+		if !g.reached[filePath] && !isUnusedPath(filePath) && !buildFiles[path.Base(filePath)] {
+			g.graph.Unused = append(g.graph.Unused, filePath)
+		}
+	}
+	for filePath := range g.missing {
+		g.graph.Nodes = append(g.graph.Nodes, GraphNode{Path: filePath, Missing: true})
+	}
+
+	sort.Slice(g.graph.Nodes, func(i, j int) bool { return g.graph.Nodes[i].Path < g.graph.Nodes[j].Path })
+	sort.Strings(g.graph.Unused)
+}
+
+// DOT renders the graph in Graphviz format. Unused files are drawn dashed
+// and missing files in red.
+func (graph *Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box];\n")
+
+	for _, node := range graph.Nodes {
+		var attrs []string
+		switch {
+		case node.Missing:
+			attrs = append(attrs, "style=dashed", "color=red", "fontcolor=red")
+		case node.Root:
+			attrs = append(attrs, "style=bold")
+		case !node.Reachable:
+			attrs = append(attrs, "style=dashed", "color=gray", "fontcolor=gray")
+		}
+		fmt.Fprintf(&b, "  %s", dotQuote(node.Path))
+		if len(attrs) > 0 {
+			fmt.Fprintf(&b, " [%s]", strings.Join(attrs, ", "))
+		}
+		b.WriteString(";\n")
+	}
+
+	for _, edge := range graph.Edges {
+		fmt.Fprintf(&b, "  %s -> %s [label=%s", dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Kind))
+		if edge.Missing {
+			b.WriteString(", color=red")
+		}
+		b.WriteString("];\n")
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// isUnusedPath reports whether a file is already in the unused folder
+func isUnusedPath(filePath string) bool {
+	return strings.HasPrefix(filePath, UnusedFolder+"/")
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
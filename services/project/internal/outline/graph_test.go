@@ -0,0 +1,98 @@
+package outline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBuildGraph(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		edges   []string
+		unused  []string
+		missing []string
+	}{
+		{
+			name: "includes, bibliographies and local packages",
+			files: map[string]string{
+				"main.tex":         "\\documentclass{article}\n\\usepackage{mystyle,amsmath}\n\\input{chapters/one}\n\\bibliography{refs}",
+				"mystyle.sty":      "",
+				"chapters/one.tex": "\\subfile{sub}",
+				"chapters/sub.tex": "",
+				"refs.bib":         "",
+			},
+			edges: []string{
+				"main.tex -> chapters/one.tex",
+				"chapters/one.tex -> chapters/sub.tex",
+				"main.tex -> refs.bib",
+				"main.tex -> mystyle.sty",
+			},
+		},
+		{
+			name: "graphics are resolved through extensions and graphicspath",
+			files: map[string]string{
+				"main.tex":      "\\graphicspath{{img/}}\\includegraphics{plot}\\includegraphics{logo.png}",
+				"img/plot.pdf":  "",
+				"img/plot.png":  "",
+				"logo.png":      "",
+				"img/other.jpg": "",
+			},
+			edges:  []string{"main.tex -> img/plot.pdf", "main.tex -> logo.png"},
+			unused: []string{"img/other.jpg", "img/plot.png"},
+		},
+		{
+			name: "malformed and missing references",
+			files: map[string]string{
+				"main.tex": "\\input{gone}\n\\input{broken\n\\include{}",
+				"old.tex":  "",
+			},
+			edges:   []string{"main.tex -> gone"},
+			unused:  []string{"old.tex"},
+			missing: []string{"gone"},
+		},
+		{
+			name: "unused folder and build files are not reported",
+			files: map[string]string{
+				"main.tex":        "",
+				"unused/old.tex":  "",
+				"latexmkrc":       "",
+				"notes/draft.tex": "",
+			},
+			unused: []string{"notes/draft.tex"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := make(map[string]*FileIndex, len(tt.files))
+			for p, content := range tt.files {
+				if IsSource(p) {
+					files[p] = Parse(p, content)
+				} else {
+					files[p] = nil
+				}
+			}
+			graph := BuildGraph("main.tex", files)
+
+			var edges, unused, missing []string
+			for _, edge := range graph.Edges {
+				edges = append(edges, edge.From+" -> "+edge.To)
+			}
+			unused = append(unused, graph.Unused...)
+			for _, edge := range graph.Missing {
+				missing = append(missing, edge.To)
+			}
+
+			if !reflect.DeepEqual(edges, tt.edges) {
+				t.Errorf("edges = %v, want %v", edges, tt.edges)
+			}
+			if !reflect.DeepEqual(unused, tt.unused) {
+				t.Errorf("unused = %v, want %v", unused, tt.unused)
+			}
+			if !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("missing = %v, want %v", missing, tt.missing)
+			}
+		})
+	}
+}
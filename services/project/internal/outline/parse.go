@@ -0,0 +1,490 @@
+// Package outline extracts the structure of LaTeX projects: sections,
+// labels, cross-references, citations, included files and bibliography
+// entries. Files are parsed one at a time so that the result can be stored
+// and refreshed incrementally, and Build joins the parsed files of a project
+// into one index by following \input, \include and \subfile from the main
+// file.
+package outline
+
+import (
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Section heading levels, following the LaTeX sectioning hierarchy
+var sectionLevels = map[string]int{
+	"part":          -1,
+	"chapter":       0,
+	"section":       1,
+	"subsection":    2,
+	"subsubsection": 3,
+	"paragraph":     4,
+	"subparagraph":  5,
+}
+
+var refCommands = map[string]bool{
+	"ref": true, "eqref": true, "pageref": true, "autoref": true, "nameref": true,
+	"cref": true, "Cref": true, "cpageref": true, "Cpageref": true, "vref": true,
+	"Vref": true, "labelcref": true, "crefrange": true, "Crefrange": true,
+}
+
+var includeCommands = map[string]bool{
+	"input": true, "include": true, "subfile": true,
+}
+
+var bibliographyCommands = map[string]bool{
+	"bibliography": true, "addbibresource": true, "addglobalbib": true,
+}
+
+// Commands that load a file by name and the extension LaTeX adds to it.
+// Only files present in the project are dependencies; anything else comes
+// from the TeX distribution.
+var packageCommands = map[string]string{
+	"usepackage": ".sty", "RequirePackage": ".sty", "documentclass": ".cls",
+	"LoadClass": ".cls", "bibliographystyle": ".bst",
+}
+
+// Environments whose content is not LaTeX and must not be scanned
+var verbatimEnvironments = map[string]bool{
+	"verbatim": true, "verbatim*": true, "Verbatim": true, "lstlisting": true,
+	"minted": true, "comment": true,
+}
+
+// Section is a sectioning command
+type Section struct {
+	File    string `bson:"-" json:"file,omitempty"`
+	Level   int    `bson:"level" json:"level"`
+	Command string `bson:"command" json:"command"`
+	Title   string `bson:"title" json:"title"`
+	Starred bool   `bson:"starred,omitempty" json:"starred,omitempty"`
+	Line    int    `bson:"line" json:"line"`
+}
+
+// Label is a \label definition
+type Label struct {
+	File string `bson:"-" json:"file,omitempty"`
+	Name string `bson:"name" json:"name"`
+	Line int    `bson:"line" json:"line"`
+}
+
+// Reference is a use of a label or citation key
+type Reference struct {
+	File    string `bson:"-" json:"file,omitempty"`
+	Command string `bson:"command" json:"command"`
+	Key     string `bson:"key" json:"key"`
+	Line    int    `bson:"line" json:"line"`
+}
+
+// Include is a reference to another project file, either a LaTeX source
+// pulled in with \input, \include or \subfile, or a bibliography database
+type Include struct {
+	Command string `bson:"command" json:"command"`
+	Path    string `bson:"path" json:"path"`
+	Line    int    `bson:"line" json:"line"`
+}
+
+// BibEntry is a bibliography entry, either from a .bib file or a \bibitem
+type BibEntry struct {
+	File string `bson:"-" json:"file,omitempty"`
+	Type string `bson:"type" json:"type"`
+	Key  string `bson:"key" json:"key"`
+	Line int    `bson:"line" json:"line"`
+}
+
+// FileIndex is the parsed structure of a single file
+type FileIndex struct {
+	DocumentClass  bool        `bson:"document_class,omitempty" json:"document_class,omitempty"`
+	Sections       []Section   `bson:"sections,omitempty" json:"sections,omitempty"`
+	Labels         []Label     `bson:"labels,omitempty" json:"labels,omitempty"`
+	References     []Reference `bson:"references,omitempty" json:"references,omitempty"`
+	Citations      []Reference `bson:"citations,omitempty" json:"citations,omitempty"`
+	Includes       []Include   `bson:"includes,omitempty" json:"includes,omitempty"`
+	Bibliographies []Include   `bson:"bibliographies,omitempty" json:"bibliographies,omitempty"`
+	BibEntries     []BibEntry  `bson:"bib_entries,omitempty" json:"bib_entries,omitempty"`
+	Graphics       []Include   `bson:"graphics,omitempty" json:"graphics,omitempty"`
+	GraphicsPaths  []string    `bson:"graphics_paths,omitempty" json:"graphics_paths,omitempty"`
+	Packages       []Include   `bson:"packages,omitempty" json:"packages,omitempty"`
+}
+
+// IsSource reports whether a project file is parsed into the index
+func IsSource(filePath string) bool {
+	switch strings.ToLower(path.Ext(filePath)) {
+	case ".tex", ".bib", ".sty", ".cls":
+		return true
+	}
+	return false
+}
+
+// Parse parses a .tex, .sty, .cls or .bib file
+func Parse(filePath, content string) *FileIndex {
+	if strings.EqualFold(path.Ext(filePath), ".bib") {
+		return ParseBib(content)
+	}
+	return ParseTeX(content)
+}
+
+// ParseTeX extracts the structure of a LaTeX source file
+func ParseTeX(content string) *FileIndex {
+	text := stripComments(content)
+	lines := newLineIndex(text)
+	index := &FileIndex{}
+
+	for i := 0; i < len(text); {
+		if text[i] != '\\' {
+			i++
+			continue
+		}
+
+		start := i
+		name, next := readCommandName(text, i+1)
+		if name == "" {
+			// Control symbols such as \\ and \{ take no arguments
+			i = next + 1
+			continue
+		}
+		i = next
+
+		starred := false
+		if i < len(text) && text[i] == '*' {
+			starred = true
+			i++
+		}
+
+		line := lines.line(start)
+		switch {
+		case name == "begin":
+			env, end, ok := readGroup(text, skipSpace(text, i), '{', '}')
+			if !ok {
+				continue
+			}
+			i = end
+			if verbatimEnvironments[strings.TrimSpace(env)] {
+				i = skipEnvironment(text, i, strings.TrimSpace(env))
+			}
+
+		case packageCommands[name] != "":
+			if name == "documentclass" {
+				index.DocumentClass = true
+			}
+			args, end := readArguments(text, i)
+			i = end
+			for _, arg := range args {
+				for _, p := range splitKeys(arg) {
+					index.Packages = append(index.Packages, Include{Command: name, Path: p, Line: line})
+				}
+			}
+
+		case name == "includegraphics":
+			args, end := readArguments(text, i)
+			if len(args) == 0 {
+				continue
+			}
+			i = end
+			if p := strings.TrimSpace(args[len(args)-1]); p != "" {
+				index.Graphics = append(index.Graphics, Include{Command: name, Path: p, Line: line})
+			}
+
+		case name == "graphicspath":
+			arg, end, ok := readGroup(text, skipSpace(text, i), '{', '}')
+			if !ok {
+				continue
+			}
+			i = end
+			for j := skipSpace(arg, 0); j < len(arg); j = skipSpace(arg, j) {
+				dir, next, ok := readGroup(arg, j, '{', '}')
+				if !ok {
+					break
+				}
+				j = next
+				if dir = strings.TrimSpace(dir); dir != "" {
+					index.GraphicsPaths = append(index.GraphicsPaths, dir)
+				}
+			}
+
+		case sectionLevels[name] != 0 || name == "chapter":
+			args, end := readArguments(text, i)
+			if len(args) == 0 {
+				continue
+			}
+			i = end
+			index.Sections = append(index.Sections, Section{
+				Level:   sectionLevels[name],
+				Command: name,
+				Title:   cleanTitle(args[len(args)-1]),
+				Starred: starred,
+				Line:    line,
+			})
+
+		case name == "label":
+			arg, end, ok := readGroup(text, skipSpace(text, i), '{', '}')
+			if !ok {
+				continue
+			}
+			i = end
+			if key := strings.TrimSpace(arg); key != "" {
+				index.Labels = append(index.Labels, Label{Name: key, Line: line})
+			}
+
+		case refCommands[name]:
+			args, end := readArguments(text, i)
+			i = end
+			for _, arg := range args {
+				for _, key := range splitKeys(arg) {
+					index.References = append(index.References, Reference{Command: name, Key: key, Line: line})
+				}
+			}
+
+		case isCiteCommand(name):
+			args, end := readArguments(text, i)
+			i = end
+			for _, arg := range args {
+				for _, key := range splitKeys(arg) {
+					index.Citations = append(index.Citations, Reference{Command: name, Key: key, Line: line})
+				}
+			}
+
+		case name == "bibitem":
+			args, end := readArguments(text, i)
+			if len(args) == 0 {
+				continue
+			}
+			i = end
+			if key := strings.TrimSpace(args[len(args)-1]); key != "" {
+				index.BibEntries = append(index.BibEntries, BibEntry{Type: "bibitem", Key: key, Line: line})
+			}
+
+		case includeCommands[name]:
+			arg, end, ok := readGroup(text, skipSpace(text, i), '{', '}')
+			if !ok {
+				continue
+			}
+			i = end
+			if p := strings.TrimSpace(arg); p != "" {
+				index.Includes = append(index.Includes, Include{Command: name, Path: p, Line: line})
+			}
+
+		case bibliographyCommands[name]:
+			args, end := readArguments(text, i)
+			i = end
+			for _, arg := range args {
+				for _, p := range splitKeys(arg) {
+					index.Bibliographies = append(index.Bibliographies, Include{Command: name, Path: p, Line: line})
+				}
+			}
+		}
+	}
+
+	return index
+}
+
+// ParseBib extracts the entry keys of a BibTeX database
+func ParseBib(content string) *FileIndex {
+	lines := newLineIndex(content)
+	index := &FileIndex{}
+
+	for i := 0; i < len(content); i++ {
+		if content[i] != '@' {
+			continue
+		}
+
+		j := i + 1
+		for j < len(content) && isLetter(content[j]) {
+			j++
+		}
+		entryType := strings.ToLower(content[i+1 : j])
+		j = skipSpace(content, j)
+		if entryType == "" || j >= len(content) || (content[j] != '{' && content[j] != '(') {
+			continue
+		}
+
+		switch entryType {
+		case "comment", "preamble", "string":
+			// Skip the whole block so entries quoted inside a comment are
+			// not counted
+			closer := byte('}')
+			if content[j] == '(' {
+				closer = ')'
+			}
+			if _, end, ok := readGroup(content, j, content[j], closer); ok {
+				i = end - 1
+			}
+			continue
+		}
+
+		keyEnd := strings.IndexAny(content[j+1:], ",}\n")
+		if keyEnd < 0 {
+			continue
+		}
+		key := strings.TrimSpace(content[j+1 : j+1+keyEnd])
+		if key == "" {
+			continue
+		}
+
+		index.BibEntries = append(index.BibEntries, BibEntry{
+			Type: entryType,
+			Key:  key,
+			Line: lines.line(i),
+		})
+		i = j + keyEnd
+	}
+
+	return index
+}
+
+// stripComments blanks out % comments while keeping line breaks so that
+// offsets still map to the original line numbers
+func stripComments(content string) string {
+	var b strings.Builder
+	b.Grow(len(content))
+
+	inComment := false
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\n':
+			inComment = false
+			b.WriteByte(c)
+		case inComment:
+			b.WriteByte(' ')
+		case c == '\\' && i+1 < len(content) && content[i+1] != '\n':
+			b.WriteByte(c)
+			b.WriteByte(content[i+1])
+			i++
+		case c == '%':
+			inComment = true
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String()
+}
+
+// readCommandName reads the letters of a control word starting at i and
+// returns the name and the offset after it
+func readCommandName(text string, i int) (string, int) {
+	j := i
+	for j < len(text) && (isLetter(text[j]) || text[j] == '@') {
+		j++
+	}
+	return text[i:j], j
+}
+
+// readArguments reads the optional [..] and mandatory {..} arguments that
+// follow a command and returns the mandatory ones
+func readArguments(text string, i int) ([]string, int) {
+	var args []string
+	for {
+		j := skipSpace(text, i)
+		if j >= len(text) {
+			return args, i
+		}
+		switch text[j] {
+		case '[':
+			_, end, ok := readGroup(text, j, '[', ']')
+			if !ok {
+				return args, i
+			}
+			i = end
+		case '{':
+			arg, end, ok := readGroup(text, j, '{', '}')
+			if !ok {
+				return args, i
+			}
+			args = append(args, arg)
+			i = end
+		default:
+			return args, i
+		}
+	}
+}
+
+// readGroup reads a balanced group starting with open at i and returns its
+// content and the offset after the closing delimiter
+func readGroup(text string, i int, open, close byte) (string, int, bool) {
+	if i >= len(text) || text[i] != open {
+		return "", i, false
+	}
+
+	depth := 0
+	for j := i; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return text[i+1 : j], j + 1, true
+			}
+		}
+	}
+
+	return "", i, false
+}
+
+// skipEnvironment returns the offset after \end{env}
+func skipEnvironment(text string, i int, env string) int {
+	end := strings.Index(text[i:], `\end{`+env+`}`)
+	if end < 0 {
+		return len(text)
+	}
+	return i + end + len(`\end{`+env+`}`)
+}
+
+// skipSpace skips whitespace, including single line breaks
+func skipSpace(text string, i int) int {
+	for i < len(text) && (text[i] == ' ' || text[i] == '\t' || text[i] == '\n' || text[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// isCiteCommand matches the citation commands of natbib and biblatex, such
+// as \cite, \citep, \parencite and \textcite
+func isCiteCommand(name string) bool {
+	lower := strings.ToLower(name)
+	return strings.HasPrefix(lower, "cite") || strings.HasSuffix(lower, "cite")
+}
+
+// splitKeys splits a comma separated key list
+func splitKeys(arg string) []string {
+	var keys []string
+	for _, key := range strings.Split(arg, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// cleanTitle collapses the whitespace of a section title
+func cleanTitle(title string) string {
+	return strings.Join(strings.FieldsFunc(title, unicode.IsSpace), " ")
+}
+
+func isLetter(c byte) bool {
+	return c < utf8.RuneSelf && unicode.IsLetter(rune(c))
+}
+
+// lineIndex maps byte offsets to 1-based line numbers
+type lineIndex []int
+
+func newLineIndex(text string) lineIndex {
+	starts := lineIndex{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return starts
+}
+
+func (l lineIndex) line(offset int) int {
+	return sort.Search(len(l), func(i int) bool { return l[i] > offset })
+}
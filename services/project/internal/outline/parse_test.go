@@ -0,0 +1,170 @@
+package outline
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseTeXIncludes(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Include
+	}{
+		{
+			name:    "input and include",
+			content: "\\input{intro}\n\\include{chapters/one}\n\\subfile{ appendix.tex }",
+			want: []Include{
+				{Command: "input", Path: "intro", Line: 1},
+				{Command: "include", Path: "chapters/one", Line: 2},
+				{Command: "subfile", Path: "appendix.tex", Line: 3},
+			},
+		},
+		{
+			name:    "argument on the next line",
+			content: "\\input\n{intro}",
+			want:    []Include{{Command: "input", Path: "intro", Line: 1}},
+		},
+		{
+			name:    "unterminated argument",
+			content: "\\input{intro\n\\section{Intro}",
+		},
+		{
+			name:    "missing argument",
+			content: "\\input intro",
+		},
+		{
+			name:    "empty argument",
+			content: "\\input{ }",
+		},
+		{
+			name:    "commented out",
+			content: "% \\input{old}\n\\input{new} % \\input{older}",
+			want:    []Include{{Command: "input", Path: "new", Line: 2}},
+		},
+		{
+			name:    "escaped percent is not a comment",
+			content: "50\\% \\input{rest}",
+			want:    []Include{{Command: "input", Path: "rest", Line: 1}},
+		},
+		{
+			name:    "inside verbatim",
+			content: "\\begin{verbatim}\n\\input{shown}\n\\end{verbatim}\n\\input{real}",
+			want:    []Include{{Command: "input", Path: "real", Line: 4}},
+		},
+		{
+			name:    "unterminated verbatim",
+			content: "\\begin{lstlisting}\n\\input{shown}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseTeX(tt.content).Includes
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseTeX().Includes = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTeXStructure(t *testing.T) {
+	content := `\documentclass[11pt]{article}
+\usepackage{amsmath, local}
+\begin{document}
+\section[Short]{A  long
+  title}\label{sec:intro}
+\subsection*{Unnumbered}
+See \cref{sec:intro,sec:other} and \citep[p.~2]{knuth84, lamport94}.
+\includegraphics[width=\linewidth]{figures/plot}
+\graphicspath{{img/}{ figures/ }}
+\bibliography{refs,more}
+\end{document}`
+
+	got := ParseTeX(content)
+	want := &FileIndex{
+		DocumentClass: true,
+		Sections: []Section{
+			{Level: 1, Command: "section", Title: "A long title", Line: 4},
+			{Level: 2, Command: "subsection", Title: "Unnumbered", Starred: true, Line: 6},
+		},
+		Labels: []Label{{Name: "sec:intro", Line: 5}},
+		References: []Reference{
+			{Command: "cref", Key: "sec:intro", Line: 7},
+			{Command: "cref", Key: "sec:other", Line: 7},
+		},
+		Citations: []Reference{
+			{Command: "citep", Key: "knuth84", Line: 7},
+			{Command: "citep", Key: "lamport94", Line: 7},
+		},
+		Bibliographies: []Include{
+			{Command: "bibliography", Path: "refs", Line: 10},
+			{Command: "bibliography", Path: "more", Line: 10},
+		},
+		Graphics:      []Include{{Command: "includegraphics", Path: "figures/plot", Line: 8}},
+		GraphicsPaths: []string{"img/", "figures/"},
+		Packages: []Include{
+			{Command: "documentclass", Path: "article", Line: 1},
+			{Command: "usepackage", Path: "amsmath", Line: 2},
+			{Command: "usepackage", Path: "local", Line: 2},
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTeX() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestParseBib(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []BibEntry
+	}{
+		{
+			name:    "entries",
+			content: "@Article{knuth84,\n  title = {Literate}\n}\n\n@book(lamport94, title = \"LaTeX\")",
+			want: []BibEntry{
+				{Type: "article", Key: "knuth84", Line: 1},
+				{Type: "book", Key: "lamport94", Line: 5},
+			},
+		},
+		{
+			name:    "string, comment and preamble are not entries",
+			content: "@string{acm = {ACM}}\n@comment{@misc{hidden,}}\n@preamble{\"\\newcommand{\\x}{}\"}\n@misc{shown,}",
+			want:    []BibEntry{{Type: "misc", Key: "shown", Line: 4}},
+		},
+		{
+			name:    "nested braces in a comment",
+			content: "@comment{see {@misc{hidden,}} and {{@book{deeper,}}}}\n@comment(@misc{paren,})\n@misc{shown,}",
+			want:    []BibEntry{{Type: "misc", Key: "shown", Line: 3}},
+		},
+		{
+			name:    "unterminated comment",
+			content: "@comment{open\n@misc{after,}",
+			want:    []BibEntry{{Type: "misc", Key: "after", Line: 2}},
+		},
+		{
+			name:    "missing key",
+			content: "@article{,\n title = {None}}\n@misc{  }",
+		},
+		{
+			name:    "unterminated entry",
+			content: "@article{knuth84",
+		},
+		{
+			name:    "at sign in text",
+			content: "mail me @ home\n@misc{key,}",
+			want:    []BibEntry{{Type: "misc", Key: "key", Line: 2}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseBib(tt.content).BibEntries
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseBib().BibEntries = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
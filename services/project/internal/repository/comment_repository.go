@@ -0,0 +1,226 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CommentRepository handles comment thread persistence. Changes to a thread
+// only apply while it is open, so that resolved threads stay as they were.
+type CommentRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewCommentRepository creates a new comment repository
+func NewCommentRepository(db *mongo.Database) *CommentRepository {
+	return &CommentRepository{
+		db:         db,
+		collection: db.Collection("comment_threads"),
+	}
+}
+
+// Create creates a new comment thread
+func (r *CommentRepository) Create(ctx context.Context, thread *models.CommentThread) error {
+	if thread.ID.IsZero() {
+		thread.ID = primitive.NewObjectID()
+	}
+	thread.CreatedAt = time.Now()
+	thread.UpdatedAt = thread.CreatedAt
+
+	_, err := r.collection.InsertOne(ctx, thread)
+	return err
+}
+
+// FindByID finds a comment thread by ID
+func (r *CommentRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.CommentThread, error) {
+	var thread models.CommentThread
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&thread)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("comment thread not found")
+		}
+		return nil, err
+	}
+
+	return &thread, nil
+}
+
+// FindByProject lists the threads of a project, oldest first, optionally
+// only those of one file or in one state
+func (r *CommentRepository) FindByProject(ctx context.Context, projectID primitive.ObjectID, fileID *primitive.ObjectID, status string) ([]*models.CommentThread, error) {
+	filter := bson.M{"project_id": projectID}
+	if fileID != nil {
+		filter["file_id"] = *fileID
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	threads := []*models.CommentThread{}
+	if err := cursor.All(ctx, &threads); err != nil {
+		return nil, err
+	}
+
+	return threads, nil
+}
+
+// FindOpenByFile lists the open threads of a file
+func (r *CommentRepository) FindOpenByFile(ctx context.Context, fileID primitive.ObjectID) ([]*models.CommentThread, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"file_id": fileID, "status": models.CommentThreadOpen})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var threads []*models.CommentThread
+	if err := cursor.All(ctx, &threads); err != nil {
+		return nil, err
+	}
+
+	return threads, nil
+}
+
+// AddComment appends a reply to an open thread
+func (r *CommentRepository) AddComment(ctx context.Context, threadID primitive.ObjectID, comment *models.Comment) (*models.CommentThread, error) {
+	return r.updateOpen(ctx, bson.M{"_id": threadID}, bson.M{
+		"$push": bson.M{"comments": comment},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+// UpdateComment replaces the body and mentions of a comment in an open
+// thread
+func (r *CommentRepository) UpdateComment(ctx context.Context, threadID, commentID primitive.ObjectID, body string, mentions []primitive.ObjectID) (*models.CommentThread, error) {
+	now := time.Now()
+	return r.updateOpen(ctx, bson.M{"_id": threadID, "comments._id": commentID}, bson.M{
+		"$set": bson.M{
+			"comments.$.body":      body,
+			"comments.$.mentions":  mentions,
+			"comments.$.edited_at": now,
+			"updated_at":           now,
+		},
+	})
+}
+
+// RemoveComment removes a reply from an open thread
+func (r *CommentRepository) RemoveComment(ctx context.Context, threadID, commentID primitive.ObjectID) (*models.CommentThread, error) {
+	return r.updateOpen(ctx, bson.M{"_id": threadID, "comments._id": commentID}, bson.M{
+		"$pull": bson.M{"comments": bson.M{"_id": commentID}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	})
+}
+
+// Resolve marks an open thread as resolved
+func (r *CommentRepository) Resolve(ctx context.Context, threadID, userID primitive.ObjectID) (*models.CommentThread, error) {
+	now := time.Now()
+	return r.updateOpen(ctx, bson.M{"_id": threadID}, bson.M{
+		"$set": bson.M{
+			"status":      models.CommentThreadResolved,
+			"resolved_by": userID,
+			"resolved_at": now,
+			"updated_at":  now,
+		},
+	})
+}
+
+// Reopen marks a resolved thread as open again
+func (r *CommentRepository) Reopen(ctx context.Context, threadID primitive.ObjectID) (*models.CommentThread, error) {
+	var thread models.CommentThread
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": threadID, "status": models.CommentThreadResolved},
+		bson.M{
+			"$set":   bson.M{"status": models.CommentThreadOpen, "updated_at": time.Now()},
+			"$unset": bson.M{"resolved_by": "", "resolved_at": ""},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&thread)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("comment thread is not resolved")
+		}
+		return nil, err
+	}
+
+	return &thread, nil
+}
+
+// SetAnchor moves the anchor of an open thread after its file was edited
+func (r *CommentRepository) SetAnchor(ctx context.Context, threadID primitive.ObjectID, anchor models.CommentAnchor) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": threadID, "status": models.CommentThreadOpen},
+		bson.M{"$set": bson.M{"anchor": anchor}},
+	)
+	return err
+}
+
+// Delete deletes an open thread
+func (r *CommentRepository) Delete(ctx context.Context, threadID primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": threadID, "status": models.CommentThreadOpen})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("comment thread is resolved")
+	}
+
+	return nil
+}
+
+// DeleteByProjectID deletes all threads of a project
+func (r *CommentRepository) DeleteByProjectID(ctx context.Context, projectID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"project_id": projectID})
+	return err
+}
+
+// updateOpen applies an update to an open thread and returns the result
+func (r *CommentRepository) updateOpen(ctx context.Context, filter, update bson.M) (*models.CommentThread, error) {
+	filter["status"] = models.CommentThreadOpen
+
+	var thread models.CommentThread
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		filter,
+		update,
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&thread)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("comment thread is resolved")
+		}
+		return nil, err
+	}
+
+	return &thread, nil
+}
+
+// CreateIndexes creates necessary indexes
+func (r *CommentRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "file_id", Value: 1}, {Key: "created_at", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "file_id", Value: 1}, {Key: "status", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
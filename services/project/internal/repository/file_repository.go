@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/texflow/services/project/internal/models"
@@ -115,15 +116,51 @@ func (r *FileRepository) FindByName(ctx context.Context, name string) ([]*models
 	return files, nil
 }
 
-// Update updates a file record
+// Update saves a file record read at its current version and increments
+// the version. It fails with "file has been modified" when another update
+// saved the file since it was read.
 func (r *FileRepository) Update(ctx context.Context, file *models.File) error {
+	expected := file.Version
+	updatedAt := file.UpdatedAt
 	file.UpdatedAt = time.Now()
 	file.Version++
 
-	filter := bson.M{"_id": file.ID}
+	filter := bson.M{"_id": file.ID, "version": expected}
 	update := bson.M{"$set": file}
 
 	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err == nil && result.MatchedCount == 0 {
+		err = r.updateConflict(ctx, file.ID)
+	}
+	if err != nil {
+		file.Version = expected
+		file.UpdatedAt = updatedAt
+		return err
+	}
+
+	return nil
+}
+
+// updateConflict tells why a versioned update matched no file
+func (r *FileRepository) updateConflict(ctx context.Context, id primitive.ObjectID) error {
+	count, err := r.collection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return fmt.Errorf("file not found")
+	}
+	return fmt.Errorf("file has been modified")
+}
+
+// SetContent records the size and hash of the stored content of a file
+// without creating a new version
+func (r *FileRepository) SetContent(ctx context.Context, id primitive.ObjectID, size int64, hash string) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"size_bytes": size, "hash": hash, "updated_at": time.Now()}},
+	)
 	if err != nil {
 		return err
 	}
@@ -135,6 +172,64 @@ func (r *FileRepository) Update(ctx context.Context, file *models.File) error {
 	return nil
 }
 
+// FindByPathPrefix finds all files of a project whose path starts with prefix
+func (r *FileRepository) FindByPathPrefix(ctx context.Context, projectID primitive.ObjectID, prefix string) ([]*models.File, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.M{
+			"project_id": projectID,
+			"path":       bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)},
+		},
+		options.Find().SetSort(bson.D{{Key: "path", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var files []*models.File
+	if err := cursor.All(ctx, &files); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Relocate updates the name, path and storage location of a file read at its
+// current version and increments the version. Like Update, it fails with
+// "file has been modified" when another update saved the file since it was
+// read, so that content written meanwhile is not pointed away from.
+func (r *FileRepository) Relocate(ctx context.Context, file *models.File) error {
+	expected := file.Version
+	updatedAt := file.UpdatedAt
+	file.UpdatedAt = time.Now()
+	file.Version++
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": file.ID, "version": expected}, bson.M{
+		"$set": bson.M{
+			"name":         file.Name,
+			"path":         file.Path,
+			"storage_key":  file.StorageKey,
+			"content_type": file.ContentType,
+			"version":      file.Version,
+			"updated_at":   file.UpdatedAt,
+		},
+	})
+	if err == nil && result.MatchedCount == 0 {
+		err = r.updateConflict(ctx, file.ID)
+	}
+	if err != nil {
+		file.Version = expected
+		file.UpdatedAt = updatedAt
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("file already exists at this path")
+		}
+		return err
+	}
+
+	return nil
+}
+
 // Delete deletes a file record
 func (r *FileRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
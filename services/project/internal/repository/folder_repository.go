@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FolderRepository handles folder data persistence
+type FolderRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewFolderRepository creates a new folder repository
+func NewFolderRepository(db *mongo.Database) *FolderRepository {
+	return &FolderRepository{
+		db:         db,
+		collection: db.Collection("folders"),
+	}
+}
+
+// Create creates a new folder record
+func (r *FolderRepository) Create(ctx context.Context, folder *models.Folder) error {
+	folder.ID = primitive.NewObjectID()
+	folder.CreatedAt = time.Now()
+	folder.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, folder)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("folder already exists at this path")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindByID finds a folder by ID
+func (r *FolderRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&folder)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("folder not found")
+		}
+		return nil, err
+	}
+
+	return &folder, nil
+}
+
+// FindByPath finds a folder by project ID and path
+func (r *FolderRepository) FindByPath(ctx context.Context, projectID primitive.ObjectID, path string) (*models.Folder, error) {
+	var folder models.Folder
+	err := r.collection.FindOne(ctx, bson.M{
+		"project_id": projectID,
+		"path":       path,
+	}).Decode(&folder)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("folder not found")
+		}
+		return nil, err
+	}
+
+	return &folder, nil
+}
+
+// FindByProjectID finds all folders in a project
+func (r *FolderRepository) FindByProjectID(ctx context.Context, projectID primitive.ObjectID) ([]*models.Folder, error) {
+	return r.find(ctx, bson.M{"project_id": projectID})
+}
+
+// FindByPathPrefix finds all folders of a project whose path starts with prefix
+func (r *FolderRepository) FindByPathPrefix(ctx context.Context, projectID primitive.ObjectID, prefix string) ([]*models.Folder, error) {
+	return r.find(ctx, bson.M{
+		"project_id": projectID,
+		"path":       bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)},
+	})
+}
+
+func (r *FolderRepository) find(ctx context.Context, filter bson.M) ([]*models.Folder, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "path", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var folders []*models.Folder
+	if err := cursor.All(ctx, &folders); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+// Relocate updates the name and path of a folder
+func (r *FolderRepository) Relocate(ctx context.Context, folder *models.Folder) error {
+	folder.UpdatedAt = time.Now()
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": folder.ID}, bson.M{
+		"$set": bson.M{
+			"name":       folder.Name,
+			"path":       folder.Path,
+			"updated_at": folder.UpdatedAt,
+		},
+	})
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("folder already exists at this path")
+		}
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("folder not found")
+	}
+
+	return nil
+}
+
+// DeleteTree deletes a folder and all folders below it
+func (r *FolderRepository) DeleteTree(ctx context.Context, projectID primitive.ObjectID, path string) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{
+		"project_id": projectID,
+		"$or": []bson.M{
+			{"path": path},
+			{"path": bson.M{"$regex": "^" + regexp.QuoteMeta(path+"/")}},
+		},
+	})
+	return err
+}
+
+// DeleteByProjectID deletes all folders in a project
+func (r *FolderRepository) DeleteByProjectID(ctx context.Context, projectID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"project_id": projectID})
+	return err
+}
+
+// CreateIndexes creates necessary indexes
+func (r *FolderRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "project_id", Value: 1}, {Key: "path", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
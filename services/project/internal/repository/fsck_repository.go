@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// FsckRepository handles storage check report persistence
+type FsckRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewFsckRepository creates a new storage check repository
+func NewFsckRepository(db *mongo.Database) *FsckRepository {
+	return &FsckRepository{
+		db:         db,
+		collection: db.Collection("fsck_reports"),
+	}
+}
+
+// Create creates a new report
+func (r *FsckRepository) Create(ctx context.Context, report *models.FsckReport) error {
+	if report.ID.IsZero() {
+		report.ID = primitive.NewObjectID()
+	}
+
+	_, err := r.collection.InsertOne(ctx, report)
+	return err
+}
+
+// Save replaces a report with its current state
+func (r *FsckRepository) Save(ctx context.Context, report *models.FsckReport) error {
+	_, err := r.collection.ReplaceOne(ctx, bson.M{"_id": report.ID}, report)
+	return err
+}
+
+// FindByID finds a report by ID
+func (r *FsckRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.FsckReport, error) {
+	var report models.FsckReport
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&report)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("storage check not found")
+		}
+		return nil, err
+	}
+
+	return &report, nil
+}
+
+// FindRecent lists the latest reports without their issues
+func (r *FsckRepository) FindRecent(ctx context.Context, limit int) ([]*models.FsckReport, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "started_at", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"issues": 0})
+
+	cursor, err := r.collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var reports []*models.FsckReport
+	if err := cursor.All(ctx, &reports); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+// CreateIndexes creates necessary indexes
+func (r *FsckRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "started_at", Value: -1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GitRepository handles the Git state of projects and the pending web edits
+// that have not been committed yet
+type GitRepository struct {
+	db      *mongo.Database
+	repos   *mongo.Collection
+	pending *mongo.Collection
+}
+
+// NewGitRepository creates a new Git repository
+func NewGitRepository(db *mongo.Database) *GitRepository {
+	return &GitRepository{
+		db:      db,
+		repos:   db.Collection("git_repos"),
+		pending: db.Collection("git_pending_changes"),
+	}
+}
+
+// Lock leases the Git state of a project, creating it on first use. It
+// returns "repository is busy" while another lease is held. An expired
+// lease, e.g. after a crash, can be taken over.
+func (r *GitRepository) Lock(ctx context.Context, projectID primitive.ObjectID, lease time.Duration) (*models.GitRepo, error) {
+	now := time.Now()
+
+	filter := bson.M{
+		"_id": projectID,
+		"$or": []bson.M{
+			{"locked_until": nil},
+			{"locked_until": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{
+		"$set":         bson.M{"locked_until": now.Add(lease)},
+		"$setOnInsert": bson.M{"index": []models.GitIndexEntry{}, "updated_at": now},
+	}
+	opts := options.FindOneAndUpdate().
+		SetUpsert(true).
+		SetReturnDocument(options.After)
+
+	var repo models.GitRepo
+	err := r.repos.FindOneAndUpdate(ctx, filter, update, opts).Decode(&repo)
+	if err != nil {
+		// The upsert collides with the existing document while it is locked
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("repository is busy")
+		}
+		return nil, err
+	}
+
+	return &repo, nil
+}
+
+// Unlock releases the lease on the Git state of a project
+func (r *GitRepository) Unlock(ctx context.Context, projectID primitive.ObjectID) error {
+	_, err := r.repos.UpdateOne(ctx, bson.M{"_id": projectID}, bson.M{
+		"$unset": bson.M{"locked_until": ""},
+	})
+	return err
+}
+
+// UpdateHead moves the branch head and replaces the index
+func (r *GitRepository) UpdateHead(ctx context.Context, projectID primitive.ObjectID, head string, index []models.GitIndexEntry) error {
+	_, err := r.repos.UpdateOne(ctx, bson.M{"_id": projectID}, bson.M{
+		"$set": bson.M{
+			"head":       head,
+			"index":      index,
+			"updated_at": time.Now(),
+		},
+	})
+	return err
+}
+
+// RecordChanges records that a user changed the given paths
+func (r *GitRepository) RecordChanges(ctx context.Context, projectID, userID primitive.ObjectID, paths ...string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, 0, len(paths))
+	for _, p := range paths {
+		docs = append(docs, models.GitPendingChange{
+			ID:        primitive.NewObjectID(),
+			ProjectID: projectID,
+			Path:      p,
+			UserID:    userID,
+			ChangedAt: now,
+		})
+	}
+
+	_, err := r.pending.InsertMany(ctx, docs)
+	return err
+}
+
+// FindPendingChanges finds the pending changes of a project, oldest first
+func (r *GitRepository) FindPendingChanges(ctx context.Context, projectID primitive.ObjectID) ([]*models.GitPendingChange, error) {
+	cursor, err := r.pending.Find(
+		ctx,
+		bson.M{"project_id": projectID},
+		options.Find().SetSort(bson.D{{Key: "changed_at", Value: 1}, {Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var changes []*models.GitPendingChange
+	if err := cursor.All(ctx, &changes); err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// DeletePendingChanges deletes the pending changes recorded up to a time
+func (r *GitRepository) DeletePendingChanges(ctx context.Context, projectID primitive.ObjectID, until time.Time) error {
+	_, err := r.pending.DeleteMany(ctx, bson.M{
+		"project_id": projectID,
+		"changed_at": bson.M{"$lte": until},
+	})
+	return err
+}
+
+// DeleteByProjectID deletes the Git state and pending changes of a project
+func (r *GitRepository) DeleteByProjectID(ctx context.Context, projectID primitive.ObjectID) error {
+	if _, err := r.pending.DeleteMany(ctx, bson.M{"project_id": projectID}); err != nil {
+		return err
+	}
+	_, err := r.repos.DeleteOne(ctx, bson.M{"_id": projectID})
+	return err
+}
+
+// CreateIndexes creates necessary indexes
+func (r *GitRepository) CreateIndexes(ctx context.Context) error {
+	_, err := r.pending.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "changed_at", Value: 1}},
+	})
+	return err
+}
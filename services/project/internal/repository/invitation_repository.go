@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InvitationRepository handles collaborator invitation persistence
+type InvitationRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewInvitationRepository creates a new invitation repository
+func NewInvitationRepository(db *mongo.Database) *InvitationRepository {
+	return &InvitationRepository{
+		db:         db,
+		collection: db.Collection("project_invitations"),
+	}
+}
+
+// Create creates a new pending invitation
+func (r *InvitationRepository) Create(ctx context.Context, invitation *models.Invitation) error {
+	if invitation.ID.IsZero() {
+		invitation.ID = primitive.NewObjectID()
+	}
+	invitation.Status = models.InvitationStatusPending
+	invitation.CreatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, invitation)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("invitation already pending for this email")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindByID finds an invitation by ID
+func (r *InvitationRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&invitation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("invitation not found")
+		}
+		return nil, err
+	}
+
+	return &invitation, nil
+}
+
+// FindByProject lists the invitations of a project, newest first. An empty
+// status lists all of them.
+func (r *InvitationRepository) FindByProject(ctx context.Context, projectID primitive.ObjectID, status string) ([]*models.Invitation, error) {
+	filter := bson.M{"project_id": projectID}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	return r.find(ctx, filter)
+}
+
+// FindPendingForUser lists the pending invitations addressed to a user,
+// either directly or by email
+func (r *InvitationRepository) FindPendingForUser(ctx context.Context, userID primitive.ObjectID, email string) ([]*models.Invitation, error) {
+	return r.find(ctx, bson.M{
+		"status": models.InvitationStatusPending,
+		"$or": []bson.M{
+			{"invitee_id": userID},
+			{"email": email},
+		},
+	})
+}
+
+// Respond moves a pending invitation to the accepted or declined status
+func (r *InvitationRepository) Respond(ctx context.Context, id, inviteeID primitive.ObjectID, status string) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": models.InvitationStatusPending},
+		bson.M{"$set": bson.M{
+			"status":       status,
+			"invitee_id":   inviteeID,
+			"responded_at": time.Now(),
+		}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("invitation is no longer pending")
+	}
+
+	return nil
+}
+
+// Delete deletes an invitation
+func (r *InvitationRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("invitation not found")
+	}
+
+	return nil
+}
+
+// DeleteByProjectID deletes all invitations of a project
+func (r *InvitationRepository) DeleteByProjectID(ctx context.Context, projectID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"project_id": projectID})
+	return err
+}
+
+func (r *InvitationRepository) find(ctx context.Context, filter bson.M) ([]*models.Invitation, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var invitations []*models.Invitation
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, err
+	}
+
+	return invitations, nil
+}
+
+// CreateIndexes creates necessary indexes
+func (r *InvitationRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			// At most one pending invitation per project and email
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "email", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"status": models.InvitationStatusPending,
+			}),
+		},
+		{
+			Keys: bson.D{{Key: "email", Value: 1}, {Key: "status", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "invitee_id", Value: 1}, {Key: "status", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
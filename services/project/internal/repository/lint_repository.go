@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LintRepository stores the lint warnings of each .tex file
+type LintRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewLintRepository creates a new lint repository
+func NewLintRepository(db *mongo.Database) *LintRepository {
+	return &LintRepository{
+		db:         db,
+		collection: db.Collection("lint_results"),
+	}
+}
+
+// Upsert stores the warnings of a file, replacing its previous result
+func (r *LintRepository) Upsert(ctx context.Context, result *models.LintResult) error {
+	result.LintedAt = time.Now()
+
+	_, err := r.collection.ReplaceOne(
+		ctx,
+		bson.M{"_id": result.FileID},
+		result,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// FindByFileID returns the lint result of a file, or nil if it has none
+func (r *LintRepository) FindByFileID(ctx context.Context, fileID primitive.ObjectID) (*models.LintResult, error) {
+	var result models.LintResult
+	err := r.collection.FindOne(ctx, bson.M{"_id": fileID}).Decode(&result)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+// FindByProjectID returns the lint results of a project's files
+func (r *LintRepository) FindByProjectID(ctx context.Context, projectID primitive.ObjectID) ([]*models.LintResult, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"project_id": projectID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var results []*models.LintResult
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// Delete removes the lint result of a file
+func (r *LintRepository) Delete(ctx context.Context, fileID primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": fileID})
+	return err
+}
+
+// DeleteByProjectID removes the lint results of all files of a project
+func (r *LintRepository) DeleteByProjectID(ctx context.Context, projectID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"project_id": projectID})
+	return err
+}
+
+// CreateIndexes creates necessary indexes
+func (r *LintRepository) CreateIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "project_id", Value: 1}},
+	})
+	return err
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// readNotificationTTL is how long notifications are kept once read
+const readNotificationTTL = 90 * 24 * time.Hour
+
+// NotificationRepository handles user notification persistence
+type NotificationRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewNotificationRepository creates a new notification repository
+func NewNotificationRepository(db *mongo.Database) *NotificationRepository {
+	return &NotificationRepository{
+		db:         db,
+		collection: db.Collection("notifications"),
+	}
+}
+
+// CreateMany stores notifications
+func (r *NotificationRepository) CreateMany(ctx context.Context, notifications []*models.Notification) error {
+	if len(notifications) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(notifications))
+	for i, n := range notifications {
+		if n.ID.IsZero() {
+			n.ID = primitive.NewObjectID()
+		}
+		n.CreatedAt = now
+		docs[i] = n
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs)
+	return err
+}
+
+// FindByUser lists the notifications of a user, newest first
+func (r *NotificationRepository) FindByUser(ctx context.Context, userID primitive.ObjectID, unreadOnly bool, limit int64) ([]*models.Notification, error) {
+	filter := bson.M{"user_id": userID}
+	if unreadOnly {
+		filter["read_at"] = nil
+	}
+
+	cursor, err := r.collection.Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}).SetLimit(limit),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	notifications := []*models.Notification{}
+	if err := cursor.All(ctx, &notifications); err != nil {
+		return nil, err
+	}
+
+	return notifications, nil
+}
+
+// CountUnread counts the unread notifications of a user
+func (r *NotificationRepository) CountUnread(ctx context.Context, userID primitive.ObjectID) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"user_id": userID, "read_at": nil})
+}
+
+// MarkRead marks notifications of a user as read, or all of them when ids
+// is empty
+func (r *NotificationRepository) MarkRead(ctx context.Context, userID primitive.ObjectID, ids []primitive.ObjectID) (int64, error) {
+	filter := bson.M{"user_id": userID, "read_at": nil}
+	if len(ids) > 0 {
+		filter["_id"] = bson.M{"$in": ids}
+	}
+
+	result, err := r.collection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"read_at": time.Now()}})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// DeleteByProjectID deletes all notifications about a project
+func (r *NotificationRepository) DeleteByProjectID(ctx context.Context, projectID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"project_id": projectID})
+	return err
+}
+
+// CreateIndexes creates necessary indexes
+func (r *NotificationRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}},
+		},
+		{
+			// Read notifications are removed by MongoDB; unread ones have no
+			// read_at and are kept
+			Keys:    bson.D{{Key: "read_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(int32(readNotificationTTL.Seconds())),
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OutlineRepository stores the parsed structure of each .tex and .bib file
+// so that the project index only reparses files that changed
+type OutlineRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewOutlineRepository creates a new outline repository
+func NewOutlineRepository(db *mongo.Database) *OutlineRepository {
+	return &OutlineRepository{
+		db:         db,
+		collection: db.Collection("outline_documents"),
+	}
+}
+
+// Upsert stores the structure of a file, replacing its previous entry
+func (r *OutlineRepository) Upsert(ctx context.Context, doc *models.OutlineDocument) error {
+	doc.UpdatedAt = time.Now()
+
+	_, err := r.collection.ReplaceOne(
+		ctx,
+		bson.M{"_id": doc.FileID},
+		doc,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// UpdatePath records that a parsed file moved
+func (r *OutlineRepository) UpdatePath(ctx context.Context, fileID primitive.ObjectID, path string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": fileID},
+		bson.M{"$set": bson.M{"path": path, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// FindByProjectID returns the parsed files of a project
+func (r *OutlineRepository) FindByProjectID(ctx context.Context, projectID primitive.ObjectID) ([]*models.OutlineDocument, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"project_id": projectID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []*models.OutlineDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// Delete removes a file from the outline index
+func (r *OutlineRepository) Delete(ctx context.Context, fileID primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": fileID})
+	return err
+}
+
+// DeleteByProjectID removes all files of a project from the outline index
+func (r *OutlineRepository) DeleteByProjectID(ctx context.Context, projectID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"project_id": projectID})
+	return err
+}
+
+// CreateIndexes creates necessary indexes
+func (r *OutlineRepository) CreateIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "project_id", Value: 1}},
+	})
+	return err
+}
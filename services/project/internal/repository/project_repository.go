@@ -53,10 +53,11 @@ func (r *ProjectRepository) FindByID(ctx context.Context, id primitive.ObjectID)
 }
 
 // FindByOwner finds all projects owned by a user
-func (r *ProjectRepository) FindByOwner(ctx context.Context, ownerID primitive.ObjectID, page, limit int) ([]*models.Project, int64, error) {
+func (r *ProjectRepository) FindByOwner(ctx context.Context, ownerID primitive.ObjectID, status string, page, limit int) ([]*models.Project, int64, error) {
 	skip := (page - 1) * limit
 
-	filter := bson.M{"owner_id": ownerID}
+	filter := projectStatusFilter(status)
+	filter["owner_id"] = ownerID
 
 	// Get total count
 	total, err := r.collection.CountDocuments(ctx, filter)
@@ -86,13 +87,14 @@ func (r *ProjectRepository) FindByOwner(ctx context.Context, ownerID primitive.O
 	return projects, total, nil
 }
 
-// FindSharedWithUser finds projects shared with a user
-func (r *ProjectRepository) FindSharedWithUser(ctx context.Context, userID primitive.ObjectID, page, limit int) ([]*models.Project, int64, error) {
+// FindSharedWithUser finds projects shared with a user. Collaborators are
+// only added once they accept an invitation, so pending invitations are not
+// listed here.
+func (r *ProjectRepository) FindSharedWithUser(ctx context.Context, userID primitive.ObjectID, status string, page, limit int) ([]*models.Project, int64, error) {
 	skip := (page - 1) * limit
 
-	filter := bson.M{
-		"collaborators.user_id": userID,
-	}
+	filter := projectStatusFilter(status)
+	filter["collaborators.user_id"] = userID
 
 	// Get total count
 	total, err := r.collection.CountDocuments(ctx, filter)
@@ -122,6 +124,18 @@ func (r *ProjectRepository) FindSharedWithUser(ctx context.Context, userID primi
 	return projects, total, nil
 }
 
+// projectStatusFilter matches the projects in a list status
+func projectStatusFilter(status string) bson.M {
+	switch status {
+	case models.ProjectStatusArchived:
+		return bson.M{"deleted_at": nil, "archived_at": bson.M{"$ne": nil}}
+	case models.ProjectStatusTrashed:
+		return bson.M{"deleted_at": bson.M{"$ne": nil}}
+	default:
+		return bson.M{"deleted_at": nil, "archived_at": nil}
+	}
+}
+
 // Update updates a project
 func (r *ProjectRepository) Update(ctx context.Context, project *models.Project) error {
 	project.UpdatedAt = time.Now()
@@ -141,9 +155,10 @@ func (r *ProjectRepository) Update(ctx context.Context, project *models.Project)
 	return nil
 }
 
-// AddCollaborator adds a collaborator to a project
+// AddCollaborator adds a collaborator to a project unless the user already
+// collaborates on it
 func (r *ProjectRepository) AddCollaborator(ctx context.Context, projectID primitive.ObjectID, collaborator models.Collaborator) error {
-	filter := bson.M{"_id": projectID}
+	filter := bson.M{"_id": projectID, "collaborators.user_id": bson.M{"$ne": collaborator.UserID}}
 	update := bson.M{
 		"$push": bson.M{"collaborators": collaborator},
 		"$set":  bson.M{"updated_at": time.Now()},
@@ -155,7 +170,26 @@ func (r *ProjectRepository) AddCollaborator(ctx context.Context, projectID primi
 	}
 
 	if result.MatchedCount == 0 {
-		return fmt.Errorf("project not found")
+		return fmt.Errorf("project already shared with this user")
+	}
+
+	return nil
+}
+
+// UpdateCollaboratorRole changes the role of a collaborator
+func (r *ProjectRepository) UpdateCollaboratorRole(ctx context.Context, projectID, userID primitive.ObjectID, role string) error {
+	filter := bson.M{"_id": projectID, "collaborators.user_id": userID}
+	update := bson.M{
+		"$set": bson.M{"collaborators.$.role": role, "updated_at": time.Now()},
+	}
+
+	result, err := r.collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("collaborator not found")
 	}
 
 	return nil
@@ -163,7 +197,7 @@ func (r *ProjectRepository) AddCollaborator(ctx context.Context, projectID primi
 
 // RemoveCollaborator removes a collaborator from a project
 func (r *ProjectRepository) RemoveCollaborator(ctx context.Context, projectID, userID primitive.ObjectID) error {
-	filter := bson.M{"_id": projectID}
+	filter := bson.M{"_id": projectID, "collaborators.user_id": userID}
 	update := bson.M{
 		"$pull": bson.M{"collaborators": bson.M{"user_id": userID}},
 		"$set":  bson.M{"updated_at": time.Now()},
@@ -174,6 +208,29 @@ func (r *ProjectRepository) RemoveCollaborator(ctx context.Context, projectID, u
 		return err
 	}
 
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("collaborator not found")
+	}
+
+	return nil
+}
+
+// SetArchived archives a project at the given time, or unarchives it when
+// archivedAt is nil
+func (r *ProjectRepository) SetArchived(ctx context.Context, id primitive.ObjectID, archivedAt *time.Time) error {
+	update := bson.M{"$set": bson.M{"archived_at": archivedAt, "updated_at": time.Now()}}
+	if archivedAt == nil {
+		update = bson.M{
+			"$unset": bson.M{"archived_at": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		}
+	}
+
+	result, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+
 	if result.MatchedCount == 0 {
 		return fmt.Errorf("project not found")
 	}
@@ -181,6 +238,161 @@ func (r *ProjectRepository) RemoveCollaborator(ctx context.Context, projectID, u
 	return nil
 }
 
+// MoveToTrash marks a project as deleted until it is purged at purgeAt
+func (r *ProjectRepository) MoveToTrash(ctx context.Context, id primitive.ObjectID, deletedAt, purgeAt time.Time) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "deleted_at": nil},
+		bson.M{"$set": bson.M{"deleted_at": deletedAt, "purge_at": purgeAt}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("project is in trash")
+	}
+
+	return nil
+}
+
+// RestoreFromTrash clears the deletion of a trashed project
+func (r *ProjectRepository) RestoreFromTrash(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "deleted_at": bson.M{"$ne": nil}},
+		bson.M{
+			"$unset": bson.M{"deleted_at": "", "purge_at": ""},
+			"$set":   bson.M{"updated_at": time.Now()},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("project is not in trash")
+	}
+
+	return nil
+}
+
+// FindPurgeable finds trashed projects whose purge time has passed
+func (r *ProjectRepository) FindPurgeable(ctx context.Context, now time.Time, limit int) ([]*models.Project, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.M{"purge_at": bson.M{"$lte": now}},
+		options.Find().
+			SetLimit(int64(limit)).
+			SetSort(bson.D{{Key: "purge_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var projects []*models.Project
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// FindAccessible finds the projects a user owns, collaborates on or holds a
+// share link grant for, most recently updated first. Trashed projects are
+// left out.
+func (r *ProjectRepository) FindAccessible(ctx context.Context, userID primitive.ObjectID, grantedIDs []primitive.ObjectID, limit int) ([]*models.Project, error) {
+	access := []bson.M{
+		{"owner_id": userID},
+		{"collaborators.user_id": userID},
+	}
+	if len(grantedIDs) > 0 {
+		access = append(access, bson.M{"_id": bson.M{"$in": grantedIDs}})
+	}
+
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.M{"deleted_at": nil, "$or": access},
+		options.Find().
+			SetLimit(int64(limit)).
+			SetSort(bson.D{{Key: "updated_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var projects []*models.Project
+	if err := cursor.All(ctx, &projects); err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+// SetSearchIndexed records when the search index of a project was rebuilt
+func (r *ProjectRepository) SetSearchIndexed(ctx context.Context, id primitive.ObjectID, indexedAt time.Time) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"search_indexed_at": indexedAt}},
+	)
+	return err
+}
+
+// SetOutlineIndexed records when the outline index of a project was rebuilt
+func (r *ProjectRepository) SetOutlineIndexed(ctx context.Context, id primitive.ObjectID, indexedAt time.Time) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$set": bson.M{"outline_indexed_at": indexedAt}},
+	)
+	return err
+}
+
+// FindAllIDs lists the IDs of all projects, including those in the trash
+func (r *ProjectRepository) FindAllIDs(ctx context.Context) ([]primitive.ObjectID, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var ids []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		ids = append(ids, doc.ID)
+	}
+
+	return ids, cursor.Err()
+}
+
+// CountOutlineIndexed counts the projects whose outline index was built
+func (r *ProjectRepository) CountOutlineIndexed(ctx context.Context) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"outline_indexed_at": bson.M{"$ne": nil}})
+}
+
+// ClearOutlineIndexed marks every outline index as stale so that each is
+// rebuilt the next time it is used, and returns how many were cleared
+func (r *ProjectRepository) ClearOutlineIndexed(ctx context.Context) (int64, error) {
+	result, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"outline_indexed_at": bson.M{"$ne": nil}},
+		bson.M{"$unset": bson.M{"outline_indexed_at": ""}},
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.ModifiedCount, nil
+}
+
 // Delete deletes a project
 func (r *ProjectRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
 	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
@@ -195,6 +407,39 @@ func (r *ProjectRepository) Delete(ctx context.Context, id primitive.ObjectID) e
 	return nil
 }
 
+// SumFileStats returns the total file count and size of the projects owned
+// by the given users, including projects in the trash
+func (r *ProjectRepository) SumFileStats(ctx context.Context, ownerIDs []primitive.ObjectID) (int64, int64, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"owner_id": bson.M{"$in": ownerIDs}}},
+		{"$group": bson.M{
+			"_id":        nil,
+			"count":      bson.M{"$sum": "$file_count"},
+			"total_size": bson.M{"$sum": "$total_size_bytes"},
+		}},
+	}
+
+	cursor, err := r.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var result struct {
+		Count     int64 `bson:"count"`
+		TotalSize int64 `bson:"total_size"`
+	}
+
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(&result); err != nil {
+			return 0, 0, err
+		}
+		return result.Count, result.TotalSize, nil
+	}
+
+	return 0, 0, nil
+}
+
 // UpdateFileStats updates file count and total size for a project
 func (r *ProjectRepository) UpdateFileStats(ctx context.Context, projectID primitive.ObjectID, fileCount int, totalSize int64) error {
 	filter := bson.M{"_id": projectID}
@@ -233,6 +478,10 @@ func (r *ProjectRepository) CreateIndexes(ctx context.Context) error {
 		{
 			Keys: bson.D{{Key: "created_at", Value: -1}},
 		},
+		{
+			Keys:    bson.D{{Key: "purge_at", Value: 1}},
+			Options: options.Index().SetSparse(true),
+		},
 	}
 
 	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
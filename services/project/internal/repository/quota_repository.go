@@ -0,0 +1,184 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// QuotaRepository handles quota overrides and storage usage totals
+type QuotaRepository struct {
+	db        *mongo.Database
+	overrides *mongo.Collection
+	usage     *mongo.Collection
+}
+
+// NewQuotaRepository creates a new quota repository
+func NewQuotaRepository(db *mongo.Database) *QuotaRepository {
+	return &QuotaRepository{
+		db:        db,
+		overrides: db.Collection("quota_overrides"),
+		usage:     db.Collection("storage_usage"),
+	}
+}
+
+// FindOverride finds the quota override of an account
+func (r *QuotaRepository) FindOverride(ctx context.Context, subjectType string, subjectID primitive.ObjectID) (*models.QuotaOverride, error) {
+	var override models.QuotaOverride
+	err := r.overrides.FindOne(ctx, bson.M{"subject_type": subjectType, "subject_id": subjectID}).Decode(&override)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("quota override not found")
+		}
+		return nil, err
+	}
+
+	return &override, nil
+}
+
+// ListOverrides lists all quota overrides, most recently changed first
+func (r *QuotaRepository) ListOverrides(ctx context.Context) ([]*models.QuotaOverride, error) {
+	cursor, err := r.overrides.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var overrides []*models.QuotaOverride
+	if err := cursor.All(ctx, &overrides); err != nil {
+		return nil, err
+	}
+
+	return overrides, nil
+}
+
+// UpsertOverride creates or replaces the quota override of an account
+func (r *QuotaRepository) UpsertOverride(ctx context.Context, override *models.QuotaOverride) error {
+	override.UpdatedAt = time.Now()
+
+	var saved models.QuotaOverride
+	err := r.overrides.FindOneAndUpdate(
+		ctx,
+		bson.M{"subject_type": override.SubjectType, "subject_id": override.SubjectID},
+		bson.M{"$set": bson.M{
+			"max_storage_bytes":   override.MaxStorageBytes,
+			"max_files":           override.MaxFiles,
+			"max_file_size_bytes": override.MaxFileSizeBytes,
+			"reason":              override.Reason,
+			"updated_by":          override.UpdatedBy,
+			"updated_at":          override.UpdatedAt,
+		}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&saved)
+	if err != nil {
+		return err
+	}
+
+	override.ID = saved.ID
+	return nil
+}
+
+// DeleteOverride removes the quota override of an account
+func (r *QuotaRepository) DeleteOverride(ctx context.Context, subjectType string, subjectID primitive.ObjectID) error {
+	result, err := r.overrides.DeleteOne(ctx, bson.M{"subject_type": subjectType, "subject_id": subjectID})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("quota override not found")
+	}
+
+	return nil
+}
+
+// FindUsage finds the storage usage of a quota subject
+func (r *QuotaRepository) FindUsage(ctx context.Context, subjectType string, subjectID primitive.ObjectID) (*models.StorageUsage, error) {
+	var usage models.StorageUsage
+	err := r.usage.FindOne(ctx, bson.M{"subject_type": subjectType, "subject_id": subjectID}).Decode(&usage)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("usage not found")
+		}
+		return nil, err
+	}
+
+	return &usage, nil
+}
+
+// SetUsage records the storage usage of a quota subject recomputed while
+// its usage was at revision, 0 for a subject without usage. It reports false
+// and changes nothing if a reservation changed the usage since.
+func (r *QuotaRepository) SetUsage(ctx context.Context, subjectType string, subjectID primitive.ObjectID, storageBytes, fileCount, revision int64) (bool, error) {
+	filter := bson.M{"subject_type": subjectType, "subject_id": subjectID, "revision": revision}
+	if revision == 0 {
+		// Usage recorded before revisions were kept has none
+		filter["revision"] = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	result, err := r.usage.UpdateOne(
+		ctx,
+		filter,
+		bson.M{
+			"$set": bson.M{
+				"storage_bytes": storageBytes,
+				"file_count":    fileCount,
+				"updated_at":    time.Now(),
+			},
+			"$inc": bson.M{"revision": 1},
+		},
+		options.Update().SetUpsert(revision == 0),
+	)
+	if mongo.IsDuplicateKeyError(err) {
+		// Another refresh or reservation created the usage first
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount > 0 || result.UpsertedCount > 0, nil
+}
+
+// Reserve adds to the usage of a quota subject only if the result stays
+// within limits, so that concurrent writes cannot together exceed a quota.
+// It reports whether the reservation was made.
+func (r *QuotaRepository) Reserve(ctx context.Context, subjectType string, subjectID primitive.ObjectID, storageBytes, fileCount int64, limits models.QuotaLimits) (bool, error) {
+	filter := bson.M{"subject_type": subjectType, "subject_id": subjectID}
+	if storageBytes > 0 && limits.MaxStorageBytes > 0 {
+		filter["storage_bytes"] = bson.M{"$lte": limits.MaxStorageBytes - storageBytes}
+	}
+	if fileCount > 0 && limits.MaxFiles > 0 {
+		filter["file_count"] = bson.M{"$lte": limits.MaxFiles - fileCount}
+	}
+
+	result, err := r.usage.UpdateOne(ctx, filter, bson.M{
+		"$inc": bson.M{"storage_bytes": storageBytes, "file_count": fileCount, "revision": 1},
+		"$set": bson.M{"updated_at": time.Now()},
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return result.MatchedCount > 0, nil
+}
+
+// CreateIndexes creates necessary indexes
+func (r *QuotaRepository) CreateIndexes(ctx context.Context) error {
+	subject := mongo.IndexModel{
+		Keys:    bson.D{{Key: "subject_type", Value: 1}, {Key: "subject_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	}
+
+	if _, err := r.overrides.Indexes().CreateOne(ctx, subject); err != nil {
+		return err
+	}
+	_, err := r.usage.Indexes().CreateOne(ctx, subject)
+	return err
+}
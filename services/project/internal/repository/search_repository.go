@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SearchRepository stores the full-text search index: one document per text
+// file holding its terms and content. Keeping the index in MongoDB lets every
+// replica of the service search the same data.
+type SearchRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewSearchRepository creates a new search repository
+func NewSearchRepository(db *mongo.Database) *SearchRepository {
+	return &SearchRepository{
+		db:         db,
+		collection: db.Collection("search_documents"),
+	}
+}
+
+// Upsert indexes a file, replacing its previous entry
+func (r *SearchRepository) Upsert(ctx context.Context, doc *models.SearchDocument) error {
+	doc.UpdatedAt = time.Now()
+
+	_, err := r.collection.ReplaceOne(
+		ctx,
+		bson.M{"_id": doc.FileID},
+		doc,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+// UpdatePath records that an indexed file moved
+func (r *SearchRepository) UpdatePath(ctx context.Context, fileID primitive.ObjectID, path string) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": fileID},
+		bson.M{"$set": bson.M{"path": path, "updated_at": time.Now()}},
+	)
+	return err
+}
+
+// Find returns the indexed files of the given projects that contain every
+// term, ordered by project and path
+func (r *SearchRepository) Find(ctx context.Context, projectIDs []primitive.ObjectID, terms []string, limit int) ([]*models.SearchDocument, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.M{
+			"project_id": bson.M{"$in": projectIDs},
+			"terms":      bson.M{"$all": terms},
+		},
+		options.Find().
+			SetProjection(bson.M{"terms": 0}).
+			SetSort(bson.D{{Key: "project_id", Value: 1}, {Key: "path", Value: 1}}).
+			SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var docs []*models.SearchDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+// Delete removes a file from the index
+func (r *SearchRepository) Delete(ctx context.Context, fileID primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": fileID})
+	return err
+}
+
+// DeleteByProjectID removes all files of a project from the index
+func (r *SearchRepository) DeleteByProjectID(ctx context.Context, projectID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"project_id": projectID})
+	return err
+}
+
+// CreateIndexes creates necessary indexes
+func (r *SearchRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "terms", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "path", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
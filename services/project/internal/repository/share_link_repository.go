@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ShareLinkRepository handles share link and share grant persistence
+type ShareLinkRepository struct {
+	db     *mongo.Database
+	links  *mongo.Collection
+	grants *mongo.Collection
+}
+
+// NewShareLinkRepository creates a new share link repository
+func NewShareLinkRepository(db *mongo.Database) *ShareLinkRepository {
+	return &ShareLinkRepository{
+		db:     db,
+		links:  db.Collection("project_share_links"),
+		grants: db.Collection("project_share_grants"),
+	}
+}
+
+// Create creates a new share link
+func (r *ShareLinkRepository) Create(ctx context.Context, link *models.ShareLink) error {
+	if link.ID.IsZero() {
+		link.ID = primitive.NewObjectID()
+	}
+	link.CreatedAt = time.Now()
+
+	_, err := r.links.InsertOne(ctx, link)
+	return err
+}
+
+// FindByID finds a share link by ID
+func (r *ShareLinkRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.ShareLink, error) {
+	var link models.ShareLink
+	err := r.links.FindOne(ctx, bson.M{"_id": id}).Decode(&link)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("share link not found")
+		}
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// FindByTokenHash finds a share link by the hash of its token and records
+// that it was used
+func (r *ShareLinkRepository) FindByTokenHash(ctx context.Context, hash string) (*models.ShareLink, error) {
+	var link models.ShareLink
+	err := r.links.FindOneAndUpdate(
+		ctx,
+		bson.M{"token_hash": hash},
+		bson.M{"$set": bson.M{"last_used_at": time.Now()}},
+	).Decode(&link)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("share link not found")
+		}
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// FindByProject lists the share links of a project, newest first
+func (r *ShareLinkRepository) FindByProject(ctx context.Context, projectID primitive.ObjectID) ([]*models.ShareLink, error) {
+	cursor, err := r.links.Find(
+		ctx,
+		bson.M{"project_id": projectID},
+		options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var links []*models.ShareLink
+	if err := cursor.All(ctx, &links); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// Revoke marks a share link as revoked and drops the grants issued through it
+func (r *ShareLinkRepository) Revoke(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.links.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "revoked_at": nil},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("share link already revoked")
+	}
+
+	_, err = r.grants.DeleteMany(ctx, bson.M{"link_id": id})
+	return err
+}
+
+// UpsertGrant gives a user the role of a share link until the grant expires,
+// replacing any earlier grant from the same link
+func (r *ShareLinkRepository) UpsertGrant(ctx context.Context, grant *models.ShareGrant) error {
+	grant.CreatedAt = time.Now()
+
+	result, err := r.grants.UpdateOne(
+		ctx,
+		bson.M{"link_id": grant.LinkID, "user_id": grant.UserID},
+		bson.M{
+			"$set": bson.M{
+				"project_id": grant.ProjectID,
+				"role":       grant.Role,
+				"expires_at": grant.ExpiresAt,
+				"created_at": grant.CreatedAt,
+			},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		return err
+	}
+
+	if id, ok := result.UpsertedID.(primitive.ObjectID); ok {
+		grant.ID = id
+	}
+
+	return nil
+}
+
+// FindActiveGrants lists the unexpired grants a user holds on a project
+func (r *ShareLinkRepository) FindActiveGrants(ctx context.Context, projectID, userID primitive.ObjectID) ([]*models.ShareGrant, error) {
+	cursor, err := r.grants.Find(ctx, bson.M{
+		"project_id": projectID,
+		"user_id":    userID,
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var grants []*models.ShareGrant
+	if err := cursor.All(ctx, &grants); err != nil {
+		return nil, err
+	}
+
+	return grants, nil
+}
+
+// FindGrantedProjectIDs lists the projects a user holds unexpired grants for
+func (r *ShareLinkRepository) FindGrantedProjectIDs(ctx context.Context, userID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	values, err := r.grants.Distinct(ctx, "project_id", bson.M{
+		"user_id":    userID,
+		"expires_at": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// DeleteByProjectID deletes all share links and grants of a project
+func (r *ShareLinkRepository) DeleteByProjectID(ctx context.Context, projectID primitive.ObjectID) error {
+	if _, err := r.grants.DeleteMany(ctx, bson.M{"project_id": projectID}); err != nil {
+		return err
+	}
+	_, err := r.links.DeleteMany(ctx, bson.M{"project_id": projectID})
+	return err
+}
+
+// CreateIndexes creates necessary indexes
+func (r *ShareLinkRepository) CreateIndexes(ctx context.Context) error {
+	linkIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "created_at", Value: -1}},
+		},
+	}
+	if _, err := r.links.Indexes().CreateMany(ctx, linkIndexes); err != nil {
+		return err
+	}
+
+	grantIndexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "link_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "user_id", Value: 1}},
+		},
+		{
+			// Expired grants are removed by MongoDB
+			Keys:    bson.D{{Key: "expires_at", Value: 1}},
+			Options: options.Index().SetExpireAfterSeconds(0),
+		},
+	}
+	_, err := r.grants.Indexes().CreateMany(ctx, grantIndexes)
+	return err
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SuggestionRepository handles tracked change persistence. Reviewed
+// suggestions are kept with their outcome.
+type SuggestionRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewSuggestionRepository creates a new suggestion repository
+func NewSuggestionRepository(db *mongo.Database) *SuggestionRepository {
+	return &SuggestionRepository{
+		db:         db,
+		collection: db.Collection("suggestions"),
+	}
+}
+
+// CreateMany stores new pending suggestions
+func (r *SuggestionRepository) CreateMany(ctx context.Context, suggestions []*models.Suggestion) error {
+	if len(suggestions) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	docs := make([]interface{}, len(suggestions))
+	for i, suggestion := range suggestions {
+		if suggestion.ID.IsZero() {
+			suggestion.ID = primitive.NewObjectID()
+		}
+		suggestion.Status = models.SuggestionPending
+		suggestion.CreatedAt = now
+		docs[i] = suggestion
+	}
+
+	_, err := r.collection.InsertMany(ctx, docs)
+	return err
+}
+
+// FindByProject lists the suggestions of a project in file order,
+// optionally only those of one file or in one state
+func (r *SuggestionRepository) FindByProject(ctx context.Context, projectID primitive.ObjectID, fileID *primitive.ObjectID, status string) ([]*models.Suggestion, error) {
+	filter := bson.M{"project_id": projectID}
+	if fileID != nil {
+		filter["file_id"] = *fileID
+	}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	return r.find(ctx, filter)
+}
+
+// FindPendingByIDs finds the pending suggestions of a project with the
+// given IDs
+func (r *SuggestionRepository) FindPendingByIDs(ctx context.Context, projectID primitive.ObjectID, ids []primitive.ObjectID) ([]*models.Suggestion, error) {
+	return r.find(ctx, bson.M{
+		"_id":        bson.M{"$in": ids},
+		"project_id": projectID,
+		"status":     models.SuggestionPending,
+	})
+}
+
+// FindPendingByFile lists the pending suggestions of a file
+func (r *SuggestionRepository) FindPendingByFile(ctx context.Context, fileID primitive.ObjectID) ([]*models.Suggestion, error) {
+	return r.find(ctx, bson.M{"file_id": fileID, "status": models.SuggestionPending})
+}
+
+// FindPendingByAuthor lists the pending suggestions a user made to a file
+func (r *SuggestionRepository) FindPendingByAuthor(ctx context.Context, fileID, authorID primitive.ObjectID) ([]*models.Suggestion, error) {
+	return r.find(ctx, bson.M{"file_id": fileID, "author_id": authorID, "status": models.SuggestionPending})
+}
+
+// Review moves a pending suggestion to accepted or rejected. It reports
+// false if the suggestion was no longer pending.
+func (r *SuggestionRepository) Review(ctx context.Context, id primitive.ObjectID, status string, reviewerID primitive.ObjectID) (bool, error) {
+	result, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": models.SuggestionPending},
+		bson.M{"$set": bson.M{
+			"status":      status,
+			"reviewed_by": reviewerID,
+			"reviewed_at": time.Now(),
+		}},
+	)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// Unreview puts suggestions back to pending after a review could not be
+// applied
+func (r *SuggestionRepository) Unreview(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := r.collection.UpdateMany(
+		ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		bson.M{
+			"$set":   bson.M{"status": models.SuggestionPending},
+			"$unset": bson.M{"reviewed_by": "", "reviewed_at": ""},
+		},
+	)
+	return err
+}
+
+// SetOffset moves a pending suggestion after its file was edited
+func (r *SuggestionRepository) SetOffset(ctx context.Context, id primitive.ObjectID, offset int) error {
+	_, err := r.collection.UpdateOne(
+		ctx,
+		bson.M{"_id": id, "status": models.SuggestionPending},
+		bson.M{"$set": bson.M{"offset": offset}},
+	)
+	return err
+}
+
+// DeletePending deletes pending suggestions, which their author replaced
+func (r *SuggestionRepository) DeletePending(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	_, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}, "status": models.SuggestionPending})
+	return err
+}
+
+// DeleteByProjectID deletes all suggestions of a project
+func (r *SuggestionRepository) DeleteByProjectID(ctx context.Context, projectID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"project_id": projectID})
+	return err
+}
+
+func (r *SuggestionRepository) find(ctx context.Context, filter bson.M) ([]*models.Suggestion, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		filter,
+		options.Find().SetSort(bson.D{{Key: "file_id", Value: 1}, {Key: "offset", Value: 1}, {Key: "created_at", Value: 1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	suggestions := []*models.Suggestion{}
+	if err := cursor.All(ctx, &suggestions); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// CreateIndexes creates necessary indexes
+func (r *SuggestionRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "status", Value: 1}, {Key: "file_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "file_id", Value: 1}, {Key: "status", Value: 1}, {Key: "author_id", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TemplateRepository handles template data persistence
+type TemplateRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewTemplateRepository creates a new template repository
+func NewTemplateRepository(db *mongo.Database) *TemplateRepository {
+	return &TemplateRepository{
+		db:         db,
+		collection: db.Collection("templates"),
+	}
+}
+
+// Create creates a new template
+func (r *TemplateRepository) Create(ctx context.Context, template *models.Template) error {
+	if template.ID.IsZero() {
+		template.ID = primitive.NewObjectID()
+	}
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = time.Now()
+
+	_, err := r.collection.InsertOne(ctx, template)
+	return err
+}
+
+// FindByID finds a template by ID
+func (r *TemplateRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.Template, error) {
+	var template models.Template
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&template)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("template not found")
+		}
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// FindByBuiltinKey finds a built-in template by its key
+func (r *TemplateRepository) FindByBuiltinKey(ctx context.Context, key string) (*models.Template, error) {
+	var template models.Template
+	err := r.collection.FindOne(ctx, bson.M{"builtin_key": key}).Decode(&template)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("template not found")
+		}
+		return nil, err
+	}
+
+	return &template, nil
+}
+
+// Search finds the templates visible to a viewer: built-in and public ones
+// plus the viewer's own. Built-in templates come first, then the most used.
+func (r *TemplateRepository) Search(ctx context.Context, filter models.TemplateFilter) ([]*models.Template, int64, error) {
+	conditions := []bson.M{{
+		"$or": []bson.M{
+			{"is_public": true},
+			{"owner_id": filter.ViewerID},
+		},
+	}}
+	if filter.Category != "" {
+		conditions = append(conditions, bson.M{"category": filter.Category})
+	}
+	if filter.Query != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(filter.Query), Options: "i"}
+		conditions = append(conditions, bson.M{
+			"$or": []bson.M{
+				{"name": pattern},
+				{"description": pattern},
+				{"category": pattern},
+				{"tags": pattern},
+			},
+		})
+	}
+	query := bson.M{"$and": conditions}
+
+	total, err := r.collection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	skip := (filter.Page - 1) * filter.Limit
+	cursor, err := r.collection.Find(
+		ctx,
+		query,
+		options.Find().
+			SetSkip(int64(skip)).
+			SetLimit(int64(filter.Limit)).
+			SetProjection(bson.M{"files": 0, "folders": 0}).
+			SetSort(bson.D{
+				{Key: "builtin_key", Value: -1},
+				{Key: "usage_count", Value: -1},
+				{Key: "created_at", Value: -1},
+			}),
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	templates := []*models.Template{}
+	if err := cursor.All(ctx, &templates); err != nil {
+		return nil, 0, err
+	}
+
+	return templates, total, nil
+}
+
+// Update updates a template
+func (r *TemplateRepository) Update(ctx context.Context, template *models.Template) error {
+	template.UpdatedAt = time.Now()
+
+	result, err := r.collection.ReplaceOne(ctx, bson.M{"_id": template.ID}, template)
+	if err != nil {
+		return err
+	}
+
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("template not found")
+	}
+
+	return nil
+}
+
+// IncrementUsage counts a project created from a template
+func (r *TemplateRepository) IncrementUsage(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$inc": bson.M{"usage_count": 1}})
+	return err
+}
+
+// Delete deletes a template
+func (r *TemplateRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	result, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("template not found")
+	}
+
+	return nil
+}
+
+// CreateIndexes creates necessary indexes
+func (r *TemplateRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "builtin_key", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{
+				"builtin_key": bson.M{"$exists": true},
+			}),
+		},
+		{
+			Keys: bson.D{{Key: "is_public", Value: 1}, {Key: "category", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "owner_id", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
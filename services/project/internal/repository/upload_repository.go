@@ -0,0 +1,129 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UploadRepository handles resumable upload session persistence
+type UploadRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewUploadRepository creates a new upload repository
+func NewUploadRepository(db *mongo.Database) *UploadRepository {
+	return &UploadRepository{
+		db:         db,
+		collection: db.Collection("upload_sessions"),
+	}
+}
+
+// Create creates a new upload session
+func (r *UploadRepository) Create(ctx context.Context, upload *models.UploadSession) error {
+	if upload.ID.IsZero() {
+		upload.ID = primitive.NewObjectID()
+	}
+	upload.CreatedAt = time.Now()
+	upload.ReceivedChunks = []int{}
+
+	_, err := r.collection.InsertOne(ctx, upload)
+	return err
+}
+
+// FindByID finds an upload session by ID
+func (r *UploadRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.UploadSession, error) {
+	var upload models.UploadSession
+	err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&upload)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("upload not found")
+		}
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+// AddChunk records that a chunk arrived and returns the updated session
+func (r *UploadRepository) AddChunk(ctx context.Context, id primitive.ObjectID, index int) (*models.UploadSession, error) {
+	var upload models.UploadSession
+	err := r.collection.FindOneAndUpdate(
+		ctx,
+		bson.M{"_id": id},
+		bson.M{"$addToSet": bson.M{"received_chunks": index}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&upload)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("upload not found")
+		}
+		return nil, err
+	}
+
+	return &upload, nil
+}
+
+// FindExpired lists upload sessions that expired before the given time
+func (r *UploadRepository) FindExpired(ctx context.Context, before time.Time, limit int) ([]*models.UploadSession, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.M{"expires_at": bson.M{"$lte": before}},
+		options.Find().SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var uploads []*models.UploadSession
+	if err := cursor.All(ctx, &uploads); err != nil {
+		return nil, err
+	}
+
+	return uploads, nil
+}
+
+// FindByProjectID lists the upload sessions of a project
+func (r *UploadRepository) FindByProjectID(ctx context.Context, projectID primitive.ObjectID) ([]*models.UploadSession, error) {
+	cursor, err := r.collection.Find(ctx, bson.M{"project_id": projectID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var uploads []*models.UploadSession
+	if err := cursor.All(ctx, &uploads); err != nil {
+		return nil, err
+	}
+
+	return uploads, nil
+}
+
+// Delete deletes an upload session
+func (r *UploadRepository) Delete(ctx context.Context, id primitive.ObjectID) error {
+	_, err := r.collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// CreateIndexes creates necessary indexes
+func (r *UploadRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}},
+		},
+		{
+			Keys: bson.D{{Key: "expires_at", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
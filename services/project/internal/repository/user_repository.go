@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// UserRepository reads the user accounts and personal access tokens owned by
+// the auth service
+type UserRepository struct {
+	db     *mongo.Database
+	users  *mongo.Collection
+	tokens *mongo.Collection
+}
+
+// NewUserRepository creates a new user repository
+func NewUserRepository(db *mongo.Database) *UserRepository {
+	return &UserRepository{
+		db:     db,
+		users:  db.Collection("users"),
+		tokens: db.Collection("personal_access_tokens"),
+	}
+}
+
+// FindByID finds a user by ID
+func (r *UserRepository) FindByID(ctx context.Context, id primitive.ObjectID) (*models.User, error) {
+	var user models.User
+	err := r.users.FindOne(
+		ctx,
+		bson.M{"_id": id},
+		options.FindOne().SetProjection(bson.M{"email": 1, "username": 1, "full_name": 1}),
+	).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// FindByEmail finds a user by email, ignoring case
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := r.users.FindOne(
+		ctx,
+		bson.M{"email": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(email) + "$", Options: "i"}},
+		options.FindOne().SetProjection(bson.M{"email": 1, "username": 1, "full_name": 1}),
+	).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// FindByIDs finds the users with the given IDs. Unknown IDs are skipped.
+func (r *UserRepository) FindByIDs(ctx context.Context, ids []primitive.ObjectID) ([]*models.User, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.users.Find(
+		ctx,
+		bson.M{"_id": bson.M{"$in": ids}},
+		options.Find().SetProjection(bson.M{"email": 1, "username": 1, "full_name": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// FindByUsernames finds the users with the given usernames. Unknown
+// usernames are skipped.
+func (r *UserRepository) FindByUsernames(ctx context.Context, usernames []string) ([]*models.User, error) {
+	if len(usernames) == 0 {
+		return nil, nil
+	}
+
+	cursor, err := r.users.Find(
+		ctx,
+		bson.M{"username": bson.M{"$in": usernames}},
+		options.Find().SetProjection(bson.M{"email": 1, "username": 1, "full_name": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var users []*models.User
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// FindOrganizationID returns the organization a user belongs to, or nil if
+// the user is not a member of one
+func (r *UserRepository) FindOrganizationID(ctx context.Context, userID primitive.ObjectID) (*primitive.ObjectID, error) {
+	var user struct {
+		OrganizationID *primitive.ObjectID `bson:"organization_id"`
+	}
+	err := r.users.FindOne(
+		ctx,
+		bson.M{"_id": userID},
+		options.FindOne().SetProjection(bson.M{"organization_id": 1}),
+	).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, err
+	}
+
+	return user.OrganizationID, nil
+}
+
+// FindIDsByOrganization lists the members of an organization
+func (r *UserRepository) FindIDsByOrganization(ctx context.Context, organizationID primitive.ObjectID) ([]primitive.ObjectID, error) {
+	values, err := r.users.Distinct(ctx, "_id", bson.M{"organization_id": organizationID})
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(values))
+	for _, v := range values {
+		if id, ok := v.(primitive.ObjectID); ok {
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// FindTokenByHash finds a personal access token by the hash of its value
+// and records that it was used
+func (r *UserRepository) FindTokenByHash(ctx context.Context, hash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	err := r.tokens.FindOneAndUpdate(
+		ctx,
+		bson.M{"token_hash": hash},
+		bson.M{"$set": bson.M{"last_used_at": time.Now()}},
+	).Decode(&token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("token not found")
+		}
+		return nil, err
+	}
+
+	return &token, nil
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// VersionRepository handles file version persistence
+type VersionRepository struct {
+	db         *mongo.Database
+	collection *mongo.Collection
+}
+
+// NewVersionRepository creates a new version repository
+func NewVersionRepository(db *mongo.Database) *VersionRepository {
+	return &VersionRepository{
+		db:         db,
+		collection: db.Collection("file_versions"),
+	}
+}
+
+// Create creates a new version record
+func (r *VersionRepository) Create(ctx context.Context, version *models.FileVersion) error {
+	version.ID = primitive.NewObjectID()
+	if version.CreatedAt.IsZero() {
+		version.CreatedAt = time.Now()
+	}
+
+	_, err := r.collection.InsertOne(ctx, version)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return fmt.Errorf("version already exists")
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindByFileID finds all versions of a file, newest first
+func (r *VersionRepository) FindByFileID(ctx context.Context, fileID primitive.ObjectID) ([]*models.FileVersion, error) {
+	cursor, err := r.collection.Find(
+		ctx,
+		bson.M{"file_id": fileID},
+		options.Find().SetSort(bson.D{{Key: "version", Value: -1}}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	versions := []*models.FileVersion{}
+	if err := cursor.All(ctx, &versions); err != nil {
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// FindByVersion finds a version of a file by its number
+func (r *VersionRepository) FindByVersion(ctx context.Context, fileID primitive.ObjectID, version int) (*models.FileVersion, error) {
+	var v models.FileVersion
+	err := r.collection.FindOne(ctx, bson.M{"file_id": fileID, "version": version}).Decode(&v)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("version not found")
+		}
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// CountByHash counts the versions of a project that share stored content
+func (r *VersionRepository) CountByHash(ctx context.Context, projectID primitive.ObjectID, hash string) (int64, error) {
+	return r.collection.CountDocuments(ctx, bson.M{"project_id": projectID, "hash": hash})
+}
+
+// DeleteByIDs deletes version records
+func (r *VersionRepository) DeleteByIDs(ctx context.Context, ids []primitive.ObjectID) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := r.collection.DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	return err
+}
+
+// DeleteByProjectID deletes all versions in a project
+func (r *VersionRepository) DeleteByProjectID(ctx context.Context, projectID primitive.ObjectID) error {
+	_, err := r.collection.DeleteMany(ctx, bson.M{"project_id": projectID})
+	return err
+}
+
+// CreateIndexes creates necessary indexes
+func (r *VersionRepository) CreateIndexes(ctx context.Context) error {
+	indexes := []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "file_id", Value: 1}, {Key: "version", Value: -1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "project_id", Value: 1}, {Key: "hash", Value: 1}},
+		},
+	}
+
+	_, err := r.collection.Indexes().CreateMany(ctx, indexes)
+	return err
+}
@@ -0,0 +1,246 @@
+// Package search implements the text analysis behind project full-text
+// search. Documents are reduced to a set of lowercase terms for the index,
+// and candidate files are matched line by line to produce results with
+// snippets.
+package search
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MaxTerms bounds the distinct terms indexed per document
+	MaxTerms = 20000
+
+	// snippetRunes is the longest snippet returned for a matching line
+	snippetRunes = 200
+
+	maxQueryTerms = 16
+)
+
+// token is a term with its byte offsets in the analysed text
+type token struct {
+	text       string
+	start, end int
+}
+
+// tokenize splits text into words, numbers and LaTeX control sequences such
+// as \newcommand. Control symbols like \\ and \% are skipped.
+func tokenize(text string) []token {
+	var tokens []token
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		switch {
+		case r == '\\':
+			j := i + size
+			for j < len(text) {
+				r, size := utf8.DecodeRuneInString(text[j:])
+				if !unicode.IsLetter(r) && r != '@' {
+					break
+				}
+				j += size
+			}
+			if j > i+1 {
+				tokens = append(tokens, token{text: text[i:j], start: i, end: j})
+				i = j
+				continue
+			}
+			i += size
+		case isWordRune(r):
+			j := i + size
+			for j < len(text) {
+				r, size := utf8.DecodeRuneInString(text[j:])
+				if !isWordRune(r) {
+					break
+				}
+				j += size
+			}
+			tokens = append(tokens, token{text: text[i:j], start: i, end: j})
+			i = j
+		default:
+			i += size
+		}
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// Terms returns the distinct lowercase index terms of a document, sorted
+func Terms(text string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, t := range tokenize(text) {
+		term := strings.ToLower(t.text)
+		if seen[term] {
+			continue
+		}
+		seen[term] = true
+		terms = append(terms, term)
+		if len(terms) == MaxTerms {
+			break
+		}
+	}
+	sort.Strings(terms)
+	return terms
+}
+
+// Query is a parsed search query. A line matches when it contains every
+// query term.
+type Query struct {
+	tokens        []string
+	terms         []string
+	caseSensitive bool
+}
+
+// ParseQuery parses a search query. Matching ignores case unless
+// caseSensitive is set; the index itself is always case-insensitive.
+func ParseQuery(q string, caseSensitive bool) (*Query, error) {
+	query := &Query{caseSensitive: caseSensitive}
+	seen := map[string]bool{}
+	for _, t := range tokenize(q) {
+		key := t.text
+		if !caseSensitive {
+			key = strings.ToLower(key)
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		query.tokens = append(query.tokens, key)
+
+		term := strings.ToLower(t.text)
+		if !containsString(query.terms, term) {
+			query.terms = append(query.terms, term)
+		}
+	}
+
+	if len(query.tokens) == 0 {
+		return nil, fmt.Errorf("search query is empty")
+	}
+	if len(query.tokens) > maxQueryTerms {
+		return nil, fmt.Errorf("search query has too many terms")
+	}
+	return query, nil
+}
+
+// Terms returns the lowercase index terms a document must contain to match
+func (q *Query) Terms() []string {
+	return q.terms
+}
+
+// Highlight is a byte range of a snippet that matched the query
+type Highlight struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Match is a line that matched a query
+type Match struct {
+	Line       int         `json:"line"`   // 1-based
+	Column     int         `json:"column"` // 1-based, in characters
+	Snippet    string      `json:"snippet"`
+	Highlights []Highlight `json:"highlights"`
+}
+
+// MatchLines returns up to max lines of content that contain every query
+// term, in order
+func (q *Query) MatchLines(content string, max int) []Match {
+	var matches []Match
+	lineNo := 0
+	for len(content) > 0 && len(matches) < max {
+		lineNo++
+		line := content
+		if i := strings.IndexByte(content, '\n'); i >= 0 {
+			line, content = content[:i], content[i+1:]
+		} else {
+			content = ""
+		}
+		line = strings.TrimSuffix(line, "\r")
+
+		if m, ok := q.matchLine(line); ok {
+			m.Line = lineNo
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+func (q *Query) matchLine(line string) (Match, bool) {
+	var highlights []Highlight
+	found := make(map[string]bool, len(q.tokens))
+	for _, t := range tokenize(line) {
+		key := t.text
+		if !q.caseSensitive {
+			key = strings.ToLower(key)
+		}
+		if containsString(q.tokens, key) {
+			found[key] = true
+			highlights = append(highlights, Highlight{Start: t.start, End: t.end})
+		}
+	}
+	if len(found) < len(q.tokens) {
+		return Match{}, false
+	}
+
+	first := highlights[0].Start
+	start, end := snippetWindow(line, first)
+	snippet := line[start:end]
+
+	var shifted []Highlight
+	for _, h := range highlights {
+		if h.Start < start || h.End > end {
+			continue
+		}
+		shifted = append(shifted, Highlight{Start: h.Start - start, End: h.End - start})
+	}
+
+	// Trim leading indentation for display
+	trimmed := strings.TrimLeftFunc(snippet, unicode.IsSpace)
+	cut := len(snippet) - len(trimmed)
+	for i := range shifted {
+		shifted[i].Start -= cut
+		shifted[i].End -= cut
+	}
+
+	return Match{
+		Column:     utf8.RuneCountInString(line[:first]) + 1,
+		Snippet:    strings.TrimRightFunc(trimmed, unicode.IsSpace),
+		Highlights: shifted,
+	}, true
+}
+
+// snippetWindow picks at most snippetRunes characters of line around the
+// byte offset at, keeping some context before it
+func snippetWindow(line string, at int) (int, int) {
+	if utf8.RuneCountInString(line) <= snippetRunes {
+		return 0, len(line)
+	}
+
+	start := at
+	for n := 0; start > 0 && n < snippetRunes/4; n++ {
+		_, size := utf8.DecodeLastRuneInString(line[:start])
+		start -= size
+	}
+	end := start
+	for n := 0; end < len(line) && n < snippetRunes; n++ {
+		_, size := utf8.DecodeRuneInString(line[end:])
+		end += size
+	}
+	return start, end
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,343 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Import limits protect against zip bombs and oversized projects
+const (
+	MaxImportArchiveSize = 100 << 20 // Compressed upload size
+	maxImportEntries     = 2000
+	maxImportFileSize    = 50 << 20
+	maxImportTotalSize   = 500 << 20
+)
+
+// documentClassPattern matches an uncommented \documentclass declaration
+var documentClassPattern = regexp.MustCompile(`(?m)^[^%\n]*\\documentclass\s*(\[[^\]]*\])?\s*\{`)
+
+// importEntry is a file of an import archive with its project path
+type importEntry struct {
+	path string
+	file *zip.File
+}
+
+// ImportProject creates a project from a zip archive. A single top-level
+// directory shared by all entries is stripped, the main file is detected by
+// its \documentclass and every file is classified as text or binary by its
+// content type. Entries are read one at a time.
+func (s *ProjectService) ImportProject(
+	ctx context.Context,
+	userID primitive.ObjectID,
+	name string,
+	archive io.ReaderAt,
+	size int64,
+) (*models.Project, error) {
+	reader, err := zip.NewReader(archive, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid zip archive")
+	}
+
+	entries, folders, err := listImportEntries(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	mainFile, err := detectMainFile(entries)
+	if err != nil {
+		return nil, err
+	}
+	if mainFile == "" {
+		return nil, fmt.Errorf("archive contains no LaTeX files")
+	}
+
+	project := &models.Project{
+		Name:    name,
+		OwnerID: userID,
+		Settings: models.ProjectSettings{
+			Compiler:    "pdflatex",
+			MainFile:    mainFile,
+			SpellCheck:  true,
+			AutoCompile: false,
+		},
+	}
+
+	var largest, total int64
+	for _, entry := range entries {
+		size := int64(entry.file.UncompressedSize64)
+		total += size
+		if size > largest {
+			largest = size
+		}
+	}
+	release, err := s.reserveQuota(ctx, userID, largest, total, int64(len(entries)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.projectRepo.Create(ctx, project); err != nil {
+		release()
+		return nil, err
+	}
+
+	// Remove everything created so far if any file fails
+	cleanup := func(cause error) error {
+		release()
+		if delErr := s.purgeProject(ctx, project.ID); delErr != nil {
+			s.logger.Error("Failed to delete project after import failure", zap.Error(delErr))
+		}
+		return cause
+	}
+
+	for _, folder := range folders {
+		if err := s.ensureFolders(ctx, project.ID, userID, folder); err != nil {
+			return nil, cleanup(err)
+		}
+	}
+
+	for _, entry := range entries {
+		content, err := readZipFile(entry.file)
+		if err != nil {
+			return nil, cleanup(err)
+		}
+
+		fileName := path.Base(entry.path)
+		if _, err := s.storeFile(ctx, project.ID, userID, entry.path, fileName, content); err != nil {
+			return nil, cleanup(fmt.Errorf("failed to import %s: %w", entry.path, err))
+		}
+	}
+
+	s.updateProjectFileStats(ctx, project.ID)
+
+	s.logger.Info("Project imported",
+		zap.String("project_id", project.ID.Hex()),
+		zap.String("user_id", userID.Hex()),
+		zap.Int("files", len(entries)),
+		zap.String("main_file", mainFile),
+	)
+
+	return s.projectRepo.FindByID(ctx, project.ID)
+}
+
+// listImportEntries validates the entries of an archive and maps them to
+// project paths. Sizes are checked against the headers here and enforced
+// again on the decompressed data when each file is read.
+func listImportEntries(reader *zip.Reader) ([]importEntry, []string, error) {
+	if len(reader.File) > maxImportEntries {
+		return nil, nil, fmt.Errorf("archive has too many entries (max %d)", maxImportEntries)
+	}
+
+	prefix := commonRootDir(reader.File)
+
+	var entries []importEntry
+	var folders []string
+	var total uint64
+
+	for _, zf := range reader.File {
+		name := strings.TrimPrefix(zf.Name, prefix)
+		if name == "" || isIgnoredArchiveEntry(zf.Name) {
+			continue
+		}
+
+		cleanPath, err := cleanFilePath(name)
+		if err != nil || cleanPath == "." {
+			return nil, nil, fmt.Errorf("invalid file path in archive: %s", zf.Name)
+		}
+
+		if zf.FileInfo().IsDir() {
+			folders = append(folders, cleanPath)
+			continue
+		}
+
+		if zf.UncompressedSize64 > maxImportFileSize {
+			return nil, nil, fmt.Errorf("file too large in archive: %s", cleanPath)
+		}
+		total += zf.UncompressedSize64
+		if total > maxImportTotalSize {
+			return nil, nil, fmt.Errorf("archive too large when extracted")
+		}
+
+		entries = append(entries, importEntry{path: cleanPath, file: zf})
+	}
+
+	return entries, folders, nil
+}
+
+// readZipFile reads an archive entry, enforcing the size limit on the
+// decompressed data rather than trusting the header
+func readZipFile(zf *zip.File) ([]byte, error) {
+	rc, err := zf.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from archive: %w", zf.Name, err)
+	}
+	defer rc.Close()
+
+	content, err := io.ReadAll(io.LimitReader(rc, maxImportFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from archive: %w", zf.Name, err)
+	}
+	if len(content) > maxImportFileSize {
+		return nil, fmt.Errorf("file too large in archive: %s", zf.Name)
+	}
+
+	return content, nil
+}
+
+// commonRootDir returns "dir/" when every entry lives below the same
+// top-level directory, as in archives created by zipping a folder
+func commonRootDir(files []*zip.File) string {
+	root := ""
+	for _, zf := range files {
+		if isIgnoredArchiveEntry(zf.Name) {
+			continue
+		}
+
+		idx := strings.Index(zf.Name, "/")
+		if idx < 0 {
+			return "" // A file at the top level
+		}
+
+		dir := zf.Name[:idx+1]
+		if root == "" {
+			root = dir
+		} else if dir != root {
+			return ""
+		}
+	}
+	return root
+}
+
+// isIgnoredArchiveEntry skips operating system metadata
+func isIgnoredArchiveEntry(name string) bool {
+	base := path.Base(name)
+	return strings.HasPrefix(name, "__MACOSX/") || base == ".DS_Store" || base == "Thumbs.db"
+}
+
+// detectMainFile picks the main file: among .tex files declaring
+// \documentclass, main.tex wins, then the shallowest path. Without any
+// \documentclass the shallowest .tex file is used.
+func detectMainFile(entries []importEntry) (string, error) {
+	var candidates, texFiles []string
+	for _, entry := range entries {
+		if strings.ToLower(path.Ext(entry.path)) != ".tex" {
+			continue
+		}
+		texFiles = append(texFiles, entry.path)
+
+		content, err := readZipFile(entry.file)
+		if err != nil {
+			return "", err
+		}
+		if documentClassPattern.Match(content) {
+			candidates = append(candidates, entry.path)
+		}
+	}
+
+	if len(candidates) == 0 {
+		candidates = texFiles
+	}
+	if len(candidates) == 0 {
+		return "", nil
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if (path.Base(a) == "main.tex") != (path.Base(b) == "main.tex") {
+			return path.Base(a) == "main.tex"
+		}
+		if da, db := strings.Count(a, "/"), strings.Count(b, "/"); da != db {
+			return da < db
+		}
+		return a < b
+	})
+
+	return candidates[0], nil
+}
+
+// GetExportTree returns the files and folders of a project for export
+func (s *ProjectService) GetExportTree(ctx context.Context, projectID, userID primitive.ObjectID) (*models.Project, []*models.File, []*models.Folder, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, nil, nil, fmt.Errorf("access denied")
+	}
+
+	files, err := s.fileRepo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	folders, err := s.folderRepo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return project, files, folders, nil
+}
+
+// WriteProjectZip streams the given tree as a zip archive to w. Each file is
+// copied straight from MinIO so the project is never held in memory.
+func (s *ProjectService) WriteProjectZip(ctx context.Context, w io.Writer, files []*models.File, folders []*models.Folder) error {
+	zw := zip.NewWriter(w)
+
+	// Folders are written so that empty ones survive a round trip
+	for _, folder := range folders {
+		header := &zip.FileHeader{
+			Name:     folder.Path + "/",
+			Modified: folder.UpdatedAt,
+		}
+		if _, err := zw.CreateHeader(header); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range files {
+		if err := s.writeZipFile(ctx, zw, file); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func (s *ProjectService) writeZipFile(ctx context.Context, zw *zip.Writer, file *models.File) error {
+	object, err := s.minioClient.DownloadFile(ctx, file.StorageKey)
+	if err != nil {
+		return err
+	}
+	defer object.Close()
+
+	method := zip.Deflate
+	if file.IsBinary {
+		// Images and PDFs are already compressed
+		method = zip.Store
+	}
+
+	entry, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     file.Path,
+		Method:   method,
+		Modified: file.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(entry, object); err != nil {
+		return fmt.Errorf("failed to export %s: %w", file.Path, err)
+	}
+
+	return nil
+}
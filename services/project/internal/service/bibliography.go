@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/texflow/services/project/internal/bibtex"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// defaultBibliographyPath is where imported references go in a project
+// without a .bib file
+const defaultBibliographyPath = "references.bib"
+
+// bibliographyFile is a parsed .bib file of a project
+type bibliographyFile struct {
+	file    *models.File
+	content string
+	db      *bibtex.Database
+}
+
+// ListBibliography lists the entries of a project's .bib files. A query
+// keeps the entries whose key or fields contain each of its words, ignoring
+// case and accents.
+func (s *ProjectService) ListBibliography(ctx context.Context, projectID, userID primitive.ObjectID, q string) ([]*models.BibliographyEntry, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	bibs, err := s.loadBibliography(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	terms := strings.Fields(bibtex.Fold(q))
+	entries := []*models.BibliographyEntry{}
+	for _, bib := range bibs {
+		for _, entry := range bib.db.Entries {
+			fields := make(map[string]string, len(entry.Fields))
+			for _, f := range entry.Fields {
+				if _, ok := fields[f.Name]; !ok {
+					fields[f.Name] = bib.db.Text(entry, f.Name)
+				}
+			}
+			if !bibliographyEntryMatches(entry.Key, fields, terms) {
+				continue
+			}
+
+			entries = append(entries, &models.BibliographyEntry{
+				FileID: bib.file.ID,
+				File:   bib.file.Path,
+				Type:   entry.Type,
+				Key:    entry.Key,
+				Line:   entry.Line,
+				Fields: fields,
+			})
+		}
+	}
+
+	return entries, nil
+}
+
+// CheckBibliography finds syntax errors, duplicate keys and missing
+// required fields in a project's .bib files
+func (s *ProjectService) CheckBibliography(ctx context.Context, projectID, userID primitive.ObjectID) ([]bibtex.Issue, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	bibs, err := s.loadBibliography(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	databases := make(map[string]*bibtex.Database, len(bibs))
+	for _, bib := range bibs {
+		databases[bib.file.Path] = bib.db
+	}
+
+	return bibtex.Check(databases), nil
+}
+
+// NormalizeBibliography rewrites a .bib file in the normalized form,
+// renaming its keys when a key style is set. Keys used by the project's
+// other .bib files are not reused.
+func (s *ProjectService) NormalizeBibliography(ctx context.Context, projectID, fileID, userID primitive.ObjectID, req *models.NormalizeBibliographyRequest) (*models.NormalizeBibliographyResponse, error) {
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	bibs, err := s.loadBibliography(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.findBibliographyFile(ctx, projectID, fileID, bibs)
+	if err != nil {
+		return nil, err
+	}
+
+	style := project.Settings.BibKeyStyle
+	if req.KeyStyle != nil {
+		style = *req.KeyStyle
+	}
+
+	reserved := map[string]bool{}
+	for _, bib := range bibs {
+		if bib != target {
+			addBibliographyKeys(reserved, bib.db)
+		}
+	}
+
+	content, renamed := bibtex.Format(target.db, bibtex.FormatOptions{
+		KeyStyle: style,
+		Sort:     req.Sort,
+		Reserved: reserved,
+	})
+	response := &models.NormalizeBibliographyResponse{File: target.file, Renamed: renamed}
+	if content == target.content {
+		return response, nil
+	}
+
+	response.File, response.Suggestions, err = s.updateBibliographyFile(ctx, target, userID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ImportBibliography converts pasted BibTeX, RIS or CSL-JSON references and
+// appends them to a .bib file. Entries whose DOI is already in the project
+// are skipped. Keys follow the project's key style; imported keys are kept
+// under the keep style unless they are taken.
+func (s *ProjectService) ImportBibliography(ctx context.Context, projectID, userID primitive.ObjectID, req *models.ImportBibliographyRequest) (*models.ImportBibliographyResponse, error) {
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := bibtex.Import(req.Content, req.Format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid references: %s", err)
+	}
+
+	bibs, err := s.loadBibliography(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var target *bibliographyFile
+	switch {
+	case req.FileID != "":
+		fileID, err := primitive.ObjectIDFromHex(req.FileID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file ID")
+		}
+		if target, err = s.findBibliographyFile(ctx, projectID, fileID, bibs); err != nil {
+			return nil, err
+		}
+	case len(bibs) == 1:
+		target = bibs[0]
+	case len(bibs) > 1:
+		return nil, fmt.Errorf("file_id is required when the project has several bibliography files")
+	}
+
+	style := project.Settings.BibKeyStyle
+	if req.KeyStyle != nil {
+		style = *req.KeyStyle
+	}
+	keyStyle := style
+	if keyStyle == "" || keyStyle == bibtex.KeyStyleKeep {
+		keyStyle = bibtex.KeyStyleAuthorYear
+	}
+
+	taken := map[string]bool{}
+	dois := map[string]string{}
+	for _, bib := range bibs {
+		addBibliographyKeys(taken, bib.db)
+		for _, entry := range bib.db.Entries {
+			if doi := normalizeDOI(bib.db.Text(entry, "doi")); doi != "" {
+				dois[doi] = entry.Key
+			}
+		}
+	}
+
+	response := &models.ImportBibliographyResponse{Imported: []string{}, Skipped: []models.SkippedBibliographyEntry{}}
+	var formatted []string
+	for _, entry := range entries {
+		doi := normalizeDOI((&bibtex.Database{}).Text(entry, "doi"))
+		if key, ok := dois[doi]; ok && doi != "" {
+			response.Skipped = append(response.Skipped, models.SkippedBibliographyEntry{
+				Key:    entry.Key,
+				Reason: fmt.Sprintf("already in the bibliography as %s", key),
+			})
+			continue
+		}
+
+		keepKey := (style == "" || style == bibtex.KeyStyleKeep) && entry.Key != "" && !taken[strings.ToLower(entry.Key)]
+		if !keepKey {
+			entry.Key = bibtex.GenerateKey(entry, keyStyle, taken)
+		}
+		taken[strings.ToLower(entry.Key)] = true
+		if doi != "" {
+			dois[doi] = entry.Key
+		}
+
+		response.Imported = append(response.Imported, entry.Key)
+		formatted = append(formatted, bibtex.FormatEntry(entry))
+	}
+
+	if target != nil {
+		response.File = target.file
+	}
+	if len(formatted) == 0 {
+		return response, nil
+	}
+
+	addition := strings.Join(formatted, "\n\n") + "\n"
+	if target == nil {
+		if err := s.checkPathFree(ctx, projectID, defaultBibliographyPath); err != nil {
+			return nil, err
+		}
+		response.File, err = s.CreateFile(ctx, projectID, userID, &models.CreateFileRequest{
+			Name:    path.Base(defaultBibliographyPath),
+			Path:    defaultBibliographyPath,
+			Content: []byte(addition),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	content := addition
+	if existing := strings.TrimRight(target.content, " \t\r\n"); existing != "" {
+		content = existing + "\n\n" + addition
+	}
+	response.File, response.Suggestions, err = s.updateBibliographyFile(ctx, target, userID, content)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// loadBibliography reads and parses the .bib files of a project, in path
+// order
+func (s *ProjectService) loadBibliography(ctx context.Context, projectID primitive.ObjectID) ([]*bibliographyFile, error) {
+	files, err := s.fileRepo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var bibs []*bibliographyFile
+	for _, file := range files {
+		if !isBibliographyFile(file) {
+			continue
+		}
+		content, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download file: %w", err)
+		}
+		bibs = append(bibs, &bibliographyFile{
+			file:    file,
+			content: string(content),
+			db:      bibtex.Parse(string(content)),
+		})
+	}
+
+	sort.Slice(bibs, func(i, j int) bool { return bibs[i].file.Path < bibs[j].file.Path })
+	return bibs, nil
+}
+
+// findBibliographyFile returns the loaded .bib file with the given ID
+func (s *ProjectService) findBibliographyFile(ctx context.Context, projectID, fileID primitive.ObjectID, bibs []*bibliographyFile) (*bibliographyFile, error) {
+	for _, bib := range bibs {
+		if bib.file.ID == fileID {
+			return bib, nil
+		}
+	}
+
+	if _, err := s.getProjectFile(ctx, projectID, fileID); err != nil {
+		return nil, fmt.Errorf("file not found")
+	}
+	return nil, fmt.Errorf("not a bibliography file")
+}
+
+// updateBibliographyFile saves a rewritten .bib file through UpdateFile, so
+// that quotas, history and tracked changes apply. The update is based on
+// the content that was read, so a concurrent edit is not overwritten.
+func (s *ProjectService) updateBibliographyFile(ctx context.Context, bib *bibliographyFile, userID primitive.ObjectID, content string) (*models.File, []*models.Suggestion, error) {
+	file, suggestions, err := s.UpdateFile(ctx, bib.file.ID, userID, &models.UpdateFileRequest{Content: content}, bib.file.ETag())
+	var conflict *FileConflictError
+	if errors.As(err, &conflict) {
+		return nil, nil, fmt.Errorf("file was modified during the update")
+	}
+	return file, suggestions, err
+}
+
+func isBibliographyFile(file *models.File) bool {
+	return !file.IsBinary && strings.EqualFold(path.Ext(file.Path), ".bib")
+}
+
+// normalizeDOI lowercases a DOI and removes the resolver or scheme it may
+// be written with
+func normalizeDOI(doi string) string {
+	doi = strings.ToLower(strings.TrimSpace(doi))
+	for _, prefix := range []string{"https://doi.org/", "http://doi.org/", "https://dx.doi.org/", "http://dx.doi.org/", "doi:"} {
+		doi = strings.TrimPrefix(doi, prefix)
+	}
+	return doi
+}
+
+// addBibliographyKeys adds the lowercase keys of a database to a set
+func addBibliographyKeys(keys map[string]bool, db *bibtex.Database) {
+	for _, entry := range db.Entries {
+		keys[strings.ToLower(entry.Key)] = true
+	}
+}
+
+func bibliographyEntryMatches(key string, fields map[string]string, terms []string) bool {
+	if len(terms) == 0 {
+		return true
+	}
+
+	var b strings.Builder
+	b.WriteString(bibtex.Fold(key))
+	for _, text := range fields {
+		b.WriteByte(' ')
+		b.WriteString(bibtex.Fold(text))
+	}
+	haystack := b.String()
+
+	for _, term := range terms {
+		if !strings.Contains(haystack, term) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,279 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// ShareProject invites an existing user to collaborate on a project. The
+// user becomes a collaborator once they accept the invitation.
+func (s *ProjectService) ShareProject(ctx context.Context, projectID, ownerID, collaboratorID primitive.ObjectID, role string) (*models.Invitation, error) {
+	user, err := s.userRepo.FindByID(ctx, collaboratorID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.InviteCollaborator(ctx, projectID, ownerID, user.Email, role)
+}
+
+// InviteCollaborator invites someone to collaborate on a project by email.
+// The email does not need to belong to an account yet: the invitation shows
+// up for whoever signs up with it.
+func (s *ProjectService) InviteCollaborator(ctx context.Context, projectID, ownerID primitive.ObjectID, email, role string) (*models.Invitation, error) {
+	project, err := s.getOwnedProject(ctx, projectID, ownerID, "only project owner can share project")
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &models.Invitation{
+		ProjectID:   projectID,
+		ProjectName: project.Name,
+		InviterID:   ownerID,
+		Email:       normalizeEmail(email),
+		Role:        role,
+	}
+
+	user, err := s.userRepo.FindByEmail(ctx, invitation.Email)
+	if err != nil && err.Error() != "user not found" {
+		return nil, err
+	}
+	if user != nil {
+		if user.ID == project.OwnerID {
+			return nil, fmt.Errorf("cannot invite the project owner")
+		}
+		if findCollaborator(project, user.ID) != nil {
+			return nil, fmt.Errorf("project already shared with this user")
+		}
+		invitation.InviteeID = &user.ID
+	}
+
+	if err := s.inviteRepo.Create(ctx, invitation); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Collaborator invited",
+		zap.String("project_id", projectID.Hex()),
+		zap.String("invitation_id", invitation.ID.Hex()),
+		zap.String("role", role),
+	)
+
+	return invitation, nil
+}
+
+// ListProjectInvitations lists the invitations of a project for its owner
+func (s *ProjectService) ListProjectInvitations(ctx context.Context, projectID, ownerID primitive.ObjectID, status string) ([]*models.Invitation, error) {
+	if _, err := s.getOwnedProject(ctx, projectID, ownerID, "only project owner can share project"); err != nil {
+		return nil, err
+	}
+
+	return s.inviteRepo.FindByProject(ctx, projectID, status)
+}
+
+// RevokeInvitation withdraws a pending invitation
+func (s *ProjectService) RevokeInvitation(ctx context.Context, projectID, invitationID, ownerID primitive.ObjectID) error {
+	if _, err := s.getOwnedProject(ctx, projectID, ownerID, "only project owner can share project"); err != nil {
+		return err
+	}
+
+	invitation, err := s.inviteRepo.FindByID(ctx, invitationID)
+	if err != nil {
+		return err
+	}
+	if invitation.ProjectID != projectID {
+		return fmt.Errorf("invitation not found")
+	}
+	if invitation.Status != models.InvitationStatusPending {
+		return fmt.Errorf("invitation is no longer pending")
+	}
+
+	return s.inviteRepo.Delete(ctx, invitationID)
+}
+
+// ListUserInvitations lists the pending invitations addressed to a user
+func (s *ProjectService) ListUserInvitations(ctx context.Context, userID primitive.ObjectID) ([]*models.Invitation, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.inviteRepo.FindPendingForUser(ctx, userID, normalizeEmail(user.Email))
+}
+
+// AcceptInvitation adds the user to the project with the invited role
+func (s *ProjectService) AcceptInvitation(ctx context.Context, invitationID, userID primitive.ObjectID) (*models.Project, error) {
+	invitation, err := s.getUserInvitation(ctx, invitationID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := s.projectRepo.FindByID(ctx, invitation.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.DeletedAt != nil {
+		return nil, fmt.Errorf("project is in trash")
+	}
+
+	if err := s.inviteRepo.Respond(ctx, invitationID, userID, models.InvitationStatusAccepted); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	collaborator := models.Collaborator{
+		UserID:     userID,
+		Role:       invitation.Role,
+		InvitedAt:  invitation.CreatedAt,
+		AcceptedAt: &now,
+	}
+	if err := s.projectRepo.AddCollaborator(ctx, project.ID, collaborator); err != nil && err.Error() != "project already shared with this user" {
+		return nil, err
+	}
+
+	s.logger.Info("Invitation accepted",
+		zap.String("project_id", project.ID.Hex()),
+		zap.String("invitation_id", invitationID.Hex()),
+		zap.String("user_id", userID.Hex()),
+	)
+
+	return s.projectRepo.FindByID(ctx, project.ID)
+}
+
+// DeclineInvitation turns down an invitation
+func (s *ProjectService) DeclineInvitation(ctx context.Context, invitationID, userID primitive.ObjectID) error {
+	if _, err := s.getUserInvitation(ctx, invitationID, userID); err != nil {
+		return err
+	}
+
+	return s.inviteRepo.Respond(ctx, invitationID, userID, models.InvitationStatusDeclined)
+}
+
+// ListCollaborators lists the collaborators of a project with their profiles
+func (s *ProjectService) ListCollaborators(ctx context.Context, projectID, userID primitive.ObjectID) ([]models.CollaboratorInfo, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(project.Collaborators))
+	for _, collab := range project.Collaborators {
+		ids = append(ids, collab.UserID)
+	}
+	users, err := s.userRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[primitive.ObjectID]*models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	collaborators := make([]models.CollaboratorInfo, 0, len(project.Collaborators))
+	for _, collab := range project.Collaborators {
+		info := models.CollaboratorInfo{Collaborator: collab}
+		if user, ok := byID[collab.UserID]; ok {
+			info.Email = user.Email
+			info.Username = user.Username
+			info.FullName = user.FullName
+		}
+		collaborators = append(collaborators, info)
+	}
+
+	return collaborators, nil
+}
+
+// UpdateCollaboratorRole switches a collaborator between editor and viewer
+func (s *ProjectService) UpdateCollaboratorRole(ctx context.Context, projectID, ownerID, collaboratorID primitive.ObjectID, role string) error {
+	project, err := s.getOwnedProject(ctx, projectID, ownerID, "only project owner can manage collaborators")
+	if err != nil {
+		return err
+	}
+	if findCollaborator(project, collaboratorID) == nil {
+		return fmt.Errorf("collaborator not found")
+	}
+
+	return s.projectRepo.UpdateCollaboratorRole(ctx, projectID, collaboratorID, role)
+}
+
+// RemoveCollaborator revokes a collaborator's access to a project
+func (s *ProjectService) RemoveCollaborator(ctx context.Context, projectID, ownerID, collaboratorID primitive.ObjectID) error {
+	if _, err := s.getOwnedProject(ctx, projectID, ownerID, "only project owner can manage collaborators"); err != nil {
+		return err
+	}
+
+	if err := s.projectRepo.RemoveCollaborator(ctx, projectID, collaboratorID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Collaborator removed",
+		zap.String("project_id", projectID.Hex()),
+		zap.String("user_id", collaboratorID.Hex()),
+	)
+
+	return nil
+}
+
+// LeaveProject removes the user from a project shared with them
+func (s *ProjectService) LeaveProject(ctx context.Context, projectID, userID primitive.ObjectID) error {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	if project.OwnerID == userID {
+		return fmt.Errorf("project owner cannot leave project")
+	}
+
+	return s.projectRepo.RemoveCollaborator(ctx, projectID, userID)
+}
+
+// ListSharedProjects lists the projects the user has accepted invitations to
+func (s *ProjectService) ListSharedProjects(ctx context.Context, userID primitive.ObjectID, status string, page, limit int) ([]*models.Project, int64, error) {
+	return s.projectRepo.FindSharedWithUser(ctx, userID, status, page, limit)
+}
+
+// getUserInvitation returns a pending invitation addressed to the user
+func (s *ProjectService) getUserInvitation(ctx context.Context, invitationID, userID primitive.ObjectID) (*models.Invitation, error) {
+	invitation, err := s.inviteRepo.FindByID(ctx, invitationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation.InviteeID == nil || *invitation.InviteeID != userID {
+		user, err := s.userRepo.FindByID(ctx, userID)
+		if err != nil {
+			return nil, err
+		}
+		if normalizeEmail(user.Email) != invitation.Email {
+			return nil, fmt.Errorf("invitation not found")
+		}
+	}
+
+	if invitation.Status != models.InvitationStatusPending {
+		return nil, fmt.Errorf("invitation is no longer pending")
+	}
+
+	return invitation, nil
+}
+
+func findCollaborator(project *models.Project, userID primitive.ObjectID) *models.Collaborator {
+	for i := range project.Collaborators {
+		if project.Collaborators[i].UserID == userID {
+			return &project.Collaborators[i]
+		}
+	}
+	return nil
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,516 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/texflow/services/project/internal/events"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxCommentQuote is the most characters of the anchored text stored
+	// with a thread
+	maxCommentQuote = 1000
+	// maxYjsPosition is the largest encoded Yjs relative position accepted
+	maxYjsPosition = 1024
+	// maxMentions is the most users one comment can notify
+	maxMentions = 20
+	// mentionExcerptLength is the length of the comment excerpt sent with a
+	// mention
+	mentionExcerptLength = 200
+)
+
+// mentionPattern matches "@username", but not the domain of an email address
+var mentionPattern = regexp.MustCompile(`(?:^|[^\w.@])@(\w[\w.-]*)`)
+
+// ListCommentThreads lists the comment threads of a project, optionally only
+// those of one file or in one state
+func (s *ProjectService) ListCommentThreads(ctx context.Context, projectID, userID primitive.ObjectID, fileID *primitive.ObjectID, status string) ([]*models.CommentThread, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	if status != "" && status != models.CommentThreadOpen && status != models.CommentThreadResolved {
+		return nil, fmt.Errorf("invalid comment status")
+	}
+
+	return s.commentRepo.FindByProject(ctx, projectID, fileID, status)
+}
+
+// GetCommentThread returns a comment thread of a project
+func (s *ProjectService) GetCommentThread(ctx context.Context, projectID, threadID, userID primitive.ObjectID) (*models.CommentThread, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	return s.getProjectThread(ctx, projectID, threadID)
+}
+
+// CreateCommentThread opens a thread on a range of a text file
+func (s *ProjectService) CreateCommentThread(ctx context.Context, projectID, userID primitive.ObjectID, req *models.CreateCommentThreadRequest) (*models.CommentThread, error) {
+	project, err := s.getCommentableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	fileID, err := primitive.ObjectIDFromHex(req.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file ID")
+	}
+	file, err := s.getProjectFile(ctx, projectID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.IsBinary {
+		return nil, fmt.Errorf("cannot comment on a binary file")
+	}
+
+	anchor, err := checkCommentAnchor(&req.Anchor)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	text := []rune(string(content))
+	if anchor.End > len(text) {
+		return nil, fmt.Errorf("invalid comment anchor")
+	}
+
+	author := s.lookupUsername(ctx, userID)
+	comment := s.newComment(ctx, project, userID, author, req.Body)
+	thread := &models.CommentThread{
+		ProjectID: projectID,
+		FileID:    fileID,
+		Anchor:    anchor,
+		Quote:     truncateRunes(string(text[anchor.Start:anchor.End]), maxCommentQuote),
+		Status:    models.CommentThreadOpen,
+		Comments:  []models.Comment{*comment},
+		CreatedBy: userID,
+	}
+	if err := s.commentRepo.Create(ctx, thread); err != nil {
+		return nil, err
+	}
+
+	s.publishThreadEvent(ctx, events.CommentThreadCreated, models.CommentActionCreated, thread, userID, author)
+	s.notifyMentions(ctx, thread, comment, comment.Mentions)
+
+	return thread, nil
+}
+
+// ReplyToCommentThread adds a comment to an open thread
+func (s *ProjectService) ReplyToCommentThread(ctx context.Context, projectID, threadID, userID primitive.ObjectID, req *models.CommentRequest) (*models.CommentThread, error) {
+	project, err := s.getCommentableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.getProjectThread(ctx, projectID, threadID); err != nil {
+		return nil, err
+	}
+
+	author := s.lookupUsername(ctx, userID)
+	comment := s.newComment(ctx, project, userID, author, req.Body)
+	thread, err := s.commentRepo.AddComment(ctx, threadID, comment)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishThreadEvent(ctx, events.CommentThreadUpdated, models.CommentActionReplied, thread, userID, author)
+	s.notifyMentions(ctx, thread, comment, comment.Mentions)
+
+	return thread, nil
+}
+
+// EditComment changes the body of a comment. Only its author can edit it,
+// and only users mentioned for the first time are notified.
+func (s *ProjectService) EditComment(ctx context.Context, projectID, threadID, commentID, userID primitive.ObjectID, req *models.CommentRequest) (*models.CommentThread, error) {
+	project, err := s.getCommentableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+	thread, err := s.getProjectThread(ctx, projectID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := findComment(thread, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if existing.AuthorID != userID {
+		return nil, fmt.Errorf("permission denied")
+	}
+
+	mentions := s.resolveMentions(ctx, project, userID, req.Body)
+	thread, err = s.commentRepo.UpdateComment(ctx, threadID, commentID, req.Body, mentions)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []primitive.ObjectID
+	for _, id := range mentions {
+		if !containsObjectID(existing.Mentions, id) {
+			added = append(added, id)
+		}
+	}
+
+	edited, _ := findComment(thread, commentID)
+	s.publishThreadEvent(ctx, events.CommentThreadUpdated, models.CommentActionEdited, thread, userID, existing.AuthorName)
+	if edited != nil {
+		s.notifyMentions(ctx, thread, edited, added)
+	}
+
+	return thread, nil
+}
+
+// DeleteComment removes a reply from an open thread. Only its author can
+// remove it; the first comment goes with the thread.
+func (s *ProjectService) DeleteComment(ctx context.Context, projectID, threadID, commentID, userID primitive.ObjectID) (*models.CommentThread, error) {
+	if _, err := s.getCommentableProject(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	thread, err := s.getProjectThread(ctx, projectID, threadID)
+	if err != nil {
+		return nil, err
+	}
+	comment, err := findComment(thread, commentID)
+	if err != nil {
+		return nil, err
+	}
+	if comment.AuthorID != userID {
+		return nil, fmt.Errorf("permission denied")
+	}
+	if comment.ID == thread.Comments[0].ID {
+		return nil, fmt.Errorf("cannot delete the first comment of a thread")
+	}
+
+	thread, err = s.commentRepo.RemoveComment(ctx, threadID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishThreadEvent(ctx, events.CommentThreadUpdated, models.CommentActionReplyDeleted, thread, userID, comment.AuthorName)
+
+	return thread, nil
+}
+
+// ResolveCommentThread marks a thread as resolved. It is kept, read-only,
+// until it is reopened.
+func (s *ProjectService) ResolveCommentThread(ctx context.Context, projectID, threadID, userID primitive.ObjectID) (*models.CommentThread, error) {
+	if _, err := s.getCommentableProject(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.getProjectThread(ctx, projectID, threadID); err != nil {
+		return nil, err
+	}
+
+	thread, err := s.commentRepo.Resolve(ctx, threadID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishThreadEvent(ctx, events.CommentThreadUpdated, models.CommentActionResolved, thread, userID, s.lookupUsername(ctx, userID))
+
+	return thread, nil
+}
+
+// ReopenCommentThread opens a resolved thread again
+func (s *ProjectService) ReopenCommentThread(ctx context.Context, projectID, threadID, userID primitive.ObjectID) (*models.CommentThread, error) {
+	if _, err := s.getCommentableProject(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+	if _, err := s.getProjectThread(ctx, projectID, threadID); err != nil {
+		return nil, err
+	}
+
+	thread, err := s.commentRepo.Reopen(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publishThreadEvent(ctx, events.CommentThreadUpdated, models.CommentActionReopened, thread, userID, s.lookupUsername(ctx, userID))
+
+	return thread, nil
+}
+
+// DeleteCommentThread deletes an open thread. Only the user who opened it
+// or the project owner can delete it; resolved threads are kept for audit.
+func (s *ProjectService) DeleteCommentThread(ctx context.Context, projectID, threadID, userID primitive.ObjectID) error {
+	project, err := s.getCommentableProject(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+	thread, err := s.getProjectThread(ctx, projectID, threadID)
+	if err != nil {
+		return err
+	}
+	if thread.CreatedBy != userID && project.OwnerID != userID {
+		return fmt.Errorf("permission denied")
+	}
+
+	if err := s.commentRepo.Delete(ctx, threadID); err != nil {
+		return err
+	}
+
+	s.events.Publish(ctx, projectID.Hex(), events.CommentThreadDeleted, models.CommentThreadEvent{
+		Action:   models.CommentActionDeleted,
+		ThreadID: thread.ID,
+		FileID:   thread.FileID,
+	}, userID.Hex(), s.lookupUsername(ctx, userID))
+
+	return nil
+}
+
+// ListNotifications returns the latest notifications of a user and how many
+// are unread
+func (s *ProjectService) ListNotifications(ctx context.Context, userID primitive.ObjectID, unreadOnly bool) ([]*models.Notification, int64, error) {
+	notifications, err := s.notificationRepo.FindByUser(ctx, userID, unreadOnly, 100)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	unread, err := s.notificationRepo.CountUnread(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return notifications, unread, nil
+}
+
+// MarkNotificationsRead marks notifications of a user as read and returns
+// how many changed
+func (s *ProjectService) MarkNotificationsRead(ctx context.Context, userID primitive.ObjectID, req *models.MarkNotificationsReadRequest) (int64, error) {
+	ids := make([]primitive.ObjectID, 0, len(req.IDs))
+	for _, hex := range req.IDs {
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			return 0, fmt.Errorf("invalid notification ID")
+		}
+		ids = append(ids, id)
+	}
+
+	return s.notificationRepo.MarkRead(ctx, userID, ids)
+}
+
+// getCommentableProject returns a project the user can comment on: members
+// can, whatever their role, but visitors of a public project cannot
+func (s *ProjectService) getCommentableProject(ctx context.Context, projectID, userID primitive.ObjectID) (*models.Project, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
+	if !s.isProjectMember(ctx, project, userID) {
+		return nil, fmt.Errorf("permission denied")
+	}
+
+	return project, nil
+}
+
+// isProjectMember reports whether a user owns a project, collaborates on it
+// or holds a share grant for it
+func (s *ProjectService) isProjectMember(ctx context.Context, project *models.Project, userID primitive.ObjectID) bool {
+	if project.OwnerID == userID {
+		return true
+	}
+
+	for _, collab := range project.Collaborators {
+		if collab.UserID == userID {
+			return true
+		}
+	}
+
+	return s.shareGrantRole(ctx, project.ID, userID) != ""
+}
+
+func (s *ProjectService) getProjectThread(ctx context.Context, projectID, threadID primitive.ObjectID) (*models.CommentThread, error) {
+	thread, err := s.commentRepo.FindByID(ctx, threadID)
+	if err != nil {
+		return nil, err
+	}
+	if thread.ProjectID != projectID {
+		return nil, fmt.Errorf("comment thread not found")
+	}
+	return thread, nil
+}
+
+// newComment creates a comment, resolving the members it mentions
+func (s *ProjectService) newComment(ctx context.Context, project *models.Project, userID primitive.ObjectID, author, body string) *models.Comment {
+	return &models.Comment{
+		ID:         primitive.NewObjectID(),
+		AuthorID:   userID,
+		AuthorName: author,
+		Body:       body,
+		Mentions:   s.resolveMentions(ctx, project, userID, body),
+		CreatedAt:  time.Now(),
+	}
+}
+
+// resolveMentions returns the project members mentioned by "@username" in a
+// comment, other than its author
+func (s *ProjectService) resolveMentions(ctx context.Context, project *models.Project, authorID primitive.ObjectID, body string) []primitive.ObjectID {
+	var usernames []string
+	seen := make(map[string]bool)
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		// A mention at the end of a sentence does not include the period
+		name := strings.TrimRight(match[1], ".-")
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		usernames = append(usernames, name)
+		if len(usernames) == maxMentions {
+			break
+		}
+	}
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	users, err := s.userRepo.FindByUsernames(ctx, usernames)
+	if err != nil {
+		s.logger.Error("Failed to resolve mentions", zap.Error(err))
+		return nil
+	}
+
+	var mentions []primitive.ObjectID
+	for _, user := range users {
+		if user.ID != authorID && s.isProjectMember(ctx, project, user.ID) {
+			mentions = append(mentions, user.ID)
+		}
+	}
+	return mentions
+}
+
+// notifyMentions stores a notification for each mentioned user and tells the
+// clients connected to the project
+func (s *ProjectService) notifyMentions(ctx context.Context, thread *models.CommentThread, comment *models.Comment, userIDs []primitive.ObjectID) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	excerpt := truncateRunes(comment.Body, mentionExcerptLength)
+	notifications := make([]*models.Notification, 0, len(userIDs))
+	for _, id := range userIDs {
+		notifications = append(notifications, &models.Notification{
+			UserID:    id,
+			Type:      models.NotificationCommentMention,
+			ProjectID: thread.ProjectID,
+			FileID:    thread.FileID,
+			ThreadID:  thread.ID,
+			CommentID: comment.ID,
+			ActorID:   comment.AuthorID,
+			ActorName: comment.AuthorName,
+			Excerpt:   excerpt,
+		})
+	}
+	if err := s.notificationRepo.CreateMany(ctx, notifications); err != nil {
+		s.logger.Error("Failed to store mention notifications", zap.String("thread_id", thread.ID.Hex()), zap.Error(err))
+	}
+
+	s.events.Publish(ctx, thread.ProjectID.Hex(), events.CommentMention, models.CommentMentionEvent{
+		UserIDs:   userIDs,
+		ThreadID:  thread.ID,
+		CommentID: comment.ID,
+		FileID:    thread.FileID,
+		Excerpt:   excerpt,
+	}, comment.AuthorID.Hex(), comment.AuthorName)
+}
+
+func (s *ProjectService) publishThreadEvent(ctx context.Context, msgType, action string, thread *models.CommentThread, userID primitive.ObjectID, username string) {
+	s.events.Publish(ctx, thread.ProjectID.Hex(), msgType, models.CommentThreadEvent{
+		Action:   action,
+		ThreadID: thread.ID,
+		FileID:   thread.FileID,
+		Thread:   thread,
+	}, userID.Hex(), username)
+}
+
+// lookupUsername returns the username shown with a comment or suggestion
+func (s *ProjectService) lookupUsername(ctx context.Context, userID primitive.ObjectID) string {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		s.logger.Warn("Failed to look up comment author", zap.String("user_id", userID.Hex()), zap.Error(err))
+		return ""
+	}
+	return user.Username
+}
+
+// checkCommentAnchor validates the range of a new thread. Yjs positions are
+// opaque to the server but must come as a pair of base64 strings.
+func checkCommentAnchor(req *models.CommentAnchorRequest) (models.CommentAnchor, error) {
+	anchor := models.CommentAnchor{
+		Start:    req.Start,
+		End:      req.End,
+		YjsStart: req.YjsStart,
+		YjsEnd:   req.YjsEnd,
+	}
+	if anchor.Start < 0 || anchor.End < anchor.Start {
+		return anchor, fmt.Errorf("invalid comment anchor")
+	}
+
+	if (anchor.YjsStart == "") != (anchor.YjsEnd == "") {
+		return anchor, fmt.Errorf("invalid comment anchor")
+	}
+	for _, pos := range []string{anchor.YjsStart, anchor.YjsEnd} {
+		if pos == "" {
+			continue
+		}
+		if len(pos) > maxYjsPosition {
+			return anchor, fmt.Errorf("invalid comment anchor")
+		}
+		if _, err := base64.StdEncoding.DecodeString(pos); err != nil {
+			return anchor, fmt.Errorf("invalid comment anchor")
+		}
+	}
+
+	return anchor, nil
+}
+
+func findComment(thread *models.CommentThread, commentID primitive.ObjectID) (*models.Comment, error) {
+	for i := range thread.Comments {
+		if thread.Comments[i].ID == commentID {
+			return &thread.Comments[i], nil
+		}
+	}
+	return nil, fmt.Errorf("comment not found")
+}
+
+func containsObjectID(ids []primitive.ObjectID, id primitive.ObjectID) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// truncateRunes shortens s to at most n characters
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/texflow/services/project/internal/models"
+	"github.com/texflow/services/project/internal/outline"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// GetDependencyGraph returns the file dependency graph of a project with
+// its unused files and missing references
+func (s *ProjectService) GetDependencyGraph(ctx context.Context, projectID, userID primitive.ObjectID) (*outline.Graph, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	root, sources, _, err := s.outlineSources(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+
+	return outline.BuildGraph(root, sources), nil
+}
+
+// MoveUnusedFiles moves files that the main file never reaches into the
+// unused folder, keeping their relative paths. Files that are used, or whose
+// destination is taken, are skipped.
+func (s *ProjectService) MoveUnusedFiles(ctx context.Context, projectID, userID primitive.ObjectID, req *models.MoveUnusedFilesRequest) (*models.MoveUnusedFilesResponse, error) {
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	root, sources, files, err := s.outlineSources(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+	// Without the main file nothing is reachable and every file would move
+	if _, ok := sources[root]; !ok {
+		return nil, fmt.Errorf("main file not found")
+	}
+
+	graph := outline.BuildGraph(root, sources)
+	unused := make(map[string]bool, len(graph.Unused))
+	for _, p := range graph.Unused {
+		unused[p] = true
+	}
+	byPath := make(map[string]*models.File, len(files))
+	for _, file := range files {
+		byPath[file.Path] = file
+	}
+
+	paths := req.Paths
+	if len(paths) == 0 {
+		paths = graph.Unused
+	}
+
+	response := &models.MoveUnusedFilesResponse{
+		Moved:   []*models.File{},
+		Skipped: []models.SkippedFile{},
+	}
+	var changed []string
+	for _, p := range paths {
+		file, ok := byPath[p]
+		if !ok {
+			response.Skipped = append(response.Skipped, models.SkippedFile{Path: p, Reason: "file not found"})
+			continue
+		}
+		if !unused[p] {
+			response.Skipped = append(response.Skipped, models.SkippedFile{Path: p, Reason: "file is used"})
+			continue
+		}
+
+		newPath := joinPath(outline.UnusedFolder, p)
+		if err := s.checkPathFree(ctx, projectID, newPath); err != nil {
+			response.Skipped = append(response.Skipped, models.SkippedFile{Path: p, Reason: err.Error()})
+			continue
+		}
+		if err := s.ensureFolders(ctx, projectID, userID, parentPath(newPath)); err != nil {
+			response.Skipped = append(response.Skipped, models.SkippedFile{Path: p, Reason: err.Error()})
+			continue
+		}
+		if err := s.relocateFile(ctx, project, file, newPath); err != nil {
+			s.logger.Error("Failed to move unused file", zap.String("path", p), zap.Error(err))
+			response.Skipped = append(response.Skipped, models.SkippedFile{Path: p, Reason: "failed to move file"})
+			continue
+		}
+
+		response.Moved = append(response.Moved, file)
+		changed = append(changed, p, newPath)
+	}
+
+	if len(changed) > 0 {
+		s.recordGitChanges(ctx, projectID, userID, changed...)
+	}
+
+	s.logger.Info("Unused files moved",
+		zap.String("project_id", projectID.Hex()),
+		zap.Int("moved", len(response.Moved)),
+		zap.Int("skipped", len(response.Skipped)),
+	)
+
+	return response, nil
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/texflow/services/project/internal/models"
+	"go.uber.org/zap"
+)
+
+// FileConflictError is returned when a file update was based on an outdated
+// version of the file
+type FileConflictError struct {
+	Conflict *models.FileConflict
+}
+
+func (e *FileConflictError) Error() string {
+	return "file has been modified"
+}
+
+// fileConflict describes a stale update, merging it with the changes saved
+// since its base version when they do not overlap
+func (s *ProjectService) fileConflict(ctx context.Context, file *models.File, ifMatch, content string) error {
+	conflict := &models.FileConflict{
+		Error:          "file has been modified",
+		FileID:         file.ID.Hex(),
+		CurrentVersion: file.Version,
+		ETag:           file.ETag(),
+	}
+	if file.IsBinary {
+		return &FileConflictError{Conflict: conflict}
+	}
+
+	current, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+	if err != nil {
+		s.logger.Error("Failed to read file for merge", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+		return &FileConflictError{Conflict: conflict}
+	}
+	conflict.CurrentContent = string(current)
+
+	baseVersion, baseHash, ok := parseETag(ifMatch)
+	if !ok || baseVersion > file.Version {
+		return &FileConflictError{Conflict: conflict}
+	}
+
+	// The base may have been pruned from the history, in which case no
+	// merge is offered
+	v, err := s.versionRepo.FindByVersion(ctx, file.ID, baseVersion)
+	if err != nil || v.Hash != baseHash {
+		return &FileConflictError{Conflict: conflict}
+	}
+	base, err := s.minioClient.DownloadBytes(ctx, v.StorageKey)
+	if err != nil {
+		s.logger.Error("Failed to read base version for merge", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+		return &FileConflictError{Conflict: conflict}
+	}
+
+	conflict.BaseVersion = baseVersion
+	if merged, ok := mergeLines(string(base), content, conflict.CurrentContent); ok {
+		conflict.MergedContent = &merged
+	}
+
+	return &FileConflictError{Conflict: conflict}
+}
+
+// etagMatches reports whether an If-Match header accepts etag. Weak
+// validators are compared by value.
+func etagMatches(ifMatch, etag string) bool {
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parseETag splits a file ETag into its version and content hash
+func parseETag(etag string) (int, string, bool) {
+	etag = strings.Trim(strings.TrimPrefix(strings.TrimSpace(etag), "W/"), `"`)
+
+	version, hash, ok := strings.Cut(etag, "-")
+	if !ok {
+		return 0, "", false
+	}
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return 0, "", false
+	}
+
+	return v, hash, true
+}
+
+// lineEdit replaces the base lines [start, end) with lines
+type lineEdit struct {
+	start, end int
+	lines      []string
+}
+
+// mergeLines applies the changes that ours and theirs each made to base.
+// It fails if the changes overlap or touch, as diff3 does.
+func mergeLines(base, ours, theirs string) (string, bool) {
+	baseLines := splitLines(base)
+	a := lineEdits(base, ours)
+	b := lineEdits(base, theirs)
+
+	var out []string
+	pos := 0
+	apply := func(e lineEdit) {
+		out = append(out, baseLines[pos:e.start]...)
+		out = append(out, e.lines...)
+		pos = e.end
+	}
+
+	for len(a) > 0 || len(b) > 0 {
+		switch {
+		case len(b) == 0:
+			apply(a[0])
+			a = a[1:]
+		case len(a) == 0:
+			apply(b[0])
+			b = b[1:]
+		case a[0].start <= b[0].end && b[0].start <= a[0].end:
+			if !sameEdit(a[0], b[0]) {
+				return "", false
+			}
+			apply(a[0])
+			a, b = a[1:], b[1:]
+		case a[0].start < b[0].start:
+			apply(a[0])
+			a = a[1:]
+		default:
+			apply(b[0])
+			b = b[1:]
+		}
+	}
+	out = append(out, baseLines[pos:]...)
+
+	return strings.Join(out, ""), true
+}
+
+// lineEdits lists the changes that turn base into text, by base line
+func lineEdits(base, text string) []lineEdit {
+	dmp := diffmatchpatch.New()
+	baseRunes, textRunes, lineArray := dmp.DiffLinesToRunes(base, text)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMainRunes(baseRunes, textRunes, false), lineArray)
+
+	var edits []lineEdit
+	var current *lineEdit
+	pos := 0
+	for _, d := range diffs {
+		lines := splitLines(d.Text)
+		if d.Type == diffmatchpatch.DiffEqual {
+			if current != nil {
+				edits = append(edits, *current)
+				current = nil
+			}
+			pos += len(lines)
+			continue
+		}
+
+		if current == nil {
+			current = &lineEdit{start: pos, end: pos}
+		}
+		if d.Type == diffmatchpatch.DiffDelete {
+			current.end += len(lines)
+			pos += len(lines)
+		} else {
+			current.lines = append(current.lines, lines...)
+		}
+	}
+	if current != nil {
+		edits = append(edits, *current)
+	}
+
+	return edits
+}
+
+func sameEdit(a, b lineEdit) bool {
+	if a.start != b.start || a.end != b.end || len(a.lines) != len(b.lines) {
+		return false
+	}
+	for i := range a.lines {
+		if a.lines[i] != b.lines[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitLines splits text into lines, keeping their line breaks
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
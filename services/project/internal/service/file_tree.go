@@ -0,0 +1,517 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// DeleteFile deletes a file from storage and its record
+func (s *ProjectService) DeleteFile(ctx context.Context, projectID, fileID, userID primitive.ObjectID) error {
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	file, err := s.getProjectFile(ctx, projectID, fileID)
+	if err != nil {
+		return err
+	}
+
+	if isMainFile(project, file.Path) {
+		return fmt.Errorf("cannot delete the main file")
+	}
+	if err := checkUntrackedWrite(project, userID); err != nil {
+		return err
+	}
+
+	if err := s.fileRepo.Delete(ctx, file.ID); err != nil {
+		return err
+	}
+
+	if err := s.minioClient.DeleteFile(ctx, file.StorageKey); err != nil {
+		s.logger.Error("Failed to delete file object", zap.String("key", file.StorageKey), zap.Error(err))
+	}
+	s.deleteFileHistory(ctx, file.ID)
+	s.unindexFile(ctx, file.ID)
+
+	s.updateProjectFileStats(ctx, projectID)
+	s.recordGitChanges(ctx, projectID, userID, file.Path)
+
+	return nil
+}
+
+// RenameFile renames a file within its folder
+func (s *ProjectService) RenameFile(ctx context.Context, projectID, fileID, userID primitive.ObjectID, name string) (*models.File, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := s.getProjectFile(ctx, projectID, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPath := file.Path
+	newPath := joinPath(parentPath(file.Path), name)
+	if err := s.checkPathFree(ctx, projectID, newPath); err != nil {
+		return nil, err
+	}
+
+	if err := s.relocateFile(ctx, project, file, newPath); err != nil {
+		return nil, err
+	}
+
+	s.recordGitChanges(ctx, projectID, userID, oldPath, newPath)
+
+	return file, nil
+}
+
+// MoveFile moves a file to another folder, creating the folder if needed
+func (s *ProjectService) MoveFile(ctx context.Context, projectID, fileID, userID primitive.ObjectID, folder string) (*models.File, error) {
+	destination, err := cleanFolderPath(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := s.getProjectFile(ctx, projectID, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	newPath := joinPath(destination, path.Base(file.Path))
+	if newPath == file.Path {
+		return file, nil
+	}
+	if err := s.checkPathFree(ctx, projectID, newPath); err != nil {
+		return nil, err
+	}
+
+	if err := s.ensureFolders(ctx, projectID, userID, destination); err != nil {
+		return nil, err
+	}
+
+	oldPath := file.Path
+	if err := s.relocateFile(ctx, project, file, newPath); err != nil {
+		return nil, err
+	}
+
+	s.recordGitChanges(ctx, projectID, userID, oldPath, newPath)
+
+	return file, nil
+}
+
+// CreateFolder creates a folder and any missing parent folders
+func (s *ProjectService) CreateFolder(ctx context.Context, projectID, userID primitive.ObjectID, req *models.CreateFolderRequest) (*models.Folder, error) {
+	folderPath, err := cleanFolderPath(req.Path)
+	if err != nil {
+		return nil, err
+	}
+	if folderPath == "" {
+		return nil, fmt.Errorf("invalid folder path")
+	}
+
+	if _, err := s.getEditableProject(ctx, projectID, userID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.folderRepo.FindByPath(ctx, projectID, folderPath); err == nil {
+		return nil, fmt.Errorf("folder already exists at this path")
+	}
+	if _, err := s.fileRepo.FindByPath(ctx, projectID, folderPath); err == nil {
+		return nil, fmt.Errorf("file already exists at this path")
+	}
+
+	if err := s.ensureFolders(ctx, projectID, userID, parentPath(folderPath)); err != nil {
+		return nil, err
+	}
+
+	folder := &models.Folder{
+		ProjectID: projectID,
+		Name:      path.Base(folderPath),
+		Path:      folderPath,
+		CreatedBy: userID,
+	}
+
+	if err := s.folderRepo.Create(ctx, folder); err != nil {
+		return nil, err
+	}
+
+	return folder, nil
+}
+
+// ListFolders lists all folders of a project
+func (s *ProjectService) ListFolders(ctx context.Context, projectID, userID primitive.ObjectID) ([]*models.Folder, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	folders, err := s.folderRepo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if folders == nil {
+		folders = []*models.Folder{}
+	}
+	return folders, nil
+}
+
+// DeleteFolder deletes a folder with all files and folders below it
+func (s *ProjectService) DeleteFolder(ctx context.Context, projectID, folderID, userID primitive.ObjectID) error {
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return err
+	}
+
+	folder, err := s.getProjectFolder(ctx, projectID, folderID)
+	if err != nil {
+		return err
+	}
+
+	files, err := s.fileRepo.FindByPathPrefix(ctx, projectID, folder.Path+"/")
+	if err != nil {
+		return err
+	}
+
+	for _, file := range files {
+		if isMainFile(project, file.Path) {
+			return fmt.Errorf("cannot delete the main file")
+		}
+	}
+	if len(files) > 0 {
+		if err := checkUntrackedWrite(project, userID); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range files {
+		if err := s.fileRepo.Delete(ctx, file.ID); err != nil {
+			return err
+		}
+		if err := s.minioClient.DeleteFile(ctx, file.StorageKey); err != nil {
+			s.logger.Error("Failed to delete file object", zap.String("key", file.StorageKey), zap.Error(err))
+		}
+		s.deleteFileHistory(ctx, file.ID)
+		s.unindexFile(ctx, file.ID)
+	}
+
+	if err := s.folderRepo.DeleteTree(ctx, projectID, folder.Path); err != nil {
+		return err
+	}
+
+	s.updateProjectFileStats(ctx, projectID)
+	s.recordGitChanges(ctx, projectID, userID, folder.Path+"/")
+
+	s.logger.Info("Folder deleted",
+		zap.String("project_id", projectID.Hex()),
+		zap.String("path", folder.Path),
+		zap.Int("files", len(files)),
+	)
+
+	return nil
+}
+
+// RenameFolder renames a folder within its parent
+func (s *ProjectService) RenameFolder(ctx context.Context, projectID, folderID, userID primitive.ObjectID, name string) (*models.Folder, error) {
+	if err := validateName(name); err != nil {
+		return nil, err
+	}
+
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := s.getProjectFolder(ctx, projectID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	oldPath := folder.Path
+	if err := s.relocateFolder(ctx, project, folder, joinPath(parentPath(folder.Path), name)); err != nil {
+		return nil, err
+	}
+
+	s.recordGitChanges(ctx, projectID, userID, oldPath+"/", folder.Path+"/")
+
+	return folder, nil
+}
+
+// MoveFolder moves a folder with its contents into another folder
+func (s *ProjectService) MoveFolder(ctx context.Context, projectID, folderID, userID primitive.ObjectID, destination string) (*models.Folder, error) {
+	destination, err := cleanFolderPath(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := s.getProjectFolder(ctx, projectID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	if destination == folder.Path || strings.HasPrefix(destination, folder.Path+"/") {
+		return nil, fmt.Errorf("cannot move a folder into itself")
+	}
+
+	if err := s.ensureFolders(ctx, projectID, userID, destination); err != nil {
+		return nil, err
+	}
+
+	oldPath := folder.Path
+	if err := s.relocateFolder(ctx, project, folder, joinPath(destination, folder.Name)); err != nil {
+		return nil, err
+	}
+
+	s.recordGitChanges(ctx, projectID, userID, oldPath+"/", folder.Path+"/")
+
+	return folder, nil
+}
+
+// relocateFolder moves a folder and everything below it to newPath
+func (s *ProjectService) relocateFolder(ctx context.Context, project *models.Project, folder *models.Folder, newPath string) error {
+	oldPath := folder.Path
+	if newPath == oldPath {
+		return nil
+	}
+
+	if err := s.checkPathFree(ctx, project.ID, newPath); err != nil {
+		return err
+	}
+
+	files, err := s.fileRepo.FindByPathPrefix(ctx, project.ID, oldPath+"/")
+	if err != nil {
+		return err
+	}
+	subfolders, err := s.folderRepo.FindByPathPrefix(ctx, project.ID, oldPath+"/")
+	if err != nil {
+		return err
+	}
+
+	// Folders first so that the tree exists when files arrive
+	folder.Name = path.Base(newPath)
+	folder.Path = newPath
+	if err := s.folderRepo.Relocate(ctx, folder); err != nil {
+		return err
+	}
+	for _, sub := range subfolders {
+		sub.Path = newPath + strings.TrimPrefix(sub.Path, oldPath)
+		if err := s.folderRepo.Relocate(ctx, sub); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range files {
+		if err := s.relocateFile(ctx, project, file, newPath+strings.TrimPrefix(file.Path, oldPath)); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info("Folder moved",
+		zap.String("project_id", project.ID.Hex()),
+		zap.String("from", oldPath),
+		zap.String("to", newPath),
+		zap.Int("files", len(files)),
+	)
+
+	return nil
+}
+
+// relocateFile moves a file's object and record to newPath. The object is
+// copied to a key of its own before the record changes so a failure never
+// leaves a record pointing at a missing object, nor deletes another file's.
+// The main file setting follows the file.
+func (s *ProjectService) relocateFile(ctx context.Context, project *models.Project, file *models.File, newPath string) error {
+	oldPath := file.Path
+	oldKey := file.StorageKey
+	newKey := newFileObjectKey(project.ID, newPath)
+
+	if err := s.minioClient.CopyFile(ctx, oldKey, newKey); err != nil {
+		return fmt.Errorf("failed to move file: %w", err)
+	}
+
+	previous := *file
+	file.Name = path.Base(newPath)
+	file.Path = newPath
+	file.StorageKey = newKey
+	file.ContentType = getContentType(file.Name)
+
+	// Fails if the content was rewritten since the file was read, in which
+	// case the copy is stale
+	if err := s.fileRepo.Relocate(ctx, file); err != nil {
+		*file = previous
+		if delErr := s.minioClient.DeleteFile(ctx, newKey); delErr != nil {
+			s.logger.Error("Failed to clean up copied object", zap.String("key", newKey), zap.Error(delErr))
+		}
+		return err
+	}
+
+	if err := s.minioClient.DeleteFile(ctx, oldKey); err != nil {
+		s.logger.Error("Failed to delete old file object", zap.String("key", oldKey), zap.Error(err))
+	}
+	s.reindexFilePath(ctx, file)
+
+	if isMainFile(project, oldPath) {
+		project.Settings.MainFile = newPath
+		if err := s.projectRepo.Update(ctx, project); err != nil {
+			s.logger.Error("Failed to update main file", zap.String("project_id", project.ID.Hex()), zap.Error(err))
+		}
+	}
+
+	return nil
+}
+
+// ensureFolders creates folderPath and its ancestors if they do not exist
+func (s *ProjectService) ensureFolders(ctx context.Context, projectID, userID primitive.ObjectID, folderPath string) error {
+	if folderPath == "" {
+		return nil
+	}
+
+	current := ""
+	for _, part := range strings.Split(folderPath, "/") {
+		current = joinPath(current, part)
+
+		if _, err := s.folderRepo.FindByPath(ctx, projectID, current); err == nil {
+			continue
+		}
+		if _, err := s.fileRepo.FindByPath(ctx, projectID, current); err == nil {
+			return fmt.Errorf("file already exists at this path")
+		}
+
+		folder := &models.Folder{
+			ProjectID: projectID,
+			Name:      part,
+			Path:      current,
+			CreatedBy: userID,
+		}
+		if err := s.folderRepo.Create(ctx, folder); err != nil && err.Error() != "folder already exists at this path" {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkPathFree returns an error if a file or folder already exists at p
+func (s *ProjectService) checkPathFree(ctx context.Context, projectID primitive.ObjectID, p string) error {
+	if _, err := s.fileRepo.FindByPath(ctx, projectID, p); err == nil {
+		return fmt.Errorf("file already exists at this path")
+	}
+	if _, err := s.folderRepo.FindByPath(ctx, projectID, p); err == nil {
+		return fmt.Errorf("folder already exists at this path")
+	}
+	return nil
+}
+
+func (s *ProjectService) getEditableProject(ctx context.Context, projectID, userID primitive.ObjectID) (*models.Project, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
+	if !s.userCanEdit(ctx, project, userID) {
+		return nil, fmt.Errorf("permission denied")
+	}
+
+	return project, nil
+}
+
+func (s *ProjectService) getProjectFile(ctx context.Context, projectID, fileID primitive.ObjectID) (*models.File, error) {
+	file, err := s.fileRepo.FindByID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if file.ProjectID != projectID {
+		return nil, fmt.Errorf("file not found")
+	}
+	return file, nil
+}
+
+func (s *ProjectService) getProjectFolder(ctx context.Context, projectID, folderID primitive.ObjectID) (*models.Folder, error) {
+	folder, err := s.folderRepo.FindByID(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if folder.ProjectID != projectID {
+		return nil, fmt.Errorf("folder not found")
+	}
+	return folder, nil
+}
+
+// cleanFilePath normalizes a file path relative to the project root
+func cleanFilePath(p string) (string, error) {
+	cleanPath := filepath.ToSlash(filepath.Clean(p))
+	if strings.HasPrefix(cleanPath, "..") {
+		return "", fmt.Errorf("invalid file path")
+	}
+	return strings.TrimPrefix(cleanPath, "/"), nil
+}
+
+// cleanFolderPath normalizes a folder path; the project root is ""
+func cleanFolderPath(p string) (string, error) {
+	if strings.Trim(p, "/") == "" {
+		return "", nil
+	}
+
+	cleanPath, err := cleanFilePath(p)
+	if err != nil || cleanPath == "." {
+		return "", fmt.Errorf("invalid folder path")
+	}
+	return cleanPath, nil
+}
+
+// validateName checks that a file or folder name is a single path element
+func validateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\") {
+		return fmt.Errorf("invalid name")
+	}
+	return nil
+}
+
+// isMainFile reports whether filePath is the project's main file
+func isMainFile(project *models.Project, filePath string) bool {
+	return strings.TrimPrefix(project.Settings.MainFile, "/") == filePath
+}
+
+func parentPath(p string) string {
+	dir := path.Dir(p)
+	if dir == "." || dir == "/" {
+		return ""
+	}
+	return dir
+}
+
+func joinPath(dir, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
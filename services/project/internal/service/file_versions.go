@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// diffContextLines is the number of unchanged lines shown around a change
+const diffContextLines = 3
+
+// VersionRetention bounds the history kept for each file. The newest
+// KeepLatest versions are always kept; older ones are pruned once there are
+// more than MaxVersions or they are older than MaxAge. Zero disables a limit.
+type VersionRetention struct {
+	KeepLatest  int
+	MaxVersions int
+	MaxAge      time.Duration
+}
+
+// ListFileVersions lists the versions of a file, newest first
+func (s *ProjectService) ListFileVersions(ctx context.Context, projectID, fileID, userID primitive.ObjectID) ([]*models.FileVersion, error) {
+	if _, err := s.getReadableFile(ctx, projectID, fileID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.versionRepo.FindByFileID(ctx, fileID)
+}
+
+// GetFileVersion returns a version of a file with its content
+func (s *ProjectService) GetFileVersion(ctx context.Context, projectID, fileID, userID primitive.ObjectID, version int) (*models.FileVersion, []byte, error) {
+	if _, err := s.getReadableFile(ctx, projectID, fileID, userID); err != nil {
+		return nil, nil, err
+	}
+
+	v, err := s.versionRepo.FindByVersion(ctx, fileID, version)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := s.minioClient.DownloadBytes(ctx, v.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download version: %w", err)
+	}
+
+	return v, content, nil
+}
+
+// DiffFileVersions diffs two versions of a text file line by line. A zero
+// version stands for the current content.
+func (s *ProjectService) DiffFileVersions(ctx context.Context, projectID, fileID, userID primitive.ObjectID, from, to int) (*models.FileDiff, error) {
+	file, err := s.getReadableFile(ctx, projectID, fileID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if file.IsBinary {
+		return nil, fmt.Errorf("cannot diff binary files")
+	}
+	if from == 0 {
+		from = file.Version
+	}
+	if to == 0 {
+		to = file.Version
+	}
+
+	oldContent, err := s.versionContent(ctx, file, from)
+	if err != nil {
+		return nil, err
+	}
+	newContent, err := s.versionContent(ctx, file, to)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := diffLines(string(oldContent), string(newContent))
+	diff := &models.FileDiff{
+		FileID:      fileID,
+		FromVersion: from,
+		ToVersion:   to,
+		Hunks:       diffHunks(lines, diffContextLines),
+	}
+	for _, line := range lines {
+		switch line.Type {
+		case "add":
+			diff.Additions++
+		case "delete":
+			diff.Deletions++
+		}
+	}
+
+	return diff, nil
+}
+
+// RestoreFileVersion makes the content of an old version current again. The
+// restore is saved as a new version, so the history is never rewritten.
+// When the project tracks the user's changes, the restore is recorded as
+// suggestions like any other edit and the file is left as it is.
+func (s *ProjectService) RestoreFileVersion(ctx context.Context, projectID, fileID, userID primitive.ObjectID, version int) (*models.File, []*models.Suggestion, error) {
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := s.getProjectFile(ctx, projectID, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	v, err := s.versionRepo.FindByVersion(ctx, fileID, version)
+	if err != nil {
+		return nil, nil, err
+	}
+	if v.Hash == file.Hash {
+		return file, nil, nil
+	}
+
+	content, err := s.minioClient.DownloadBytes(ctx, v.StorageKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download version: %w", err)
+	}
+
+	if tracksChangesOf(project, userID) {
+		if file.IsBinary {
+			return nil, nil, checkUntrackedWrite(project, userID)
+		}
+		suggestions, err := s.suggestFileChanges(ctx, file, userID, string(content))
+		if err != nil {
+			return nil, nil, err
+		}
+		return file, suggestions, nil
+	}
+
+	release, err := s.reserveQuota(ctx, project.OwnerID, int64(len(content)), int64(len(content))-file.SizeBytes, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.writeFileContent(ctx, file, content); err != nil {
+		release()
+		return nil, nil, err
+	}
+	s.recordFileVersion(ctx, file, userID, content, version)
+
+	s.updateProjectFileStats(ctx, projectID)
+	s.recordGitChanges(ctx, projectID, userID, file.Path)
+
+	s.logger.Info("File version restored",
+		zap.String("file_id", fileID.Hex()),
+		zap.Int("restored_from", version),
+		zap.Int("version", file.Version),
+	)
+
+	return file, nil, nil
+}
+
+// recordFileVersion saves content as the current version of a file and
+// applies the retention policy. The file itself is already saved, so
+// failures are logged rather than returned.
+func (s *ProjectService) recordFileVersion(ctx context.Context, file *models.File, userID primitive.ObjectID, content []byte, restoredFrom int) {
+	v := &models.FileVersion{
+		FileID:       file.ID,
+		ProjectID:    file.ProjectID,
+		Version:      file.Version,
+		Path:         file.Path,
+		SizeBytes:    int64(len(content)),
+		Hash:         file.Hash,
+		IsBinary:     file.IsBinary,
+		CreatedBy:    userID,
+		CreatedAt:    file.UpdatedAt,
+		RestoredFrom: restoredFrom,
+	}
+	if err := s.storeFileVersion(ctx, v, content); err != nil {
+		s.logger.Error("Failed to record file version",
+			zap.String("file_id", file.ID.Hex()),
+			zap.Int("version", file.Version),
+			zap.Error(err),
+		)
+		return
+	}
+
+	s.pruneFileVersions(ctx, file.ID)
+}
+
+// ensureBaseVersion snapshots the current content of a file that predates
+// version history before it is overwritten
+func (s *ProjectService) ensureBaseVersion(ctx context.Context, file *models.File) {
+	if _, err := s.versionRepo.FindByVersion(ctx, file.ID, file.Version); err == nil || err.Error() != "version not found" {
+		return
+	}
+
+	content, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+	if err != nil {
+		s.logger.Error("Failed to read file for base version", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+		return
+	}
+
+	v := &models.FileVersion{
+		FileID:    file.ID,
+		ProjectID: file.ProjectID,
+		Version:   file.Version,
+		Path:      file.Path,
+		SizeBytes: int64(len(content)),
+		Hash:      file.Hash,
+		IsBinary:  file.IsBinary,
+		CreatedBy: file.CreatedBy,
+		CreatedAt: file.UpdatedAt,
+	}
+	if err := s.storeFileVersion(ctx, v, content); err != nil {
+		s.logger.Error("Failed to record base version", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+	}
+}
+
+// recordCopiedFileVersion records the first version of a file whose content
+// was copied from another object, without downloading it
+func (s *ProjectService) recordCopiedFileVersion(ctx context.Context, file *models.File, userID primitive.ObjectID, sourceKey string) {
+	v := &models.FileVersion{
+		FileID:     file.ID,
+		ProjectID:  file.ProjectID,
+		Version:    file.Version,
+		Path:       file.Path,
+		SizeBytes:  file.SizeBytes,
+		Hash:       file.Hash,
+		StorageKey: versionStorageKey(file.ProjectID, file.Hash),
+		IsBinary:   file.IsBinary,
+		CreatedBy:  userID,
+		CreatedAt:  file.UpdatedAt,
+	}
+
+	err := s.minioClient.CopyFile(ctx, sourceKey, v.StorageKey)
+	if err == nil {
+		err = s.versionRepo.Create(ctx, v)
+	}
+	if err != nil {
+		s.logger.Error("Failed to record file version", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+	}
+}
+
+// storeFileVersion uploads version content under its hash and creates the
+// version record. The upload is repeated even if the object exists, so a
+// concurrent prune of the same content cannot leave the record dangling.
+func (s *ProjectService) storeFileVersion(ctx context.Context, v *models.FileVersion, content []byte) error {
+	v.StorageKey = versionStorageKey(v.ProjectID, v.Hash)
+	if err := s.minioClient.UploadBytes(ctx, v.StorageKey, content, "application/octet-stream"); err != nil {
+		return fmt.Errorf("failed to upload version: %w", err)
+	}
+
+	return s.versionRepo.Create(ctx, v)
+}
+
+// pruneFileVersions deletes the versions of a file that fall outside the
+// retention policy, and their content once no other version uses it
+func (s *ProjectService) pruneFileVersions(ctx context.Context, fileID primitive.ObjectID) {
+	versions, err := s.versionRepo.FindByFileID(ctx, fileID)
+	if err != nil {
+		s.logger.Error("Failed to list versions for pruning", zap.String("file_id", fileID.Hex()), zap.Error(err))
+		return
+	}
+
+	policy := s.versionRetention
+	var expired []*models.FileVersion
+	for i, v := range versions {
+		if i < policy.KeepLatest || i == 0 {
+			continue
+		}
+		tooMany := policy.MaxVersions > 0 && i >= policy.MaxVersions
+		tooOld := policy.MaxAge > 0 && time.Since(v.CreatedAt) > policy.MaxAge
+		if tooMany || tooOld {
+			expired = append(expired, v)
+		}
+	}
+
+	s.deleteFileVersions(ctx, expired)
+}
+
+// deleteFileVersions deletes version records and the content no other
+// version of the project refers to
+func (s *ProjectService) deleteFileVersions(ctx context.Context, versions []*models.FileVersion) {
+	if len(versions) == 0 {
+		return
+	}
+
+	ids := make([]primitive.ObjectID, 0, len(versions))
+	for _, v := range versions {
+		ids = append(ids, v.ID)
+	}
+	if err := s.versionRepo.DeleteByIDs(ctx, ids); err != nil {
+		s.logger.Error("Failed to delete file versions", zap.Error(err))
+		return
+	}
+
+	checked := map[string]bool{}
+	for _, v := range versions {
+		if checked[v.StorageKey] {
+			continue
+		}
+		checked[v.StorageKey] = true
+
+		count, err := s.versionRepo.CountByHash(ctx, v.ProjectID, v.Hash)
+		if err != nil || count > 0 {
+			continue
+		}
+		if err := s.minioClient.DeleteFile(ctx, v.StorageKey); err != nil {
+			s.logger.Error("Failed to delete version object", zap.String("key", v.StorageKey), zap.Error(err))
+		}
+	}
+}
+
+// deleteFileHistory deletes all versions of a deleted file
+func (s *ProjectService) deleteFileHistory(ctx context.Context, fileID primitive.ObjectID) {
+	versions, err := s.versionRepo.FindByFileID(ctx, fileID)
+	if err != nil {
+		s.logger.Error("Failed to list file versions", zap.String("file_id", fileID.Hex()), zap.Error(err))
+		return
+	}
+	s.deleteFileVersions(ctx, versions)
+}
+
+// versionContent reads the content of a version, falling back to the file
+// itself for the current version of a file without history
+func (s *ProjectService) versionContent(ctx context.Context, file *models.File, version int) ([]byte, error) {
+	v, err := s.versionRepo.FindByVersion(ctx, file.ID, version)
+	if err != nil {
+		if err.Error() == "version not found" && version == file.Version {
+			return s.minioClient.DownloadBytes(ctx, file.StorageKey)
+		}
+		return nil, err
+	}
+
+	return s.minioClient.DownloadBytes(ctx, v.StorageKey)
+}
+
+func versionStorageKey(projectID primitive.ObjectID, hash string) string {
+	return fmt.Sprintf("projects/%s/versions/%s", projectID.Hex(), hash)
+}
+
+func (s *ProjectService) getReadableFile(ctx context.Context, projectID, fileID, userID primitive.ObjectID) (*models.File, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	return s.getProjectFile(ctx, projectID, fileID)
+}
+
+// diffLines computes a line diff of two texts
+func diffLines(oldText, newText string) []models.DiffLine {
+	dmp := diffmatchpatch.New()
+	oldRunes, newRunes, lineArray := dmp.DiffLinesToRunes(oldText, newText)
+	diffs := dmp.DiffCharsToLines(dmp.DiffMainRunes(oldRunes, newRunes, false), lineArray)
+
+	var lines []models.DiffLine
+	for _, d := range diffs {
+		lineType := "context"
+		switch d.Type {
+		case diffmatchpatch.DiffInsert:
+			lineType = "add"
+		case diffmatchpatch.DiffDelete:
+			lineType = "delete"
+		}
+
+		for _, text := range strings.SplitAfter(d.Text, "\n") {
+			if text == "" {
+				continue
+			}
+			lines = append(lines, models.DiffLine{Type: lineType, Content: strings.TrimSuffix(text, "\n")})
+		}
+	}
+
+	return lines
+}
+
+// diffHunks groups changed lines into hunks with up to context unchanged
+// lines around them; changes closer than twice that share a hunk
+func diffHunks(lines []models.DiffLine, context int) []models.DiffHunk {
+	hunks := []models.DiffHunk{}
+
+	// Line numbers before each line, 1-based
+	oldLine := make([]int, len(lines)+1)
+	newLine := make([]int, len(lines)+1)
+	oldLine[0], newLine[0] = 1, 1
+	for i, line := range lines {
+		oldLine[i+1], newLine[i+1] = oldLine[i], newLine[i]
+		if line.Type != "add" {
+			oldLine[i+1]++
+		}
+		if line.Type != "delete" {
+			newLine[i+1]++
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		if lines[i].Type == "context" {
+			i++
+			continue
+		}
+
+		start := max(i-context, 0)
+		end := i
+		for j := i; j < len(lines) && j <= end+2*context; j++ {
+			if lines[j].Type != "context" {
+				end = j
+			}
+		}
+		stop := min(end+context+1, len(lines))
+
+		hunks = append(hunks, models.DiffHunk{
+			OldStart: oldLine[start],
+			OldLines: oldLine[stop] - oldLine[start],
+			NewStart: newLine[start],
+			NewLines: newLine[stop] - newLine[start],
+			Lines:    lines[start:stop],
+		})
+		i = stop
+	}
+
+	return hunks
+}
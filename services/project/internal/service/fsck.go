@@ -0,0 +1,426 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	// fsckGracePeriod skips objects written shortly before a check starts,
+	// whose records may not be written yet
+	fsckGracePeriod = time.Hour
+
+	// maxFsckIssues bounds the issues kept in a report
+	maxFsckIssues = 1000
+)
+
+// Storage check triggers
+const (
+	FsckTriggerManual    = "manual"
+	FsckTriggerScheduled = "scheduled"
+)
+
+// storageCheck is one run of the storage consistency check
+type storageCheck struct {
+	s      *ProjectService
+	report *models.FsckReport
+	cutoff time.Time
+}
+
+// StartStorageCheck starts a storage check in the background and returns
+// its report, which is updated once the check finishes. Unless req.Repair is
+// set the check only reports what it finds.
+func (s *ProjectService) StartStorageCheck(ctx context.Context, adminID primitive.ObjectID, req *models.RunFsckRequest) (*models.FsckReport, error) {
+	report, err := s.newStorageCheck(ctx, FsckTriggerManual, req)
+	if err != nil {
+		return nil, err
+	}
+	report.RequestedBy = &adminID
+
+	if !s.fsckRunning.CompareAndSwap(false, true) {
+		return nil, fmt.Errorf("storage check already running")
+	}
+	if err := s.fsckRepo.Create(ctx, report); err != nil {
+		s.fsckRunning.Store(false)
+		return nil, err
+	}
+
+	// The check goes on updating report while the copy is returned
+	started := *report
+	started.Summary = map[string]int{}
+	go func() {
+		defer s.fsckRunning.Store(false)
+		s.runStorageCheck(context.Background(), report)
+	}()
+
+	return &started, nil
+}
+
+// RunStorageCheck checks the whole storage and waits for the result
+func (s *ProjectService) RunStorageCheck(ctx context.Context, repair bool) (*models.FsckReport, error) {
+	report, err := s.newStorageCheck(ctx, FsckTriggerScheduled, &models.RunFsckRequest{Repair: repair})
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.fsckRunning.CompareAndSwap(false, true) {
+		return nil, fmt.Errorf("storage check already running")
+	}
+	defer s.fsckRunning.Store(false)
+
+	if err := s.fsckRepo.Create(ctx, report); err != nil {
+		return nil, err
+	}
+	s.runStorageCheck(ctx, report)
+
+	return report, nil
+}
+
+// GetStorageCheck returns the report of a storage check
+func (s *ProjectService) GetStorageCheck(ctx context.Context, checkID primitive.ObjectID) (*models.FsckReport, error) {
+	return s.fsckRepo.FindByID(ctx, checkID)
+}
+
+// ListStorageChecks lists the latest storage checks without their issues
+func (s *ProjectService) ListStorageChecks(ctx context.Context) ([]*models.FsckReport, error) {
+	return s.fsckRepo.FindRecent(ctx, 50)
+}
+
+func (s *ProjectService) newStorageCheck(ctx context.Context, trigger string, req *models.RunFsckRequest) (*models.FsckReport, error) {
+	report := &models.FsckReport{
+		Status:       models.FsckStatusRunning,
+		Trigger:      trigger,
+		DryRun:       !req.Repair,
+		VerifyHashes: req.VerifyHashes,
+		Summary:      map[string]int{},
+		Issues:       []models.FsckIssue{},
+		StartedAt:    time.Now(),
+	}
+
+	if req.ProjectID != "" {
+		projectID, err := primitive.ObjectIDFromHex(req.ProjectID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid project ID")
+		}
+		if _, err := s.projectRepo.FindByID(ctx, projectID); err != nil {
+			return nil, err
+		}
+		report.ProjectID = &projectID
+	}
+
+	return report, nil
+}
+
+// runStorageCheck compares the file records of every project, or of the
+// project the report is scoped to, with the objects in storage, and saves
+// the report
+func (s *ProjectService) runStorageCheck(ctx context.Context, report *models.FsckReport) {
+	c := &storageCheck{s: s, report: report, cutoff: report.StartedAt.Add(-fsckGracePeriod)}
+
+	err := c.run(ctx)
+	finished := time.Now()
+	report.FinishedAt = &finished
+	report.Status = models.FsckStatusCompleted
+	if err != nil {
+		report.Status = models.FsckStatusFailed
+		report.Error = err.Error()
+		s.logger.Error("Storage check failed", zap.String("check_id", report.ID.Hex()), zap.Error(err))
+	}
+
+	if err := s.fsckRepo.Save(ctx, report); err != nil {
+		s.logger.Error("Failed to save storage check", zap.String("check_id", report.ID.Hex()), zap.Error(err))
+	}
+
+	s.logger.Info("Storage check finished",
+		zap.String("check_id", report.ID.Hex()),
+		zap.Bool("dry_run", report.DryRun),
+		zap.Int("projects", report.ProjectsChecked),
+		zap.Int("issues", len(report.Issues)),
+		zap.Int("repaired", report.Repaired),
+	)
+}
+
+func (c *storageCheck) run(ctx context.Context) error {
+	if c.report.ProjectID != nil {
+		return c.checkProject(ctx, *c.report.ProjectID)
+	}
+
+	projectIDs, err := c.s.projectRepo.FindAllIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool, len(projectIDs))
+	for _, projectID := range projectIDs {
+		known[projectID.Hex()] = true
+		if err := c.checkProject(ctx, projectID); err != nil {
+			return fmt.Errorf("failed to check project %s: %w", projectID.Hex(), err)
+		}
+	}
+
+	if err := c.checkDeletedProjects(ctx, known); err != nil {
+		return err
+	}
+	return c.checkAbandonedUploads(ctx)
+}
+
+// checkProject compares the file records of a project with the objects
+// under its files prefix
+func (c *storageCheck) checkProject(ctx context.Context, projectID primitive.ObjectID) error {
+	files, err := c.s.fileRepo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	objects, err := c.s.minioClient.ListObjects(ctx, fmt.Sprintf("projects/%s/files/", projectID.Hex()))
+	if err != nil {
+		return err
+	}
+
+	unreferenced := make(map[string]minio.ObjectInfo, len(objects))
+	for _, object := range objects {
+		unreferenced[object.Key] = object
+	}
+	c.report.ProjectsChecked++
+	c.report.ObjectsChecked += len(objects)
+
+	changed := false
+	for _, file := range files {
+		c.report.FilesChecked++
+
+		object, ok := unreferenced[file.StorageKey]
+		delete(unreferenced, file.StorageKey)
+		if !ok {
+			if c.checkMissingObject(ctx, file) {
+				changed = true
+			}
+			continue
+		}
+		if object.LastModified.After(c.cutoff) {
+			continue
+		}
+		if c.checkContent(ctx, file, object) {
+			changed = true
+		}
+	}
+
+	for _, object := range unreferenced {
+		c.orphan(ctx, &projectID, object, "no file record refers to this object")
+	}
+
+	if changed {
+		c.s.updateProjectFileStats(ctx, projectID)
+	}
+
+	return nil
+}
+
+// checkMissingObject reports a file whose object is not in storage and, when
+// repairing, restores it from the stored version with the same content. It
+// reports whether the file was repaired.
+func (c *storageCheck) checkMissingObject(ctx context.Context, file *models.File) bool {
+	// The listing may be stale if the file was moved or deleted meanwhile
+	if exists, err := c.s.minioClient.FileExists(ctx, file.StorageKey); err != nil || exists {
+		return false
+	}
+	if _, err := c.s.fileRepo.FindByID(ctx, file.ID); err != nil {
+		return false
+	}
+
+	issue := fileIssue(models.FsckMissingObject, file, "the file content is not in storage")
+	if !c.report.DryRun {
+		versionKey := versionStorageKey(file.ProjectID, file.Hash)
+		exists, err := c.s.minioClient.FileExists(ctx, versionKey)
+		switch {
+		case err != nil:
+			issue.RepairError = err.Error()
+		case !exists:
+			issue.RepairError = "no stored version has the same content"
+		default:
+			if err := c.s.minioClient.CopyFile(ctx, versionKey, file.StorageKey); err != nil {
+				issue.RepairError = err.Error()
+			} else {
+				issue.Repaired = true
+			}
+		}
+	}
+
+	c.add(issue)
+	return issue.Repaired
+}
+
+// checkContent reports a file whose record does not describe its object
+// and, when repairing, updates the record to match the stored content. It
+// reports whether the file was repaired.
+func (c *storageCheck) checkContent(ctx context.Context, file *models.File, object minio.ObjectInfo) bool {
+	size, hash := object.Size, ""
+	detail := ""
+	if size != file.SizeBytes {
+		detail = fmt.Sprintf("stored size is %d bytes but the record says %d", size, file.SizeBytes)
+	} else if c.report.VerifyHashes {
+		var err error
+		if size, hash, _, err = c.s.inspectObject(ctx, file.StorageKey); err != nil {
+			c.s.logger.Error("Failed to read object", zap.String("key", file.StorageKey), zap.Error(err))
+			return false
+		}
+		if hash != file.Hash {
+			detail = fmt.Sprintf("stored content has hash %s but the record says %s", hash, file.Hash)
+		}
+	}
+	if detail == "" {
+		return false
+	}
+
+	issue := fileIssue(models.FsckHashMismatch, file, detail)
+	if !c.report.DryRun {
+		if err := c.repairContent(ctx, file); err != nil {
+			issue.RepairError = err.Error()
+		} else {
+			issue.Repaired = true
+		}
+	}
+
+	c.add(issue)
+	return issue.Repaired
+}
+
+// repairContent makes a file record match its stored content, which is what
+// users download
+func (c *storageCheck) repairContent(ctx context.Context, file *models.File) error {
+	size, hash, _, err := c.s.inspectObject(ctx, file.StorageKey)
+	if err != nil {
+		return err
+	}
+
+	if err := c.s.fileRepo.SetContent(ctx, file.ID, size, hash); err != nil {
+		return err
+	}
+	file.SizeBytes = size
+	file.Hash = hash
+	c.s.indexStoredFile(ctx, file)
+
+	return nil
+}
+
+// checkDeletedProjects reports the objects left behind by projects that no
+// longer exist
+func (c *storageCheck) checkDeletedProjects(ctx context.Context, known map[string]bool) error {
+	prefixes, err := c.s.minioClient.ListPrefixes(ctx, "projects/")
+	if err != nil {
+		return err
+	}
+
+	for _, prefix := range prefixes {
+		id := strings.TrimSuffix(strings.TrimPrefix(prefix, "projects/"), "/")
+		if known[id] {
+			continue
+		}
+		// The project may have been created after the projects were listed
+		if projectID, err := primitive.ObjectIDFromHex(id); err == nil {
+			if _, err := c.s.projectRepo.FindByID(ctx, projectID); err == nil || err.Error() != "project not found" {
+				continue
+			}
+		}
+
+		if err := c.orphanPrefix(ctx, prefix, "the project does not exist"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAbandonedUploads reports chunks of resumable uploads whose session is
+// gone
+func (c *storageCheck) checkAbandonedUploads(ctx context.Context) error {
+	prefixes, err := c.s.minioClient.ListPrefixes(ctx, "uploads/")
+	if err != nil {
+		return err
+	}
+
+	for _, prefix := range prefixes {
+		id := strings.TrimSuffix(strings.TrimPrefix(prefix, "uploads/"), "/")
+		if uploadID, err := primitive.ObjectIDFromHex(id); err == nil {
+			if _, err := c.s.uploadRepo.FindByID(ctx, uploadID); err == nil || err.Error() != "upload not found" {
+				continue
+			}
+		}
+
+		if err := c.orphanPrefix(ctx, prefix, "the upload does not exist"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// orphanPrefix reports every object under a prefix as orphaned
+func (c *storageCheck) orphanPrefix(ctx context.Context, prefix, detail string) error {
+	objects, err := c.s.minioClient.ListObjects(ctx, prefix)
+	if err != nil {
+		return err
+	}
+
+	c.report.ObjectsChecked += len(objects)
+	for _, object := range objects {
+		c.orphan(ctx, nil, object, detail)
+	}
+
+	return nil
+}
+
+// orphan reports an object that no record refers to and, when repairing,
+// deletes it. Recently written objects are left alone.
+func (c *storageCheck) orphan(ctx context.Context, projectID *primitive.ObjectID, object minio.ObjectInfo, detail string) {
+	if object.LastModified.After(c.cutoff) {
+		return
+	}
+
+	issue := models.FsckIssue{
+		Kind:       models.FsckOrphanedObject,
+		ProjectID:  projectID,
+		StorageKey: object.Key,
+		Detail:     detail,
+	}
+	if !c.report.DryRun {
+		if err := c.s.minioClient.DeleteFile(ctx, object.Key); err != nil {
+			issue.RepairError = err.Error()
+		} else {
+			issue.Repaired = true
+		}
+	}
+
+	c.add(issue)
+}
+
+func (c *storageCheck) add(issue models.FsckIssue) {
+	c.report.Summary[issue.Kind]++
+	if issue.Repaired {
+		c.report.Repaired++
+	}
+
+	if len(c.report.Issues) >= maxFsckIssues {
+		c.report.Truncated = true
+		return
+	}
+	c.report.Issues = append(c.report.Issues, issue)
+}
+
+func fileIssue(kind string, file *models.File, detail string) models.FsckIssue {
+	projectID, fileID := file.ProjectID, file.ID
+	return models.FsckIssue{
+		Kind:       kind,
+		ProjectID:  &projectID,
+		FileID:     &fileID,
+		Path:       file.Path,
+		StorageKey: file.StorageKey,
+		Detail:     detail,
+	}
+}
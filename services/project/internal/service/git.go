@@ -0,0 +1,351 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/format/pktline"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp/capability"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+	"github.com/texflow/services/project/internal/models"
+	"github.com/texflow/services/project/internal/storage"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// Every project is a Git repository with a single branch. The files remain
+// the source of truth: web edits are committed when a Git client next talks
+// to the repository, and a push is applied to the files before the branch
+// moves.
+
+// Git smart HTTP services
+const (
+	GitUploadPack  = "git-upload-pack"
+	GitReceivePack = "git-receive-pack"
+)
+
+const (
+	// MaxGitPushSize limits the packfile accepted by a single push
+	MaxGitPushSize = 200 << 20
+
+	gitBranch    = plumbing.ReferenceName("refs/heads/main")
+	gitAgent     = "texflow"
+	gitLockLease = 10 * time.Minute
+	gitLockWait  = 15 * time.Second
+)
+
+// AuthorizeGit checks that a user may use a Git service on a project:
+// fetching needs read access and pushing needs edit access
+func (s *ProjectService) AuthorizeGit(ctx context.Context, projectID, userID primitive.ObjectID, service string) error {
+	_, err := s.getGitProject(ctx, projectID, userID, service)
+	return err
+}
+
+// AdvertiseGitRefs commits pending web edits and returns the references
+// advertised to a Git client. The caller must have authorized the user.
+func (s *ProjectService) AdvertiseGitRefs(ctx context.Context, projectID, userID primitive.ObjectID, service string) (*packp.AdvRefs, error) {
+	project, err := s.getGitProject(ctx, projectID, userID, service)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := s.lockGitRepo(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.unlockGitRepo(projectID)
+
+	head, err := s.syncGitRepo(ctx, project, repo, s.gitObjects(ctx, projectID))
+	if err != nil {
+		return nil, err
+	}
+
+	refs := packp.NewAdvRefs()
+	if err := refs.Capabilities.Set(capability.Agent, gitAgent); err != nil {
+		return nil, err
+	}
+	if err := refs.Capabilities.Set(capability.OFSDelta); err != nil {
+		return nil, err
+	}
+	if service == GitReceivePack {
+		if err := refs.Capabilities.Set(capability.ReportStatus); err != nil {
+			return nil, err
+		}
+	}
+
+	if !head.IsZero() {
+		if service == GitUploadPack {
+			refs.Head = &head
+			if err := refs.AddReference(plumbing.NewSymbolicReference(plumbing.HEAD, gitBranch)); err != nil {
+				return nil, err
+			}
+		}
+		if err := refs.AddReference(plumbing.NewHashReference(gitBranch, head)); err != nil {
+			return nil, err
+		}
+	}
+
+	return refs, nil
+}
+
+// WriteGitAdvertisement writes advertised references in the smart HTTP format
+func WriteGitAdvertisement(w io.Writer, service string, refs *packp.AdvRefs) error {
+	e := pktline.NewEncoder(w)
+	if err := e.Encodef("# service=%s\n", service); err != nil {
+		return err
+	}
+	if err := e.Flush(); err != nil {
+		return err
+	}
+	return refs.Encode(w)
+}
+
+// UploadPack answers a fetch request by writing the objects the client is
+// missing. The caller must have authorized the user.
+//
+// Neither multi_ack nor side-band is offered: while the client is still
+// negotiating it is answered with NAK, and the pack is computed from the
+// haves of its final round.
+func (s *ProjectService) UploadPack(ctx context.Context, projectID primitive.ObjectID, body io.Reader, w io.Writer) error {
+	req, err := decodeUploadRequest(body)
+	if err != nil {
+		return writeGitError(w, "invalid upload-pack request")
+	}
+	if req.shallow {
+		return writeGitError(w, "shallow clones are not supported")
+	}
+
+	e := pktline.NewEncoder(w)
+	if !req.done {
+		return e.Encodef("NAK\n")
+	}
+
+	st := s.gitObjects(ctx, projectID)
+	for _, want := range req.wants {
+		if err := st.HasEncodedObject(want); err != nil {
+			return writeGitError(w, fmt.Sprintf("upload-pack: not our ref %s", want))
+		}
+	}
+
+	// Clients may have commits the server has never seen
+	var haves []plumbing.Hash
+	for _, have := range req.haves {
+		if st.HasEncodedObject(have) == nil {
+			haves = append(haves, have)
+		}
+	}
+
+	common, err := revlist.Objects(st, haves, nil)
+	if err != nil {
+		return err
+	}
+	objects, err := revlist.Objects(st, req.wants, common)
+	if err != nil {
+		return err
+	}
+
+	if err := e.Encodef("NAK\n"); err != nil {
+		return err
+	}
+	_, err = packfile.NewEncoder(w, st, false).Encode(objects, 10)
+	return err
+}
+
+// ReceivePack applies a push and writes the report for the client. The
+// caller must have authorized the user.
+func (s *ProjectService) ReceivePack(ctx context.Context, projectID, userID primitive.ObjectID, body io.Reader, w io.Writer) error {
+	req := packp.NewReferenceUpdateRequest()
+	if err := req.Decode(body); err != nil {
+		return writeGitError(w, "invalid receive-pack request")
+	}
+
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	repo, err := s.lockGitRepo(ctx, projectID)
+	if err != nil {
+		return writeGitError(w, err.Error())
+	}
+	defer s.unlockGitRepo(projectID)
+
+	st := s.gitObjects(ctx, projectID)
+	if _, err := s.syncGitRepo(ctx, project, repo, st); err != nil {
+		return err
+	}
+
+	report := packp.NewReportStatus()
+	report.UnpackStatus = "ok"
+
+	if hasGitUpdates(req) {
+		parser, err := packfile.NewParserWithStorage(packfile.NewScanner(req.Packfile), st)
+		if err == nil {
+			_, err = parser.Parse()
+		}
+		if err != nil {
+			s.logger.Warn("Failed to unpack pushed objects", zap.String("project_id", projectID.Hex()), zap.Error(err))
+			report.UnpackStatus = "unpack failed"
+		}
+	}
+
+	for _, cmd := range req.Commands {
+		status := "ok"
+		if report.UnpackStatus != "ok" {
+			status = "unpacker error"
+		} else if err := s.applyGitPush(ctx, project, repo, st, userID, cmd); err != nil {
+			status = err.Error()
+		}
+		report.CommandStatuses = append(report.CommandStatuses, &packp.CommandStatus{
+			ReferenceName: cmd.Name,
+			Status:        status,
+		})
+	}
+
+	if !req.Capabilities.Supports(capability.ReportStatus) {
+		return nil
+	}
+	return report.Encode(w)
+}
+
+func (s *ProjectService) getGitProject(ctx context.Context, projectID, userID primitive.ObjectID, service string) (*models.Project, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch service {
+	case GitUploadPack:
+		if !s.userHasAccess(ctx, project, userID) {
+			return nil, fmt.Errorf("access denied")
+		}
+	case GitReceivePack:
+		if !s.userCanEdit(ctx, project, userID) {
+			return nil, fmt.Errorf("permission denied")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported git service")
+	}
+
+	return project, nil
+}
+
+// gitObjects returns the object store of a project for one request
+func (s *ProjectService) gitObjects(ctx context.Context, projectID primitive.ObjectID) *storage.GitObjectStore {
+	return storage.NewGitObjectStore(ctx, s.minioClient, projectID.Hex())
+}
+
+// lockGitRepo waits for the lease on a project's Git state, so that syncs
+// and pushes of the same project never interleave
+func (s *ProjectService) lockGitRepo(ctx context.Context, projectID primitive.ObjectID) (*models.GitRepo, error) {
+	deadline := time.Now().Add(gitLockWait)
+	for {
+		repo, err := s.gitRepo.Lock(ctx, projectID, gitLockLease)
+		if err == nil || err.Error() != "repository is busy" || time.Now().After(deadline) {
+			return repo, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+func (s *ProjectService) unlockGitRepo(projectID primitive.ObjectID) {
+	// The request context may already be cancelled
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.gitRepo.Unlock(ctx, projectID); err != nil {
+		s.logger.Error("Failed to unlock git repository", zap.String("project_id", projectID.Hex()), zap.Error(err))
+	}
+}
+
+// recordGitChanges remembers who changed paths in the web editor so that the
+// next commit is attributed to them
+func (s *ProjectService) recordGitChanges(ctx context.Context, projectID, userID primitive.ObjectID, paths ...string) {
+	if err := s.gitRepo.RecordChanges(ctx, projectID, userID, paths...); err != nil {
+		s.logger.Error("Failed to record git changes", zap.String("project_id", projectID.Hex()), zap.Error(err))
+	}
+}
+
+// uploadRequest is the part of an upload-pack request this server acts on
+type uploadRequest struct {
+	wants   []plumbing.Hash
+	haves   []plumbing.Hash
+	done    bool
+	shallow bool
+}
+
+// decodeUploadRequest reads the want, have and done lines of a request
+func decodeUploadRequest(r io.Reader) (*uploadRequest, error) {
+	req := &uploadRequest{}
+	scanner := pktline.NewScanner(r)
+
+	for scanner.Scan() {
+		line := bytes.TrimSuffix(scanner.Bytes(), []byte("\n"))
+		switch {
+		case len(line) == 0:
+			// Flush packet between sections
+		case bytes.HasPrefix(line, []byte("want ")):
+			hash, err := parseGitHash(line[5:])
+			if err != nil {
+				return nil, err
+			}
+			req.wants = append(req.wants, hash)
+		case bytes.HasPrefix(line, []byte("have ")):
+			hash, err := parseGitHash(line[5:])
+			if err != nil {
+				return nil, err
+			}
+			req.haves = append(req.haves, hash)
+		case bytes.Equal(line, []byte("done")):
+			req.done = true
+		case bytes.HasPrefix(line, []byte("shallow ")), bytes.HasPrefix(line, []byte("deepen")):
+			req.shallow = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(req.wants) == 0 {
+		return nil, fmt.Errorf("no wants")
+	}
+
+	return req, nil
+}
+
+// parseGitHash parses the object ID at the start of a line; capabilities may
+// follow it on the first want line
+func parseGitHash(b []byte) (plumbing.Hash, error) {
+	if len(b) < 40 || !plumbing.IsHash(string(b[:40])) {
+		return plumbing.ZeroHash, fmt.Errorf("invalid object id")
+	}
+	return plumbing.NewHash(string(b[:40])), nil
+}
+
+// hasGitUpdates reports whether a push carries a packfile, which is the case
+// unless every command deletes a reference
+func hasGitUpdates(req *packp.ReferenceUpdateRequest) bool {
+	for _, cmd := range req.Commands {
+		if cmd.Action() != packp.Delete {
+			return true
+		}
+	}
+	return false
+}
+
+// writeGitError sends an error message that Git clients print as
+// "remote error"
+func writeGitError(w io.Writer, message string) error {
+	return pktline.NewEncoder(w).Encodef("ERR %s\n", message)
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/texflow/services/project/internal/repository"
+	"github.com/texflow/services/project/pkg/auth"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// personalTokenPrefix marks personal access tokens issued by the auth service
+const personalTokenPrefix = "tfp_"
+
+// GitAuthenticator resolves the user of a Git request. Git clients cannot
+// go through the browser login, so they present either a personal access
+// token or a JWT access token as their password.
+type GitAuthenticator struct {
+	jwtManager *auth.JWTManager
+	userRepo   *repository.UserRepository
+	logger     *zap.Logger
+}
+
+// NewGitAuthenticator creates a new Git authenticator
+func NewGitAuthenticator(jwtManager *auth.JWTManager, userRepo *repository.UserRepository, logger *zap.Logger) *GitAuthenticator {
+	return &GitAuthenticator{
+		jwtManager: jwtManager,
+		userRepo:   userRepo,
+		logger:     logger,
+	}
+}
+
+// Authenticate returns the user a personal access token or JWT belongs to
+func (a *GitAuthenticator) Authenticate(ctx context.Context, secret string) (primitive.ObjectID, error) {
+	if strings.HasPrefix(secret, personalTokenPrefix) {
+		sum := sha256.Sum256([]byte(secret))
+		token, err := a.userRepo.FindTokenByHash(ctx, hex.EncodeToString(sum[:]))
+		if err != nil {
+			if err.Error() != "token not found" {
+				a.logger.Error("Failed to look up access token", zap.Error(err))
+			}
+			return primitive.NilObjectID, fmt.Errorf("invalid credentials")
+		}
+		if token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt) {
+			return primitive.NilObjectID, fmt.Errorf("invalid credentials")
+		}
+		return token.UserID, nil
+	}
+
+	claims, err := a.jwtManager.ValidateToken(secret)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid credentials")
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.UserID)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid credentials")
+	}
+
+	return userID, nil
+}
@@ -0,0 +1,399 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// gitPushPlan lists the file operations that turn the indexed tree into a
+// pushed one
+type gitPushPlan struct {
+	renames map[string]string // Old path to new path, for unchanged content
+	deletes []string
+	creates []string
+	updates []string
+	tree    map[string]plumbing.Hash
+}
+
+// applyGitPush validates a reference update, applies the pushed tree to the
+// project files and moves the branch. Only fast-forward updates of the main
+// branch are accepted, so web edits committed by the sync are never lost.
+func (s *ProjectService) applyGitPush(
+	ctx context.Context,
+	project *models.Project,
+	repo *models.GitRepo,
+	st storer.EncodedObjectStorer,
+	userID primitive.ObjectID,
+	cmd *packp.Command,
+) error {
+	// A push replaces files wholesale, which cannot be recorded as
+	// suggestions
+	if err := checkUntrackedWrite(project, userID); err != nil {
+		return err
+	}
+	if cmd.Name != gitBranch {
+		return fmt.Errorf("only the %s branch can be pushed", gitBranch.Short())
+	}
+	if cmd.Action() == packp.Delete {
+		return fmt.Errorf("the %s branch cannot be deleted", gitBranch.Short())
+	}
+
+	head := plumbing.NewHash(repo.Head)
+	if cmd.Old != head {
+		return fmt.Errorf("fetch first")
+	}
+
+	commit, err := object.GetCommit(st, cmd.New)
+	if err != nil {
+		return fmt.Errorf("missing commit %s", cmd.New)
+	}
+	if !head.IsZero() {
+		headCommit, err := object.GetCommit(st, head)
+		if err != nil {
+			return fmt.Errorf("failed to read head commit")
+		}
+		ancestor, err := headCommit.IsAncestor(commit)
+		if err != nil || !ancestor {
+			return fmt.Errorf("non-fast-forward")
+		}
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("missing tree of %s", cmd.New)
+	}
+
+	index := make(map[string]models.GitIndexEntry, len(repo.Index))
+	for _, entry := range repo.Index {
+		index[entry.Path] = entry
+	}
+
+	plan, err := planGitPush(project, st, index, tree)
+	if err != nil {
+		return err
+	}
+
+	release, err := s.reserveGitPush(ctx, project, st, plan)
+	if err != nil {
+		return err
+	}
+
+	newIndex, err := s.applyGitPushPlan(ctx, project, st, userID, index, plan)
+	if err != nil {
+		release()
+		s.logger.Error("Failed to apply push",
+			zap.String("project_id", project.ID.Hex()),
+			zap.String("commit", cmd.New.String()),
+			zap.Error(err),
+		)
+		return fmt.Errorf("failed to apply changes")
+	}
+
+	if err := s.saveGitHead(ctx, repo, cmd.New, newIndex); err != nil {
+		return fmt.Errorf("failed to update branch")
+	}
+
+	s.logger.Info("Push applied",
+		zap.String("project_id", project.ID.Hex()),
+		zap.String("user_id", userID.Hex()),
+		zap.String("commit", cmd.New.String()),
+		zap.Int("created", len(plan.creates)),
+		zap.Int("updated", len(plan.updates)),
+		zap.Int("renamed", len(plan.renames)),
+		zap.Int("deleted", len(plan.deletes)),
+	)
+
+	return nil
+}
+
+// planGitPush diffs the pushed tree against the index. A deleted path whose
+// content reappears elsewhere becomes a rename so the file keeps its record.
+func planGitPush(
+	project *models.Project,
+	st storer.EncodedObjectStorer,
+	index map[string]models.GitIndexEntry,
+	tree *object.Tree,
+) (*gitPushPlan, error) {
+	plan := &gitPushPlan{
+		renames: map[string]string{},
+		tree:    map[string]plumbing.Hash{},
+	}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+	for {
+		name, entry, err := walker.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid tree")
+		}
+
+		switch entry.Mode {
+		case filemode.Dir:
+			continue
+		case filemode.Regular, filemode.Executable, filemode.Deprecated:
+		default:
+			return nil, fmt.Errorf("unsupported file type at %s", name)
+		}
+
+		if cleanPath, err := cleanFilePath(name); err != nil || cleanPath != name {
+			return nil, fmt.Errorf("invalid file path %s", name)
+		}
+		plan.tree[name] = entry.Hash
+	}
+
+	removedByBlob := map[plumbing.Hash][]string{}
+	var removed []string
+	for p := range index {
+		if _, ok := plan.tree[p]; !ok {
+			removed = append(removed, p)
+		}
+	}
+	sort.Strings(removed)
+	for _, p := range removed {
+		blob := plumbing.NewHash(index[p].Blob)
+		removedByBlob[blob] = append(removedByBlob[blob], p)
+	}
+
+	var added []string
+	for p, blob := range plan.tree {
+		entry, ok := index[p]
+		switch {
+		case !ok:
+			added = append(added, p)
+		case entry.Blob != blob.String():
+			plan.updates = append(plan.updates, p)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(plan.updates)
+
+	for _, p := range added {
+		blob := plan.tree[p]
+		if sources := removedByBlob[blob]; len(sources) > 0 {
+			plan.renames[sources[0]] = p
+			removedByBlob[blob] = sources[1:]
+			continue
+		}
+		plan.creates = append(plan.creates, p)
+	}
+	for _, p := range removed {
+		if _, ok := plan.renames[p]; !ok {
+			plan.deletes = append(plan.deletes, p)
+		}
+	}
+
+	for _, p := range plan.deletes {
+		if isMainFile(project, p) {
+			return nil, fmt.Errorf("cannot delete the main file")
+		}
+	}
+
+	// Reject oversized files before anything is applied
+	for _, p := range append(plan.creates, plan.updates...) {
+		size, err := st.EncodedObjectSize(plan.tree[p])
+		if err != nil {
+			return nil, fmt.Errorf("missing object for %s", p)
+		}
+		if size > maxImportFileSize {
+			return nil, fmt.Errorf("file too large: %s", p)
+		}
+	}
+
+	return plan, nil
+}
+
+// reserveGitPush checks a push against the quotas of the project owner and
+// reserves the storage and files it adds, net of what it deletes, as web
+// writes do. The returned function gives the reservation back.
+func (s *ProjectService) reserveGitPush(ctx context.Context, project *models.Project, st storer.EncodedObjectStorer, plan *gitPushPlan) (func(), error) {
+	var largest, storageBytes int64
+	fileCount := int64(len(plan.creates) - len(plan.deletes))
+
+	for _, p := range append(plan.creates, plan.updates...) {
+		size, err := st.EncodedObjectSize(plan.tree[p])
+		if err != nil {
+			return nil, fmt.Errorf("missing object for %s", p)
+		}
+		largest = max(largest, size)
+		storageBytes += size
+	}
+
+	for _, p := range append(plan.updates, plan.deletes...) {
+		file, err := s.fileRepo.FindByPath(ctx, project.ID, p)
+		if err != nil {
+			if err.Error() != "file not found" {
+				return nil, err
+			}
+			// An update of a file missing from the project creates it
+			if slices.Contains(plan.updates, p) {
+				fileCount++
+			}
+			continue
+		}
+		storageBytes -= file.SizeBytes
+	}
+
+	return s.reserveQuota(ctx, project.OwnerID, largest, storageBytes, fileCount)
+}
+
+// applyGitPushPlan performs the file operations of a push and returns the
+// index of the pushed tree
+func (s *ProjectService) applyGitPushPlan(
+	ctx context.Context,
+	project *models.Project,
+	st storer.EncodedObjectStorer,
+	userID primitive.ObjectID,
+	index map[string]models.GitIndexEntry,
+	plan *gitPushPlan,
+) (map[string]models.GitIndexEntry, error) {
+	newIndex := make(map[string]models.GitIndexEntry, len(plan.tree))
+	for p, blob := range plan.tree {
+		if entry, ok := index[p]; ok && entry.Blob == blob.String() {
+			newIndex[p] = entry
+		}
+	}
+
+	oldPaths := make([]string, 0, len(plan.renames))
+	for oldPath := range plan.renames {
+		oldPaths = append(oldPaths, oldPath)
+	}
+	sort.Strings(oldPaths)
+
+	for _, oldPath := range oldPaths {
+		newPath := plan.renames[oldPath]
+		file, err := s.fileRepo.FindByPath(ctx, project.ID, oldPath)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.ensureFolders(ctx, project.ID, userID, parentPath(newPath)); err != nil {
+			return nil, err
+		}
+		if err := s.relocateFile(ctx, project, file, newPath); err != nil {
+			return nil, err
+		}
+		newIndex[newPath] = models.GitIndexEntry{Path: newPath, Blob: index[oldPath].Blob, Hash: index[oldPath].Hash}
+	}
+
+	for _, p := range plan.deletes {
+		file, err := s.fileRepo.FindByPath(ctx, project.ID, p)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.fileRepo.Delete(ctx, file.ID); err != nil {
+			return nil, err
+		}
+		if err := s.minioClient.DeleteFile(ctx, file.StorageKey); err != nil {
+			s.logger.Error("Failed to delete file object", zap.String("key", file.StorageKey), zap.Error(err))
+		}
+		s.deleteFileHistory(ctx, file.ID)
+		s.unindexFile(ctx, file.ID)
+	}
+
+	if err := s.pruneGitFolders(ctx, project.ID, plan); err != nil {
+		return nil, err
+	}
+
+	for _, p := range append(plan.creates, plan.updates...) {
+		content, err := readGitBlob(st, plan.tree[p])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p, err)
+		}
+
+		if file, err := s.fileRepo.FindByPath(ctx, project.ID, p); err == nil {
+			err = s.writeFileContent(ctx, file, content)
+			if err != nil {
+				return nil, err
+			}
+			s.recordFileVersion(ctx, file, userID, content, 0)
+		} else {
+			name := path.Base(p)
+			if _, err := s.storeFile(ctx, project.ID, userID, p, name, content); err != nil {
+				return nil, err
+			}
+		}
+
+		newIndex[p] = models.GitIndexEntry{
+			Path: p,
+			Blob: plan.tree[p].String(),
+			Hash: fmt.Sprintf("%x", sha256.Sum256(content)),
+		}
+	}
+
+	s.updateProjectFileStats(ctx, project.ID)
+
+	return newIndex, nil
+}
+
+// pruneGitFolders deletes the folders left without files by a push. Git has
+// no empty directories, so removing the last file removes the folder.
+func (s *ProjectService) pruneGitFolders(ctx context.Context, projectID primitive.ObjectID, plan *gitPushPlan) error {
+	vacated := append([]string{}, plan.deletes...)
+	for oldPath := range plan.renames {
+		vacated = append(vacated, oldPath)
+	}
+
+	pruned := map[string]bool{}
+	for _, p := range vacated {
+		for dir := parentPath(p); dir != "" && !pruned[dir]; dir = parentPath(dir) {
+			if gitTreeHasPrefix(plan.tree, dir+"/") {
+				break
+			}
+			pruned[dir] = true
+		}
+	}
+
+	for dir := range pruned {
+		// Only the outermost folder needs deleting, the tree goes with it
+		if parent := parentPath(dir); parent != "" && pruned[parent] {
+			continue
+		}
+		if err := s.folderRepo.DeleteTree(ctx, projectID, dir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func gitTreeHasPrefix(tree map[string]plumbing.Hash, prefix string) bool {
+	for p := range tree {
+		if strings.HasPrefix(p, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readGitBlob reads the content of a pushed blob
+func readGitBlob(st storer.EncodedObjectStorer, hash plumbing.Hash) ([]byte, error) {
+	blob, err := object.GetBlob(st, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/protocol/packp"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestApplyGitPushTrackChanges(t *testing.T) {
+	owner := primitive.NewObjectID()
+	collaborator := primitive.NewObjectID()
+
+	tests := []struct {
+		name         string
+		trackChanges bool
+		userID       primitive.ObjectID
+		want         string
+	}{
+		{"collaborator while tracking", true, collaborator, "changes are tracked; only the project owner can make this change"},
+		{"owner while tracking", true, owner, "fetch first"},
+		{"collaborator without tracking", false, collaborator, "fetch first"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := &models.Project{
+				ID:       primitive.NewObjectID(),
+				OwnerID:  owner,
+				Settings: models.ProjectSettings{TrackChanges: tt.trackChanges},
+			}
+			// A stale old hash stops pushes that get past the tracking check
+			// before any storage is touched
+			cmd := &packp.Command{
+				Name: gitBranch,
+				Old:  plumbing.NewHash("1111111111111111111111111111111111111111"),
+				New:  plumbing.NewHash("2222222222222222222222222222222222222222"),
+			}
+
+			s := &ProjectService{}
+			err := s.applyGitPush(context.Background(), project, &models.GitRepo{}, nil, tt.userID, cmd)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("applyGitPush() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
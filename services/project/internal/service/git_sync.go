@@ -0,0 +1,345 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// gitChange is a changed path with the user it is attributed to
+type gitChange struct {
+	path   string
+	userID primitive.ObjectID
+	at     time.Time
+}
+
+// syncGitRepo commits the files that differ from the Git index and returns
+// the new head. Changes are committed in the order they were made, one
+// commit per run of consecutive changes by the same user. Changes nobody
+// recorded, such as files that predate Git support, are attributed to the
+// project owner.
+func (s *ProjectService) syncGitRepo(ctx context.Context, project *models.Project, repo *models.GitRepo, st storer.EncodedObjectStorer) (plumbing.Hash, error) {
+	started := time.Now()
+	head := plumbing.NewHash(repo.Head)
+
+	files, err := s.fileRepo.FindByProjectID(ctx, project.ID)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	index := make(map[string]models.GitIndexEntry, len(repo.Index))
+	for _, entry := range repo.Index {
+		index[entry.Path] = entry
+	}
+
+	current := make(map[string]*models.File, len(files))
+	var changed []string
+	for _, file := range files {
+		current[file.Path] = file
+		if entry, ok := index[file.Path]; !ok || entry.Hash != file.Hash {
+			changed = append(changed, file.Path)
+		}
+	}
+	for p := range index {
+		if _, ok := current[p]; !ok {
+			changed = append(changed, p)
+		}
+	}
+
+	if len(changed) == 0 {
+		if err := s.gitRepo.DeletePendingChanges(ctx, project.ID, started); err != nil {
+			s.logger.Error("Failed to clear pending git changes", zap.Error(err))
+		}
+		return head, nil
+	}
+
+	pending, err := s.gitRepo.FindPendingChanges(ctx, project.ID)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	changes := attributeGitChanges(changed, pending, project.OwnerID, current, started)
+
+	var lastTree plumbing.Hash
+	if !head.IsZero() {
+		commit, err := object.GetCommit(st, head)
+		if err != nil {
+			return plumbing.ZeroHash, fmt.Errorf("failed to read head commit: %w", err)
+		}
+		lastTree = commit.TreeHash
+	}
+
+	authors := map[primitive.ObjectID]object.Signature{}
+	commits := 0
+
+	for start := 0; start < len(changes); {
+		end := start + 1
+		for end < len(changes) && changes[end].userID == changes[start].userID {
+			end++
+		}
+		run := changes[start:end]
+		start = end
+
+		var lines []string
+		for _, change := range run {
+			file, exists := current[change.path]
+			_, tracked := index[change.path]
+			switch {
+			case !exists:
+				delete(index, change.path)
+				lines = append(lines, "Delete "+change.path)
+			default:
+				entry, err := s.writeGitBlob(ctx, st, file)
+				if err != nil {
+					return plumbing.ZeroHash, err
+				}
+				index[change.path] = entry
+				if tracked {
+					lines = append(lines, "Update "+change.path)
+				} else {
+					lines = append(lines, "Add "+change.path)
+				}
+			}
+		}
+
+		tree, err := writeGitTree(st, index)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if tree == lastTree {
+			continue // e.g. an edit that was undone
+		}
+
+		author, ok := authors[run[0].userID]
+		if !ok {
+			author = s.gitAuthor(ctx, run[0].userID)
+			authors[run[0].userID] = author
+		}
+		author.When = run[len(run)-1].at
+
+		commit := &object.Commit{
+			Author:    author,
+			Committer: author,
+			Message:   gitCommitMessage(lines),
+			TreeHash:  tree,
+		}
+		if !head.IsZero() {
+			commit.ParentHashes = []plumbing.Hash{head}
+		}
+
+		obj := st.NewEncodedObject()
+		if err := commit.Encode(obj); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if head, err = st.SetEncodedObject(obj); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		lastTree = tree
+		commits++
+	}
+
+	if err := s.saveGitHead(ctx, repo, head, index); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := s.gitRepo.DeletePendingChanges(ctx, project.ID, started); err != nil {
+		s.logger.Error("Failed to clear pending git changes", zap.Error(err))
+	}
+
+	s.logger.Info("Committed web edits",
+		zap.String("project_id", project.ID.Hex()),
+		zap.Int("paths", len(changes)),
+		zap.Int("commits", commits),
+	)
+
+	return head, nil
+}
+
+// attributeGitChanges assigns each changed path to the user of the latest
+// pending change covering it and sorts the result chronologically
+func attributeGitChanges(
+	paths []string,
+	pending []*models.GitPendingChange,
+	ownerID primitive.ObjectID,
+	current map[string]*models.File,
+	now time.Time,
+) []gitChange {
+	changes := make([]gitChange, 0, len(paths))
+	for _, p := range paths {
+		change := gitChange{path: p, userID: ownerID, at: now}
+		if file, ok := current[p]; ok {
+			change.at = file.UpdatedAt
+		}
+
+		// Pending changes are sorted oldest first, so the last match wins
+		for _, pc := range pending {
+			if pc.Path == p || (strings.HasSuffix(pc.Path, "/") && strings.HasPrefix(p, pc.Path)) {
+				change.userID = pc.UserID
+				change.at = pc.ChangedAt
+			}
+		}
+
+		changes = append(changes, change)
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if !changes[i].at.Equal(changes[j].at) {
+			return changes[i].at.Before(changes[j].at)
+		}
+		return changes[i].path < changes[j].path
+	})
+
+	return changes
+}
+
+// writeGitBlob stores the content of a file as a blob
+func (s *ProjectService) writeGitBlob(ctx context.Context, st storer.EncodedObjectStorer, file *models.File) (models.GitIndexEntry, error) {
+	content, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+	if err != nil {
+		return models.GitIndexEntry{}, fmt.Errorf("failed to read %s: %w", file.Path, err)
+	}
+
+	blob, err := writeGitBlobContent(st, content)
+	if err != nil {
+		return models.GitIndexEntry{}, err
+	}
+
+	// Hash what was read rather than the record, which may have moved on
+	return models.GitIndexEntry{
+		Path: file.Path,
+		Blob: blob.String(),
+		Hash: fmt.Sprintf("%x", sha256.Sum256(content)),
+	}, nil
+}
+
+func writeGitBlobContent(st storer.EncodedObjectStorer, content []byte) (plumbing.Hash, error) {
+	obj := st.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	w, err := obj.Writer()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := w.Write(content); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return st.SetEncodedObject(obj)
+}
+
+// gitTreeNode is a directory while a tree is built from the flat index
+type gitTreeNode struct {
+	blobs map[string]plumbing.Hash
+	dirs  map[string]*gitTreeNode
+}
+
+func newGitTreeNode() *gitTreeNode {
+	return &gitTreeNode{
+		blobs: map[string]plumbing.Hash{},
+		dirs:  map[string]*gitTreeNode{},
+	}
+}
+
+// writeGitTree stores the trees for an index and returns the root tree
+func writeGitTree(st storer.EncodedObjectStorer, index map[string]models.GitIndexEntry) (plumbing.Hash, error) {
+	root := newGitTreeNode()
+	for p, entry := range index {
+		node := root
+		parts := strings.Split(p, "/")
+		for _, dir := range parts[:len(parts)-1] {
+			child, ok := node.dirs[dir]
+			if !ok {
+				child = newGitTreeNode()
+				node.dirs[dir] = child
+			}
+			node = child
+		}
+		node.blobs[parts[len(parts)-1]] = plumbing.NewHash(entry.Blob)
+	}
+
+	return root.write(st)
+}
+
+func (n *gitTreeNode) write(st storer.EncodedObjectStorer) (plumbing.Hash, error) {
+	entries := make([]object.TreeEntry, 0, len(n.blobs)+len(n.dirs))
+	for name, hash := range n.blobs {
+		entries = append(entries, object.TreeEntry{Name: name, Mode: filemode.Regular, Hash: hash})
+	}
+	for name, child := range n.dirs {
+		hash, err := child.write(st)
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		entries = append(entries, object.TreeEntry{Name: name, Mode: filemode.Dir, Hash: hash})
+	}
+	sort.Sort(object.TreeEntrySorter(entries))
+
+	obj := st.NewEncodedObject()
+	if err := (&object.Tree{Entries: entries}).Encode(obj); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return st.SetEncodedObject(obj)
+}
+
+// saveGitHead persists the branch head with its index
+func (s *ProjectService) saveGitHead(ctx context.Context, repo *models.GitRepo, head plumbing.Hash, index map[string]models.GitIndexEntry) error {
+	entries := make([]models.GitIndexEntry, 0, len(index))
+	for _, entry := range index {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+
+	repo.Head = ""
+	if !head.IsZero() {
+		repo.Head = head.String()
+	}
+	repo.Index = entries
+
+	return s.gitRepo.UpdateHead(ctx, repo.ProjectID, repo.Head, repo.Index)
+}
+
+// gitAuthor builds a commit signature from a user record
+func (s *ProjectService) gitAuthor(ctx context.Context, userID primitive.ObjectID) object.Signature {
+	signature := object.Signature{
+		Name:  "TexFlow user",
+		Email: userID.Hex() + "@users.noreply.texflow",
+	}
+
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err.Error() != "user not found" {
+			s.logger.Error("Failed to look up commit author", zap.String("user_id", userID.Hex()), zap.Error(err))
+		}
+		return signature
+	}
+
+	switch {
+	case user.FullName != "":
+		signature.Name = user.FullName
+	case user.Username != "":
+		signature.Name = user.Username
+	}
+	if user.Email != "" {
+		signature.Email = user.Email
+	}
+
+	return signature
+}
+
+// gitCommitMessage summarises the changes of a web edit commit
+func gitCommitMessage(lines []string) string {
+	if len(lines) == 1 {
+		return lines[0] + "\n"
+	}
+	return fmt.Sprintf("Update %d files\n\n%s\n", len(lines), strings.Join(lines, "\n"))
+}
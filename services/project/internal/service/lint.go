@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/texflow/services/project/internal/events"
+	"github.com/texflow/services/project/internal/lint"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// GetFileLint returns the lint warnings of a .tex file. Stored results that
+// predate the file's content or the project's rules are redone.
+func (s *ProjectService) GetFileLint(ctx context.Context, projectID, fileID, userID primitive.ObjectID) (*models.LintResult, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	file, err := s.getProjectFile(ctx, projectID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if !isLintableFile(file) {
+		return nil, fmt.Errorf("only .tex files are linted")
+	}
+
+	stored, err := s.lintRepo.FindByFileID(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.currentLint(ctx, project, file, stored)
+}
+
+// ListProjectLint returns the lint warnings of every .tex file of a
+// project, in path order
+func (s *ProjectService) ListProjectLint(ctx context.Context, projectID, userID primitive.ObjectID) ([]*models.LintResult, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	files, err := s.fileRepo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	stored, err := s.lintRepo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	byFile := make(map[primitive.ObjectID]*models.LintResult, len(stored))
+	for _, result := range stored {
+		byFile[result.FileID] = result
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	results := []*models.LintResult{}
+	for _, file := range files {
+		if !isLintableFile(file) {
+			continue
+		}
+		result, err := s.currentLint(ctx, project, file, byFile[file.ID])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// ListLintRules lists the lint rules with the severity the project gives
+// them
+func (s *ProjectService) ListLintRules(ctx context.Context, projectID, userID primitive.ObjectID) ([]*models.LintRule, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	settings := project.Settings.Lint
+	rules := lint.Rules()
+	list := make([]*models.LintRule, 0, len(rules))
+	for _, rule := range rules {
+		item := &models.LintRule{Rule: rule, DefaultSeverity: rule.Severity}
+		switch {
+		case settings != nil && settings.Disabled:
+			item.Severity = lint.SeverityOff
+		case settings != nil && settings.Rules[rule.ID] != "":
+			item.Severity = settings.Rules[rule.ID]
+		}
+		list = append(list, item)
+	}
+
+	return list, nil
+}
+
+// currentLint returns a stored lint result if it matches the file's content
+// and the project's rules, linting the file from storage otherwise
+func (s *ProjectService) currentLint(ctx context.Context, project *models.Project, file *models.File, stored *models.LintResult) (*models.LintResult, error) {
+	settings := project.Settings.Lint
+	if settings != nil && settings.Disabled {
+		return &models.LintResult{
+			FileID:    file.ID,
+			ProjectID: file.ProjectID,
+			Path:      file.Path,
+			Hash:      file.Hash,
+			Warnings:  []lint.Warning{},
+		}, nil
+	}
+
+	cfg := lintConfig(settings)
+	if stored != nil && stored.Hash == file.Hash && stored.Config == cfg.Fingerprint() {
+		stored.Path = file.Path
+		return stored, nil
+	}
+
+	content, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	return s.storeLint(ctx, file, content, cfg), nil
+}
+
+// lintFile lints a saved .tex file with the rules of its project and sends
+// the warnings to the project's clients. Failures are logged, as they must
+// not fail the save.
+func (s *ProjectService) lintFile(ctx context.Context, file *models.File, content []byte) {
+	if !isLintableFile(file) {
+		return
+	}
+
+	project, err := s.projectRepo.FindByID(ctx, file.ProjectID)
+	if err != nil {
+		s.logger.Error("Failed to lint file", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+		return
+	}
+
+	settings := project.Settings.Lint
+	if settings != nil && settings.Disabled {
+		if err := s.lintRepo.Delete(ctx, file.ID); err != nil {
+			s.logger.Error("Failed to remove lint results", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+		}
+		return
+	}
+
+	result := s.storeLint(ctx, file, content, lintConfig(settings))
+	s.events.Publish(ctx, file.ProjectID.Hex(), events.LintResults, result, "", "")
+}
+
+// storeLint lints the content of a file and stores the result. The result
+// is returned even if it could not be stored.
+func (s *ProjectService) storeLint(ctx context.Context, file *models.File, content []byte, cfg lint.Config) *models.LintResult {
+	result := &models.LintResult{
+		FileID:    file.ID,
+		ProjectID: file.ProjectID,
+		Hash:      file.Hash,
+		Config:    cfg.Fingerprint(),
+		Warnings:  lint.Lint(string(content), cfg),
+	}
+	if err := s.lintRepo.Upsert(ctx, result); err != nil {
+		s.logger.Error("Failed to store lint results", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+	}
+	result.Path = file.Path
+
+	return result
+}
+
+// lintConfig returns the linter configuration of a project's settings
+func lintConfig(settings *models.LintSettings) lint.Config {
+	if settings == nil {
+		return lint.Config{}
+	}
+	return lint.Config{Severities: settings.Rules}
+}
+
+func isLintableFile(file *models.File) bool {
+	return !file.IsBinary && file.SizeBytes <= MaxIndexedFileSize && lint.IsLintable(file.Path)
+}
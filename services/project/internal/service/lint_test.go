@@ -0,0 +1,49 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/texflow/services/project/internal/lint"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCurrentLintSettings(t *testing.T) {
+	stored := []lint.Warning{{Rule: "ellipsis", Severity: lint.SeverityInfo, Line: 1, Column: 1, Length: 3}}
+	raised := map[string]string{"ellipsis": lint.SeverityError}
+
+	tests := []struct {
+		name     string
+		settings *models.LintSettings
+		config   string
+		want     int
+	}{
+		{"disabled", &models.LintSettings{Disabled: true}, lint.Config{}.Fingerprint(), 0},
+		{"disabled with rules", &models.LintSettings{Disabled: true, Rules: raised}, lint.Config{Severities: raised}.Fingerprint(), 0},
+		{"stored result for default rules", nil, lint.Config{}.Fingerprint(), 1},
+		{"stored result for the same rules", &models.LintSettings{Rules: raised}, lint.Config{Severities: raised}.Fingerprint(), 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			project := &models.Project{ID: primitive.NewObjectID(), Settings: models.ProjectSettings{Lint: tt.settings}}
+			file := &models.File{ID: primitive.NewObjectID(), ProjectID: project.ID, Path: "main.tex", Hash: "h"}
+			result := &models.LintResult{FileID: file.ID, ProjectID: project.ID, Hash: "h", Config: tt.config, Warnings: stored}
+
+			// Results that would need relinting read storage, which this
+			// service does not have
+			s := &ProjectService{}
+			got, err := s.currentLint(context.Background(), project, file, result)
+			if err != nil {
+				t.Fatalf("currentLint() error = %v", err)
+			}
+			if len(got.Warnings) != tt.want {
+				t.Errorf("currentLint() warnings = %+v, want %d", got.Warnings, tt.want)
+			}
+			if got.Path != file.Path {
+				t.Errorf("currentLint() path = %q, want %q", got.Path, file.Path)
+			}
+		})
+	}
+}
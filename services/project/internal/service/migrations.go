@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/texflow/pkg/migrate"
+	"go.uber.org/zap"
+)
+
+// Migrations lists the data migrations of the project service. Versions are
+// permanent: add new migrations at the end and never renumber them.
+func (s *ProjectService) Migrations() []migrate.Migration {
+	return []migrate.Migration{
+		{Version: 1, Name: "escape_main_tex_titles", Up: s.escapeMainTexTitles},
+		{Version: 2, Name: "reparse_outline_dependencies", Up: s.reparseOutlines},
+	}
+}
+
+// escapeMainTexTitles escapes LaTeX special characters in the \title of
+// main.tex files created before project names were escaped
+func (s *ProjectService) escapeMainTexTitles(ctx context.Context, dryRun bool) (string, error) {
+	files, err := s.fileRepo.FindByName(ctx, "main.tex")
+	if err != nil {
+		return "", fmt.Errorf("failed to find main.tex files: %w", err)
+	}
+
+	fixed := 0
+	var failures []string
+
+	for _, file := range files {
+		content, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("failed to download %s: %v", file.StorageKey, err))
+			continue
+		}
+
+		originalContent := string(content)
+		fixedContent := fixLatexTitle(originalContent)
+		if fixedContent == originalContent {
+			continue
+		}
+		if dryRun {
+			fixed++
+			continue
+		}
+
+		// Saved like any other edit, so a failed or concurrent write leaves
+		// the file as it was. The change is attributed to the file's creator.
+		if err := s.writeFileContent(ctx, file, []byte(fixedContent)); err != nil {
+			failures = append(failures, fmt.Sprintf("failed to update %s: %v", file.ID.Hex(), err))
+			continue
+		}
+		s.recordFileVersion(ctx, file, file.CreatedBy, []byte(fixedContent), 0)
+		s.updateProjectFileStats(ctx, file.ProjectID)
+		s.recordGitChanges(ctx, file.ProjectID, file.CreatedBy, file.Path)
+
+		s.logger.Info("Fixed LaTeX file",
+			zap.String("file_id", file.ID.Hex()),
+			zap.String("project_id", file.ProjectID.Hex()),
+		)
+		fixed++
+	}
+
+	// Fixed titles are skipped on the next run, so failures can be retried
+	if len(failures) > 0 {
+		return "", fmt.Errorf("%d of %d files failed: %s", len(failures), len(files), strings.Join(failures, "; "))
+	}
+
+	if dryRun {
+		return fmt.Sprintf("%d of %d main.tex files would be fixed", fixed, len(files)), nil
+	}
+	return fmt.Sprintf("fixed %d of %d main.tex files", fixed, len(files)), nil
+}
+
+// reparseOutlines makes every project rebuild its outline index on next use,
+// so that graphics, packages and .sty and .cls files are indexed for the
+// dependency graph
+func (s *ProjectService) reparseOutlines(ctx context.Context, dryRun bool) (string, error) {
+	if dryRun {
+		count, err := s.projectRepo.CountOutlineIndexed(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d outline indexes would be rebuilt", count), nil
+	}
+
+	count, err := s.projectRepo.ClearOutlineIndexed(ctx)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d outline indexes will be rebuilt", count), nil
+}
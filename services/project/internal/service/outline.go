@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"github.com/texflow/services/project/internal/outline"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// GetProjectIndex returns the outline, labels, references, citations and
+// bibliography entries of a project, starting from its main file
+func (s *ProjectService) GetProjectIndex(ctx context.Context, projectID, userID primitive.ObjectID) (*outline.Index, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	root, sources, _, err := s.outlineSources(ctx, project)
+	if err != nil {
+		return nil, err
+	}
+
+	return outline.Build(root, sources), nil
+}
+
+// outlineSources returns the main file of a project and the parsed files
+// keyed by path, with nil for files that are not parsed, along with the file
+// records
+func (s *ProjectService) outlineSources(ctx context.Context, project *models.Project) (string, map[string]*outline.FileIndex, []*models.File, error) {
+	s.ensureOutlineIndex(ctx, project)
+
+	files, err := s.fileRepo.FindByProjectID(ctx, project.ID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	docs, err := s.outlineRepo.FindByProjectID(ctx, project.ID)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	sources := make(map[string]*outline.FileIndex, len(files))
+	for _, file := range files {
+		sources[file.Path] = nil
+	}
+	for _, doc := range docs {
+		if _, ok := sources[doc.Path]; ok {
+			sources[doc.Path] = doc.Index
+		}
+	}
+
+	root := project.Settings.MainFile
+	if root == "" {
+		root = "main.tex"
+	}
+
+	return root, sources, files, nil
+}
+
+// ensureOutlineIndex parses the files of a project that has never been
+// indexed, such as one created before the outline index existed
+func (s *ProjectService) ensureOutlineIndex(ctx context.Context, project *models.Project) {
+	if project.OutlineIndexedAt != nil {
+		return
+	}
+
+	startedAt := time.Now()
+	files, err := s.fileRepo.FindByProjectID(ctx, project.ID)
+	if err != nil {
+		s.logger.Error("Failed to build outline index", zap.String("project_id", project.ID.Hex()), zap.Error(err))
+		return
+	}
+
+	for _, file := range files {
+		s.indexStoredOutline(ctx, file)
+	}
+
+	if err := s.projectRepo.SetOutlineIndexed(ctx, project.ID, startedAt); err != nil {
+		s.logger.Error("Failed to record outline index", zap.String("project_id", project.ID.Hex()), zap.Error(err))
+	}
+}
+
+// indexOutline stores the parsed structure of a .tex or .bib file
+func (s *ProjectService) indexOutline(ctx context.Context, file *models.File, content []byte) {
+	if !outline.IsSource(file.Path) {
+		return
+	}
+
+	doc := &models.OutlineDocument{
+		FileID:    file.ID,
+		ProjectID: file.ProjectID,
+		Path:      file.Path,
+		Hash:      file.Hash,
+		Index:     outline.Parse(file.Path, string(content)),
+	}
+	if err := s.outlineRepo.Upsert(ctx, doc); err != nil {
+		s.logger.Error("Failed to index file outline", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+	}
+}
+
+// indexStoredOutline parses a .tex or .bib file by reading its content from
+// storage
+func (s *ProjectService) indexStoredOutline(ctx context.Context, file *models.File) {
+	if file.IsBinary || file.SizeBytes > MaxIndexedFileSize || !outline.IsSource(file.Path) {
+		return
+	}
+
+	content, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+	if err != nil {
+		s.logger.Error("Failed to read file for outline", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+		return
+	}
+
+	s.indexOutline(ctx, file, content)
+}
+
+// moveOutlineFile reparses a moved file, since a rename can change whether
+// and how it is parsed
+func (s *ProjectService) moveOutlineFile(ctx context.Context, file *models.File) {
+	if !outline.IsSource(file.Path) {
+		if err := s.outlineRepo.Delete(ctx, file.ID); err != nil {
+			s.logger.Error("Failed to remove file from outline index", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+		}
+		return
+	}
+
+	s.indexStoredOutline(ctx, file)
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// purgeBatchSize bounds the projects purged by one sweep
+const purgeBatchSize = 100
+
+// ArchiveProject hides a project from the default list and makes it
+// read-only until it is unarchived
+func (s *ProjectService) ArchiveProject(ctx context.Context, projectID, userID primitive.ObjectID) (*models.Project, error) {
+	project, err := s.getOwnedProject(ctx, projectID, userID, "only project owner can archive project")
+	if err != nil {
+		return nil, err
+	}
+
+	if project.ArchivedAt == nil {
+		now := time.Now()
+		if err := s.projectRepo.SetArchived(ctx, projectID, &now); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.projectRepo.FindByID(ctx, projectID)
+}
+
+// UnarchiveProject makes an archived project active again
+func (s *ProjectService) UnarchiveProject(ctx context.Context, projectID, userID primitive.ObjectID) (*models.Project, error) {
+	project, err := s.getOwnedProject(ctx, projectID, userID, "only project owner can archive project")
+	if err != nil {
+		return nil, err
+	}
+
+	if project.ArchivedAt != nil {
+		if err := s.projectRepo.SetArchived(ctx, projectID, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.projectRepo.FindByID(ctx, projectID)
+}
+
+// RestoreProject moves a project out of the trash
+func (s *ProjectService) RestoreProject(ctx context.Context, projectID, userID primitive.ObjectID) (*models.Project, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.OwnerID != userID {
+		return nil, fmt.Errorf("only project owner can restore project")
+	}
+
+	if err := s.projectRepo.RestoreFromTrash(ctx, projectID); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Project restored from trash",
+		zap.String("project_id", projectID.Hex()),
+		zap.String("user_id", userID.Hex()),
+	)
+
+	return s.projectRepo.FindByID(ctx, projectID)
+}
+
+// PurgeProject permanently deletes a trashed project without waiting for
+// the retention period
+func (s *ProjectService) PurgeProject(ctx context.Context, projectID, userID primitive.ObjectID) error {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
+	if project.OwnerID != userID {
+		return fmt.Errorf("only project owner can delete project")
+	}
+	if project.DeletedAt == nil {
+		return fmt.Errorf("project is not in trash")
+	}
+
+	return s.purgeProject(ctx, projectID)
+}
+
+// PurgeExpiredProjects permanently deletes the trashed projects whose
+// retention period has passed and returns how many were purged
+func (s *ProjectService) PurgeExpiredProjects(ctx context.Context) (int, error) {
+	projects, err := s.projectRepo.FindPurgeable(ctx, time.Now(), purgeBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for _, project := range projects {
+		if err := s.purgeProject(ctx, project.ID); err != nil {
+			s.logger.Error("Failed to purge project", zap.String("project_id", project.ID.Hex()), zap.Error(err))
+			continue
+		}
+		purged++
+	}
+
+	return purged, nil
+}
+
+// DuplicateProject copies a project with its folders and files into a new
+// project owned by the user. File contents are copied server-side in MinIO;
+// history, Git state and compilations are not carried over.
+func (s *ProjectService) DuplicateProject(ctx context.Context, projectID, userID primitive.ObjectID, req *models.DuplicateProjectRequest) (*models.Project, error) {
+	source, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, source, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+	if req.CopyCollaborators && source.OwnerID != userID {
+		return nil, fmt.Errorf("only project owner can copy collaborators")
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "Copy of " + source.Name
+		if len(name) > 100 {
+			name = name[:100]
+		}
+	}
+
+	project := &models.Project{
+		Name:        name,
+		Description: source.Description,
+		OwnerID:     userID,
+		Settings:    source.Settings,
+		TemplateID:  source.TemplateID,
+		Tags:        source.Tags,
+	}
+	if req.CopyCollaborators {
+		now := time.Now()
+		for _, collab := range source.Collaborators {
+			collab.InvitedAt = now
+			project.Collaborators = append(project.Collaborators, collab)
+		}
+	}
+
+	release, err := s.reserveQuota(ctx, userID, 0, source.TotalSizeBytes, int64(source.FileCount))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.projectRepo.Create(ctx, project); err != nil {
+		release()
+		return nil, err
+	}
+
+	if err := s.copyProjectFiles(ctx, source.ID, project.ID, userID); err != nil {
+		s.logger.Error("Failed to copy project files", zap.String("project_id", projectID.Hex()), zap.Error(err))
+		release()
+		if delErr := s.purgeProject(ctx, project.ID); delErr != nil {
+			s.logger.Error("Failed to delete project after duplication failure", zap.Error(delErr))
+		}
+		return nil, fmt.Errorf("failed to copy project files: %w", err)
+	}
+
+	s.updateProjectFileStats(ctx, project.ID)
+
+	s.logger.Info("Project duplicated",
+		zap.String("source_id", projectID.Hex()),
+		zap.String("project_id", project.ID.Hex()),
+		zap.String("user_id", userID.Hex()),
+	)
+
+	return s.projectRepo.FindByID(ctx, project.ID)
+}
+
+// copyProjectFiles copies the folders and files of one project into another
+func (s *ProjectService) copyProjectFiles(ctx context.Context, sourceID, targetID, userID primitive.ObjectID) error {
+	folders, err := s.folderRepo.FindByProjectID(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+	for _, folder := range folders {
+		if err := s.ensureFolders(ctx, targetID, userID, folder.Path); err != nil {
+			return err
+		}
+	}
+
+	files, err := s.fileRepo.FindByProjectID(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+	for _, src := range files {
+		file := &models.File{
+			ProjectID:   targetID,
+			Name:        src.Name,
+			Path:        src.Path,
+			ContentType: src.ContentType,
+			SizeBytes:   src.SizeBytes,
+			StorageKey:  newFileObjectKey(targetID, src.Path),
+			CreatedBy:   userID,
+			IsBinary:    src.IsBinary,
+			Hash:        src.Hash,
+		}
+		if err := s.minioClient.CopyFile(ctx, src.StorageKey, file.StorageKey); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", src.Path, err)
+		}
+		if err := s.fileRepo.Create(ctx, file); err != nil {
+			return err
+		}
+		s.recordCopiedFileVersion(ctx, file, userID, file.StorageKey)
+		s.indexStoredFile(ctx, file)
+	}
+
+	return nil
+}
+
+// getOwnedProject returns a project that is not in the trash, checking that
+// the user owns it
+func (s *ProjectService) getOwnedProject(ctx context.Context, projectID, userID primitive.ObjectID, denied string) (*models.Project, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.OwnerID != userID {
+		return nil, fmt.Errorf("%s", denied)
+	}
+	if project.DeletedAt != nil {
+		return nil, fmt.Errorf("project is in trash")
+	}
+
+	return project, nil
+}
+
+// checkProjectWritable returns an error for archived and trashed projects
+func checkProjectWritable(project *models.Project) error {
+	if project.DeletedAt != nil {
+		return fmt.Errorf("project is in trash")
+	}
+	if project.ArchivedAt != nil {
+		return fmt.Errorf("project is archived")
+	}
+	return nil
+}
@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/texflow/services/project/internal/events"
+	"github.com/texflow/services/project/internal/lint"
 	"github.com/texflow/services/project/internal/models"
 	"github.com/texflow/services/project/internal/repository"
 	"github.com/texflow/services/project/internal/storage"
@@ -16,47 +20,143 @@ import (
 
 // ProjectService handles project business logic
 type ProjectService struct {
-	projectRepo *repository.ProjectRepository
-	fileRepo    *repository.FileRepository
-	minioClient *storage.MinIOClient
-	logger      *zap.Logger
+	projectRepo  *repository.ProjectRepository
+	fileRepo     *repository.FileRepository
+	folderRepo   *repository.FolderRepository
+	gitRepo      *repository.GitRepository
+	userRepo     *repository.UserRepository
+	versionRepo  *repository.VersionRepository
+	templateRepo *repository.TemplateRepository
+	inviteRepo   *repository.InvitationRepository
+	shareRepo    *repository.ShareLinkRepository
+	searchRepo   *repository.SearchRepository
+	outlineRepo  *repository.OutlineRepository
+	uploadRepo   *repository.UploadRepository
+	quotaRepo    *repository.QuotaRepository
+	fsckRepo     *repository.FsckRepository
+	commentRepo  *repository.CommentRepository
+	minioClient  *storage.MinIOClient
+	events       *events.Publisher
+	logger       *zap.Logger
+
+	notificationRepo *repository.NotificationRepository
+	suggestionRepo   *repository.SuggestionRepository
+	lintRepo         *repository.LintRepository
+
+	versionRetention VersionRetention
+	trashRetention   time.Duration
+	shareGrantTTL    time.Duration
+	maxUploadSize    int64
+	quotaDefaults    QuotaDefaults
+
+	// fsckRunning is set while a storage check runs
+	fsckRunning atomic.Bool
 }
 
 // NewProjectService creates a new project service
 func NewProjectService(
 	projectRepo *repository.ProjectRepository,
 	fileRepo *repository.FileRepository,
+	folderRepo *repository.FolderRepository,
+	gitRepo *repository.GitRepository,
+	userRepo *repository.UserRepository,
+	versionRepo *repository.VersionRepository,
+	templateRepo *repository.TemplateRepository,
+	inviteRepo *repository.InvitationRepository,
+	shareRepo *repository.ShareLinkRepository,
+	searchRepo *repository.SearchRepository,
+	outlineRepo *repository.OutlineRepository,
+	uploadRepo *repository.UploadRepository,
+	quotaRepo *repository.QuotaRepository,
+	fsckRepo *repository.FsckRepository,
+	commentRepo *repository.CommentRepository,
+	notificationRepo *repository.NotificationRepository,
+	suggestionRepo *repository.SuggestionRepository,
+	lintRepo *repository.LintRepository,
 	minioClient *storage.MinIOClient,
+	eventPublisher *events.Publisher,
+	versionRetention VersionRetention,
+	trashRetention time.Duration,
+	shareGrantTTL time.Duration,
+	maxUploadSize int64,
+	quotaDefaults QuotaDefaults,
 	logger *zap.Logger,
 ) *ProjectService {
 	return &ProjectService{
-		projectRepo: projectRepo,
-		fileRepo:    fileRepo,
-		minioClient: minioClient,
-		logger:      logger,
+		projectRepo:  projectRepo,
+		fileRepo:     fileRepo,
+		folderRepo:   folderRepo,
+		gitRepo:      gitRepo,
+		userRepo:     userRepo,
+		versionRepo:  versionRepo,
+		templateRepo: templateRepo,
+		inviteRepo:   inviteRepo,
+		shareRepo:    shareRepo,
+		searchRepo:   searchRepo,
+		outlineRepo:  outlineRepo,
+		uploadRepo:   uploadRepo,
+		quotaRepo:    quotaRepo,
+		fsckRepo:     fsckRepo,
+		commentRepo:  commentRepo,
+		minioClient:  minioClient,
+		events:       eventPublisher,
+		logger:       logger,
+
+		notificationRepo: notificationRepo,
+		suggestionRepo:   suggestionRepo,
+		lintRepo:         lintRepo,
+
+		versionRetention: versionRetention,
+		trashRetention:   trashRetention,
+		shareGrantTTL:    shareGrantTTL,
+		maxUploadSize:    maxUploadSize,
+		quotaDefaults:    quotaDefaults,
 	}
 }
 
-// CreateProject creates a new project
+// CreateProject creates a new project, either from a template or with a
+// default main.tex
 func (s *ProjectService) CreateProject(ctx context.Context, userID primitive.ObjectID, req *models.CreateProjectRequest) (*models.Project, error) {
+	var template *models.Template
+	if req.TemplateID != "" {
+		templateID, err := primitive.ObjectIDFromHex(req.TemplateID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template ID")
+		}
+		if template, err = s.GetTemplate(ctx, templateID, userID); err != nil {
+			return nil, err
+		}
+	}
+
 	compiler := req.Compiler
+	if compiler == "" && template != nil {
+		compiler = template.Compiler
+	}
 	if compiler == "" {
 		compiler = "pdflatex"
 	}
 
+	mainFile := "main.tex"
+	if template != nil && template.MainFile != "" {
+		mainFile = template.MainFile
+	}
+
 	project := &models.Project{
 		Name:        req.Name,
 		Description: req.Description,
 		OwnerID:     userID,
 		Settings: models.ProjectSettings{
 			Compiler:    compiler,
-			MainFile:    "main.tex",
+			MainFile:    mainFile,
 			SpellCheck:  true,
 			AutoCompile: false,
 		},
 		IsPublic: req.IsPublic,
 		Tags:     req.Tags,
 	}
+	if template != nil {
+		project.TemplateID = &template.ID
+	}
 
 	if err := s.projectRepo.Create(ctx, project); err != nil {
 		return nil, err
@@ -67,6 +167,19 @@ func (s *ProjectService) CreateProject(ctx context.Context, userID primitive.Obj
 		zap.String("user_id", userID.Hex()),
 	)
 
+	if template != nil {
+		if err := s.copyTemplateFiles(ctx, project, userID, template); err != nil {
+			s.logger.Error("Failed to copy template files", zap.String("template_id", template.ID.Hex()), zap.Error(err))
+			if delErr := s.purgeProject(ctx, project.ID); delErr != nil {
+				s.logger.Error("Failed to delete project after template copy failure", zap.Error(delErr))
+			}
+			return nil, fmt.Errorf("failed to copy template files: %w", err)
+		}
+
+		s.updateProjectFileStats(ctx, project.ID)
+		return s.projectRepo.FindByID(ctx, project.ID)
+	}
+
 	// Create default main.tex with escaped project name
 	escapedTitle := escapeLatex(project.Name)
 	defaultContent := fmt.Sprintf(`\documentclass{article}
@@ -86,10 +199,9 @@ Start writing your document here.
 \end{document}`, escapedTitle)
 
 	fileReq := &models.CreateFileRequest{
-		Name:     "main.tex",
-		Path:     "/main.tex",
-		Content:  []byte(defaultContent),
-		IsBinary: false,
+		Name:    "main.tex",
+		Path:    "/main.tex",
+		Content: []byte(defaultContent),
 	}
 
 	_, err := s.CreateFile(ctx, project.ID, userID, fileReq)
@@ -113,7 +225,7 @@ func (s *ProjectService) GetProject(ctx context.Context, projectID primitive.Obj
 	}
 
 	// Check access
-	if !s.userHasAccess(project, userID) {
+	if !s.userHasAccess(ctx, project, userID) {
 		return nil, fmt.Errorf("access denied")
 	}
 
@@ -127,7 +239,7 @@ func (s *ProjectService) ListFiles(ctx context.Context, projectID, userID primit
 		return nil, err
 	}
 
-	if !s.userHasAccess(project, userID) {
+	if !s.userHasAccess(ctx, project, userID) {
 		return nil, fmt.Errorf("access denied")
 	}
 
@@ -153,23 +265,27 @@ func (s *ProjectService) GetFileMetadata(ctx context.Context, fileID, userID pri
 		return nil, err
 	}
 
-	if !s.userHasAccess(project, userID) {
+	if !s.userHasAccess(ctx, project, userID) {
 		return nil, fmt.Errorf("access denied")
 	}
 
 	return file, nil
 }
 
-// ListUserProjects lists all projects owned by or shared with a user
-func (s *ProjectService) ListUserProjects(ctx context.Context, userID primitive.ObjectID, page, limit int) ([]*models.Project, int64, error) {
+// ListUserProjects lists the projects owned by or shared with a user that
+// are in the given status. The trash only holds the user's own projects.
+func (s *ProjectService) ListUserProjects(ctx context.Context, userID primitive.ObjectID, status string, page, limit int) ([]*models.Project, int64, error) {
 	// Get owned projects
-	ownedProjects, ownedTotal, err := s.projectRepo.FindByOwner(ctx, userID, page, limit)
+	ownedProjects, ownedTotal, err := s.projectRepo.FindByOwner(ctx, userID, status, page, limit)
 	if err != nil {
 		return nil, 0, err
 	}
+	if status == models.ProjectStatusTrashed {
+		return ownedProjects, ownedTotal, nil
+	}
 
 	// Get shared projects
-	sharedProjects, sharedTotal, err := s.projectRepo.FindSharedWithUser(ctx, userID, page, limit)
+	sharedProjects, sharedTotal, err := s.projectRepo.FindSharedWithUser(ctx, userID, status, page, limit)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -192,6 +308,9 @@ func (s *ProjectService) UpdateProject(ctx context.Context, projectID, userID pr
 	if project.OwnerID != userID {
 		return nil, fmt.Errorf("only project owner can update settings")
 	}
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
 
 	// Update fields
 	if req.Name != nil {
@@ -212,6 +331,21 @@ func (s *ProjectService) UpdateProject(ctx context.Context, projectID, userID pr
 	if req.Tags != nil {
 		project.Tags = req.Tags
 	}
+	if req.PostProcess != nil {
+		project.Settings.PostProcess = req.PostProcess
+	}
+	if req.TrackChanges != nil {
+		project.Settings.TrackChanges = *req.TrackChanges
+	}
+	if req.BibKeyStyle != nil {
+		project.Settings.BibKeyStyle = *req.BibKeyStyle
+	}
+	if req.Lint != nil {
+		if err := (lint.Config{Severities: req.Lint.Rules}).Validate(); err != nil {
+			return nil, err
+		}
+		project.Settings.Lint = req.Lint
+	}
 
 	if err := s.projectRepo.Update(ctx, project); err != nil {
 		return nil, err
@@ -220,7 +354,8 @@ func (s *ProjectService) UpdateProject(ctx context.Context, projectID, userID pr
 	return project, nil
 }
 
-// DeleteProject deletes a project and all its files
+// DeleteProject moves a project to its owner's trash. It is purged after
+// the trash retention period unless restored.
 func (s *ProjectService) DeleteProject(ctx context.Context, projectID, userID primitive.ObjectID) error {
 	project, err := s.projectRepo.FindByID(ctx, projectID)
 	if err != nil {
@@ -232,6 +367,26 @@ func (s *ProjectService) DeleteProject(ctx context.Context, projectID, userID pr
 		return fmt.Errorf("only project owner can delete project")
 	}
 
+	now := time.Now()
+	if err := s.projectRepo.MoveToTrash(ctx, projectID, now, now.Add(s.trashRetention)); err != nil {
+		return err
+	}
+
+	s.logger.Info("Project moved to trash",
+		zap.String("project_id", projectID.Hex()),
+		zap.String("user_id", userID.Hex()),
+	)
+
+	return nil
+}
+
+// purgeProject permanently deletes a project and all its files
+func (s *ProjectService) purgeProject(ctx context.Context, projectID primitive.ObjectID) error {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return err
+	}
+
 	// Delete all files from MinIO
 	prefix := fmt.Sprintf("projects/%s/", projectID.Hex())
 	objects, err := s.minioClient.ListObjects(ctx, prefix)
@@ -249,88 +404,142 @@ func (s *ProjectService) DeleteProject(ctx context.Context, projectID, userID pr
 	if err := s.fileRepo.DeleteByProjectID(ctx, projectID); err != nil {
 		s.logger.Error("Failed to delete file records", zap.Error(err))
 	}
+	if err := s.folderRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		s.logger.Error("Failed to delete folder records", zap.Error(err))
+	}
+	if err := s.gitRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		s.logger.Error("Failed to delete git state", zap.Error(err))
+	}
+	if err := s.versionRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		s.logger.Error("Failed to delete file versions", zap.Error(err))
+	}
+	if err := s.inviteRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		s.logger.Error("Failed to delete invitations", zap.Error(err))
+	}
+	if err := s.shareRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		s.logger.Error("Failed to delete share links", zap.Error(err))
+	}
+	if err := s.searchRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		s.logger.Error("Failed to delete search index", zap.Error(err))
+	}
+	if err := s.outlineRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		s.logger.Error("Failed to delete outline index", zap.Error(err))
+	}
+	if err := s.commentRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		s.logger.Error("Failed to delete comments", zap.Error(err))
+	}
+	if err := s.notificationRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		s.logger.Error("Failed to delete notifications", zap.Error(err))
+	}
+	if err := s.suggestionRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		s.logger.Error("Failed to delete suggestions", zap.Error(err))
+	}
+	if err := s.lintRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		s.logger.Error("Failed to delete lint results", zap.Error(err))
+	}
+	uploads, err := s.uploadRepo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		s.logger.Error("Failed to find uploads", zap.Error(err))
+	}
+	for _, upload := range uploads {
+		s.discardUpload(ctx, upload)
+	}
 
 	// Delete project
-	return s.projectRepo.Delete(ctx, projectID)
+	if err := s.projectRepo.Delete(ctx, projectID); err != nil {
+		return err
+	}
+	s.refreshStorageUsage(ctx, project.OwnerID)
+
+	return nil
 }
 
-// ShareProject shares a project with another user
-func (s *ProjectService) ShareProject(ctx context.Context, projectID, ownerID, collaboratorID primitive.ObjectID, role string) error {
+// CreateFile creates a file in a project
+func (s *ProjectService) CreateFile(ctx context.Context, projectID, userID primitive.ObjectID, req *models.CreateFileRequest) (*models.File, error) {
 	project, err := s.projectRepo.FindByID(ctx, projectID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Check if user is owner
-	if project.OwnerID != ownerID {
-		return fmt.Errorf("only project owner can share project")
+	// Check access
+	if !s.userCanEdit(ctx, project, userID) {
+		return nil, fmt.Errorf("permission denied")
 	}
 
-	// Check if already shared
-	for _, collab := range project.Collaborators {
-		if collab.UserID == collaboratorID {
-			return fmt.Errorf("project already shared with this user")
-		}
+	if int64(len(req.Content)) > s.maxUploadSize {
+		return nil, fmt.Errorf("file exceeds the maximum upload size")
 	}
 
-	collaborator := models.Collaborator{
-		UserID:    collaboratorID,
-		Role:      role,
-		InvitedAt: project.CreatedAt,
+	// Clean and validate path, removing the leading slash for the storage key
+	cleanPath, err := cleanFilePath(req.Path)
+	if err != nil {
+		return nil, err
 	}
 
-	return s.projectRepo.AddCollaborator(ctx, projectID, collaborator)
-}
-
-// CreateFile creates a file in a project
-func (s *ProjectService) CreateFile(ctx context.Context, projectID, userID primitive.ObjectID, req *models.CreateFileRequest) (*models.File, error) {
-	project, err := s.projectRepo.FindByID(ctx, projectID)
+	size := int64(len(req.Content))
+	release, err := s.reserveQuota(ctx, project.OwnerID, size, size, 1)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check access
-	if !s.userCanEdit(project, userID) {
-		return nil, fmt.Errorf("permission denied")
+	file, err := s.storeFile(ctx, projectID, userID, cleanPath, req.Name, req.Content)
+	if err != nil {
+		release()
+		return nil, err
 	}
 
-	// Clean and validate path
-	cleanPath := filepath.Clean(req.Path)
-	if strings.HasPrefix(cleanPath, "..") {
-		return nil, fmt.Errorf("invalid file path")
+	// Update project stats
+	s.updateProjectFileStats(ctx, projectID)
+	s.recordGitChanges(ctx, projectID, userID, cleanPath)
+
+	return file, nil
+}
+
+// storeFile uploads content and creates its file record, creating parent
+// folders as needed. Whether the file is binary is sniffed from its content.
+// Project stats are left to the caller.
+func (s *ProjectService) storeFile(
+	ctx context.Context,
+	projectID, userID primitive.ObjectID,
+	cleanPath, name string,
+	content []byte,
+) (*models.File, error) {
+	// Parent folders become first-class folder entities
+	if err := s.ensureFolders(ctx, projectID, userID, parentPath(cleanPath)); err != nil {
+		return nil, err
 	}
-	// Remove leading slash for storage key
-	cleanPath = strings.TrimPrefix(cleanPath, "/")
 
 	// Calculate hash
-	hash := fmt.Sprintf("%x", sha256.Sum256(req.Content))
+	hash := fmt.Sprintf("%x", sha256.Sum256(content))
 
-	// Upload to MinIO
-	storageKey := fmt.Sprintf("projects/%s/files/%s", projectID.Hex(), cleanPath)
-	contentType := getContentType(req.Name)
-	if err := s.minioClient.UploadBytes(ctx, storageKey, req.Content, contentType); err != nil {
+	// Upload to MinIO under a key of its own, so that a concurrent create
+	// of the same path cannot overwrite the object
+	storageKey := newFileObjectKey(projectID, cleanPath)
+	contentType := getContentType(name)
+	if err := s.minioClient.UploadBytes(ctx, storageKey, content, contentType); err != nil {
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
 
 	// Create file record
 	file := &models.File{
 		ProjectID:   projectID,
-		Name:        req.Name,
+		Name:        name,
 		Path:        cleanPath,
 		ContentType: contentType,
-		SizeBytes:   int64(len(req.Content)),
+		SizeBytes:   int64(len(content)),
 		StorageKey:  storageKey,
 		CreatedBy:   userID,
-		IsBinary:    req.IsBinary,
+		IsBinary:    sniffBinary(content),
 		Hash:        hash,
 	}
 
 	if err := s.fileRepo.Create(ctx, file); err != nil {
+		s.deleteObject(ctx, storageKey)
 		return nil, err
 	}
-
-	// Update project stats
-	s.updateProjectFileStats(ctx, projectID)
+	s.recordFileVersion(ctx, file, userID, content, 0)
+	s.indexFile(ctx, file, content)
+	s.lintFile(ctx, file, content)
 
 	return file, nil
 }
@@ -348,7 +557,7 @@ func (s *ProjectService) GetFile(ctx context.Context, fileID, userID primitive.O
 	}
 
 	// Check access
-	if !s.userHasAccess(project, userID) {
+	if !s.userHasAccess(ctx, project, userID) {
 		return nil, nil, fmt.Errorf("access denied")
 	}
 
@@ -361,51 +570,119 @@ func (s *ProjectService) GetFile(ctx context.Context, fileID, userID primitive.O
 	return file, content, nil
 }
 
-// UpdateFile updates a file's content
-func (s *ProjectService) UpdateFile(ctx context.Context, fileID, userID primitive.ObjectID, req *models.UpdateFileRequest) (*models.File, error) {
+// UpdateFile updates a file's content. ifMatch must match the ETag of the
+// saved content, or be "*" to overwrite whatever is saved; otherwise a
+// *FileConflictError is returned. When the project tracks changes, edits by
+// anyone but the owner leave the file as it is and are returned as
+// suggestions instead; otherwise the suggestions are nil.
+func (s *ProjectService) UpdateFile(ctx context.Context, fileID, userID primitive.ObjectID, req *models.UpdateFileRequest, ifMatch string) (*models.File, []*models.Suggestion, error) {
 	file, err := s.fileRepo.FindByID(ctx, fileID)
 	if err != nil {
-		return nil, fmt.Errorf("file not found")
+		return nil, nil, fmt.Errorf("file not found")
 	}
 
 	project, err := s.projectRepo.FindByID(ctx, file.ProjectID)
 	if err != nil {
-		return nil, fmt.Errorf("project not found")
+		return nil, nil, fmt.Errorf("project not found")
 	}
 
 	// Check edit access
-	if !s.userCanEdit(project, userID) {
-		return nil, fmt.Errorf("permission denied")
+	if !s.userCanEdit(ctx, project, userID) {
+		return nil, nil, fmt.Errorf("permission denied")
+	}
+
+	if !etagMatches(ifMatch, file.ETag()) {
+		return nil, nil, s.fileConflict(ctx, file, ifMatch, req.Content)
+	}
+
+	if tracksChangesOf(project, userID) {
+		// Binary content has no text to suggest
+		if file.IsBinary {
+			return nil, nil, checkUntrackedWrite(project, userID)
+		}
+		suggestions, err := s.suggestFileChanges(ctx, file, userID, req.Content)
+		if err != nil {
+			return nil, nil, err
+		}
+		return file, suggestions, nil
+	}
+
+	content := []byte(req.Content)
+	release, err := s.reserveQuota(ctx, project.OwnerID, int64(len(content)), int64(len(content))-file.SizeBytes, 0)
+	if err != nil {
+		return nil, nil, err
 	}
+	if err := s.writeFileContent(ctx, file, content); err != nil {
+		release()
+		if err.Error() == "file has been modified" {
+			// Another save won the race after the If-Match check
+			if current, findErr := s.fileRepo.FindByID(ctx, fileID); findErr == nil {
+				return nil, nil, s.fileConflict(ctx, current, ifMatch, req.Content)
+			}
+		}
+		return nil, nil, err
+	}
+	s.recordFileVersion(ctx, file, userID, content, 0)
+
+	// Update project stats
+	s.updateProjectFileStats(ctx, file.ProjectID)
+	s.recordGitChanges(ctx, file.ProjectID, userID, file.Path)
 
-	// Convert string content to bytes
-	contentBytes := []byte(req.Content)
+	return file, nil, nil
+}
+
+// writeFileContent replaces the content of an existing file. The caller
+// records the new version.
+func (s *ProjectService) writeFileContent(ctx context.Context, file *models.File, content []byte) error {
+	s.ensureBaseVersion(ctx, file)
+	moveRanges := s.rangeMover(ctx, file, content)
 
 	// Calculate new hash
-	hash := fmt.Sprintf("%x", sha256.Sum256(contentBytes))
+	hash := fmt.Sprintf("%x", sha256.Sum256(content))
 
-	// Upload to MinIO (overwrites existing file)
-	if err := s.minioClient.UploadBytes(ctx, file.StorageKey, contentBytes, file.ContentType); err != nil {
-		return nil, fmt.Errorf("failed to upload file: %w", err)
+	// The content goes to a new object, which only becomes the file's content
+	// if the record is updated. A write that fails or loses to a concurrent
+	// one leaves the saved content as it was.
+	storageKey := newFileObjectKey(file.ProjectID, file.Path)
+	if err := s.minioClient.UploadBytes(ctx, storageKey, content, file.ContentType); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
 	}
 
 	// Update file record
-	file.SizeBytes = int64(len(contentBytes))
+	previous := *file
+	file.StorageKey = storageKey
+	file.SizeBytes = int64(len(content))
 	file.Hash = hash
-	// Note: Version is incremented by fileRepo.Update()
+	// Note: Version is incremented by fileRepo.Update(), which fails if
+	// the file was saved since it was read
 
 	if err := s.fileRepo.Update(ctx, file); err != nil {
-		return nil, err
+		*file = previous
+		s.deleteObject(ctx, storageKey)
+		return err
 	}
+	s.deleteObject(ctx, previous.StorageKey)
+	s.indexFile(ctx, file, content)
+	s.lintFile(ctx, file, content)
+	moveRanges()
 
-	// Update project stats
-	s.updateProjectFileStats(ctx, file.ProjectID)
+	return nil
+}
 
-	return file, nil
+// newFileObjectKey returns an unused storage key for content of a file.
+// Each write has its own object, so concurrent writes never overwrite the
+// object a file record refers to.
+func newFileObjectKey(projectID primitive.ObjectID, filePath string) string {
+	return fmt.Sprintf("projects/%s/files/%s~%s", projectID.Hex(), filePath, primitive.NewObjectID().Hex())
 }
 
 // Helper methods
-func (s *ProjectService) userHasAccess(project *models.Project, userID primitive.ObjectID) bool {
+func (s *ProjectService) userHasAccess(ctx context.Context, project *models.Project, userID primitive.ObjectID) bool {
+	// A trashed project is only reachable through the trash endpoints
+	if project.DeletedAt != nil {
+		return false
+	}
+
 	if project.OwnerID == userID {
 		return true
 	}
@@ -416,10 +693,18 @@ func (s *ProjectService) userHasAccess(project *models.Project, userID primitive
 		}
 	}
 
-	return project.IsPublic
+	if project.IsPublic {
+		return true
+	}
+
+	return s.shareGrantRole(ctx, project.ID, userID) != ""
 }
 
-func (s *ProjectService) userCanEdit(project *models.Project, userID primitive.ObjectID) bool {
+func (s *ProjectService) userCanEdit(ctx context.Context, project *models.Project, userID primitive.ObjectID) bool {
+	if checkProjectWritable(project) != nil {
+		return false
+	}
+
 	if project.OwnerID == userID {
 		return true
 	}
@@ -430,7 +715,7 @@ func (s *ProjectService) userCanEdit(project *models.Project, userID primitive.O
 		}
 	}
 
-	return false
+	return s.shareGrantRole(ctx, project.ID, userID) == "editor"
 }
 
 func (s *ProjectService) updateProjectFileStats(ctx context.Context, projectID primitive.ObjectID) {
@@ -442,13 +727,24 @@ func (s *ProjectService) updateProjectFileStats(ctx context.Context, projectID p
 
 	if err := s.projectRepo.UpdateFileStats(ctx, projectID, count, size); err != nil {
 		s.logger.Error("Failed to update file stats", zap.Error(err))
+		return
+	}
+
+	// Quota usage is the sum of the stats of all projects of the owner
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		s.logger.Error("Failed to find project", zap.Error(err))
+		return
 	}
+	s.refreshStorageUsage(ctx, project.OwnerID)
 }
 
 func getContentType(filename string) string {
 	ext := strings.ToLower(filepath.Ext(filename))
 	switch ext {
-	case ".tex":
+	case ".tex", ".ltx":
+		return "text/x-tex"
+	case ".sty", ".cls", ".bst", ".bbx", ".cbx", ".def", ".cfg", ".clo", ".dtx", ".ins":
 		return "text/x-tex"
 	case ".pdf":
 		return "application/pdf"
@@ -456,13 +752,28 @@ func getContentType(filename string) string {
 		return "image/png"
 	case ".jpg", ".jpeg":
 		return "image/jpeg"
+	case ".gif":
+		return "image/gif"
+	case ".svg":
+		return "image/svg+xml"
+	case ".eps", ".ps":
+		return "application/postscript"
 	case ".bib":
 		return "text/x-bibtex"
+	case ".txt", ".md", ".latexmkrc":
+		return "text/plain"
+	case ".csv", ".dat":
+		return "text/csv"
 	default:
 		return "application/octet-stream"
 	}
 }
 
+// isTextContentType reports whether a content type from getContentType holds text
+func isTextContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/")
+}
+
 // escapeLatex escapes special LaTeX characters in a string
 func escapeLatex(s string) string {
 	// LaTeX special characters that need escaping: \ # $ % & _ { } ~ ^
@@ -524,78 +835,3 @@ func fixLatexTitle(content string) string {
 	// Reconstruct the content with escaped title
 	return content[:titleContentStart] + escapedTitle + content[titleEnd:]
 }
-
-// MigrateLatexFiles fixes existing main.tex files with unescaped LaTeX characters
-func (s *ProjectService) MigrateLatexFiles(ctx context.Context) (int, []string, error) {
-	// Find all main.tex files
-	files, err := s.fileRepo.FindByName(ctx, "main.tex")
-	if err != nil {
-		return 0, nil, fmt.Errorf("failed to find main.tex files: %w", err)
-	}
-
-	s.logger.Info("Starting LaTeX migration",
-		zap.Int("file_count", len(files)),
-	)
-
-	fixed := 0
-	errors := []string{}
-
-	for _, file := range files {
-		// Download file content from MinIO
-		content, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
-		if err != nil {
-			errMsg := fmt.Sprintf("Failed to download %s: %v", file.StorageKey, err)
-			s.logger.Error(errMsg)
-			errors = append(errors, errMsg)
-			continue
-		}
-
-		// Fix LaTeX title
-		originalContent := string(content)
-		fixedContent := fixLatexTitle(originalContent)
-
-		// Check if content changed
-		if fixedContent == originalContent {
-			s.logger.Debug("File already escaped or no title found",
-				zap.String("file_id", file.ID.Hex()),
-				zap.String("storage_key", file.StorageKey),
-			)
-			continue
-		}
-
-		// Upload fixed content back to MinIO
-		err = s.minioClient.UploadBytes(ctx, file.StorageKey, []byte(fixedContent), "text/x-tex")
-		if err != nil {
-			errMsg := fmt.Sprintf("Failed to upload %s: %v", file.StorageKey, err)
-			s.logger.Error(errMsg)
-			errors = append(errors, errMsg)
-			continue
-		}
-
-		// Update file hash and version
-		file.Hash = fmt.Sprintf("%x", sha256.Sum256([]byte(fixedContent)))
-		file.SizeBytes = int64(len(fixedContent))
-
-		err = s.fileRepo.Update(ctx, file)
-		if err != nil {
-			errMsg := fmt.Sprintf("Failed to update file record %s: %v", file.ID.Hex(), err)
-			s.logger.Error(errMsg)
-			errors = append(errors, errMsg)
-			continue
-		}
-
-		s.logger.Info("Fixed LaTeX file",
-			zap.String("file_id", file.ID.Hex()),
-			zap.String("project_id", file.ProjectID.Hex()),
-		)
-		fixed++
-	}
-
-	s.logger.Info("LaTeX migration completed",
-		zap.Int("total_files", len(files)),
-		zap.Int("fixed", fixed),
-		zap.Int("errors", len(errors)),
-	)
-
-	return fixed, errors, nil
-}
@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// QuotaDefaults are the limits of accounts without a quota override
+type QuotaDefaults struct {
+	User         models.QuotaLimits
+	Organization models.QuotaLimits
+}
+
+// usageRefreshAttempts bounds how often a usage refresh is retried when
+// reservations change the usage meanwhile
+const usageRefreshAttempts = 3
+
+// quotaSubject is an account whose storage is limited
+type quotaSubject struct {
+	kind string
+	id   primitive.ObjectID
+}
+
+// GetStorageUsage reports the usage and limits of a user and of their
+// organization
+func (s *ProjectService) GetStorageUsage(ctx context.Context, userID primitive.ObjectID) (*models.UsageResponse, error) {
+	subjects, err := s.quotaSubjects(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.UsageResponse{}
+	for _, subject := range subjects {
+		status, err := s.quotaStatus(ctx, subject)
+		if err != nil {
+			return nil, err
+		}
+		if subject.kind == models.QuotaSubjectUser {
+			response.User = status
+		} else {
+			response.Organization = status
+		}
+	}
+
+	return response, nil
+}
+
+// GetQuota reports the usage and limits of an account
+func (s *ProjectService) GetQuota(ctx context.Context, subjectType string, subjectID primitive.ObjectID) (*models.QuotaStatus, error) {
+	if err := checkQuotaSubjectType(subjectType); err != nil {
+		return nil, err
+	}
+
+	return s.quotaStatus(ctx, quotaSubject{kind: subjectType, id: subjectID})
+}
+
+// ListQuotaOverrides lists the accounts whose quotas differ from the defaults
+func (s *ProjectService) ListQuotaOverrides(ctx context.Context) ([]*models.QuotaOverride, error) {
+	return s.quotaRepo.ListOverrides(ctx)
+}
+
+// SetQuotaOverride replaces the default limits of an account. Limits left
+// out of the request keep their default.
+func (s *ProjectService) SetQuotaOverride(ctx context.Context, subjectType string, subjectID, adminID primitive.ObjectID, req *models.SetQuotaOverrideRequest) (*models.QuotaStatus, error) {
+	if err := checkQuotaSubjectType(subjectType); err != nil {
+		return nil, err
+	}
+
+	override := &models.QuotaOverride{
+		SubjectType:      subjectType,
+		SubjectID:        subjectID,
+		MaxStorageBytes:  req.MaxStorageBytes,
+		MaxFiles:         req.MaxFiles,
+		MaxFileSizeBytes: req.MaxFileSizeBytes,
+		Reason:           req.Reason,
+		UpdatedBy:        adminID,
+	}
+	if err := s.quotaRepo.UpsertOverride(ctx, override); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Quota override set",
+		zap.String("subject_type", subjectType),
+		zap.String("subject_id", subjectID.Hex()),
+		zap.String("admin_id", adminID.Hex()),
+	)
+
+	return s.quotaStatus(ctx, quotaSubject{kind: subjectType, id: subjectID})
+}
+
+// DeleteQuotaOverride restores the default limits of an account
+func (s *ProjectService) DeleteQuotaOverride(ctx context.Context, subjectType string, subjectID primitive.ObjectID) error {
+	if err := checkQuotaSubjectType(subjectType); err != nil {
+		return err
+	}
+
+	return s.quotaRepo.DeleteOverride(ctx, subjectType, subjectID)
+}
+
+// reserveQuota checks a write against the quotas of the project owner and
+// their organization and reserves the bytes and files it adds. The returned
+// function gives the reservation back if the write fails; after a
+// successful write the usage is recomputed from the project stats.
+func (s *ProjectService) reserveQuota(ctx context.Context, ownerID primitive.ObjectID, fileSize, storageBytes, fileCount int64) (func(), error) {
+	subjects, err := s.quotaSubjects(ctx, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var reserved []quotaSubject
+	release := func() {
+		for _, subject := range reserved {
+			if _, err := s.quotaRepo.Reserve(ctx, subject.kind, subject.id, -storageBytes, -fileCount, models.QuotaLimits{}); err != nil {
+				s.logger.Error("Failed to release quota", zap.String("subject_id", subject.id.Hex()), zap.Error(err))
+			}
+		}
+	}
+
+	for _, subject := range subjects {
+		limits, _, err := s.quotaLimits(ctx, subject)
+		if err != nil {
+			release()
+			return nil, err
+		}
+
+		if limits.MaxFileSizeBytes > 0 && fileSize > limits.MaxFileSizeBytes {
+			release()
+			return nil, fmt.Errorf("file exceeds the maximum file size")
+		}
+		if storageBytes <= 0 && fileCount <= 0 {
+			continue
+		}
+
+		if _, err := s.quotaRepo.FindUsage(ctx, subject.kind, subject.id); err != nil {
+			if err.Error() != "usage not found" {
+				release()
+				return nil, err
+			}
+			if _, err := s.refreshSubjectUsage(ctx, subject); err != nil {
+				release()
+				return nil, err
+			}
+		}
+
+		ok, err := s.quotaRepo.Reserve(ctx, subject.kind, subject.id, storageBytes, fileCount, limits)
+		if err != nil {
+			release()
+			return nil, err
+		}
+		if !ok {
+			release()
+			return nil, s.quotaExceeded(ctx, subject, limits, fileCount)
+		}
+		reserved = append(reserved, subject)
+	}
+
+	return release, nil
+}
+
+// quotaExceeded tells which limit a rejected reservation would have broken
+func (s *ProjectService) quotaExceeded(ctx context.Context, subject quotaSubject, limits models.QuotaLimits, fileCount int64) error {
+	usage, err := s.quotaRepo.FindUsage(ctx, subject.kind, subject.id)
+	if err == nil && fileCount > 0 && limits.MaxFiles > 0 && usage.FileCount+fileCount > limits.MaxFiles {
+		return fmt.Errorf("file quota exceeded")
+	}
+	return fmt.Errorf("storage quota exceeded")
+}
+
+// refreshStorageUsage recomputes the usage of a project owner and their
+// organization from the project stats
+func (s *ProjectService) refreshStorageUsage(ctx context.Context, ownerID primitive.ObjectID) {
+	subjects, err := s.quotaSubjects(ctx, ownerID)
+	if err != nil {
+		s.logger.Error("Failed to find quota subjects", zap.String("user_id", ownerID.Hex()), zap.Error(err))
+		return
+	}
+
+	for _, subject := range subjects {
+		if _, err := s.refreshSubjectUsage(ctx, subject); err != nil {
+			s.logger.Error("Failed to refresh storage usage", zap.String("subject_id", subject.id.Hex()), zap.Error(err))
+		}
+	}
+}
+
+// refreshSubjectUsage recomputes the usage of an account from the project
+// stats. A reservation made while the stats are read would be lost by the
+// recomputed total, so it is then read again; the usage is left as it is if
+// reservations keep coming in.
+func (s *ProjectService) refreshSubjectUsage(ctx context.Context, subject quotaSubject) (*models.StorageUsage, error) {
+	owners := []primitive.ObjectID{subject.id}
+	if subject.kind == models.QuotaSubjectOrganization {
+		members, err := s.userRepo.FindIDsByOrganization(ctx, subject.id)
+		if err != nil {
+			return nil, err
+		}
+		owners = members
+	}
+
+	for attempt := 0; attempt < usageRefreshAttempts; attempt++ {
+		var revision int64
+		usage, err := s.quotaRepo.FindUsage(ctx, subject.kind, subject.id)
+		if err == nil {
+			revision = usage.Revision
+		} else if err.Error() != "usage not found" {
+			return nil, err
+		}
+
+		fileCount, storageBytes, err := s.projectRepo.SumFileStats(ctx, owners)
+		if err != nil {
+			return nil, err
+		}
+		ok, err := s.quotaRepo.SetUsage(ctx, subject.kind, subject.id, storageBytes, fileCount, revision)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+	}
+
+	return s.quotaRepo.FindUsage(ctx, subject.kind, subject.id)
+}
+
+func (s *ProjectService) quotaStatus(ctx context.Context, subject quotaSubject) (*models.QuotaStatus, error) {
+	limits, overridden, err := s.quotaLimits(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	usage, err := s.refreshSubjectUsage(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.QuotaStatus{
+		SubjectType: subject.kind,
+		SubjectID:   subject.id,
+		Usage:       *usage,
+		Limits:      limits,
+		Overridden:  overridden,
+	}, nil
+}
+
+// quotaLimits returns the effective limits of an account and whether an
+// override applies
+func (s *ProjectService) quotaLimits(ctx context.Context, subject quotaSubject) (models.QuotaLimits, bool, error) {
+	limits := s.quotaDefaults.User
+	if subject.kind == models.QuotaSubjectOrganization {
+		limits = s.quotaDefaults.Organization
+	}
+
+	override, err := s.quotaRepo.FindOverride(ctx, subject.kind, subject.id)
+	if err != nil {
+		if err.Error() == "quota override not found" {
+			return limits, false, nil
+		}
+		return limits, false, err
+	}
+
+	if override.MaxStorageBytes != nil {
+		limits.MaxStorageBytes = *override.MaxStorageBytes
+	}
+	if override.MaxFiles != nil {
+		limits.MaxFiles = *override.MaxFiles
+	}
+	if override.MaxFileSizeBytes != nil {
+		limits.MaxFileSizeBytes = *override.MaxFileSizeBytes
+	}
+
+	return limits, true, nil
+}
+
+// quotaSubjects lists the quotas that apply to a project owner: their own
+// and, if they belong to one, their organization's
+func (s *ProjectService) quotaSubjects(ctx context.Context, ownerID primitive.ObjectID) ([]quotaSubject, error) {
+	subjects := []quotaSubject{{kind: models.QuotaSubjectUser, id: ownerID}}
+
+	organizationID, err := s.userRepo.FindOrganizationID(ctx, ownerID)
+	if err != nil && err.Error() != "user not found" {
+		return nil, err
+	}
+	if organizationID != nil {
+		subjects = append(subjects, quotaSubject{kind: models.QuotaSubjectOrganization, id: *organizationID})
+	}
+
+	return subjects, nil
+}
+
+func checkQuotaSubjectType(subjectType string) error {
+	switch subjectType {
+	case models.QuotaSubjectUser, models.QuotaSubjectOrganization:
+		return nil
+	}
+	return fmt.Errorf("invalid quota subject")
+}
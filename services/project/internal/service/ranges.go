@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/texflow/services/project/internal/models"
+	"go.uber.org/zap"
+)
+
+// rangeMover prepares moving the comment anchors and pending suggestions of
+// a file along with an edit. It reads the current content, so it must be
+// called before the new content is stored; the returned function applies
+// the move once it is.
+func (s *ProjectService) rangeMover(ctx context.Context, file *models.File, content []byte) func() {
+	threads, err := s.commentRepo.FindOpenByFile(ctx, file.ID)
+	if err != nil {
+		s.logger.Error("Failed to find comment threads", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+	}
+	suggestions, err := s.suggestionRepo.FindPendingByFile(ctx, file.ID)
+	if err != nil {
+		s.logger.Error("Failed to find suggestions", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+	}
+	if len(threads) == 0 && len(suggestions) == 0 {
+		return func() {}
+	}
+
+	previous, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+	if err != nil {
+		s.logger.Error("Failed to read file for moving ranges", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+		return func() {}
+	}
+
+	return func() {
+		dmp := diffmatchpatch.New()
+		diffs := dmp.DiffMain(string(previous), string(content), false)
+
+		for _, thread := range threads {
+			anchor := thread.Anchor
+			anchor.Start = shiftOffset(diffs, anchor.Start, true)
+			anchor.End = shiftOffset(diffs, anchor.End, false)
+			if anchor.End < anchor.Start {
+				anchor.End = anchor.Start
+			}
+			if anchor == thread.Anchor {
+				continue
+			}
+			if err := s.commentRepo.SetAnchor(ctx, thread.ID, anchor); err != nil {
+				s.logger.Error("Failed to move comment anchor", zap.String("thread_id", thread.ID.Hex()), zap.Error(err))
+			}
+		}
+
+		// A suggestion whose deleted text was edited no longer applies,
+		// which is found when it is accepted
+		for _, suggestion := range suggestions {
+			offset := shiftOffset(diffs, suggestion.Offset, true)
+			if offset == suggestion.Offset {
+				continue
+			}
+			if err := s.suggestionRepo.SetOffset(ctx, suggestion.ID, offset); err != nil {
+				s.logger.Error("Failed to move suggestion", zap.String("suggestion_id", suggestion.ID.Hex()), zap.Error(err))
+			}
+		}
+	}
+}
+
+// shiftOffset maps a character offset in the text before an edit to the
+// text after it. Text inserted exactly at the offset ends up before it when
+// afterInsert is set and after it otherwise, so that a range does not grow
+// when text is typed at either of its ends. An offset inside deleted text
+// moves to where the deletion was.
+func shiftOffset(diffs []diffmatchpatch.Diff, offset int, afterInsert bool) int {
+	oldPos, newPos := 0, 0
+	for _, d := range diffs {
+		n := utf8.RuneCountInString(d.Text)
+		switch d.Type {
+		case diffmatchpatch.DiffEqual:
+			if offset < oldPos+n {
+				return newPos + offset - oldPos
+			}
+			oldPos += n
+			newPos += n
+		case diffmatchpatch.DiffDelete:
+			if offset < oldPos+n {
+				return newPos
+			}
+			oldPos += n
+		case diffmatchpatch.DiffInsert:
+			if oldPos == offset && !afterInsert {
+				return newPos
+			}
+			newPos += n
+		}
+	}
+	return newPos + offset - oldPos
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"github.com/texflow/services/project/internal/search"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	// MaxIndexedFileSize bounds the text files added to the search index
+	MaxIndexedFileSize = 1 << 20
+
+	maxSearchProjects  = 500
+	maxSearchDocuments = 200
+	maxMatchesPerFile  = 20
+)
+
+// SearchProject searches the text files of one project
+func (s *ProjectService) SearchProject(ctx context.Context, projectID, userID primitive.ObjectID, q string, caseSensitive bool, limit int) (*models.SearchResponse, error) {
+	query, err := search.ParseQuery(q, caseSensitive)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	return s.searchProjects(ctx, []*models.Project{project}, q, query, limit)
+}
+
+// SearchAllProjects searches the text files of every project the user owns,
+// collaborates on or opened through a share link
+func (s *ProjectService) SearchAllProjects(ctx context.Context, userID primitive.ObjectID, q string, caseSensitive bool, limit int) (*models.SearchResponse, error) {
+	query, err := search.ParseQuery(q, caseSensitive)
+	if err != nil {
+		return nil, err
+	}
+
+	grantedIDs, err := s.shareRepo.FindGrantedProjectIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := s.projectRepo.FindAccessible(ctx, userID, grantedIDs, maxSearchProjects)
+	if err != nil {
+		return nil, err
+	}
+
+	var projects []*models.Project
+	for _, project := range candidates {
+		if s.userHasAccess(ctx, project, userID) {
+			projects = append(projects, project)
+		}
+	}
+
+	return s.searchProjects(ctx, projects, q, query, limit)
+}
+
+// ReindexProject rebuilds the search index of a project and returns the
+// number of files indexed
+func (s *ProjectService) ReindexProject(ctx context.Context, projectID, userID primitive.ObjectID) (int, error) {
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	return s.rebuildSearchIndex(ctx, project.ID)
+}
+
+func (s *ProjectService) searchProjects(ctx context.Context, projects []*models.Project, q string, query *search.Query, limit int) (*models.SearchResponse, error) {
+	response := &models.SearchResponse{Query: q, Results: []*models.SearchResult{}}
+	if len(projects) == 0 {
+		return response, nil
+	}
+
+	names := make(map[primitive.ObjectID]string, len(projects))
+	ids := make([]primitive.ObjectID, 0, len(projects))
+	for _, project := range projects {
+		s.ensureSearchIndex(ctx, project)
+		names[project.ID] = project.Name
+		ids = append(ids, project.ID)
+	}
+
+	docs, err := s.searchRepo.Find(ctx, ids, query.Terms(), maxSearchDocuments+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) > maxSearchDocuments {
+		docs = docs[:maxSearchDocuments]
+		response.Truncated = true
+	}
+
+	for _, doc := range docs {
+		for _, match := range query.MatchLines(doc.Content, maxMatchesPerFile) {
+			if len(response.Results) == limit {
+				response.Truncated = true
+				return response, nil
+			}
+			response.Results = append(response.Results, &models.SearchResult{
+				ProjectID:   doc.ProjectID,
+				ProjectName: names[doc.ProjectID],
+				FileID:      doc.FileID,
+				Path:        doc.Path,
+				Match:       match,
+			})
+		}
+	}
+
+	return response, nil
+}
+
+// ensureSearchIndex builds the index of a project that has never been
+// indexed, such as one created before search existed
+func (s *ProjectService) ensureSearchIndex(ctx context.Context, project *models.Project) {
+	if project.SearchIndexedAt != nil {
+		return
+	}
+
+	if _, err := s.rebuildSearchIndex(ctx, project.ID); err != nil {
+		s.logger.Error("Failed to build search index", zap.String("project_id", project.ID.Hex()), zap.Error(err))
+	}
+}
+
+// rebuildSearchIndex indexes every text file of a project from storage
+func (s *ProjectService) rebuildSearchIndex(ctx context.Context, projectID primitive.ObjectID) (int, error) {
+	startedAt := time.Now()
+
+	if err := s.searchRepo.DeleteByProjectID(ctx, projectID); err != nil {
+		return 0, err
+	}
+
+	files, err := s.fileRepo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return 0, err
+	}
+
+	indexed := 0
+	for _, file := range files {
+		if s.indexStoredFile(ctx, file) {
+			indexed++
+		}
+	}
+
+	if err := s.projectRepo.SetSearchIndexed(ctx, projectID, startedAt); err != nil {
+		return indexed, err
+	}
+
+	s.logger.Info("Search index rebuilt",
+		zap.String("project_id", projectID.Hex()),
+		zap.Int("files", indexed),
+	)
+
+	return indexed, nil
+}
+
+// indexFile adds the content of a text file to the search and outline
+// indexes. Binary and oversized files are removed from them instead.
+func (s *ProjectService) indexFile(ctx context.Context, file *models.File, content []byte) bool {
+	if file.IsBinary || len(content) > MaxIndexedFileSize {
+		s.unindexFile(ctx, file.ID)
+		return false
+	}
+
+	s.indexOutline(ctx, file, content)
+
+	text := string(content)
+	doc := &models.SearchDocument{
+		FileID:    file.ID,
+		ProjectID: file.ProjectID,
+		Path:      file.Path,
+		Hash:      file.Hash,
+		Terms:     search.Terms(text),
+		Content:   text,
+	}
+	if err := s.searchRepo.Upsert(ctx, doc); err != nil {
+		s.logger.Error("Failed to index file", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+// indexStoredFile indexes a text file by reading its content from storage
+func (s *ProjectService) indexStoredFile(ctx context.Context, file *models.File) bool {
+	if file.IsBinary || file.SizeBytes > MaxIndexedFileSize {
+		return false
+	}
+
+	content, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+	if err != nil {
+		s.logger.Error("Failed to read file for indexing", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+		return false
+	}
+
+	return s.indexFile(ctx, file, content)
+}
+
+// reindexFilePath records that an indexed file moved
+func (s *ProjectService) reindexFilePath(ctx context.Context, file *models.File) {
+	if err := s.searchRepo.UpdatePath(ctx, file.ID, file.Path); err != nil {
+		s.logger.Error("Failed to update search index", zap.String("file_id", file.ID.Hex()), zap.Error(err))
+	}
+	s.moveOutlineFile(ctx, file)
+}
+
+// unindexFile removes a file from the search and outline indexes and drops
+// its lint warnings
+func (s *ProjectService) unindexFile(ctx context.Context, fileID primitive.ObjectID) {
+	if err := s.searchRepo.Delete(ctx, fileID); err != nil {
+		s.logger.Error("Failed to remove file from search index", zap.String("file_id", fileID.Hex()), zap.Error(err))
+	}
+	if err := s.outlineRepo.Delete(ctx, fileID); err != nil {
+		s.logger.Error("Failed to remove file from outline index", zap.String("file_id", fileID.Hex()), zap.Error(err))
+	}
+	if err := s.lintRepo.Delete(ctx, fileID); err != nil {
+		s.logger.Error("Failed to remove lint results", zap.String("file_id", fileID.Hex()), zap.Error(err))
+	}
+}
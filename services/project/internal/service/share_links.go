@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Share link tokens look like "tfs_<40 hex chars>"
+const (
+	ShareLinkPrefix       = "tfs_"
+	maxShareLinks         = 50
+	shareLinkDisplayChars = 8
+)
+
+// CreateShareLink creates a share link for a project and returns its token
+// in plain text together with the stored link
+func (s *ProjectService) CreateShareLink(ctx context.Context, projectID, ownerID primitive.ObjectID, req *models.CreateShareLinkRequest) (*models.CreateShareLinkResponse, error) {
+	if _, err := s.getOwnedProject(ctx, projectID, ownerID, "only project owner can share project"); err != nil {
+		return nil, err
+	}
+
+	links, err := s.shareRepo.FindByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	active := 0
+	for _, link := range links {
+		if link.RevokedAt == nil {
+			active++
+		}
+	}
+	if active >= maxShareLinks {
+		return nil, fmt.Errorf("share link limit reached")
+	}
+
+	raw := make([]byte, 20)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	plain := ShareLinkPrefix + hex.EncodeToString(raw)
+
+	link := &models.ShareLink{
+		ProjectID: projectID,
+		CreatedBy: ownerID,
+		Role:      req.Role,
+		TokenHash: hashShareToken(plain),
+		Prefix:    plain[:len(ShareLinkPrefix)+shareLinkDisplayChars],
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		link.ExpiresAt = &expiresAt
+	}
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash password: %w", err)
+		}
+		link.PasswordHash = string(hash)
+		link.HasPassword = true
+	}
+
+	if err := s.shareRepo.Create(ctx, link); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Share link created",
+		zap.String("project_id", projectID.Hex()),
+		zap.String("link_id", link.ID.Hex()),
+		zap.String("role", link.Role),
+	)
+
+	return &models.CreateShareLinkResponse{ShareLink: link, Token: plain}, nil
+}
+
+// ListShareLinks lists the share links of a project, including revoked ones
+func (s *ProjectService) ListShareLinks(ctx context.Context, projectID, ownerID primitive.ObjectID) ([]*models.ShareLink, error) {
+	if _, err := s.getOwnedProject(ctx, projectID, ownerID, "only project owner can share project"); err != nil {
+		return nil, err
+	}
+
+	links, err := s.shareRepo.FindByProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if links == nil {
+		links = []*models.ShareLink{}
+	}
+	return links, nil
+}
+
+// RevokeShareLink disables a share link and ends the access it granted
+func (s *ProjectService) RevokeShareLink(ctx context.Context, projectID, linkID, ownerID primitive.ObjectID) error {
+	if _, err := s.getOwnedProject(ctx, projectID, ownerID, "only project owner can share project"); err != nil {
+		return err
+	}
+
+	link, err := s.shareRepo.FindByID(ctx, linkID)
+	if err != nil {
+		return err
+	}
+	if link.ProjectID != projectID {
+		return fmt.Errorf("share link not found")
+	}
+
+	if err := s.shareRepo.Revoke(ctx, linkID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Share link revoked",
+		zap.String("project_id", projectID.Hex()),
+		zap.String("link_id", linkID.Hex()),
+	)
+	return nil
+}
+
+// ResolveShareLink checks a share link token and password and gives the user
+// the link's role on the project until the grant expires
+func (s *ProjectService) ResolveShareLink(ctx context.Context, userID primitive.ObjectID, req *models.ResolveShareLinkRequest) (*models.ShareGrant, error) {
+	link, err := s.shareRepo.FindByTokenHash(ctx, hashShareToken(req.Token))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if link.RevokedAt != nil {
+		return nil, fmt.Errorf("share link has been revoked")
+	}
+	if link.ExpiresAt != nil && !now.Before(*link.ExpiresAt) {
+		return nil, fmt.Errorf("share link has expired")
+	}
+	if link.HasPassword {
+		if req.Password == "" {
+			return nil, fmt.Errorf("password required")
+		}
+		if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(req.Password)) != nil {
+			return nil, fmt.Errorf("invalid password")
+		}
+	}
+
+	project, err := s.projectRepo.FindByID(ctx, link.ProjectID)
+	if err != nil {
+		return nil, err
+	}
+	if project.DeletedAt != nil {
+		return nil, fmt.Errorf("project is in trash")
+	}
+
+	grant := &models.ShareGrant{
+		LinkID:    link.ID,
+		ProjectID: link.ProjectID,
+		UserID:    userID,
+		Role:      link.Role,
+		ExpiresAt: now.Add(s.shareGrantTTL),
+	}
+	if link.ExpiresAt != nil && link.ExpiresAt.Before(grant.ExpiresAt) {
+		grant.ExpiresAt = *link.ExpiresAt
+	}
+
+	// The owner already has full access
+	if project.OwnerID != userID {
+		if err := s.shareRepo.UpsertGrant(ctx, grant); err != nil {
+			return nil, err
+		}
+	}
+
+	s.logger.Info("Share link resolved",
+		zap.String("project_id", link.ProjectID.Hex()),
+		zap.String("link_id", link.ID.Hex()),
+		zap.String("user_id", userID.Hex()),
+	)
+
+	return grant, nil
+}
+
+// shareGrantRole returns the strongest role the user holds on a project
+// through share links, or "" when they hold none
+func (s *ProjectService) shareGrantRole(ctx context.Context, projectID, userID primitive.ObjectID) string {
+	grants, err := s.shareRepo.FindActiveGrants(ctx, projectID, userID)
+	if err != nil {
+		s.logger.Error("Failed to look up share grants", zap.Error(err))
+		return ""
+	}
+
+	role := ""
+	for _, grant := range grants {
+		if grant.Role == "editor" {
+			return grant.Role
+		}
+		role = grant.Role
+	}
+	return role
+}
+
+func hashShareToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// ListSuggestions lists the tracked changes of a project, optionally only
+// those of one file or in one state
+func (s *ProjectService) ListSuggestions(ctx context.Context, projectID, userID primitive.ObjectID, fileID *primitive.ObjectID, status string) ([]*models.Suggestion, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.userHasAccess(ctx, project, userID) {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	switch status {
+	case "", models.SuggestionPending, models.SuggestionAccepted, models.SuggestionRejected:
+	default:
+		return nil, fmt.Errorf("invalid suggestion status")
+	}
+
+	return s.suggestionRepo.FindByProject(ctx, projectID, fileID, status)
+}
+
+// AcceptSuggestions applies pending suggestions to their files. Only the
+// project owner can accept them. Each file is written once, and suggestions
+// whose text was changed since they were made are left pending.
+func (s *ProjectService) AcceptSuggestions(ctx context.Context, projectID, userID primitive.ObjectID, req *models.ReviewSuggestionsRequest) (*models.ReviewSuggestionsResponse, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
+	if project.OwnerID != userID {
+		return nil, fmt.Errorf("only project owner can accept suggestions")
+	}
+
+	suggestions, err := s.selectSuggestions(ctx, projectID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.ReviewSuggestionsResponse{
+		Reviewed: []*models.Suggestion{},
+		Skipped:  []models.SkippedSuggestion{},
+	}
+
+	byFile := make(map[primitive.ObjectID][]*models.Suggestion)
+	var fileIDs []primitive.ObjectID
+	for _, suggestion := range suggestions {
+		if _, ok := byFile[suggestion.FileID]; !ok {
+			fileIDs = append(fileIDs, suggestion.FileID)
+		}
+		byFile[suggestion.FileID] = append(byFile[suggestion.FileID], suggestion)
+	}
+
+	for _, fileID := range fileIDs {
+		accepted, skipped := s.acceptFileSuggestions(ctx, project, fileID, byFile[fileID], userID)
+		response.Reviewed = append(response.Reviewed, accepted...)
+		response.Skipped = append(response.Skipped, skipped...)
+	}
+
+	return response, nil
+}
+
+// RejectSuggestions discards pending suggestions. The project owner can
+// reject any suggestion and other users only withdraw their own.
+func (s *ProjectService) RejectSuggestions(ctx context.Context, projectID, userID primitive.ObjectID, req *models.ReviewSuggestionsRequest) (*models.ReviewSuggestionsResponse, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkProjectWritable(project); err != nil {
+		return nil, err
+	}
+	if !s.userCanEdit(ctx, project, userID) {
+		return nil, fmt.Errorf("permission denied")
+	}
+
+	suggestions, err := s.selectSuggestions(ctx, projectID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.ReviewSuggestionsResponse{
+		Reviewed: []*models.Suggestion{},
+		Skipped:  []models.SkippedSuggestion{},
+	}
+
+	now := time.Now()
+	for _, suggestion := range suggestions {
+		if project.OwnerID != userID && suggestion.AuthorID != userID {
+			response.Skipped = append(response.Skipped, models.SkippedSuggestion{
+				ID:     suggestion.ID,
+				Reason: "only project owner can reject the suggestions of others",
+			})
+			continue
+		}
+
+		ok, err := s.suggestionRepo.Review(ctx, suggestion.ID, models.SuggestionRejected, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			response.Skipped = append(response.Skipped, models.SkippedSuggestion{ID: suggestion.ID, Reason: "suggestion already reviewed"})
+			continue
+		}
+
+		markReviewed(suggestion, models.SuggestionRejected, userID, now)
+		response.Reviewed = append(response.Reviewed, suggestion)
+	}
+
+	return response, nil
+}
+
+// suggestFileChanges records an edit as the suggestions of its author. They
+// replace the author's earlier pending suggestions on the file, since the
+// edit starts from the stored content plus those suggestions; unchanged
+// ones are kept as they were.
+func (s *ProjectService) suggestFileChanges(ctx context.Context, file *models.File, userID primitive.ObjectID, content string) ([]*models.Suggestion, error) {
+	current, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	existing, err := s.suggestionRepo.FindPendingByAuthor(ctx, file.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+	previous := make(map[string]*models.Suggestion, len(existing))
+	for _, suggestion := range existing {
+		previous[suggestionKey(suggestion)] = suggestion
+	}
+
+	author := s.lookupUsername(ctx, userID)
+	suggestions := []*models.Suggestion{}
+	var created []*models.Suggestion
+	for _, hunk := range diffSuggestions(string(current), content) {
+		key := suggestionKey(hunk)
+		if kept, ok := previous[key]; ok {
+			delete(previous, key)
+			suggestions = append(suggestions, kept)
+			continue
+		}
+
+		hunk.ProjectID = file.ProjectID
+		hunk.FileID = file.ID
+		hunk.AuthorID = userID
+		hunk.AuthorName = author
+		created = append(created, hunk)
+		suggestions = append(suggestions, hunk)
+	}
+
+	var replaced []primitive.ObjectID
+	for _, suggestion := range previous {
+		replaced = append(replaced, suggestion.ID)
+	}
+	if err := s.suggestionRepo.DeletePending(ctx, replaced); err != nil {
+		return nil, err
+	}
+	if err := s.suggestionRepo.CreateMany(ctx, created); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
+
+// acceptFileSuggestions applies the suggestions of one file in a single
+// write
+func (s *ProjectService) acceptFileSuggestions(ctx context.Context, project *models.Project, fileID primitive.ObjectID, suggestions []*models.Suggestion, userID primitive.ObjectID) ([]*models.Suggestion, []models.SkippedSuggestion) {
+	var skipped []models.SkippedSuggestion
+	skipAll := func(list []*models.Suggestion, reason string) {
+		for _, suggestion := range list {
+			skipped = append(skipped, models.SkippedSuggestion{ID: suggestion.ID, Reason: reason})
+		}
+	}
+
+	file, err := s.getProjectFile(ctx, project.ID, fileID)
+	if err != nil {
+		skipAll(suggestions, "file not found")
+		return nil, skipped
+	}
+
+	// Claim the suggestions so that a concurrent review cannot apply them
+	// twice
+	var claimed []*models.Suggestion
+	for _, suggestion := range suggestions {
+		ok, err := s.suggestionRepo.Review(ctx, suggestion.ID, models.SuggestionAccepted, userID)
+		if err != nil {
+			s.logger.Error("Failed to accept suggestion", zap.String("suggestion_id", suggestion.ID.Hex()), zap.Error(err))
+			skipAll([]*models.Suggestion{suggestion}, "failed to accept suggestion")
+			continue
+		}
+		if !ok {
+			skipAll([]*models.Suggestion{suggestion}, "suggestion already reviewed")
+			continue
+		}
+		claimed = append(claimed, suggestion)
+	}
+	if len(claimed) == 0 {
+		return nil, skipped
+	}
+
+	unclaim := func(list []*models.Suggestion, reason string) {
+		ids := make([]primitive.ObjectID, len(list))
+		for i, suggestion := range list {
+			ids[i] = suggestion.ID
+		}
+		if err := s.suggestionRepo.Unreview(ctx, ids); err != nil {
+			s.logger.Error("Failed to restore suggestions", zap.String("file_id", fileID.Hex()), zap.Error(err))
+		}
+		skipAll(list, reason)
+	}
+
+	current, err := s.minioClient.DownloadBytes(ctx, file.StorageKey)
+	if err != nil {
+		s.logger.Error("Failed to read file for suggestions", zap.String("file_id", fileID.Hex()), zap.Error(err))
+		unclaim(claimed, "failed to read file")
+		return nil, skipped
+	}
+
+	content, applied, stale := applySuggestions(string(current), claimed)
+	if len(stale) > 0 {
+		unclaim(stale, "suggestion no longer applies")
+	}
+	if len(applied) == 0 {
+		return nil, skipped
+	}
+
+	newContent := []byte(content)
+	release, err := s.reserveQuota(ctx, project.OwnerID, int64(len(newContent)), int64(len(newContent))-file.SizeBytes, 0)
+	if err != nil {
+		unclaim(applied, err.Error())
+		return nil, skipped
+	}
+	if err := s.writeFileContent(ctx, file, newContent); err != nil {
+		release()
+		s.logger.Error("Failed to apply suggestions", zap.String("file_id", fileID.Hex()), zap.Error(err))
+		unclaim(applied, "failed to update file")
+		return nil, skipped
+	}
+	s.recordFileVersion(ctx, file, userID, newContent, 0)
+	s.updateProjectFileStats(ctx, project.ID)
+	s.recordGitChanges(ctx, project.ID, userID, file.Path)
+
+	now := time.Now()
+	for _, suggestion := range applied {
+		markReviewed(suggestion, models.SuggestionAccepted, userID, now)
+	}
+
+	return applied, skipped
+}
+
+// selectSuggestions finds the pending suggestions a review request is about
+func (s *ProjectService) selectSuggestions(ctx context.Context, projectID primitive.ObjectID, req *models.ReviewSuggestionsRequest) ([]*models.Suggestion, error) {
+	if len(req.IDs) > 0 {
+		ids := make([]primitive.ObjectID, 0, len(req.IDs))
+		for _, hex := range req.IDs {
+			id, err := primitive.ObjectIDFromHex(hex)
+			if err != nil {
+				return nil, fmt.Errorf("invalid suggestion ID")
+			}
+			ids = append(ids, id)
+		}
+
+		suggestions, err := s.suggestionRepo.FindPendingByIDs(ctx, projectID, ids)
+		if err != nil {
+			return nil, err
+		}
+		if len(suggestions) == 0 {
+			return nil, fmt.Errorf("suggestion not found")
+		}
+		return suggestions, nil
+	}
+
+	if req.FileID != "" {
+		fileID, err := primitive.ObjectIDFromHex(req.FileID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file ID")
+		}
+		return s.suggestionRepo.FindByProject(ctx, projectID, &fileID, models.SuggestionPending)
+	}
+
+	return s.suggestionRepo.FindByProject(ctx, projectID, nil, models.SuggestionPending)
+}
+
+// diffSuggestions splits the changes from one text to another into
+// suggestions, one for each run of deleted and inserted text
+func diffSuggestions(from, to string) []*models.Suggestion {
+	dmp := diffmatchpatch.New()
+	diffs := dmp.DiffCleanupSemantic(dmp.DiffMain(from, to, false))
+
+	var suggestions []*models.Suggestion
+	var current *models.Suggestion
+	offset := 0
+	for _, d := range diffs {
+		if d.Type == diffmatchpatch.DiffEqual {
+			current = nil
+			offset += len([]rune(d.Text))
+			continue
+		}
+
+		if current == nil {
+			current = &models.Suggestion{Offset: offset}
+			suggestions = append(suggestions, current)
+		}
+		if d.Type == diffmatchpatch.DiffDelete {
+			current.Deleted += d.Text
+			offset += len([]rune(d.Text))
+		} else {
+			current.Inserted += d.Text
+		}
+	}
+
+	for _, suggestion := range suggestions {
+		switch {
+		case suggestion.Deleted == "":
+			suggestion.Kind = models.SuggestionInsertion
+		case suggestion.Inserted == "":
+			suggestion.Kind = models.SuggestionDeletion
+		default:
+			suggestion.Kind = models.SuggestionReplacement
+		}
+	}
+
+	return suggestions
+}
+
+// applySuggestions applies suggestions to a text from the end backwards, so
+// that every offset still refers to the original text. Suggestions whose
+// deleted text is not at their offset, or that overlap one already applied,
+// are returned as stale.
+func applySuggestions(text string, suggestions []*models.Suggestion) (string, []*models.Suggestion, []*models.Suggestion) {
+	sorted := append([]*models.Suggestion(nil), suggestions...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Offset != sorted[j].Offset {
+			return sorted[i].Offset > sorted[j].Offset
+		}
+		// Insertions at the same offset keep the order they were made in
+		return sorted[i].CreatedAt.After(sorted[j].CreatedAt)
+	})
+
+	runes := []rune(text)
+	limit := len(runes)
+	var applied, stale []*models.Suggestion
+	for _, suggestion := range sorted {
+		deleted := []rune(suggestion.Deleted)
+		end := suggestion.Offset + len(deleted)
+		if suggestion.Offset < 0 || end > limit || string(runes[suggestion.Offset:end]) != suggestion.Deleted {
+			stale = append(stale, suggestion)
+			continue
+		}
+
+		var b strings.Builder
+		b.WriteString(string(runes[:suggestion.Offset]))
+		b.WriteString(suggestion.Inserted)
+		b.WriteString(string(runes[end:]))
+		runes = []rune(b.String())
+		limit = suggestion.Offset
+		applied = append(applied, suggestion)
+	}
+
+	return string(runes), applied, stale
+}
+
+// tracksChangesOf reports whether the writes of a user to a project are
+// recorded as suggestions
+func tracksChangesOf(project *models.Project, userID primitive.ObjectID) bool {
+	return project.Settings.TrackChanges && project.OwnerID != userID
+}
+
+// checkUntrackedWrite rejects a write that cannot be recorded as
+// suggestions, such as a push or a deletion, when the project tracks the
+// changes of the user
+func checkUntrackedWrite(project *models.Project, userID primitive.ObjectID) error {
+	if tracksChangesOf(project, userID) {
+		return fmt.Errorf("changes are tracked; only the project owner can make this change")
+	}
+	return nil
+}
+
+func suggestionKey(suggestion *models.Suggestion) string {
+	return fmt.Sprintf("%d\x00%s\x00%s", suggestion.Offset, suggestion.Deleted, suggestion.Inserted)
+}
+
+func markReviewed(suggestion *models.Suggestion, status string, userID primitive.ObjectID, at time.Time) {
+	suggestion.Status = status
+	suggestion.ReviewedBy = &userID
+	suggestion.ReviewedAt = &at
+}
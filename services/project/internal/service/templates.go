@@ -0,0 +1,387 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+
+	"github.com/texflow/services/project/internal/models"
+	"github.com/texflow/services/project/internal/templates"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+// MaxTemplateThumbnailSize limits the size of a template preview image
+const MaxTemplateThumbnailSize = 2 << 20
+
+// thumbnailContentTypes are the accepted preview image formats
+var thumbnailContentTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/webp": true,
+}
+
+// SeedBuiltinTemplates creates the built-in templates, or refreshes their
+// files when the embedded sources changed
+func (s *ProjectService) SeedBuiltinTemplates(ctx context.Context) error {
+	for _, builtin := range templates.Builtins {
+		files, err := builtin.Files()
+		if err != nil {
+			return fmt.Errorf("failed to read built-in template %s: %w", builtin.Key, err)
+		}
+
+		template, err := s.templateRepo.FindByBuiltinKey(ctx, builtin.Key)
+		isNew := err != nil && err.Error() == "template not found"
+		if err != nil && !isNew {
+			return err
+		}
+		if isNew {
+			template = &models.Template{
+				ID:         primitive.NewObjectID(),
+				BuiltinKey: builtin.Key,
+				IsPublic:   true,
+			}
+		}
+
+		template.Name = builtin.Name
+		template.Description = builtin.Description
+		template.Category = builtin.Category
+		template.Compiler = builtin.Compiler
+		template.MainFile = builtin.MainFile
+
+		template.Files, template.Folders, err = s.uploadBuiltinFiles(ctx, template, files)
+		if err != nil {
+			return fmt.Errorf("failed to upload built-in template %s: %w", builtin.Key, err)
+		}
+
+		if isNew {
+			err = s.templateRepo.Create(ctx, template)
+		} else {
+			err = s.templateRepo.Update(ctx, template)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// uploadBuiltinFiles uploads the files of a built-in template that are new
+// or changed since the last seed
+func (s *ProjectService) uploadBuiltinFiles(ctx context.Context, template *models.Template, files map[string][]byte) ([]models.TemplateFile, []string, error) {
+	existing := map[string]string{}
+	for _, f := range template.Files {
+		existing[f.Path] = f.Hash
+	}
+
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	result := make([]models.TemplateFile, 0, len(paths))
+	folderSet := map[string]bool{}
+	for _, p := range paths {
+		content := files[p]
+		f := models.TemplateFile{
+			Path:       p,
+			SizeBytes:  int64(len(content)),
+			IsBinary:   !isTextContentType(getContentType(p)),
+			Hash:       fmt.Sprintf("%x", sha256.Sum256(content)),
+			StorageKey: templateStorageKey(template.ID, p),
+		}
+		if existing[p] != f.Hash {
+			if err := s.minioClient.UploadBytes(ctx, f.StorageKey, content, getContentType(p)); err != nil {
+				return nil, nil, err
+			}
+		}
+		result = append(result, f)
+
+		for dir := parentPath(p); dir != ""; dir = parentPath(dir) {
+			folderSet[dir] = true
+		}
+	}
+
+	folders := make([]string, 0, len(folderSet))
+	for dir := range folderSet {
+		folders = append(folders, dir)
+	}
+	sort.Strings(folders)
+
+	return result, folders, nil
+}
+
+// ListTemplates searches the templates visible to a user
+func (s *ProjectService) ListTemplates(ctx context.Context, filter models.TemplateFilter) ([]*models.Template, int64, error) {
+	results, total, err := s.templateRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, t := range results {
+		t.HasThumbnail = t.ThumbnailKey != ""
+	}
+
+	return results, total, nil
+}
+
+// GetTemplate returns a template visible to a user
+func (s *ProjectService) GetTemplate(ctx context.Context, templateID, userID primitive.ObjectID) (*models.Template, error) {
+	template, err := s.templateRepo.FindByID(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Private templates are hidden rather than forbidden
+	if !template.IsPublic && template.OwnerID != userID {
+		return nil, fmt.Errorf("template not found")
+	}
+
+	template.HasThumbnail = template.ThumbnailKey != ""
+	return template, nil
+}
+
+// PublishTemplate snapshots the files of a project into a new template.
+// Later edits to the project do not change the template.
+func (s *ProjectService) PublishTemplate(ctx context.Context, projectID, userID primitive.ObjectID, req *models.PublishTemplateRequest) (*models.Template, error) {
+	project, err := s.projectRepo.FindByID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.OwnerID != userID {
+		return nil, fmt.Errorf("only project owner can publish a template")
+	}
+
+	files, err := s.fileRepo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	folders, err := s.folderRepo.FindByProjectID(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	compiler := req.Compiler
+	if compiler == "" {
+		compiler = project.Settings.Compiler
+	}
+
+	template := &models.Template{
+		ID:              primitive.NewObjectID(),
+		Name:            req.Name,
+		Description:     req.Description,
+		Category:        req.Category,
+		Compiler:        compiler,
+		MainFile:        project.Settings.MainFile,
+		Tags:            req.Tags,
+		OwnerID:         userID,
+		SourceProjectID: &projectID,
+		IsPublic:        req.IsPublic,
+		Files:           make([]models.TemplateFile, 0, len(files)),
+	}
+	for _, folder := range folders {
+		template.Folders = append(template.Folders, folder.Path)
+	}
+
+	for _, file := range files {
+		key := templateStorageKey(template.ID, file.Path)
+		if err := s.minioClient.CopyFile(ctx, file.StorageKey, key); err != nil {
+			s.deleteTemplateObjects(ctx, template)
+			return nil, fmt.Errorf("failed to copy %s: %w", file.Path, err)
+		}
+		template.Files = append(template.Files, models.TemplateFile{
+			Path:       file.Path,
+			SizeBytes:  file.SizeBytes,
+			IsBinary:   file.IsBinary,
+			Hash:       file.Hash,
+			StorageKey: key,
+		})
+	}
+
+	if err := s.templateRepo.Create(ctx, template); err != nil {
+		s.deleteTemplateObjects(ctx, template)
+		return nil, err
+	}
+
+	s.logger.Info("Template published",
+		zap.String("template_id", template.ID.Hex()),
+		zap.String("project_id", projectID.Hex()),
+		zap.Int("files", len(template.Files)),
+	)
+
+	return template, nil
+}
+
+// UpdateTemplate updates the metadata of a user's template
+func (s *ProjectService) UpdateTemplate(ctx context.Context, templateID, userID primitive.ObjectID, req *models.UpdateTemplateRequest) (*models.Template, error) {
+	template, err := s.getOwnedTemplate(ctx, templateID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		template.Name = *req.Name
+	}
+	if req.Description != nil {
+		template.Description = *req.Description
+	}
+	if req.Category != nil {
+		template.Category = *req.Category
+	}
+	if req.Compiler != nil {
+		template.Compiler = *req.Compiler
+	}
+	if req.Tags != nil {
+		template.Tags = req.Tags
+	}
+	if req.IsPublic != nil {
+		template.IsPublic = *req.IsPublic
+	}
+
+	if err := s.templateRepo.Update(ctx, template); err != nil {
+		return nil, err
+	}
+
+	template.HasThumbnail = template.ThumbnailKey != ""
+	return template, nil
+}
+
+// DeleteTemplate deletes a user's template and its files. Projects created
+// from it keep their copies.
+func (s *ProjectService) DeleteTemplate(ctx context.Context, templateID, userID primitive.ObjectID) error {
+	template, err := s.getOwnedTemplate(ctx, templateID, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.templateRepo.Delete(ctx, templateID); err != nil {
+		return err
+	}
+	s.deleteTemplateObjects(ctx, template)
+
+	return nil
+}
+
+// SetTemplateThumbnail stores the preview image of a user's template
+func (s *ProjectService) SetTemplateThumbnail(ctx context.Context, templateID, userID primitive.ObjectID, image []byte) (*models.Template, error) {
+	template, err := s.getOwnedTemplate(ctx, templateID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	contentType := http.DetectContentType(image)
+	if !thumbnailContentTypes[contentType] {
+		return nil, fmt.Errorf("thumbnail must be a PNG, JPEG or WebP image")
+	}
+
+	key := fmt.Sprintf("templates/%s/thumbnail", templateID.Hex())
+	if err := s.minioClient.UploadBytes(ctx, key, image, contentType); err != nil {
+		return nil, fmt.Errorf("failed to upload thumbnail: %w", err)
+	}
+
+	template.ThumbnailKey = key
+	if err := s.templateRepo.Update(ctx, template); err != nil {
+		return nil, err
+	}
+
+	template.HasThumbnail = true
+	return template, nil
+}
+
+// GetTemplateThumbnail returns the preview image of a template
+func (s *ProjectService) GetTemplateThumbnail(ctx context.Context, templateID, userID primitive.ObjectID) ([]byte, error) {
+	template, err := s.GetTemplate(ctx, templateID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if template.ThumbnailKey == "" {
+		return nil, fmt.Errorf("thumbnail not found")
+	}
+
+	return s.minioClient.DownloadBytes(ctx, template.ThumbnailKey)
+}
+
+// copyTemplateFiles deep-copies the folders and files of a template into a
+// new project
+func (s *ProjectService) copyTemplateFiles(ctx context.Context, project *models.Project, userID primitive.ObjectID, template *models.Template) error {
+	for _, folder := range template.Folders {
+		if err := s.ensureFolders(ctx, project.ID, userID, folder); err != nil {
+			return err
+		}
+	}
+
+	for _, tf := range template.Files {
+		if err := s.ensureFolders(ctx, project.ID, userID, parentPath(tf.Path)); err != nil {
+			return err
+		}
+
+		name := path.Base(tf.Path)
+		file := &models.File{
+			ProjectID:   project.ID,
+			Name:        name,
+			Path:        tf.Path,
+			ContentType: getContentType(name),
+			SizeBytes:   tf.SizeBytes,
+			StorageKey:  newFileObjectKey(project.ID, tf.Path),
+			CreatedBy:   userID,
+			IsBinary:    tf.IsBinary,
+			Hash:        tf.Hash,
+		}
+		if err := s.minioClient.CopyFile(ctx, tf.StorageKey, file.StorageKey); err != nil {
+			return fmt.Errorf("failed to copy %s: %w", tf.Path, err)
+		}
+		if err := s.fileRepo.Create(ctx, file); err != nil {
+			return err
+		}
+		s.recordCopiedFileVersion(ctx, file, userID, tf.StorageKey)
+		s.indexStoredFile(ctx, file)
+	}
+
+	if err := s.templateRepo.IncrementUsage(ctx, template.ID); err != nil {
+		s.logger.Error("Failed to count template usage", zap.String("template_id", template.ID.Hex()), zap.Error(err))
+	}
+
+	return nil
+}
+
+func (s *ProjectService) getOwnedTemplate(ctx context.Context, templateID, userID primitive.ObjectID) (*models.Template, error) {
+	template, err := s.GetTemplate(ctx, templateID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if template.BuiltinKey != "" {
+		return nil, fmt.Errorf("built-in templates cannot be modified")
+	}
+	if template.OwnerID != userID {
+		return nil, fmt.Errorf("permission denied")
+	}
+
+	return template, nil
+}
+
+// deleteTemplateObjects deletes the stored files and thumbnail of a template
+func (s *ProjectService) deleteTemplateObjects(ctx context.Context, template *models.Template) {
+	prefix := fmt.Sprintf("templates/%s/", template.ID.Hex())
+	objects, err := s.minioClient.ListObjects(ctx, prefix)
+	if err != nil {
+		s.logger.Error("Failed to list template objects", zap.String("template_id", template.ID.Hex()), zap.Error(err))
+		return
+	}
+
+	for _, obj := range objects {
+		if err := s.minioClient.DeleteFile(ctx, obj.Key); err != nil {
+			s.logger.Error("Failed to delete template object", zap.String("key", obj.Key), zap.Error(err))
+		}
+	}
+}
+
+func templateStorageKey(templateID primitive.ObjectID, filePath string) string {
+	return fmt.Sprintf("templates/%s/files/%s", templateID.Hex(), filePath)
+}
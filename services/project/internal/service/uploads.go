@@ -0,0 +1,383 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/texflow/services/project/internal/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.uber.org/zap"
+)
+
+const (
+	// UploadChunkSize is the size of every chunk of a resumable upload but
+	// the last. Object storage requires at least 5 MiB to compose chunks.
+	UploadChunkSize = 8 << 20
+
+	uploadSessionTTL = 24 * time.Hour
+
+	// sniffLength is the number of leading bytes inspected to tell text from
+	// binary content
+	sniffLength = 512
+)
+
+// MaxUploadSize returns the largest file that can be uploaded, in bytes
+func (s *ProjectService) MaxUploadSize() int64 {
+	return s.maxUploadSize
+}
+
+// UploadFile stores a file streamed from r, such as the file part of a
+// multipart form, without buffering it in memory
+func (s *ProjectService) UploadFile(ctx context.Context, projectID, userID primitive.ObjectID, filePath string, r io.Reader) (*models.File, error) {
+	project, cleanPath, err := s.prepareUpload(ctx, projectID, userID, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReaderSize(r, sniffLength)
+	head, _ := br.Peek(sniffLength)
+	isBinary := sniffBinary(head)
+
+	hasher := sha256.New()
+	var size byteCounter
+	body := io.TeeReader(io.LimitReader(br, s.maxUploadSize+1), io.MultiWriter(hasher, &size))
+
+	// The object has its own key until the record claims the path, so a
+	// concurrent upload to the same path cannot overwrite it
+	storageKey := newFileObjectKey(projectID, cleanPath)
+	if err := s.minioClient.UploadFile(ctx, storageKey, body, -1, getContentType(cleanPath)); err != nil {
+		return nil, err
+	}
+	if int64(size) > s.maxUploadSize {
+		s.deleteObject(ctx, storageKey)
+		return nil, fmt.Errorf("file exceeds the maximum upload size")
+	}
+
+	// The size of a streamed file is only known once it is stored
+	release, err := s.reserveQuota(ctx, project.OwnerID, int64(size), int64(size), 1)
+	if err != nil {
+		s.deleteObject(ctx, storageKey)
+		return nil, err
+	}
+
+	file, err := s.createUploadedFile(ctx, projectID, userID, cleanPath, storageKey, int64(size), fmt.Sprintf("%x", hasher.Sum(nil)), isBinary)
+	if err != nil {
+		release()
+		return nil, err
+	}
+
+	s.updateProjectFileStats(ctx, projectID)
+	s.recordGitChanges(ctx, projectID, userID, cleanPath)
+
+	return file, nil
+}
+
+// CreateUpload starts a resumable upload
+func (s *ProjectService) CreateUpload(ctx context.Context, projectID, userID primitive.ObjectID, req *models.CreateUploadRequest) (*models.UploadSession, error) {
+	if req.SizeBytes > s.maxUploadSize {
+		return nil, fmt.Errorf("file exceeds the maximum upload size")
+	}
+
+	project, cleanPath, err := s.prepareUpload(ctx, projectID, userID, req.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fail early rather than after the chunks are sent; the quota is only
+	// reserved when the upload completes
+	release, err := s.reserveQuota(ctx, project.OwnerID, req.SizeBytes, req.SizeBytes, 1)
+	if err != nil {
+		return nil, err
+	}
+	release()
+
+	upload := &models.UploadSession{
+		ProjectID:   projectID,
+		UserID:      userID,
+		Name:        path.Base(cleanPath),
+		Path:        cleanPath,
+		SizeBytes:   req.SizeBytes,
+		ChunkSize:   UploadChunkSize,
+		TotalChunks: int((req.SizeBytes + UploadChunkSize - 1) / UploadChunkSize),
+		ExpiresAt:   time.Now().Add(uploadSessionTTL),
+	}
+	if err := s.uploadRepo.Create(ctx, upload); err != nil {
+		return nil, err
+	}
+
+	return upload, nil
+}
+
+// GetUpload returns a resumable upload, listing the chunks received so far
+// so that an interrupted client knows what to resend
+func (s *ProjectService) GetUpload(ctx context.Context, projectID, uploadID, userID primitive.ObjectID) (*models.UploadSession, error) {
+	return s.getUpload(ctx, projectID, uploadID, userID)
+}
+
+// UploadChunk stores one chunk of a resumable upload. Chunks may be sent in
+// any order, and resending a chunk replaces it.
+func (s *ProjectService) UploadChunk(ctx context.Context, projectID, uploadID, userID primitive.ObjectID, index int, r io.Reader, size int64) (*models.UploadSession, error) {
+	upload, err := s.getUpload(ctx, projectID, uploadID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if index < 0 || index >= upload.TotalChunks {
+		return nil, fmt.Errorf("invalid chunk index")
+	}
+	if size != chunkLength(upload, index) {
+		return nil, fmt.Errorf("chunk has the wrong size")
+	}
+
+	if err := s.minioClient.UploadFile(ctx, uploadChunkKey(upload.ID, index), r, size, "application/octet-stream"); err != nil {
+		return nil, err
+	}
+
+	return s.uploadRepo.AddChunk(ctx, upload.ID, index)
+}
+
+// CompleteUpload joins the chunks of a resumable upload into a new file
+func (s *ProjectService) CompleteUpload(ctx context.Context, projectID, uploadID, userID primitive.ObjectID) (*models.File, error) {
+	upload, err := s.getUpload(ctx, projectID, uploadID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(upload.ReceivedChunks) < upload.TotalChunks {
+		return nil, fmt.Errorf("upload is incomplete")
+	}
+
+	// The project or path may have changed since the upload started
+	project, _, err := s.prepareUpload(ctx, projectID, userID, upload.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := s.reserveQuota(ctx, project.OwnerID, upload.SizeBytes, upload.SizeBytes, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	chunks := make([]string, upload.TotalChunks)
+	for i := range chunks {
+		chunks[i] = uploadChunkKey(upload.ID, i)
+	}
+
+	storageKey := newFileObjectKey(projectID, upload.Path)
+	if err := s.minioClient.ComposeFile(ctx, storageKey, chunks); err != nil {
+		release()
+		return nil, err
+	}
+
+	size, hash, isBinary, err := s.inspectObject(ctx, storageKey)
+	if err != nil {
+		release()
+		s.deleteObject(ctx, storageKey)
+		return nil, err
+	}
+	if size != upload.SizeBytes {
+		release()
+		s.deleteObject(ctx, storageKey)
+		return nil, fmt.Errorf("upload is incomplete")
+	}
+
+	file, err := s.createUploadedFile(ctx, projectID, userID, upload.Path, storageKey, size, hash, isBinary)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	s.discardUpload(ctx, upload)
+
+	s.updateProjectFileStats(ctx, projectID)
+	s.recordGitChanges(ctx, projectID, userID, upload.Path)
+
+	return file, nil
+}
+
+// AbortUpload cancels a resumable upload and deletes its chunks
+func (s *ProjectService) AbortUpload(ctx context.Context, projectID, uploadID, userID primitive.ObjectID) error {
+	upload, err := s.getUpload(ctx, projectID, uploadID, userID)
+	if err != nil {
+		return err
+	}
+
+	s.discardUpload(ctx, upload)
+	return nil
+}
+
+// PurgeExpiredUploads deletes abandoned resumable uploads and returns how
+// many were removed
+func (s *ProjectService) PurgeExpiredUploads(ctx context.Context) (int, error) {
+	uploads, err := s.uploadRepo.FindExpired(ctx, time.Now(), 100)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, upload := range uploads {
+		s.discardUpload(ctx, upload)
+	}
+
+	return len(uploads), nil
+}
+
+// OpenFile opens a file for streaming. The caller must close the reader.
+func (s *ProjectService) OpenFile(ctx context.Context, projectID, fileID, userID primitive.ObjectID) (*models.File, io.ReadSeekCloser, error) {
+	file, err := s.getReadableFile(ctx, projectID, fileID, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	object, err := s.minioClient.DownloadFile(ctx, file.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, object, nil
+}
+
+// prepareUpload checks that a file can be uploaded to filePath and creates
+// its parent folders
+func (s *ProjectService) prepareUpload(ctx context.Context, projectID, userID primitive.ObjectID, filePath string) (*models.Project, string, error) {
+	project, err := s.getEditableProject(ctx, projectID, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	cleanPath, err := cleanFilePath(filePath)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.checkPathFree(ctx, projectID, cleanPath); err != nil {
+		return nil, "", err
+	}
+	if err := s.ensureFolders(ctx, projectID, userID, parentPath(cleanPath)); err != nil {
+		return nil, "", err
+	}
+
+	return project, cleanPath, nil
+}
+
+// createUploadedFile creates the record of a file already in storage under
+// a key of its own. The object is deleted if the record cannot be created,
+// such as when another file took the path meanwhile.
+func (s *ProjectService) createUploadedFile(
+	ctx context.Context,
+	projectID, userID primitive.ObjectID,
+	cleanPath, storageKey string,
+	size int64,
+	hash string,
+	isBinary bool,
+) (*models.File, error) {
+	file := &models.File{
+		ProjectID:   projectID,
+		Name:        path.Base(cleanPath),
+		Path:        cleanPath,
+		ContentType: getContentType(cleanPath),
+		SizeBytes:   size,
+		StorageKey:  storageKey,
+		CreatedBy:   userID,
+		IsBinary:    isBinary,
+		Hash:        hash,
+	}
+
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		s.deleteObject(ctx, storageKey)
+		return nil, err
+	}
+	s.recordCopiedFileVersion(ctx, file, userID, storageKey)
+	s.indexStoredFile(ctx, file)
+
+	s.logger.Info("File uploaded",
+		zap.String("project_id", projectID.Hex()),
+		zap.String("path", cleanPath),
+		zap.Int64("size", size),
+		zap.Bool("binary", isBinary),
+	)
+
+	return file, nil
+}
+
+// inspectObject streams a stored object to compute its size and hash and
+// sniff whether it is binary
+func (s *ProjectService) inspectObject(ctx context.Context, storageKey string) (int64, string, bool, error) {
+	object, err := s.minioClient.DownloadFile(ctx, storageKey)
+	if err != nil {
+		return 0, "", false, err
+	}
+	defer object.Close()
+
+	br := bufio.NewReaderSize(object, sniffLength)
+	head, _ := br.Peek(sniffLength)
+
+	hasher := sha256.New()
+	size, err := io.Copy(hasher, br)
+	if err != nil {
+		return 0, "", false, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	return size, fmt.Sprintf("%x", hasher.Sum(nil)), sniffBinary(head), nil
+}
+
+// getUpload finds an unexpired upload that belongs to the user and project
+func (s *ProjectService) getUpload(ctx context.Context, projectID, uploadID, userID primitive.ObjectID) (*models.UploadSession, error) {
+	upload, err := s.uploadRepo.FindByID(ctx, uploadID)
+	if err != nil {
+		return nil, err
+	}
+
+	if upload.ProjectID != projectID || upload.UserID != userID || time.Now().After(upload.ExpiresAt) {
+		return nil, fmt.Errorf("upload not found")
+	}
+
+	return upload, nil
+}
+
+// discardUpload deletes the chunks and record of an upload
+func (s *ProjectService) discardUpload(ctx context.Context, upload *models.UploadSession) {
+	for _, index := range upload.ReceivedChunks {
+		s.deleteObject(ctx, uploadChunkKey(upload.ID, index))
+	}
+
+	if err := s.uploadRepo.Delete(ctx, upload.ID); err != nil {
+		s.logger.Error("Failed to delete upload", zap.String("upload_id", upload.ID.Hex()), zap.Error(err))
+	}
+}
+
+func (s *ProjectService) deleteObject(ctx context.Context, key string) {
+	if err := s.minioClient.DeleteFile(ctx, key); err != nil {
+		s.logger.Error("Failed to delete object", zap.String("key", key), zap.Error(err))
+	}
+}
+
+// chunkLength returns the size of a chunk; only the last may be short
+func chunkLength(upload *models.UploadSession, index int) int64 {
+	if index == upload.TotalChunks-1 {
+		return upload.SizeBytes - int64(index)*upload.ChunkSize
+	}
+	return upload.ChunkSize
+}
+
+func uploadChunkKey(uploadID primitive.ObjectID, index int) string {
+	return fmt.Sprintf("uploads/%s/%05d", uploadID.Hex(), index)
+}
+
+// sniffBinary reports whether content starting with head is binary. Text is
+// recognised by its bytes rather than its extension, so a PNG named .tex is
+// still treated as binary.
+func sniffBinary(head []byte) bool {
+	return !strings.HasPrefix(http.DetectContentType(head), "text/")
+}
+
+// byteCounter counts the bytes written to it
+type byteCounter int64
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	*c += byteCounter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,182 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/format/objfile"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+// gitObjectCacheSize bounds the objects kept in memory during one request
+const gitObjectCacheSize = 64 * cache.MiByte
+
+// GitObjectStore keeps the Git objects of a project in MinIO, one
+// zlib-compressed loose object per key as in a .git/objects directory.
+// It implements go-git's storer.EncodedObjectStorer and is meant to live
+// for a single request, since the storer interface carries no context.
+type GitObjectStore struct {
+	ctx    context.Context
+	client *MinIOClient
+	prefix string
+	cache  *cache.ObjectLRU
+}
+
+// NewGitObjectStore creates an object store for the given project
+func NewGitObjectStore(ctx context.Context, client *MinIOClient, projectID string) *GitObjectStore {
+	return &GitObjectStore{
+		ctx:    ctx,
+		client: client,
+		prefix: fmt.Sprintf("projects/%s/git/objects/", projectID),
+		cache:  cache.NewObjectLRU(gitObjectCacheSize),
+	}
+}
+
+// NewEncodedObject returns an empty in-memory object
+func (s *GitObjectStore) NewEncodedObject() plumbing.EncodedObject {
+	return &plumbing.MemoryObject{}
+}
+
+// SetEncodedObject stores an object under its hash
+func (s *GitObjectStore) SetEncodedObject(obj plumbing.EncodedObject) (plumbing.Hash, error) {
+	hash := obj.Hash()
+	if _, ok := s.cache.Get(hash); ok {
+		return hash, nil // Objects are immutable, a cached one is already stored
+	}
+
+	reader, err := obj.Reader()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	defer reader.Close()
+
+	var buf bytes.Buffer
+	w := objfile.NewWriter(&buf)
+	if err := w.WriteHeader(obj.Type(), obj.Size()); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if _, err := io.Copy(w, reader); err != nil {
+		return plumbing.ZeroHash, err
+	}
+	if err := w.Close(); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	if err := s.client.UploadBytes(s.ctx, s.key(hash), buf.Bytes(), "application/x-git-loose-object"); err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	s.cache.Put(obj)
+	return hash, nil
+}
+
+// EncodedObject reads an object, returning plumbing.ErrObjectNotFound if it
+// does not exist or is not of type t
+func (s *GitObjectStore) EncodedObject(t plumbing.ObjectType, hash plumbing.Hash) (plumbing.EncodedObject, error) {
+	obj, ok := s.cache.Get(hash)
+	if !ok {
+		var err error
+		obj, err = s.download(hash)
+		if err != nil {
+			return nil, err
+		}
+		s.cache.Put(obj)
+	}
+
+	if t != plumbing.AnyObject && obj.Type() != t {
+		return nil, plumbing.ErrObjectNotFound
+	}
+
+	return obj, nil
+}
+
+func (s *GitObjectStore) download(hash plumbing.Hash) (plumbing.EncodedObject, error) {
+	data, err := s.client.DownloadBytes(s.ctx, s.key(hash))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, plumbing.ErrObjectNotFound
+		}
+		return nil, err
+	}
+
+	r, err := objfile.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	t, size, err := r.Header()
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &plumbing.MemoryObject{}
+	obj.SetType(t)
+	obj.SetSize(size)
+	if _, err := io.Copy(obj, r); err != nil {
+		return nil, err
+	}
+
+	if obj.Hash() != hash {
+		return nil, fmt.Errorf("corrupt git object %s", hash)
+	}
+
+	return obj, nil
+}
+
+// IterEncodedObjects iterates over all stored objects of type t
+func (s *GitObjectStore) IterEncodedObjects(t plumbing.ObjectType) (storer.EncodedObjectIter, error) {
+	objects, err := s.client.ListObjects(s.ctx, s.prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]plumbing.Hash, 0, len(objects))
+	for _, object := range objects {
+		name := object.Key[len(s.prefix):]
+		if plumbing.IsHash(name) {
+			hashes = append(hashes, plumbing.NewHash(name))
+		}
+	}
+
+	return storer.NewEncodedObjectLookupIter(s, t, hashes), nil
+}
+
+// HasEncodedObject returns nil if the object exists
+func (s *GitObjectStore) HasEncodedObject(hash plumbing.Hash) error {
+	if _, ok := s.cache.Get(hash); ok {
+		return nil
+	}
+
+	exists, err := s.client.FileExists(s.ctx, s.key(hash))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return plumbing.ErrObjectNotFound
+	}
+
+	return nil
+}
+
+// EncodedObjectSize returns the inflated size of an object
+func (s *GitObjectStore) EncodedObjectSize(hash plumbing.Hash) (int64, error) {
+	obj, err := s.EncodedObject(plumbing.AnyObject, hash)
+	if err != nil {
+		return 0, err
+	}
+	return obj.Size(), nil
+}
+
+// AddAlternate is not supported, every project has its own objects
+func (s *GitObjectStore) AddAlternate(remote string) error {
+	return fmt.Errorf("alternates are not supported")
+}
+
+func (s *GitObjectStore) key(hash plumbing.Hash) string {
+	return s.prefix + hash.String()
+}
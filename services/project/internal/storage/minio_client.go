@@ -3,8 +3,10 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 	"time"
 
 	"github.com/minio/minio-go/v7"
@@ -154,6 +156,28 @@ func (m *MinIOClient) ListObjects(ctx context.Context, prefix string) ([]minio.O
 	return objects, nil
 }
 
+// ListPrefixes lists the "directories" directly below a prefix, such as
+// the project IDs under "projects/"
+func (m *MinIOClient) ListPrefixes(ctx context.Context, prefix string) ([]string, error) {
+	var prefixes []string
+
+	objectCh := m.client.ListObjects(ctx, m.bucket, minio.ListObjectsOptions{
+		Prefix:    prefix,
+		Recursive: false,
+	})
+
+	for object := range objectCh {
+		if object.Err != nil {
+			return nil, fmt.Errorf("failed to list objects: %w", object.Err)
+		}
+		if strings.HasSuffix(object.Key, "/") {
+			prefixes = append(prefixes, object.Key)
+		}
+	}
+
+	return prefixes, nil
+}
+
 // CopyFile copies a file within MinIO
 func (m *MinIOClient) CopyFile(ctx context.Context, sourceObject, destObject string) error {
 	src := minio.CopySrcOptions{
@@ -187,3 +211,29 @@ func (m *MinIOClient) FileExists(ctx context.Context, objectName string) (bool,
 
 	return true, nil
 }
+
+// IsNotFound reports whether err means that an object does not exist
+func IsNotFound(err error) bool {
+	var errResponse minio.ErrorResponse
+	return errors.As(err, &errResponse) && errResponse.Code == "NoSuchKey"
+}
+
+// ComposeFile concatenates source objects into destObject on the server.
+// Every source but the last must be at least 5 MiB.
+func (m *MinIOClient) ComposeFile(ctx context.Context, destObject string, sourceObjects []string) error {
+	srcs := make([]minio.CopySrcOptions, 0, len(sourceObjects))
+	for _, source := range sourceObjects {
+		srcs = append(srcs, minio.CopySrcOptions{Bucket: m.bucket, Object: source})
+	}
+
+	dst := minio.CopyDestOptions{
+		Bucket: m.bucket,
+		Object: destObject,
+	}
+
+	if _, err := m.client.ComposeObject(ctx, dst, srcs...); err != nil {
+		return fmt.Errorf("failed to compose file: %w", err)
+	}
+
+	return nil
+}
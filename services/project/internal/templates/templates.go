@@ -0,0 +1,81 @@
+// Package templates embeds the built-in project templates
+package templates
+
+import (
+	"embed"
+	"io/fs"
+	"path"
+)
+
+//go:embed builtin
+var builtinFS embed.FS
+
+// Builtin describes a template shipped with the service. Its files live in
+// builtin/<Key>.
+type Builtin struct {
+	Key         string
+	Name        string
+	Description string
+	Category    string
+	Compiler    string
+	MainFile    string
+}
+
+// Builtins lists the built-in templates in gallery order
+var Builtins = []Builtin{
+	{
+		Key:         "article",
+		Name:        "Article",
+		Description: "A journal or conference article with abstract and bibliography",
+		Category:    "article",
+		Compiler:    "pdflatex",
+		MainFile:    "main.tex",
+	},
+	{
+		Key:         "beamer",
+		Name:        "Beamer Presentation",
+		Description: "Slides for a talk using the Beamer class",
+		Category:    "presentation",
+		Compiler:    "pdflatex",
+		MainFile:    "main.tex",
+	},
+	{
+		Key:         "thesis",
+		Name:        "Thesis",
+		Description: "A thesis or dissertation with a title page and one file per chapter",
+		Category:    "thesis",
+		Compiler:    "pdflatex",
+		MainFile:    "main.tex",
+	},
+	{
+		Key:         "letter",
+		Name:        "Letter",
+		Description: "A formal letter",
+		Category:    "letter",
+		Compiler:    "pdflatex",
+		MainFile:    "main.tex",
+	},
+}
+
+// Files returns the files of a built-in template keyed by project path
+func (b Builtin) Files() (map[string][]byte, error) {
+	root := path.Join("builtin", b.Key)
+	files := map[string][]byte{}
+
+	err := fs.WalkDir(builtinFS, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := builtinFS.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[p[len(root)+1:]] = content
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
@@ -15,10 +15,13 @@ import (
 	"github.com/texflow/services/websocket/internal/config"
 	"github.com/texflow/services/websocket/internal/handlers"
 	"github.com/texflow/services/websocket/internal/middleware"
+	"github.com/texflow/services/websocket/internal/repository"
 	ws "github.com/texflow/services/websocket/internal/websocket"
 	"github.com/texflow/services/websocket/pkg/auth"
 	"github.com/texflow/services/websocket/pkg/logger"
 	"github.com/texflow/services/websocket/pkg/metrics"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.uber.org/zap"
 )
 
@@ -43,6 +46,15 @@ func main() {
 		zap.String("port", cfg.Port),
 	)
 
+	// Connect to MongoDB, used to authorize project access
+	mongoClient, err := connectMongoDB(cfg.MongoURI, log)
+	if err != nil {
+		log.Fatal("Failed to connect to MongoDB", zap.Error(err))
+	}
+	defer mongoClient.Disconnect(context.Background())
+
+	projectRepo := repository.NewProjectRepository(mongoClient.Database(cfg.MongoDatabase))
+
 	// Connect to Redis
 	redisClient := redis.NewClient(&redis.Options{
 		Addr:     cfg.RedisAddr,
@@ -74,7 +86,7 @@ func main() {
 	log.Info("WebSocket hub started")
 
 	// Initialize handlers
-	wsHandler := handlers.NewWebSocketHandler(hub, log)
+	wsHandler := handlers.NewWebSocketHandler(hub, projectRepo, log)
 
 	// Initialize metrics
 	metricsInst := metrics.NewMetrics("websocket_service")
@@ -116,6 +128,29 @@ func main() {
 	log.Info("Server exited")
 }
 
+func connectMongoDB(uri string, log *zap.Logger) (*mongo.Client, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	clientOptions := options.Client().
+		ApplyURI(uri).
+		SetMaxPoolSize(50).
+		SetServerSelectionTimeout(5 * time.Second)
+
+	client, err := mongo.Connect(ctx, clientOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ping to verify connection
+	if err := client.Ping(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	log.Info("Connected to MongoDB successfully")
+	return client, nil
+}
+
 func setupRouter(
 	wsHandler *handlers.WebSocketHandler,
 	jwtValidator *auth.JWTValidator,
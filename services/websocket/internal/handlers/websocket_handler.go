@@ -8,7 +8,10 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/texflow/services/websocket/internal/models"
+	"github.com/texflow/services/websocket/internal/repository"
 	ws "github.com/texflow/services/websocket/internal/websocket"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.uber.org/zap"
 )
 
@@ -23,15 +26,17 @@ var upgrader = websocket.Upgrader{
 
 // WebSocketHandler handles WebSocket connections
 type WebSocketHandler struct {
-	hub    *ws.Hub
-	logger *zap.Logger
+	hub         *ws.Hub
+	projectRepo *repository.ProjectRepository
+	logger      *zap.Logger
 }
 
 // NewWebSocketHandler creates a new WebSocket handler
-func NewWebSocketHandler(hub *ws.Hub, logger *zap.Logger) *WebSocketHandler {
+func NewWebSocketHandler(hub *ws.Hub, projectRepo *repository.ProjectRepository, logger *zap.Logger) *WebSocketHandler {
 	return &WebSocketHandler{
-		hub:    hub,
-		logger: logger,
+		hub:         hub,
+		projectRepo: projectRepo,
+		logger:      logger,
 	}
 }
 
@@ -50,6 +55,30 @@ func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
 		return
 	}
 
+	projectOID, err := primitive.ObjectIDFromHex(projectID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid project ID"})
+		return
+	}
+	userOID, err := primitive.ObjectIDFromHex(userID.(string))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	// Owners, collaborators, share link holders and, for public projects,
+	// anyone may join; only those who can edit may send document updates
+	role, err := h.projectRepo.UserRole(c.Request.Context(), projectOID, userOID)
+	if err != nil {
+		h.logger.Error("Failed to check project access", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check project access"})
+		return
+	}
+	if role == "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
 	username, exists := c.Get("username")
 	if !exists {
 		username = "Anonymous"
@@ -76,6 +105,7 @@ func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
 		userID.(string),
 		username.(string),
 		color,
+		models.CanEdit(role),
 		h.logger,
 	)
 
@@ -90,6 +120,7 @@ func (h *WebSocketHandler) HandleConnection(c *gin.Context) {
 		zap.String("user_id", userID.(string)),
 		zap.String("username", username.(string)),
 		zap.String("project_id", projectID),
+		zap.String("role", role),
 	)
 }
 
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Project roles, strongest first
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// ProjectAccess is the part of a project document needed to authorize access
+type ProjectAccess struct {
+	OwnerID       primitive.ObjectID `bson:"owner_id"`
+	Collaborators []struct {
+		UserID primitive.ObjectID `bson:"user_id"`
+		Role   string             `bson:"role"`
+	} `bson:"collaborators"`
+	IsPublic   bool       `bson:"is_public"`
+	ArchivedAt *time.Time `bson:"archived_at"`
+}
+
+// CanEdit reports whether a role may change project content
+func CanEdit(role string) bool {
+	return role == RoleOwner || role == RoleEditor
+}
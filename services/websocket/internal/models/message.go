@@ -30,6 +30,15 @@ const (
 	MessageTypeCompilationCompleted MessageType = "compilation_completed"
 	MessageTypeCompilationFailed    MessageType = "compilation_failed"
 
+	// Comment events, published by the project service
+	MessageTypeCommentThreadCreated MessageType = "comment_thread_created"
+	MessageTypeCommentThreadUpdated MessageType = "comment_thread_updated"
+	MessageTypeCommentThreadDeleted MessageType = "comment_thread_deleted"
+	MessageTypeCommentMention       MessageType = "comment_mention"
+
+	// Lint warnings of a saved file, published by the project service
+	MessageTypeLintResults MessageType = "lint_results"
+
 	// System events
 	MessageTypePing  MessageType = "ping"
 	MessageTypePong  MessageType = "pong"
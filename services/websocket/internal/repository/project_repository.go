@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/texflow/services/websocket/internal/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ProjectRepository reads project membership and share link grants owned by
+// the project service
+type ProjectRepository struct {
+	db       *mongo.Database
+	projects *mongo.Collection
+	grants   *mongo.Collection
+}
+
+// NewProjectRepository creates a new project repository
+func NewProjectRepository(db *mongo.Database) *ProjectRepository {
+	return &ProjectRepository{
+		db:       db,
+		projects: db.Collection("projects"),
+		grants:   db.Collection("project_share_grants"),
+	}
+}
+
+// UserRole returns the role a user holds on a project: owner, editor or
+// viewer, or "" when they have no access. Share link grants count as
+// temporary roles, and archived projects are read-only for everyone.
+func (r *ProjectRepository) UserRole(ctx context.Context, projectID, userID primitive.ObjectID) (string, error) {
+	var project models.ProjectAccess
+	err := r.projects.FindOne(
+		ctx,
+		bson.M{"_id": projectID, "deleted_at": nil},
+		options.FindOne().SetProjection(bson.M{
+			"owner_id":      1,
+			"collaborators": 1,
+			"is_public":     1,
+			"archived_at":   1,
+		}),
+	).Decode(&project)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return "", nil
+		}
+		return "", err
+	}
+
+	role := ""
+	if project.OwnerID == userID {
+		role = models.RoleOwner
+	}
+	for _, collab := range project.Collaborators {
+		if role == "" && collab.UserID == userID {
+			role = collab.Role
+		}
+	}
+
+	if role != models.RoleOwner && role != models.RoleEditor {
+		granted, err := r.grantedRole(ctx, projectID, userID)
+		if err != nil {
+			return "", err
+		}
+		if granted != "" {
+			role = granted
+		}
+	}
+	if role == "" && project.IsPublic {
+		role = models.RoleViewer
+	}
+
+	if role != "" && project.ArchivedAt != nil {
+		role = models.RoleViewer
+	}
+
+	return role, nil
+}
+
+// grantedRole returns the strongest role of the user's unexpired share link
+// grants on a project
+func (r *ProjectRepository) grantedRole(ctx context.Context, projectID, userID primitive.ObjectID) (string, error) {
+	cursor, err := r.grants.Find(ctx, bson.M{
+		"project_id": projectID,
+		"user_id":    userID,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}, options.Find().SetProjection(bson.M{"role": 1}))
+	if err != nil {
+		return "", err
+	}
+	defer cursor.Close(ctx)
+
+	role := ""
+	for cursor.Next(ctx) {
+		var grant struct {
+			Role string `bson:"role"`
+		}
+		if err := cursor.Decode(&grant); err != nil {
+			return "", err
+		}
+		if grant.Role == models.RoleEditor {
+			return grant.Role, nil
+		}
+		role = grant.Role
+	}
+
+	return role, cursor.Err()
+}
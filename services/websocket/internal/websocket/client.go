@@ -18,6 +18,7 @@ type Client struct {
 	userID    string
 	username  string
 	color     string
+	canEdit   bool
 	logger    *zap.Logger
 
 	// Connection settings
@@ -30,7 +31,7 @@ type Client struct {
 }
 
 // NewClient creates a new WebSocket client
-func NewClient(hub *Hub, conn *websocket.Conn, projectID, userID, username, color string, logger *zap.Logger) *Client {
+func NewClient(hub *Hub, conn *websocket.Conn, projectID, userID, username, color string, canEdit bool, logger *zap.Logger) *Client {
 	return &Client{
 		hub:             hub,
 		conn:            conn,
@@ -39,6 +40,7 @@ func NewClient(hub *Hub, conn *websocket.Conn, projectID, userID, username, colo
 		userID:          userID,
 		username:        username,
 		color:           color,
+		canEdit:         canEdit,
 		logger:          logger,
 		readBufferSize:  1024,
 		writeBufferSize: 1024,
@@ -150,6 +152,11 @@ func (c *Client) handleMessage(msg *models.Message) {
 		c.sendMessage(pongMsg)
 
 	case models.MessageTypeYjsUpdate:
+		if !c.canEdit {
+			c.sendError("read_only", "You have read-only access to this project")
+			return
+		}
+
 		// Broadcast Yjs update to all clients in the room
 		c.hub.broadcast <- &BroadcastMessage{
 			roomID:  c.projectID,